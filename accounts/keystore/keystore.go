@@ -0,0 +1,242 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+// Package keystore implements encrypted storage of secp256k1 private keys.
+//
+// Keys are stored as encrypted JSON files according to the Web3 Secret Storage specification.
+// See https://github.com/ethereum/wiki/wiki/Web3-Secret-Storage-Definition for more information.
+package keystore
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/log/v3"
+)
+
+var (
+	ErrLocked  = errors.New("account is locked")
+	ErrNoMatch = errors.New("no key for given address or file")
+
+	// ErrAccountAlreadyExists is returned if an account attempted to import is
+	// already present in the keystore.
+	ErrAccountAlreadyExists = errors.New("account already exists")
+)
+
+// KeyStore manages a key storage directory on disk.
+type KeyStore struct {
+	keydir  string // Root directory of the keystore
+	scryptN int    // Scrypt parameters of the keys stored by the keystore
+	scryptP int
+
+	unlocked map[common.Address]*Key // Currently unlocked accounts (decrypted private keys)
+	mu       sync.RWMutex
+}
+
+// NewKeyStore creates a keystore for the given directory.
+func NewKeyStore(keydir string, scryptN, scryptP int) *KeyStore {
+	keydir, _ = filepath.Abs(keydir)
+	return &KeyStore{
+		keydir:   keydir,
+		scryptN:  scryptN,
+		scryptP:  scryptP,
+		unlocked: make(map[common.Address]*Key),
+	}
+}
+
+// Accounts returns the addresses of the keys present in the directory, in ascending order.
+func (ks *KeyStore) Accounts() []common.Address {
+	files, err := os.ReadDir(ks.keydir)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			log.Warn("Failed to read the keystore", "dir", ks.keydir, "err", err)
+		}
+		return nil
+	}
+	var (
+		seen     = make(map[common.Address]struct{}, len(files))
+		accounts = make([]common.Address, 0, len(files))
+	)
+	for _, fi := range files {
+		if nonKeyFile(fi) {
+			continue
+		}
+		addr, err := readAddress(filepath.Join(ks.keydir, fi.Name()))
+		if err != nil {
+			log.Debug("Failed to decode keystore key", "path", fi.Name(), "err", err)
+			continue
+		}
+		if _, ok := seen[addr]; ok {
+			continue
+		}
+		seen[addr] = struct{}{}
+		accounts = append(accounts, addr)
+	}
+	sort.Slice(accounts, func(i, j int) bool { return bytes.Compare(accounts[i][:], accounts[j][:]) < 0 })
+	return accounts
+}
+
+// HasAddress reports whether a key with the given address is present.
+func (ks *KeyStore) HasAddress(addr common.Address) bool {
+	_, err := ks.find(addr)
+	return err == nil
+}
+
+// NewAccount generates a new key and stores it into the key directory,
+// encrypting it with the passphrase.
+func (ks *KeyStore) NewAccount(passphrase string) (common.Address, error) {
+	key, err := newKey()
+	if err != nil {
+		return common.Address{}, err
+	}
+	if err := ks.storeKey(key, passphrase); err != nil {
+		return common.Address{}, err
+	}
+	return key.Address, nil
+}
+
+// ImportECDSA stores the given key into the key directory, encrypting it with the passphrase.
+func (ks *KeyStore) ImportECDSA(priv *ecdsa.PrivateKey, passphrase string) (common.Address, error) {
+	key := newKeyFromECDSA(priv)
+	if ks.HasAddress(key.Address) {
+		return common.Address{}, ErrAccountAlreadyExists
+	}
+	if err := ks.storeKey(key, passphrase); err != nil {
+		return common.Address{}, err
+	}
+	return key.Address, nil
+}
+
+// Unlock unlocks the given account indefinitely.
+func (ks *KeyStore) Unlock(addr common.Address, passphrase string) error {
+	key, err := ks.getDecryptedKey(addr, passphrase)
+	if err != nil {
+		return err
+	}
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.unlocked[addr] = key
+	return nil
+}
+
+// Lock removes the private key with the given address from memory.
+func (ks *KeyStore) Lock(addr common.Address) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	delete(ks.unlocked, addr)
+}
+
+// SignHash calculates a ECDSA signature for the given hash. The produced
+// signature is in the [R || S || V] format where V is 0 or 1.
+func (ks *KeyStore) SignHash(addr common.Address, hash []byte) ([]byte, error) {
+	// Look up the key to sign with and abort if it cannot be found
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	unlockedKey, found := ks.unlocked[addr]
+	if !found {
+		return nil, ErrLocked
+	}
+	// Sign the hash using plain ECDSA operations
+	return crypto.Sign(hash, unlockedKey.PrivateKey)
+}
+
+func (ks *KeyStore) getDecryptedKey(addr common.Address, auth string) (*Key, error) {
+	path, err := ks.find(addr)
+	if err != nil {
+		return nil, err
+	}
+	keyjson, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := DecryptKey(keyjson, auth)
+	if err != nil {
+		return nil, err
+	}
+	// Make sure we're really operating on the requested key (no swap attacks)
+	if key.Address != addr {
+		return nil, ErrNoMatch
+	}
+	return key, nil
+}
+
+func (ks *KeyStore) storeKey(key *Key, auth string) error {
+	keyjson, err := EncryptKey(key, auth, ks.scryptN, ks.scryptP)
+	if err != nil {
+		return err
+	}
+	return writeKeyFile(filepath.Join(ks.keydir, keyFileName(key.Address)), keyjson)
+}
+
+// find returns the path of the key file of the address.
+func (ks *KeyStore) find(addr common.Address) (string, error) {
+	files, err := os.ReadDir(ks.keydir)
+	if err != nil && !os.IsNotExist(err) {
+		return "", err
+	}
+	for _, fi := range files {
+		if nonKeyFile(fi) {
+			continue
+		}
+		path := filepath.Join(ks.keydir, fi.Name())
+		if a, err := readAddress(path); err == nil && a == addr {
+			return path, nil
+		}
+	}
+	return "", ErrNoMatch
+}
+
+// readAddress reads the address of the key file, without decrypting the key.
+func readAddress(path string) (common.Address, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return common.Address{}, err
+	}
+	var key struct {
+		Address string `json:"address"`
+	}
+	if err := json.Unmarshal(content, &key); err != nil {
+		return common.Address{}, err
+	}
+	addr := common.HexToAddress(key.Address)
+	if addr == (common.Address{}) {
+		return common.Address{}, errors.New("missing or zero address")
+	}
+	return addr, nil
+}
+
+// nonKeyFile ignores editor backups, hidden files and folders/symlinks.
+func nonKeyFile(fi os.DirEntry) bool {
+	// Skip editor backups and UNIX-style hidden files.
+	if strings.HasSuffix(fi.Name(), "~") || strings.HasPrefix(fi.Name(), ".") {
+		return true
+	}
+	// Skip misc special files, directories (yes, symlinks too).
+	if fi.IsDir() || !fi.Type().IsRegular() {
+		return true
+	}
+	return false
+}
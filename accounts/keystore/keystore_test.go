@@ -0,0 +1,125 @@
+// Copyright 2017 The go-ethereum Authors
+// This file is part of the go-ethereum library.
+//
+// The go-ethereum library is free software: you can redistribute it and/or modify
+// it under the terms of the GNU Lesser General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// The go-ethereum library is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU Lesser General Public License for more details.
+//
+// You should have received a copy of the GNU Lesser General Public License
+// along with the go-ethereum library. If not, see <http://www.gnu.org/licenses/>.
+
+package keystore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/crypto"
+)
+
+const (
+	veryLightScryptN = 2
+	veryLightScryptP = 1
+)
+
+func TestKeyStore(t *testing.T) {
+	dir := t.TempDir()
+	ks := NewKeyStore(dir, veryLightScryptN, veryLightScryptP)
+
+	a, err := ks.NewAccount("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ks.HasAddress(a) {
+		t.Errorf("HasAccount(%x) should've returned true", a)
+	}
+	if accounts := ks.Accounts(); len(accounts) != 1 || accounts[0] != a {
+		t.Errorf("Accounts() = %x, want [%x]", accounts, a)
+	}
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 {
+		t.Fatalf("want one key file, got %d", len(files))
+	}
+	if stat, err := os.Stat(filepath.Join(dir, files[0].Name())); err != nil {
+		t.Fatal(err)
+	} else if stat.Mode()&0077 != 0 {
+		t.Errorf("account file %s has wrong mode: got %o, want %o", files[0].Name(), stat.Mode(), 0600)
+	}
+}
+
+func TestSignHash(t *testing.T) {
+	ks := NewKeyStore(t.TempDir(), veryLightScryptN, veryLightScryptP)
+
+	a, err := ks.NewAccount("foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	hash := crypto.Keccak256([]byte("payload"))
+	if _, err := ks.SignHash(a, hash); err != ErrLocked {
+		t.Fatalf("signing with a locked key: got %v, want %v", err, ErrLocked)
+	}
+	if err := ks.Unlock(a, "bar"); err != ErrDecrypt {
+		t.Fatalf("unlocking with a wrong passphrase: got %v, want %v", err, ErrDecrypt)
+	}
+	if err := ks.Unlock(common.Address{1}, "foo"); err != ErrNoMatch {
+		t.Fatalf("unlocking a missing account: got %v, want %v", err, ErrNoMatch)
+	}
+	if err := ks.Unlock(a, "foo"); err != nil {
+		t.Fatal(err)
+	}
+	sig, err := ks.SignHash(a, hash)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pub, err := crypto.SigToPub(hash, sig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if signer := crypto.PubkeyToAddress(*pub); signer != a {
+		t.Errorf("signed by %x, want %x", signer, a)
+	}
+	ks.Lock(a)
+	if _, err := ks.SignHash(a, hash); err != ErrLocked {
+		t.Fatalf("signing after lock: got %v, want %v", err, ErrLocked)
+	}
+}
+
+func TestImportECDSA(t *testing.T) {
+	dir := t.TempDir()
+	ks := NewKeyStore(dir, veryLightScryptN, veryLightScryptP)
+
+	key, err := crypto.GenerateKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, err := ks.ImportECDSA(key, "foo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := crypto.PubkeyToAddress(key.PublicKey); a != want {
+		t.Errorf("imported %x, want %x", a, want)
+	}
+	if _, err := ks.ImportECDSA(key, "foo"); err != ErrAccountAlreadyExists {
+		t.Errorf("importing twice: got %v, want %v", err, ErrAccountAlreadyExists)
+	}
+
+	// the key is found by a keystore reopening the directory
+	ks = NewKeyStore(dir, veryLightScryptN, veryLightScryptP)
+	if err := ks.Unlock(a, "foo"); err != nil {
+		t.Fatal(err)
+	}
+	if ks.unlocked[a].PrivateKey.D.Cmp(key.D) != 0 {
+		t.Error("decrypted key doesn't match the imported one")
+	}
+}
@@ -20,7 +20,7 @@ func RunDaemon() {
 		ctx := cmd.Context()
 		logger := log.New()
 		time.Sleep(100 * time.Millisecond)
-		db, borDb, backend, txPool, mining, starknet, stateCache, blockReader, ff, agg, txNums, err := cli.RemoteServices(ctx, *cfg, logger, rootCancel)
+		db, borDb, parliaDb, tracesDb, backend, txPool, mining, starknet, stateCache, blockReader, ff, agg, txNums, err := cli.RemoteServices(ctx, *cfg, logger, rootCancel)
 		if err != nil {
 			log.Error("Could not connect to DB", "err", err)
 			return nil
@@ -29,8 +29,14 @@ func RunDaemon() {
 		if borDb != nil {
 			defer borDb.Close()
 		}
+		if parliaDb != nil {
+			defer parliaDb.Close()
+		}
+		if tracesDb != nil {
+			defer tracesDb.Close()
+		}
 
-		apiList := commands.APIList(db, borDb, backend, txPool, mining, starknet, ff, stateCache, blockReader, agg, txNums, *cfg)
+		apiList := commands.APIList(db, borDb, parliaDb, tracesDb, backend, txPool, mining, starknet, ff, stateCache, blockReader, agg, txNums, *cfg)
 		if err := cli.StartRpcServer(ctx, *cfg, apiList, nil); err != nil {
 			log.Error(err.Error())
 			return nil
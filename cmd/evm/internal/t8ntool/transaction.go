@@ -0,0 +1,173 @@
+// Copyright 2021 The go-ethereum Authors
+// This file is part of go-ethereum.
+//
+// go-ethereum is free software: you can redistribute it and/or modify
+// it under the terms of the GNU General Public License as published by
+// the Free Software Foundation, either version 3 of the License, or
+// (at your option) any later version.
+//
+// go-ethereum is distributed in the hope that it will be useful,
+// but WITHOUT ANY WARRANTY; without even the implied warranty of
+// MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE. See the
+// GNU General Public License for more details.
+//
+// You should have received a copy of the GNU General Public License
+// along with go-ethereum. If not, see <http://www.gnu.org/licenses/>.
+
+package t8ntool
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/ledgerwatch/erigon/tests"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/urfave/cli"
+)
+
+type result struct {
+	Error        error
+	Address      common.Address
+	Hash         common.Hash
+	IntrinsicGas uint64
+}
+
+// MarshalJSON marshals as JSON with a hash.
+func (r *result) MarshalJSON() ([]byte, error) {
+	type xx struct {
+		Err          string          `json:"error,omitempty"`
+		Address      *common.Address `json:"address,omitempty"`
+		Hash         *common.Hash    `json:"hash,omitempty"`
+		IntrinsicGas hexutil.Uint64  `json:"intrinsicGas,omitempty"`
+	}
+	var out xx
+	if r.Error != nil {
+		out.Err = r.Error.Error()
+	}
+	if r.Address != (common.Address{}) {
+		out.Address = &r.Address
+	}
+	if r.Hash != (common.Hash{}) {
+		out.Hash = &r.Hash
+	}
+	out.IntrinsicGas = hexutil.Uint64(r.IntrinsicGas)
+	return json.Marshal(out)
+}
+
+// Transaction validates the transactions against the rules of the fork, reporting their sender, hash and intrinsic
+// gas. The transactions are either an rlp list of signed transactions, in a file with the .rlp extension or in the
+// txsRlp field of stdin, or json transactions, which are signed if they have a secretKey and no signature.
+func Transaction(ctx *cli.Context) error {
+	log.Root().SetHandler(log.LvlFilterHandler(log.Lvl(ctx.Int(VerbosityFlag.Name)), log.StderrHandler))
+
+	var (
+		txStr       = ctx.String(InputTxsFlag.Name)
+		inputData   = &input{}
+		chainConfig *params.ChainConfig
+	)
+	// Construct the chainconfig
+	if cConf, _, err := tests.GetChainConfig(ctx.String(ForknameFlag.Name)); err != nil {
+		return NewError(ErrorVMConfig, fmt.Errorf("failed constructing chain configuration: %v", err))
+	} else { //nolint:golint
+		chainConfig = cConf
+	}
+	// Set the chain id
+	chainConfig.ChainID = big.NewInt(ctx.Int64(ChainIDFlag.Name))
+	signer := types.MakeSigner(chainConfig, 0)
+
+	var body hexutil.Bytes
+	var txsWithKeys []*txWithKey
+	if txStr == stdinSelector {
+		decoder := json.NewDecoder(os.Stdin)
+		if err := decoder.Decode(inputData); err != nil {
+			return NewError(ErrorJson, fmt.Errorf("failed unmarshaling input: %v", err))
+		}
+		// Decode the body of already signed transactions
+		body = common.FromHex(inputData.TxRlp)
+		txsWithKeys = inputData.Txs
+	} else if strings.HasSuffix(txStr, ".rlp") {
+		if err := readFile(txStr, "txs", &body); err != nil {
+			return err
+		}
+	} else if err := readFile(txStr, "txs", &txsWithKeys); err != nil {
+		return err
+	}
+
+	var results []result
+	if len(body) > 0 {
+		// We now have the transactions in 'body', which is supposed to be an rlp list of transactions
+		it, err := rlp.NewListIterator([]byte(body))
+		if err != nil {
+			return NewError(ErrorRlp, err)
+		}
+		for it.Next() {
+			if err := it.Err(); err != nil {
+				return NewError(ErrorIO, err)
+			}
+			tx, err := types.UnmarshalTransactionFromBinary(it.Value())
+			if err != nil {
+				results = append(results, result{Error: err})
+				continue
+			}
+			results = append(results, validateTransaction(chainConfig, signer, tx))
+		}
+	}
+	txs, err := signUnsignedTransactions(txsWithKeys, *signer)
+	if err != nil {
+		return NewError(ErrorJson, fmt.Errorf("failed signing transactions: %v", err))
+	}
+	for _, tx := range txs {
+		results = append(results, validateTransaction(chainConfig, signer, tx))
+	}
+
+	out, err := json.MarshalIndent(results, "", "  ")
+	if err != nil {
+		return NewError(ErrorJson, fmt.Errorf("failed marshalling output: %v", err))
+	}
+	fmt.Println(string(out))
+	return nil
+}
+
+// validateTransaction checks the signature, the intrinsic gas and the fee fields of the transaction
+func validateTransaction(chainConfig *params.ChainConfig, signer *types.Signer, tx types.Transaction) result {
+	r := result{Hash: tx.Hash()}
+	sender, err := tx.Sender(*signer)
+	if err != nil {
+		r.Error = err
+		return r
+	}
+	r.Address = sender
+	// Check intrinsic gas
+	gas, err := core.IntrinsicGas(tx.GetData(), tx.GetAccessList(), tx.GetTo() == nil,
+		chainConfig.IsHomestead(0), chainConfig.IsIstanbul(0))
+	if err != nil {
+		r.Error = err
+		return r
+	}
+	r.IntrinsicGas = gas
+	if tx.GetGas() < gas {
+		r.Error = fmt.Errorf("%w: have %d, want %d", core.ErrIntrinsicGas, tx.GetGas(), gas)
+		return r
+	}
+	// Validate the fields, the fees fitting in 256 bits by construction
+	switch _, overflow := new(uint256.Int).MulOverflow(tx.GetFeeCap(), uint256.NewInt(tx.GetGas())); {
+	case tx.GetNonce()+1 < tx.GetNonce():
+		r.Error = errors.New("nonce exceeds 2^64-1")
+	case tx.GetFeeCap().Lt(tx.GetTip()):
+		r.Error = errors.New("maxFeePerGas < maxPriorityFeePerGas")
+	case overflow:
+		r.Error = errors.New("gas * maxFeePerGas exceeds 256 bits")
+	}
+	return r
+}
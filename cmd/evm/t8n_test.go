@@ -196,6 +196,22 @@ func TestT8n(t *testing.T) {
 			expOut: "exp_arrowglacier.json",
 			output: t8nOutput{alloc: true, result: true},
 		},
+		{ // BSC forks active from the genesis, no system contract upgrade
+			base: "./testdata/13",
+			input: t8nInput{
+				"alloc.json", "txs.json", "env.json", "Bruno",
+			},
+			expOut: "exp.json",
+			output: t8nOutput{result: true},
+		},
+		{ // System contract upgrades of the Euler fork
+			base: "./testdata/13",
+			input: t8nInput{
+				"alloc.json", "txs.json", "env.json", "BrunoToEulerAt5",
+			},
+			expOut: "exp_euler.json",
+			output: t8nOutput{result: true},
+		},
 	} {
 
 		args := []string{"t8n"}
@@ -233,6 +249,175 @@ func TestT8n(t *testing.T) {
 	}
 }
 
+type t9nInput struct {
+	inTxs  string
+	stFork string
+}
+
+func (args *t9nInput) get(base string) []string {
+	var out []string
+	if opt := args.inTxs; opt != "" {
+		out = append(out, "--input.txs")
+		out = append(out, fmt.Sprintf("%v/%v", base, opt))
+	}
+	if opt := args.stFork; opt != "" {
+		out = append(out, "--state.fork", opt)
+	}
+	return out
+}
+
+func TestT9n(t *testing.T) {
+	tt := new(testT8n)
+	tt.TestCmd = cmdtest.NewTestCmd(t, tt)
+	for i, tc := range []struct {
+		base        string
+		input       t9nInput
+		expExitCode int
+		expOut      string
+	}{
+		{ // London txs on London
+			base: "./testdata/15",
+			input: t9nInput{
+				inTxs:  "signed_txs.rlp",
+				stFork: "London",
+			},
+			expOut: "exp.json",
+		},
+		{ // London txs on Berlin
+			base: "./testdata/15",
+			input: t9nInput{
+				inTxs:  "signed_txs.rlp",
+				stFork: "Berlin",
+			},
+			expOut: "exp2.json",
+		},
+		{ // json txs signed by the tool
+			base: "./testdata/15",
+			input: t9nInput{
+				inTxs:  "txs.json",
+				stFork: "London",
+			},
+			expOut: "exp3.json",
+		},
+		{ // typed txs can't be signed on Frontier
+			base: "./testdata/15",
+			input: t9nInput{
+				inTxs:  "txs.json",
+				stFork: "Frontier",
+			},
+			expExitCode: 10,
+		},
+	} {
+
+		args := []string{"t9n"}
+		args = append(args, tc.input.get(tc.base)...)
+
+		tt.Logf("args: %v\n", strings.Join(args, " "))
+		tt.Run("evm-test", args...)
+		// Compare the expected output, if provided
+		if tc.expOut != "" {
+			want, err := os.ReadFile(fmt.Sprintf("%v/%v", tc.base, tc.expOut))
+			if err != nil {
+				t.Fatalf("test %d: could not read expected output: %v", i, err)
+			}
+			have := tt.Output()
+			ok, err := cmpJson(have, want)
+			switch {
+			case err != nil:
+				t.Log(string(have))
+				t.Fatalf("test %d, json parsing failed: %v", i, err)
+			case !ok:
+				t.Fatalf("test %d: output wrong, have \n%v\nwant\n%v\n", i, string(have), string(want))
+			}
+		}
+		tt.WaitExit()
+		if have, want := tt.ExitStatus(), tc.expExitCode; have != want {
+			t.Fatalf("test %d: wrong exit code, have %d, want %d", i, have, want)
+		}
+	}
+}
+
+type b11rInput struct {
+	inEnv    string
+	inOmmers string
+	inTxs    string
+}
+
+func (args *b11rInput) get(base string) []string {
+	var out []string
+	if opt := args.inEnv; opt != "" {
+		out = append(out, "--input.header")
+		out = append(out, fmt.Sprintf("%v/%v", base, opt))
+	}
+	if opt := args.inOmmers; opt != "" {
+		out = append(out, "--input.ommers")
+		out = append(out, fmt.Sprintf("%v/%v", base, opt))
+	}
+	if opt := args.inTxs; opt != "" {
+		out = append(out, "--input.txs")
+		out = append(out, fmt.Sprintf("%v/%v", base, opt))
+	}
+	out = append(out, "--output.block")
+	out = append(out, "stdout")
+	return out
+}
+
+func TestB11r(t *testing.T) {
+	tt := new(testT8n)
+	tt.TestCmd = cmdtest.NewTestCmd(t, tt)
+	for i, tc := range []struct {
+		base        string
+		input       b11rInput
+		expExitCode int
+		expOut      string
+	}{
+		{ // block with ommers
+			base: "./testdata/20",
+			input: b11rInput{
+				inEnv:    "header.json",
+				inOmmers: "ommers.json",
+				inTxs:    "txs.rlp",
+			},
+			expOut: "exp.json",
+		},
+		{ // missing header
+			base: "./testdata/20",
+			input: b11rInput{
+				inEnv: "missing.json",
+				inTxs: "txs.rlp",
+			},
+			expExitCode: 11,
+		},
+	} {
+
+		args := []string{"b11r"}
+		args = append(args, tc.input.get(tc.base)...)
+
+		tt.Logf("args: %v\n", strings.Join(args, " "))
+		tt.Run("evm-test", args...)
+		// Compare the expected output, if provided
+		if tc.expOut != "" {
+			want, err := os.ReadFile(fmt.Sprintf("%v/%v", tc.base, tc.expOut))
+			if err != nil {
+				t.Fatalf("test %d: could not read expected output: %v", i, err)
+			}
+			have := tt.Output()
+			ok, err := cmpJson(have, want)
+			switch {
+			case err != nil:
+				t.Log(string(have))
+				t.Fatalf("test %d, json parsing failed: %v", i, err)
+			case !ok:
+				t.Fatalf("test %d: output wrong, have \n%v\nwant\n%v\n", i, string(have), string(want))
+			}
+		}
+		tt.WaitExit()
+		if have, want := tt.ExitStatus(), tc.expExitCode; have != want {
+			t.Fatalf("test %d: wrong exit code, have %d, want %d", i, have, want)
+		}
+	}
+}
+
 // cmpJson compares the JSON in two byte slices.
 func cmpJson(a, b []byte) (bool, error) {
 	var j, j2 interface{}
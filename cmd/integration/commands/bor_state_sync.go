@@ -0,0 +1,146 @@
+package commands
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	common2 "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/cmd/hack/tool"
+	"github.com/ledgerwatch/erigon/consensus/bor"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/spf13/cobra"
+)
+
+var stateSyncFrom, stateSyncTo uint64
+
+var cmdVerifyStateSync = &cobra.Command{
+	Use:   "verify_state_sync",
+	Short: "Re-execute the state-sync of Bor from the events in the Heimdall cache, without Heimdall, and print the differences with the execution of the node",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, _ := common2.RootContext()
+		logger := log.New()
+		db := openDB(dbCfg(kv.ChainDB, chaindata).Readonly(), false)
+		defer db.Close()
+
+		if tool.HistoryV2FromDB(db) {
+			return fmt.Errorf("verify_state_sync doesn't support history v2 yet")
+		}
+		chainConfig, pm := tool.ChainConfigFromDB(db), tool.PruneModeFromDB(db)
+		if chainConfig.Bor == nil {
+			return fmt.Errorf("verify_state_sync needs a Bor chain, not %s", chainConfig.ChainName)
+		}
+		engine, ok := initConsensusEngine(chainConfig, logger, allSnapshots(db), datadirCli).(*bor.Bor)
+		if !ok {
+			return fmt.Errorf("verify_state_sync needs the Bor consensus engine")
+		}
+		defer engine.Close()
+		// the spans and the events come from what the node cached, Heimdall isn't trusted
+		engine.SetHeimdallClient(bor.NewStoredHeimdallClient(engine.DB))
+		blockReader := getBlockReader(db)
+
+		if err := db.View(ctx, func(tx kv.Tx) error {
+			// the blocks are re-executed on the history of the state
+			to, err := stages.GetStageProgress(tx, stages.Execution)
+			if err != nil {
+				return err
+			}
+			historyTo, err := stages.GetStageProgress(tx, stages.AccountHistoryIndex)
+			if err != nil {
+				return err
+			}
+			if historyTo < to {
+				to = historyTo
+			}
+			if stateSyncTo != 0 && stateSyncTo < to {
+				to = stateSyncTo
+			}
+			from := pm.History.PruneTo(to)
+			if stateSyncFrom > from {
+				from = stateSyncFrom
+			}
+
+			w := new(tabwriter.Writer)
+			defer w.Flush()
+			w.Init(os.Stdout, 8, 8, 1, '\t', 0)
+			fmt.Fprintf(w, "State-sync of the sprints ending in blocks %d-%d, the history of the other blocks is pruned or not indexed\n\n", from, to)
+			fmt.Fprint(w, "block\tevents\tmismatch\n")
+
+			logEvery := time.NewTicker(20 * time.Second)
+			defer logEvery.Stop()
+			chain := stagedsync.ChainReader{Cfg: *chainConfig, Db: tx}
+			sprint := chainConfig.Bor.Sprint
+			var sprints, mismatched, unverified int
+			for number := (from + sprint - 1) / sprint * sprint; number <= to; number += sprint {
+				if number == 0 {
+					continue
+				}
+				hash, err := rawdb.ReadCanonicalHash(tx, number)
+				if err != nil {
+					return err
+				}
+				block, _, err := blockReader.BlockWithSenders(ctx, tx, hash, number)
+				if err != nil {
+					return err
+				}
+				if block == nil {
+					return fmt.Errorf("block %d not found", number)
+				}
+				check, err := engine.VerifyStateSync(tx, block, chain)
+				switch {
+				case errors.Is(err, bor.ErrNotInHeimdallCache):
+					// the node didn't cache what it fetched from Heimdall for this sprint
+					unverified++
+					fmt.Fprintf(w, "%d\t?\tnot verified: %v\n", number, err)
+				case err != nil:
+					return err
+				default:
+					sprints++
+					events := "-"
+					if len(check.Events) > 0 {
+						events = fmt.Sprintf("%d-%d", check.Events[0], check.Events[len(check.Events)-1])
+					}
+					if len(check.Mismatches) == 0 {
+						fmt.Fprintf(w, "%d\t%s\t-\n", number, events)
+					} else {
+						mismatched++
+						for _, mismatch := range check.Mismatches {
+							fmt.Fprintf(w, "%d\t%s\t%s\n", number, events, mismatch)
+						}
+					}
+				}
+
+				select {
+				case <-ctx.Done():
+					return ctx.Err()
+				case <-logEvery.C:
+					log.Info("Verifying state-sync", "block", number, "sprints", sprints, "mismatched", mismatched, "unverified", unverified)
+				default:
+				}
+			}
+			fmt.Fprintf(w, "\n%d sprints verified, %d with mismatches, %d not verified for lack of cached Heimdall data\n", sprints, mismatched, unverified)
+			if mismatched > 0 {
+				return fmt.Errorf("the state-sync of %d sprints differs from the execution of the node", mismatched)
+			}
+			return nil
+		}); err != nil {
+			log.Error("Error", "err", err)
+			return err
+		}
+		return nil
+	},
+}
+
+func init() {
+	withDataDir(cmdVerifyStateSync)
+	withChain(cmdVerifyStateSync)
+	cmdVerifyStateSync.Flags().Uint64Var(&stateSyncFrom, "from", 0, "first block to verify, the first one with history by default")
+	cmdVerifyStateSync.Flags().Uint64Var(&stateSyncTo, "to", 0, "last block to verify, the last executed one by default")
+	rootCmd.AddCommand(cmdVerifyStateSync)
+}
@@ -0,0 +1,72 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	common2 "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/cmd/hack/tool"
+	"github.com/ledgerwatch/erigon/core/systemcontracts"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/spf13/cobra"
+)
+
+var cmdSystemContractUpgrades = &cobra.Command{
+	Use:   "system_contract_upgrades",
+	Short: "Print the upgrades of the system contracts applied by the hard forks, with the code they replaced",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		ctx, _ := common2.RootContext()
+		db := openDB(dbCfg(kv.ChainDB, chaindata).Readonly(), false)
+		defer db.Close()
+
+		if tool.HistoryV2FromDB(db) {
+			return fmt.Errorf("system_contract_upgrades doesn't support history v2 yet")
+		}
+		chainConfig, pm := tool.ChainConfigFromDB(db), tool.PruneModeFromDB(db)
+		if err := db.View(ctx, func(tx kv.Tx) error {
+			// the accounts of the upgraded contracts are read from the history of the state
+			to, err := stages.GetStageProgress(tx, stages.Execution)
+			if err != nil {
+				return err
+			}
+			historyTo, err := stages.GetStageProgress(tx, stages.AccountHistoryIndex)
+			if err != nil {
+				return err
+			}
+			if historyTo < to {
+				to = historyTo
+			}
+			from := pm.History.PruneTo(to)
+			report, err := systemcontracts.ReportUpgrades(tx, chainConfig, from, to)
+			if err != nil {
+				return err
+			}
+			printSystemContractUpgrades(report, from, to)
+			return nil
+		}); err != nil {
+			log.Error("Error", "err", err)
+			return err
+		}
+		return nil
+	},
+}
+
+func init() {
+	withDataDir(cmdSystemContractUpgrades)
+	withChain(cmdSystemContractUpgrades)
+	rootCmd.AddCommand(cmdSystemContractUpgrades)
+}
+
+func printSystemContractUpgrades(report []systemcontracts.ContractUpgrade, from, to uint64) {
+	w := new(tabwriter.Writer)
+	defer w.Flush()
+	w.Init(os.Stdout, 8, 8, 1, '\t', 0)
+	fmt.Fprintf(w, "Upgrades applied in blocks %d-%d, the history of the other blocks is pruned or not indexed\n\n", from, to)
+	fmt.Fprint(w, "block\tupgrade\tcontract\told_code_hash\told_size\tnew_code_hash\tnew_size\tchanged\tconfigured_before_upgrade\tconfigured_after_upgrade\n")
+	for _, u := range report {
+		fmt.Fprintf(w, "%d\t%s\t%x\t%x\t%d\t%x\t%d\t%t\t%s\t%s\n", u.Block, u.Upgrade, u.Contract, u.OldCodeHash, u.OldCodeSize, u.NewCodeHash, u.NewCodeSize, u.Changed, u.ConfiguredBeforeUpgrade, u.ConfiguredAfterUpgrade)
+	}
+}
@@ -17,6 +17,7 @@ import (
 	libstate "github.com/ledgerwatch/erigon-lib/state"
 	"github.com/ledgerwatch/erigon/cmd/state/exec22"
 	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/ethdb/tracedb"
 	"github.com/ledgerwatch/erigon/rpc/rpccfg"
 
 	"github.com/ledgerwatch/erigon-lib/direct"
@@ -60,6 +61,8 @@ var rootCmd = &cobra.Command{
 	Short: "rpcdaemon is JSON RPC server that connects to Erigon node for remote DB access",
 }
 
+var rpcLimitsCosts, rpcLimitsAPIKeys string
+
 func RootCommand() (*cobra.Command, *httpcfg.HttpCfg) {
 	utils.CobraFlags(rootCmd, append(debug.Flags, utils.MetricFlags...))
 
@@ -77,11 +80,19 @@ func RootCommand() (*cobra.Command, *httpcfg.HttpCfg) {
 	rootCmd.PersistentFlags().StringSliceVar(&cfg.API, "http.api", []string{"eth", "erigon"}, "API's offered over the HTTP-RPC interface: eth,erigon,web3,net,debug,trace,txpool,db,starknet. Supported methods: https://github.com/ledgerwatch/erigon/tree/devel/cmd/rpcdaemon")
 	rootCmd.PersistentFlags().Uint64Var(&cfg.Gascap, "rpc.gascap", 50000000, "Sets a cap on gas that can be used in eth_call/estimateGas")
 	rootCmd.PersistentFlags().Uint64Var(&cfg.MaxTraces, "trace.maxtraces", 200, "Sets a limit on traces that can be returned in trace_filter")
+	rootCmd.PersistentFlags().IntVar(&cfg.MaxGetProofRewindBlockCount, utils.RpcMaxGetProofRewindBlockCountFlag.Name, utils.RpcMaxGetProofRewindBlockCountFlag.Value, utils.RpcMaxGetProofRewindBlockCountFlag.Usage)
 	rootCmd.PersistentFlags().BoolVar(&cfg.WebsocketEnabled, "ws", false, "Enable Websockets")
 	rootCmd.PersistentFlags().BoolVar(&cfg.WebsocketCompression, "ws.compression", false, "Enable Websocket compression (RFC 7692)")
 	rootCmd.PersistentFlags().StringVar(&cfg.RpcAllowListFilePath, "rpc.accessList", "", "Specify granular (method-by-method) API allowlist")
 	rootCmd.PersistentFlags().UintVar(&cfg.RpcBatchConcurrency, utils.RpcBatchConcurrencyFlag.Name, 2, utils.RpcBatchConcurrencyFlag.Usage)
 	rootCmd.PersistentFlags().BoolVar(&cfg.RpcStreamingDisable, utils.RpcStreamingDisableFlag.Name, false, utils.RpcStreamingDisableFlag.Usage)
+	rootCmd.PersistentFlags().IntVar(&cfg.RpcLimits.Rate, utils.RpcLimitsRateFlag.Name, 0, utils.RpcLimitsRateFlag.Usage)
+	rootCmd.PersistentFlags().IntVar(&cfg.RpcLimits.Burst, utils.RpcLimitsBurstFlag.Name, 0, utils.RpcLimitsBurstFlag.Usage)
+	rootCmd.PersistentFlags().StringVar(&rpcLimitsCosts, utils.RpcLimitsCostsFlag.Name, "", utils.RpcLimitsCostsFlag.Usage)
+	rootCmd.PersistentFlags().IntVar(&cfg.RpcLimits.MaxBatchSize, utils.RpcLimitsBatchFlag.Name, 0, utils.RpcLimitsBatchFlag.Usage)
+	rootCmd.PersistentFlags().IntVar(&cfg.RpcLimits.MaxResponseSize, utils.RpcLimitsResponseFlag.Name, 0, utils.RpcLimitsResponseFlag.Usage)
+	rootCmd.PersistentFlags().StringVar(&cfg.RpcLimits.APIKeyHeader, utils.RpcLimitsAPIKeyHeaderFlag.Name, "", utils.RpcLimitsAPIKeyHeaderFlag.Usage)
+	rootCmd.PersistentFlags().StringVar(&rpcLimitsAPIKeys, utils.RpcLimitsAPIKeysFlag.Name, "", utils.RpcLimitsAPIKeysFlag.Usage)
 	rootCmd.PersistentFlags().IntVar(&cfg.DBReadConcurrency, utils.DBReadConcurrencyFlag.Name, utils.DBReadConcurrencyFlag.Value, utils.DBReadConcurrencyFlag.Usage)
 	rootCmd.PersistentFlags().BoolVar(&cfg.TraceCompatibility, "trace.compat", false, "Bug for bug compatibility with OE for trace_ routines")
 	rootCmd.PersistentFlags().StringVar(&cfg.TxPoolApiAddr, "txpool.api.addr", "", "txpool api network address, for example: 127.0.0.1:9090 (default: use value of --private.api.addr)")
@@ -119,6 +130,19 @@ func RootCommand() (*cobra.Command, *httpcfg.HttpCfg) {
 		if cfg.TxPoolApiAddr == "" {
 			cfg.TxPoolApiAddr = cfg.PrivateApiAddr
 		}
+		costs, err := rpccfg.ParseMethodCosts(rpcLimitsCosts)
+		if err != nil {
+			return fmt.Errorf("invalid %s: %w", utils.RpcLimitsCostsFlag.Name, err)
+		}
+		cfg.RpcLimits.MethodCosts = costs
+		if cfg.RpcLimits.APIKeyHeader != "" {
+			if rpcLimitsAPIKeys == "" {
+				return fmt.Errorf("%s requires %s", utils.RpcLimitsAPIKeyHeaderFlag.Name, utils.RpcLimitsAPIKeysFlag.Name)
+			}
+			if cfg.RpcLimits.APIKeys, err = rpccfg.ReadAPIKeys(rpcLimitsAPIKeys); err != nil {
+				return fmt.Errorf("invalid %s: %w", utils.RpcLimitsAPIKeysFlag.Name, err)
+			}
+		}
 		return nil
 	}
 	rootCmd.PersistentPostRunE = func(cmd *cobra.Command, args []string) error {
@@ -261,7 +285,7 @@ func EmbeddedServices(ctx context.Context,
 // RemoteServices - use when RPCDaemon run as independent process. Still it can use --datadir flag to enable
 // `cfg.WithDatadir` (mode when it on 1 machine with Erigon)
 func RemoteServices(ctx context.Context, cfg httpcfg.HttpCfg, logger log.Logger, rootCancel context.CancelFunc) (
-	db kv.RoDB, borDb kv.RoDB,
+	db kv.RoDB, borDb kv.RoDB, parliaDb kv.RoDB, tracesDb kv.RoDB,
 	eth rpchelper.ApiBackend, txPool txpool.TxpoolClient, mining txpool.MiningClient,
 	starknet *rpcservices.StarknetService,
 	stateCache kvcache.Cache, blockReader services.FullBlockReader,
@@ -270,7 +294,7 @@ func RemoteServices(ctx context.Context, cfg httpcfg.HttpCfg, logger log.Logger,
 	txNums *exec22.TxNums,
 	err error) {
 	if !cfg.WithDatadir && cfg.PrivateApiAddr == "" {
-		return nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, fmt.Errorf("either remote db or local db must be specified")
+		return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, fmt.Errorf("either remote db or local db must be specified")
 	}
 
 	// Do not change the order of these checks. Chaindata needs to be checked first, because PrivateApiAddr has default value which is not ""
@@ -281,10 +305,10 @@ func RemoteServices(ctx context.Context, cfg httpcfg.HttpCfg, logger log.Logger,
 		limiter := semaphore.NewWeighted(int64(cfg.DBReadConcurrency))
 		rwKv, err = kv2.NewMDBX(logger).RoTxsLimiter(limiter).Path(cfg.Dirs.Chaindata).Readonly().Open()
 		if err != nil {
-			return nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, err
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, err
 		}
 		if compatErr := checkDbCompatibility(ctx, rwKv); compatErr != nil {
-			return nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, compatErr
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, compatErr
 		}
 		db = rwKv
 		stateCache = kvcache.NewDummy()
@@ -297,17 +321,18 @@ func RemoteServices(ctx context.Context, cfg httpcfg.HttpCfg, logger log.Logger,
 			// ensure db exist
 			tmpDb, err := kv2.NewMDBX(logger).Path(borDbPath).Label(kv.ConsensusDB).Open()
 			if err != nil {
-				return nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, err
+				return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, err
 			}
 			tmpDb.Close()
 		}
 		log.Trace("Creating consensus db", "path", borDbPath)
 		borKv, err = kv2.NewMDBX(logger).Path(borDbPath).Label(kv.ConsensusDB).Readonly().Open()
 		if err != nil {
-			return nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, err
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, err
 		}
 		// Skip the compatibility check, until we have a schema in erigon-lib
 		borDb = borKv
+
 	} else {
 		if cfg.StateCache.KeysLimit > 0 {
 			stateCache = kvcache.NewDummy()
@@ -318,8 +343,8 @@ func RemoteServices(ctx context.Context, cfg httpcfg.HttpCfg, logger log.Logger,
 		log.Info("if you run RPCDaemon on same machine with Erigon add --datadir option")
 	}
 
+	var cc *params.ChainConfig
 	if db != nil {
-		var cc *params.ChainConfig
 		if err := db.View(context.Background(), func(tx kv.Tx) error {
 			genesisBlock, err := rawdb.ReadBlockByNumber(tx, 0)
 			if err != nil {
@@ -338,27 +363,57 @@ func RemoteServices(ctx context.Context, cfg httpcfg.HttpCfg, logger log.Logger,
 			}
 			return nil
 		}); err != nil {
-			return nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, err
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, err
 		}
 		if cc == nil {
-			return nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, fmt.Errorf("chain config not found in db. Need start erigon at least once on this db")
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, fmt.Errorf("chain config not found in db. Need start erigon at least once on this db")
 		}
 		cfg.Snap.Enabled = cfg.Snap.Enabled || cfg.Sync.UseSnapshots
 	}
 
+	if cfg.WithDatadir {
+		// parlia (consensus) specific db, only for the chains it runs
+		if cc.Parlia != nil {
+			parliaDbPath := filepath.Join(cfg.DataDir, "parlia")
+			{
+				// ensure db exist
+				tmpDb, err := kv2.NewMDBX(logger).Path(parliaDbPath).Label(kv.ConsensusDB).Open()
+				if err != nil {
+					return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, err
+				}
+				tmpDb.Close()
+			}
+			log.Trace("Creating consensus db", "path", parliaDbPath)
+			parliaDb, err = kv2.NewMDBX(logger).Path(parliaDbPath).Label(kv.ConsensusDB).Readonly().Open()
+			if err != nil {
+				return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, err
+			}
+		}
+
+		// traces stored by the Traces stage, only once it created their db
+		tracesDbPath := filepath.Join(cfg.DataDir, "traces")
+		if dir.Exist(filepath.Join(tracesDbPath, "mdbx.dat")) {
+			log.Trace("Creating traces db", "path", tracesDbPath)
+			tracesDb, err = tracedb.Open(tracesDbPath, logger, true)
+			if err != nil {
+				return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, err
+			}
+		}
+	}
+
 	creds, err := grpcutil.TLS(cfg.TLSCACert, cfg.TLSCertfile, cfg.TLSKeyFile)
 	if err != nil {
-		return nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, fmt.Errorf("open tls cert: %w", err)
+		return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, fmt.Errorf("open tls cert: %w", err)
 	}
 	conn, err := grpcutil.Connect(creds, cfg.PrivateApiAddr)
 	if err != nil {
-		return nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, fmt.Errorf("could not connect to execution service privateApi: %w", err)
+		return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, fmt.Errorf("could not connect to execution service privateApi: %w", err)
 	}
 
 	kvClient := remote.NewKVClient(conn)
 	remoteKv, err := remotedb.NewRemote(gointerfaces.VersionFromProto(remotedbserver.KvServiceAPIVersion), logger, kvClient).Open()
 	if err != nil {
-		return nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, fmt.Errorf("could not connect to remoteKv: %w", err)
+		return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, fmt.Errorf("could not connect to remoteKv: %w", err)
 	}
 
 	subscribeToStateChangesLoop(ctx, kvClient, stateCache)
@@ -404,7 +459,7 @@ func RemoteServices(ctx context.Context, cfg httpcfg.HttpCfg, logger log.Logger,
 	if cfg.TxPoolApiAddr != cfg.PrivateApiAddr {
 		txpoolConn, err = grpcutil.Connect(creds, cfg.TxPoolApiAddr)
 		if err != nil {
-			return nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, fmt.Errorf("could not connect to txpool api: %w", err)
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, fmt.Errorf("could not connect to txpool api: %w", err)
 		}
 	}
 
@@ -434,7 +489,7 @@ func RemoteServices(ctx context.Context, cfg httpcfg.HttpCfg, logger log.Logger,
 	if cfg.StarknetGRPCAddress != "" {
 		starknetConn, err := grpcutil.Connect(creds, cfg.StarknetGRPCAddress)
 		if err != nil {
-			return nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, fmt.Errorf("could not connect to starknet api: %w", err)
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, fmt.Errorf("could not connect to starknet api: %w", err)
 		}
 		starknet = rpcservices.NewStarknetService(starknetConn)
 	}
@@ -444,10 +499,10 @@ func RemoteServices(ctx context.Context, cfg httpcfg.HttpCfg, logger log.Logger,
 		e22Dir := filepath.Join(cfg.DataDir, "erigon22")
 		dir.MustExist(e22Dir)
 		if agg, err = libstate.NewAggregator22(e22Dir, ethconfig.HistoryV2AggregationStep); err != nil {
-			return nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, fmt.Errorf("create aggregator: %w", err)
+			return nil, nil, nil, nil, nil, nil, nil, nil, nil, nil, ff, nil, nil, fmt.Errorf("create aggregator: %w", err)
 		}
 	}
-	return db, borDb, eth, txPool, mining, starknet, stateCache, blockReader, ff, agg, txNums, err
+	return db, borDb, parliaDb, tracesDb, eth, txPool, mining, starknet, stateCache, blockReader, ff, agg, txNums, err
 }
 
 func StartRpcServer(ctx context.Context, cfg httpcfg.HttpCfg, rpcAPI []rpc.API, authAPI []rpc.API) error {
@@ -478,6 +533,7 @@ func startRegularRpcServer(ctx context.Context, cfg httpcfg.HttpCfg, rpcAPI []rp
 		return err
 	}
 	srv.SetAllowList(allowListForRPC)
+	srv.SetRequestLimits(cfg.RpcLimits)
 
 	var defaultAPIList []rpc.API
 
@@ -21,7 +21,7 @@ func TestNotFoundMustReturnNil(t *testing.T) {
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 	api := NewEthAPI(
 		NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), nil, nil, false),
-		db, nil, nil, nil, 5000000)
+		db, nil, nil, nil, 5000000, 100_000)
 	ctx := context.Background()
 
 	a, err := api.GetTransactionByBlockNumberAndIndex(ctx, 10_000, 1)
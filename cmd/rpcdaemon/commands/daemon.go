@@ -14,7 +14,7 @@ import (
 )
 
 // APIList describes the list of available RPC apis
-func APIList(db kv.RoDB, borDb kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.TxpoolClient, mining txpool.MiningClient,
+func APIList(db kv.RoDB, borDb kv.RoDB, parliaDb kv.RoDB, tracesDb kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.TxpoolClient, mining txpool.MiningClient,
 	starknet starknet.CAIROVMClient, filters *rpchelper.Filters, stateCache kvcache.Cache,
 	blockReader services.FullBlockReader, agg *libstate.Aggregator22, txNums *exec22.TxNums, cfg httpcfg.HttpCfg) (list []rpc.API) {
 
@@ -22,18 +22,20 @@ func APIList(db kv.RoDB, borDb kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.
 	if cfg.TevmEnabled {
 		base.EnableTevmExperiment()
 	}
-	ethImpl := NewEthAPI(base, db, eth, txPool, mining, cfg.Gascap)
+	ethImpl := NewEthAPI(base, db, eth, txPool, mining, cfg.Gascap, cfg.MaxGetProofRewindBlockCount)
+	syncingImpl := NewSyncingAPI(base)
 	erigonImpl := NewErigonAPI(base, db, eth)
 	starknetImpl := NewStarknetAPI(base, db, starknet, txPool)
 	txpoolImpl := NewTxPoolAPI(base, db, txPool)
 	netImpl := NewNetAPIImpl(eth)
-	debugImpl := NewPrivateDebugAPI(base, db, cfg.Gascap)
-	traceImpl := NewTraceAPI(base, db, &cfg)
+	debugImpl := NewPrivateDebugAPI(base, db, parliaDb, cfg.Gascap, cfg.MaxGetProofRewindBlockCount)
+	traceImpl := NewTraceAPI(base, db, tracesDb, &cfg)
 	web3Impl := NewWeb3APIImpl(eth)
 	dbImpl := NewDBAPIImpl() /* deprecated */
 	adminImpl := NewAdminAPI(eth)
 	parityImpl := NewParityAPIImpl(db)
-	borImpl := NewBorAPI(base, db, borDb) // bor (consensus) specific
+	borImpl := NewBorAPI(base, db, borDb)          // bor (consensus) specific
+	parliaImpl := NewParliaAPI(base, db, parliaDb) // parlia (consensus) specific
 
 	for _, enabledAPI := range cfg.API {
 		switch enabledAPI {
@@ -43,6 +45,11 @@ func APIList(db kv.RoDB, borDb kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.
 				Public:    true,
 				Service:   EthAPI(ethImpl),
 				Version:   "1.0",
+			}, rpc.API{
+				Namespace: "eth",
+				Public:    true,
+				Service:   syncingImpl,
+				Version:   "1.0",
 			})
 		case "debug":
 			list = append(list, rpc.API{
@@ -107,6 +114,13 @@ func APIList(db kv.RoDB, borDb kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.
 				Service:   BorAPI(borImpl),
 				Version:   "1.0",
 			})
+		case "parlia":
+			list = append(list, rpc.API{
+				Namespace: "parlia",
+				Public:    true,
+				Service:   ParliaAPI(parliaImpl),
+				Version:   "1.0",
+			})
 		case "admin":
 			list = append(list, rpc.API{
 				Namespace: "admin",
@@ -132,7 +146,7 @@ func AuthAPIList(db kv.RoDB, eth rpchelper.ApiBackend, txPool txpool.TxpoolClien
 	cfg httpcfg.HttpCfg) (list []rpc.API) {
 	base := NewBaseApi(filters, stateCache, blockReader, nil, nil, cfg.WithDatadir)
 
-	ethImpl := NewEthAPI(base, db, eth, txPool, mining, cfg.Gascap)
+	ethImpl := NewEthAPI(base, db, eth, txPool, mining, cfg.Gascap, cfg.MaxGetProofRewindBlockCount)
 	engineImpl := NewEngineAPI(base, db, eth)
 
 	list = append(list, rpc.API{
@@ -3,12 +3,14 @@ package commands
 import (
 	"context"
 	"fmt"
+	"sync"
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/changeset"
 	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/consensus"
 	"github.com/ledgerwatch/erigon/consensus/ethash"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/state"
@@ -36,21 +38,30 @@ type PrivateDebugAPI interface {
 	GetModifiedAccountsByHash(_ context.Context, startHash common.Hash, endHash *common.Hash) ([]common.Address, error)
 	TraceCall(ctx context.Context, args ethapi.CallArgs, blockNrOrHash rpc.BlockNumberOrHash, config *tracers.TraceConfig, stream *jsoniter.Stream) error
 	AccountAt(ctx context.Context, blockHash common.Hash, txIndex uint64, account common.Address) (*AccountResult, error)
+	GetBlockWitness(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error)
+	DumpBlock(ctx context.Context, blockNr rpc.BlockNumber, stream *jsoniter.Stream) error
+	StateDiff(ctx context.Context, fromBlock rpc.BlockNumber, toBlock rpc.BlockNumber, stream *jsoniter.Stream) error
 }
 
 // PrivateDebugAPIImpl is implementation of the PrivateDebugAPI interface based on remote Db access
 type PrivateDebugAPIImpl struct {
 	*BaseAPI
-	db     kv.RoDB
-	GasCap uint64
+	db                          kv.RoDB
+	parliaDb                    kv.RoDB
+	_witnessEngine              consensus.Engine // the engine of debug_getBlockWitness, created by the first call
+	_witnessEngineLock          sync.Mutex
+	GasCap                      uint64
+	MaxGetProofRewindBlockCount int
 }
 
 // NewPrivateDebugAPI returns PrivateDebugAPIImpl instance
-func NewPrivateDebugAPI(base *BaseAPI, db kv.RoDB, gascap uint64) *PrivateDebugAPIImpl {
+func NewPrivateDebugAPI(base *BaseAPI, db kv.RoDB, parliaDb kv.RoDB, gascap uint64, maxGetProofRewindBlockCount int) *PrivateDebugAPIImpl {
 	return &PrivateDebugAPIImpl{
-		BaseAPI: base,
-		db:      db,
-		GasCap:  gascap,
+		BaseAPI:                     base,
+		db:                          db,
+		parliaDb:                    parliaDb,
+		GasCap:                      gascap,
+		MaxGetProofRewindBlockCount: maxGetProofRewindBlockCount,
 	}
 }
 
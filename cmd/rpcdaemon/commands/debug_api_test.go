@@ -4,16 +4,19 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"testing"
 
 	jsoniter "github.com/json-iterator/go"
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
 	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/state"
 	"github.com/ledgerwatch/erigon/eth/tracers"
 	"github.com/ledgerwatch/erigon/internal/ethapi"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
 )
 
 var debugTraceTransactionTests = []struct {
@@ -42,8 +45,8 @@ func TestTraceBlockByNumber(t *testing.T) {
 	db := rpcdaemontest.CreateTestKV(t)
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 	baseApi := NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), nil, nil, false)
-	ethApi := NewEthAPI(baseApi, db, nil, nil, nil, 5000000)
-	api := NewPrivateDebugAPI(baseApi, db, 0)
+	ethApi := NewEthAPI(baseApi, db, nil, nil, nil, 5000000, 100_000)
+	api := NewPrivateDebugAPI(baseApi, db, nil, 0, 100_000)
 	for _, tt := range debugTraceTransactionTests {
 		var buf bytes.Buffer
 		stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
@@ -89,8 +92,8 @@ func TestTraceBlockByHash(t *testing.T) {
 	db := rpcdaemontest.CreateTestKV(t)
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 	baseApi := NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), nil, nil, false)
-	ethApi := NewEthAPI(baseApi, db, nil, nil, nil, 5000000)
-	api := NewPrivateDebugAPI(baseApi, db, 0)
+	ethApi := NewEthAPI(baseApi, db, nil, nil, nil, 5000000, 100_000)
+	api := NewPrivateDebugAPI(baseApi, db, nil, 0, 100_000)
 	for _, tt := range debugTraceTransactionTests {
 		var buf bytes.Buffer
 		stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
@@ -124,7 +127,7 @@ func TestTraceTransaction(t *testing.T) {
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 	api := NewPrivateDebugAPI(
 		NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), nil, nil, false),
-		db, 0)
+		db, nil, 0, 100_000)
 	for _, tt := range debugTraceTransactionTests {
 		var buf bytes.Buffer
 		stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
@@ -156,7 +159,7 @@ func TestTraceTransactionNoRefund(t *testing.T) {
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 	api := NewPrivateDebugAPI(
 		NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), nil, nil, false),
-		db, 0)
+		db, nil, 0, 100_000)
 	for _, tt := range debugTraceTransactionNoRefundTests {
 		var buf bytes.Buffer
 		stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
@@ -183,3 +186,109 @@ func TestTraceTransactionNoRefund(t *testing.T) {
 		}
 	}
 }
+
+func TestDumpBlock(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	baseApi := NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), nil, nil, false)
+	ethApi := NewEthAPI(baseApi, db, nil, nil, nil, 5000000, 100_000)
+	api := NewPrivateDebugAPI(baseApi, db, nil, 0, 100_000)
+	ctx := context.Background()
+
+	for _, blockNr := range []rpc.BlockNumber{0, 5, rpc.LatestBlockNumber} {
+		var buf bytes.Buffer
+		stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+		require.NoError(t, api.DumpBlock(ctx, blockNr, stream))
+		var dump state.Dump
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &dump), buf.String())
+
+		block, err := ethApi.GetBlockByNumber(ctx, blockNr, false)
+		require.NoError(t, err)
+		require.Equal(t, fmt.Sprintf("%x", block["stateRoot"]), dump.Root)
+		require.NotEmpty(t, dump.Accounts)
+		for addr, account := range dump.Accounts {
+			balance, err := ethApi.GetBalance(ctx, addr, rpc.BlockNumberOrHashWithNumber(blockNr))
+			require.NoError(t, err)
+			require.Equal(t, balance.ToInt().String(), account.Balance, "balance of %x at block %d", addr, blockNr)
+			for loc, value := range account.Storage {
+				want, err := ethApi.GetStorageAt(ctx, addr, loc, rpc.BlockNumberOrHashWithNumber(blockNr))
+				require.NoError(t, err)
+				require.Equal(t, common.HexToHash(want), common.HexToHash(value), "storage %s of %x at block %d", loc, addr, blockNr)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+	require.Error(t, api.DumpBlock(ctx, rpc.PendingBlockNumber, stream))
+	require.Error(t, api.DumpBlock(ctx, 1000, stream))
+}
+
+func TestStateDiff(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	baseApi := NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), nil, nil, false)
+	ethApi := NewEthAPI(baseApi, db, nil, nil, nil, 5000000, 100_000)
+	api := NewPrivateDebugAPI(baseApi, db, nil, 0, 100_000)
+	ctx := context.Background()
+
+	latest, err := ethApi.BlockNumber(ctx)
+	require.NoError(t, err)
+	to := rpc.BlockNumber(latest)
+
+	var buf bytes.Buffer
+	stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+	for _, from := range []rpc.BlockNumber{1, 5} {
+		buf.Reset()
+		require.NoError(t, api.StateDiff(ctx, from, to, stream))
+		var diff map[common.Address]struct {
+			Balance interface{}                                `json:"balance"`
+			Storage map[common.Hash]map[string]json.RawMessage `json:"storage"`
+		}
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &diff), buf.String())
+		require.NotEmpty(t, diff)
+
+		var storageItems int
+		for addr, accountDiff := range diff {
+			fromBalance, err := ethApi.GetBalance(ctx, addr, rpc.BlockNumberOrHashWithNumber(from))
+			require.NoError(t, err)
+			toBalance, err := ethApi.GetBalance(ctx, addr, rpc.BlockNumberOrHashWithNumber(to))
+			require.NoError(t, err)
+			switch balance := accountDiff.Balance.(type) {
+			case string:
+				require.Equal(t, "=", balance)
+				require.Equal(t, fromBalance, toBalance, "balance of %x", addr)
+			case map[string]interface{}:
+				if change, ok := balance["*"].(map[string]interface{}); ok {
+					require.Equal(t, fromBalance.String(), change["from"], "balance of %x", addr)
+					require.Equal(t, toBalance.String(), change["to"], "balance of %x", addr)
+				} else {
+					require.Equal(t, toBalance.String(), balance["+"], "balance of %x", addr)
+				}
+			}
+			for loc, m := range accountDiff.Storage {
+				fromValue, err := ethApi.GetStorageAt(ctx, addr, loc.Hex(), rpc.BlockNumberOrHashWithNumber(from))
+				require.NoError(t, err)
+				toValue, err := ethApi.GetStorageAt(ctx, addr, loc.Hex(), rpc.BlockNumberOrHashWithNumber(to))
+				require.NoError(t, err)
+				if enc, ok := m["*"]; ok {
+					var change StateDiffStorage
+					require.NoError(t, json.Unmarshal(enc, &change))
+					require.Equal(t, common.HexToHash(fromValue), change.From, "storage %x of %x", loc, addr)
+					require.Equal(t, common.HexToHash(toValue), change.To, "storage %x of %x", loc, addr)
+				} else {
+					var value common.Hash
+					require.NoError(t, json.Unmarshal(m["+"], &value))
+					require.Equal(t, common.HexToHash(toValue), value, "storage %x of %x", loc, addr)
+				}
+				storageItems++
+			}
+		}
+		require.NotZero(t, storageItems)
+	}
+
+	buf.Reset()
+	require.NoError(t, api.StateDiff(ctx, to, to, stream))
+	require.Equal(t, "{}", buf.String())
+	require.Error(t, api.StateDiff(ctx, to, 1, stream))
+}
@@ -0,0 +1,239 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/holiman/uint256"
+	jsoniter "github.com/json-iterator/go"
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/kv"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/changeset"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	prune2 "github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+)
+
+// DumpBlock implements debug_dumpBlock. Streams the whole state after the given block, as state.Dump: the accounts
+// with their code and storage, the root being the state root of the header.
+func (api *PrivateDebugAPIImpl) DumpBlock(ctx context.Context, blockNr rpc.BlockNumber, stream *jsoniter.Stream) error {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	blockNumber, err := api.stateBlockNumber(tx, blockNr)
+	if err != nil {
+		return err
+	}
+	var root common.Hash
+	if header := rawdb.ReadHeaderByNumber(tx, blockNumber); header != nil {
+		root = header.Root
+	}
+
+	collector := &dumpStream{stream: stream, root: root}
+	if _, err = state.NewDumper(tx, blockNumber).DumpToCollector(collector, false, false, common.Address{}, 0); err != nil {
+		return err
+	}
+	stream.WriteObjectEnd()
+	stream.WriteObjectEnd()
+	if err = stream.Flush(); err != nil {
+		return err
+	}
+	return collector.err
+}
+
+// dumpStream is a state.DumpCollector writing the dump to the stream account by account, instead of collecting it
+type dumpStream struct {
+	stream *jsoniter.Stream
+	root   common.Hash
+	first  bool
+	err    error
+}
+
+// OnRoot implements DumpCollector interface, the root given being replaced by the one of the header
+func (d *dumpStream) OnRoot(common.Hash) {
+	d.stream.WriteObjectStart()
+	d.stream.WriteObjectField("root")
+	d.stream.WriteString(fmt.Sprintf("%x", d.root))
+	d.stream.WriteMore()
+	d.stream.WriteObjectField("accounts")
+	d.stream.WriteObjectStart()
+	d.first = true
+}
+
+// OnAccount implements DumpCollector interface
+func (d *dumpStream) OnAccount(addr common.Address, account state.DumpAccount) {
+	if d.err != nil {
+		return
+	}
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	b, err := json.Marshal(account)
+	if err != nil {
+		d.err = err
+		return
+	}
+	if d.first {
+		d.first = false
+	} else {
+		d.stream.WriteMore()
+	}
+	d.stream.WriteObjectField(hexutil.Encode(addr[:]))
+	d.stream.Write(b)
+	d.err = d.stream.Flush()
+}
+
+// StateDiff implements debug_stateDiff. Streams the difference between the state after fromBlock and the state
+// after toBlock, for the accounts and storage items found in the account and storage change sets of the blocks in
+// between. Each account is in the format of the stateDiff of trace_call: "=" for the fields left
+// unchanged, "*" with the values at both ends for the changed ones, "+" and "-" for the accounts created and deleted.
+// Accounts whose values are identical at both ends are left out.
+func (api *PrivateDebugAPIImpl) StateDiff(ctx context.Context, fromBlock rpc.BlockNumber, toBlock rpc.BlockNumber, stream *jsoniter.Stream) error {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	fromNum, err := api.stateBlockNumber(tx, fromBlock)
+	if err != nil {
+		return err
+	}
+	toNum, err := api.stateBlockNumber(tx, toBlock)
+	if err != nil {
+		return err
+	}
+	if fromNum > toNum {
+		return fmt.Errorf("start block (%d) must be less than or equal to end block (%d)", fromNum, toNum)
+	}
+	if fromNum < toNum {
+		if err = checkChangeSetsAvailable(tx, fromNum+1); err != nil {
+			return err
+		}
+	}
+
+	// Collect the accounts and storage items changed by the blocks fromNum+1..toNum
+	storageKeys := make(map[common.Address]map[common.Hash]struct{})
+	if err = changeset.ForRange(tx, kv.AccountChangeSet, fromNum+1, toNum+1, func(_ uint64, k, _ []byte) error {
+		addr := common.BytesToAddress(k)
+		if _, ok := storageKeys[addr]; !ok {
+			storageKeys[addr] = make(map[common.Hash]struct{})
+		}
+		return nil
+	}); err != nil {
+		return err
+	}
+	if err = changeset.ForRange(tx, kv.StorageChangeSet, fromNum+1, toNum+1, func(_ uint64, k, _ []byte) error {
+		addr := common.BytesToAddress(k[:length.Addr])
+		keys, ok := storageKeys[addr]
+		if !ok {
+			keys = make(map[common.Hash]struct{})
+			storageKeys[addr] = keys
+		}
+		keys[common.BytesToHash(k[length.Addr+length.Incarnation:])] = struct{}{}
+		return nil
+	}); err != nil {
+		return err
+	}
+	addrs := make([]common.Address, 0, len(storageKeys))
+	for addr := range storageKeys {
+		addrs = append(addrs, addr)
+	}
+	sort.Slice(addrs, func(i, j int) bool { return bytes.Compare(addrs[i][:], addrs[j][:]) < 0 })
+
+	var json = jsoniter.ConfigCompatibleWithStandardLibrary
+	fromReader := state.NewPlainState(tx, fromNum+1)
+	toReader := state.NewPlainState(tx, toNum+1)
+	stream.WriteObjectStart()
+	first := true
+	for _, addr := range addrs {
+		select {
+		default:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		// Fresh states for each account, not to cache the whole range in memory
+		initialIbs := state.New(fromReader)
+		ibs := state.New(toReader)
+		sd := &StateDiff{sdMap: map[common.Address]*StateDiffAccount{
+			addr: {Storage: make(map[common.Hash]map[string]interface{})},
+		}}
+		for key := range storageKeys[addr] {
+			key := key
+			var original, value uint256.Int
+			initialIbs.GetState(addr, &key, &original)
+			ibs.GetState(addr, &key, &value)
+			if err = sd.WriteAccountStorage(addr, 0, &key, &original, &value); err != nil {
+				return err
+			}
+		}
+		sd.CompareStates(initialIbs, ibs)
+		accountDiff, ok := sd.sdMap[addr]
+		if !ok {
+			continue
+		}
+		b, err := json.Marshal(accountDiff)
+		if err != nil {
+			return err
+		}
+		if first {
+			first = false
+		} else {
+			stream.WriteMore()
+		}
+		stream.WriteObjectField(hexutil.Encode(addr[:]))
+		stream.Write(b)
+		if err = stream.Flush(); err != nil {
+			return err
+		}
+	}
+	stream.WriteObjectEnd()
+	return stream.Flush()
+}
+
+// stateBlockNumber resolves the block whose state is read, which has to be executed already
+func (api *PrivateDebugAPIImpl) stateBlockNumber(tx kv.Tx, blockNr rpc.BlockNumber) (uint64, error) {
+	if blockNr == rpc.PendingBlockNumber {
+		return 0, fmt.Errorf("state of the pending block not supported")
+	}
+	blockNumber, _, _, err := rpchelper.GetBlockNumber(rpc.BlockNumberOrHashWithNumber(blockNr), tx, api.filters)
+	if err != nil {
+		return 0, err
+	}
+	executed, err := stages.GetStageProgress(tx, stages.Execution)
+	if err != nil {
+		return 0, err
+	}
+	if blockNumber > executed {
+		return 0, fmt.Errorf("block (%d) is later than the latest executed block (%d)", blockNumber, executed)
+	}
+	return blockNumber, nil
+}
+
+// checkChangeSetsAvailable fails if the change sets from the given block on may have been pruned
+func checkChangeSetsAvailable(tx kv.Tx, from uint64) error {
+	pm, err := prune2.Get(tx)
+	if err != nil {
+		return err
+	}
+	if !pm.History.Enabled() {
+		return nil
+	}
+	executed, err := stages.GetStageProgress(tx, stages.Execution)
+	if err != nil {
+		return err
+	}
+	if pruneTo := pm.History.PruneTo(executed); from <= pruneTo {
+		return fmt.Errorf("change sets of block %d are pruned, the history is kept after block %d", from, pruneTo)
+	}
+	return nil
+}
@@ -0,0 +1,199 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+
+	"github.com/ledgerwatch/erigon/cmd/state/exec22"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/consensus/ethash"
+	"github.com/ledgerwatch/erigon/consensus/parlia"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
+	"github.com/ledgerwatch/erigon/turbo/trie"
+)
+
+// GetBlockWitness implements debug_getBlockWitness. It returns the serialized witness needed to execute the block
+// statelessly: the state trie before the block, with the accounts, storage items and codes the block touches, the
+// rest of the trie being replaced by hashes. As for eth_getProof, the state before the block is built by rewinding
+// the hashed state in memory, which is limited to MaxGetProofRewindBlockCount blocks back from the head.
+func (api *PrivateDebugAPIImpl) GetBlockWitness(ctx context.Context, blockNrOrHash rpc.BlockNumberOrHash) (hexutil.Bytes, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	blockNr, hash, _, err := rpchelper.GetCanonicalBlockNumber(blockNrOrHash, tx, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	if blockNr == 0 {
+		return nil, errors.New("the genesis block has no witness")
+	}
+	block, err := api.blockWithSenders(tx, hash, blockNr)
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block %d(%x) not found", blockNr, hash)
+	}
+	parent, err := api._blockReader.Header(ctx, tx, block.ParentHash(), blockNr-1)
+	if err != nil {
+		return nil, err
+	}
+	if parent == nil {
+		return nil, fmt.Errorf("parent of block %d(%x) not found", blockNr, hash)
+	}
+	if api.historyV2(tx) {
+		return nil, fmt.Errorf(NotImplemented, "debug_getBlockWitness with history.v2")
+	}
+	chainConfig, err := api.chainConfig(tx)
+	if err != nil {
+		return nil, err
+	}
+	engine, err := api.witnessEngine(chainConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	// the execution of the block on its state tells what the witness has to contain
+	recorder := state.NewWitnessRecorder(state.NewPlainState(tx, blockNr))
+	getHeader := func(hash common.Hash, number uint64) *types.Header {
+		h, _ := api._blockReader.Header(ctx, tx, hash, number)
+		return h
+	}
+	if err = executeWitnessBlock(chainConfig, engine, block, getHeader, exec22.NewChainReader(chainConfig, tx, api._blockReader), recorder, recorder); err != nil {
+		return nil, err
+	}
+
+	latestBlock, err := stages.GetStageProgress(tx, stages.IntermediateHashes)
+	if err != nil {
+		return nil, err
+	}
+	if latestBlock < blockNr-1 {
+		return nil, fmt.Errorf("block number is in the future latest=%d requested=%d", latestBlock, blockNr)
+	}
+	var loadTx kv.Tx = tx
+	var loaderRl *trie.RetainList
+	if latestBlock > blockNr-1 {
+		if latestBlock-blockNr+1 > uint64(api.MaxGetProofRewindBlockCount) {
+			return nil, fmt.Errorf("requested block is too old, block must be within %d blocks of the head block number (currently %d)", api.MaxGetProofRewindBlockCount, latestBlock)
+		}
+		batch := memdb.NewMemoryBatch(tx)
+		defer batch.Rollback()
+		if loaderRl, err = stagedsync.UnwindHashedStateForTrieLoader("debug_getBlockWitness", batch, latestBlock, blockNr-1, os.TempDir(), ctx.Done()); err != nil {
+			return nil, err
+		}
+		loadTx = batch
+	} else {
+		loaderRl = trie.NewRetainList(0)
+	}
+
+	proofRl, witnessRl := trie.NewRetainList(0), trie.NewRetainList(0)
+	for _, key := range recorder.LoaderKeys() {
+		loaderRl.AddKey(key)
+		proofRl.AddKey(key)
+	}
+	for _, key := range recorder.TrieKeys() {
+		witnessRl.AddKey(key)
+	}
+	tr, err := trie.LoadTrieForProofs("debug_getBlockWitness", loadTx, loaderRl, proofRl, ctx.Done())
+	if err != nil {
+		return nil, err
+	}
+	if root := tr.Hash(); root != parent.Root {
+		return nil, fmt.Errorf("mismatch in expected state root computed %x vs %x indicates bug in witness implementation", root, parent.Root)
+	}
+	// the nodes which the deletions merge with have to be loaded too
+	if loaderHexes, trieHexes := recorder.DeletionSiblings(tr); len(loaderHexes) > 0 {
+		for i := range loaderHexes {
+			loaderRl.AddHex(loaderHexes[i])
+			proofRl.AddHex(loaderHexes[i])
+			witnessRl.AddHex(trieHexes[i])
+		}
+		if tr, err = trie.LoadTrieForProofs("debug_getBlockWitness", loadTx, loaderRl, proofRl, ctx.Done()); err != nil {
+			return nil, err
+		}
+	}
+	if err = recorder.AttachCodes(tr, witnessRl); err != nil {
+		return nil, err
+	}
+
+	witness, err := tr.ExtractWitness(false, witnessRl)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if _, err = witness.WriteInto(&buf); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// witnessEngine returns the consensus engine executing the blocks for their witnesses, the finalization of the
+// blocks changing the state too. The engine is created once, so that parlia keeps the validator snapshots it
+// computes in its memory cache.
+func (api *PrivateDebugAPIImpl) witnessEngine(chainConfig *params.ChainConfig) (consensus.Engine, error) {
+	api._witnessEngineLock.Lock()
+	defer api._witnessEngineLock.Unlock()
+	if api._witnessEngine != nil {
+		return api._witnessEngine, nil
+	}
+	switch chainConfig.Consensus {
+	case params.EtHashConsensus:
+		api._witnessEngine = ethash.NewFaker()
+	case params.ParliaConsensus:
+		if api.parliaDb == nil {
+			return nil, errNoParliaDb
+		}
+		// the engine only reads the snapshots of the validators, the db belonging to the node
+		api._witnessEngine = parlia.New(chainConfig, readOnlyConsensusDb{api.parliaDb}, nil, nil)
+	default:
+		return nil, fmt.Errorf(NotImplemented, "debug_getBlockWitness with the "+string(chainConfig.Consensus)+" consensus")
+	}
+	return api._witnessEngine, nil
+}
+
+// errReadOnlyConsensusDb is returned when a consensus engine opens a write transaction on its read-only db
+var errReadOnlyConsensusDb = errors.New("the consensus db is read-only")
+
+// readOnlyConsensusDb gives a consensus engine a read-only access to its db: the checkpoints the engine would store
+// are dropped, which leaves them in the memory cache of the engine only
+type readOnlyConsensusDb struct {
+	kv.RoDB
+}
+
+func (readOnlyConsensusDb) Update(context.Context, func(tx kv.RwTx) error) error {
+	return nil
+}
+
+func (readOnlyConsensusDb) BeginRw(context.Context) (kv.RwTx, error) {
+	return nil, errReadOnlyConsensusDb
+}
+
+// executeWitnessBlock executes the block with the consensus engine, which may use system transactions
+func executeWitnessBlock(chainConfig *params.ChainConfig, engine consensus.Engine, block *types.Block, getHeader func(hash common.Hash, number uint64) *types.Header,
+	chainReader consensus.ChainHeaderReader, stateReader state.StateReader, stateWriter state.WriterWithChangeSets) error {
+	execute := core.ExecuteBlockEphemerally
+	if _, isPoSA := engine.(consensus.PoSA); isPoSA {
+		execute = core.ExecuteBlockEphemerallyForBSC
+	}
+	_, err := execute(chainConfig, &vm.Config{}, core.GetHashFn(block.Header(), getHeader), engine, block, stateReader, stateWriter, nil, chainReader, nil, false, nil)
+	return err
+}
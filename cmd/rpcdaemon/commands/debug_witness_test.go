@@ -0,0 +1,73 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/cmd/state/exec22"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/consensus/ethash"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/ledgerwatch/erigon/turbo/trie"
+)
+
+func TestGetBlockWitness(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewPrivateDebugAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), nil, nil, false), db, nil, 0, 100_000)
+	ctx := context.Background()
+	tx, err := db.BeginRo(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	chainConfig, err := api.chainConfig(tx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	getHeader := func(hash common.Hash, number uint64) *types.Header { return rawdb.ReadHeader(tx, hash, number) }
+
+	// the blocks of the test chain deploy contracts, write storage and self-destruct
+	for number := uint64(1); number <= 10; number++ {
+		enc, err := api.GetBlockWitness(ctx, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(number)))
+		if err != nil {
+			t.Fatalf("block %d: %v", number, err)
+		}
+		witness, err := trie.NewWitnessFromReader(bytes.NewReader(enc), false)
+		if err != nil {
+			t.Fatalf("block %d: %v", number, err)
+		}
+		tr, err := trie.BuildTrieFromWitness(witness, false)
+		if err != nil {
+			t.Fatalf("block %d: %v", number, err)
+		}
+		block, err := rawdb.ReadBlockByNumber(tx, number)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if parent := rawdb.ReadHeaderByNumber(tx, number-1); tr.Hash() != parent.Root {
+			t.Fatalf("block %d: witness root %x, state root of the parent %x", number, tr.Hash(), parent.Root)
+		}
+
+		stateless := state.NewStateless(tr)
+		if err = executeWitnessBlock(chainConfig, ethash.NewFaker(), block, getHeader, exec22.NewChainReader(chainConfig, tx, snapshotsync.NewBlockReader()), stateless, stateless); err != nil {
+			t.Fatalf("block %d: stateless execution: %v", number, err)
+		}
+		if root := stateless.Root(); root != block.Root() {
+			t.Errorf("block %d: stateless root %x, state root %x", number, root, block.Root())
+		}
+	}
+
+	api.MaxGetProofRewindBlockCount = 1
+	if _, err = api.GetBlockWitness(ctx, rpc.BlockNumberOrHashWithNumber(2)); err == nil {
+		t.Errorf("expected an error for a block too old to rewind the state to")
+	}
+}
@@ -193,7 +193,11 @@ func (api *ErigonImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria)
 		for i, log := range blockLogs {
 			log.BlockNumber = blockNumber
 			log.BlockHash = blockHash
-			log.TxHash = body.Transactions[log.TxIndex].Hash()
+			if log.TxIndex < uint(len(body.Transactions)) {
+				log.TxHash = body.Transactions[log.TxIndex].Hash()
+			} else {
+				log.TxHash = types.ComputeBorTxHash(blockNumber, blockHash) // the state-sync logs of Bor
+			}
 
 			erigonLogs[i].Log = *log
 			erigonLogs[i].Timestamp = timestamp
@@ -2,24 +2,32 @@ package commands
 
 import (
 	"context"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"math/big"
+	"os"
+	"strings"
 
 	"github.com/holiman/uint256"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces"
 	txpool_proto "github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
 	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
 	"github.com/ledgerwatch/log/v3"
 	"google.golang.org/grpc"
 
 	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
 	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/state"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
 	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/eth/tracers/logger"
 	"github.com/ledgerwatch/erigon/ethdb"
 	"github.com/ledgerwatch/erigon/internal/ethapi"
@@ -27,6 +35,7 @@ import (
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/erigon/turbo/transactions"
+	"github.com/ledgerwatch/erigon/turbo/trie"
 )
 
 // Call implements eth_call. Executes a new message call immediately without creating a transaction on the block chain.
@@ -277,10 +286,169 @@ func (api *APIImpl) EstimateGas(ctx context.Context, argsOrNil *ethapi.CallArgs,
 	return hexutil.Uint64(hi), nil
 }
 
-// GetProof not implemented
-func (api *APIImpl) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNr rpc.BlockNumber) (*interface{}, error) {
-	var stub interface{}
-	return &stub, fmt.Errorf(NotImplemented, "eth_getProof")
+// GetProof implements eth_getProof. It returns the account and storage values of the specified account
+// including the Merkle-proof. Historical blocks are served by rewinding the hashed state in memory,
+// which is limited to MaxGetProofRewindBlockCount blocks back from the head.
+func (api *APIImpl) GetProof(ctx context.Context, address common.Address, storageKeys []string, blockNrOrHash rpc.BlockNumberOrHash) (*ethapi.AccountResult, error) {
+	keys := make([]common.Hash, len(storageKeys))
+	for i, key := range storageKeys {
+		var err error
+		if keys[i], err = decodeStorageKey(key); err != nil {
+			return nil, err
+		}
+	}
+
+	roTx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer roTx.Rollback()
+
+	blockNr, hash, _, err := rpchelper.GetBlockNumber(blockNrOrHash, roTx, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	header, err := api._blockReader.Header(ctx, roTx, hash, blockNr)
+	if err != nil {
+		return nil, err
+	}
+	if header == nil {
+		return nil, fmt.Errorf("block %d(%x) not found", blockNr, hash)
+	}
+
+	latestBlock, err := stages.GetStageProgress(roTx, stages.IntermediateHashes)
+	if err != nil {
+		return nil, err
+	}
+	if latestBlock < blockNr {
+		// shouldn't happen, but check anyway
+		return nil, fmt.Errorf("block number is in the future latest=%d requested=%d", latestBlock, blockNr)
+	}
+
+	var tx kv.Tx = roTx
+	var loaderRl *trie.RetainList
+	if latestBlock > blockNr {
+		if api.historyV2(roTx) {
+			return nil, fmt.Errorf(NotImplemented, "eth_getProof for historical blocks with history.v2")
+		}
+		if latestBlock-blockNr > uint64(api.MaxGetProofRewindBlockCount) {
+			return nil, fmt.Errorf("requested block is too old, block must be within %d blocks of the head block number (currently %d)", api.MaxGetProofRewindBlockCount, latestBlock)
+		}
+		batch := memdb.NewMemoryBatch(roTx)
+		defer batch.Rollback()
+		if loaderRl, err = stagedsync.UnwindHashedStateForTrieLoader("eth_getProof", batch, latestBlock, blockNr, os.TempDir(), ctx.Done()); err != nil {
+			return nil, err
+		}
+		tx = batch
+	} else {
+		loaderRl = trie.NewRetainList(0)
+	}
+
+	addrHash, err := common.HashData(address[:])
+	if err != nil {
+		return nil, err
+	}
+	var incarnation uint64
+	enc, err := tx.GetOne(kv.HashedAccounts, addrHash[:])
+	if err != nil {
+		return nil, err
+	}
+	if len(enc) > 0 {
+		var acc accounts.Account
+		if err = acc.DecodeForStorage(enc); err != nil {
+			return nil, err
+		}
+		incarnation = acc.Incarnation
+	}
+
+	// the loader must not use intermediate hashes on the paths to the proven keys
+	proofRl := trie.NewRetainList(0)
+	proofRl.AddKey(addrHash[:])
+	loaderRl.AddKey(addrHash[:])
+	keyHashes := make([]common.Hash, len(storageKeys))
+	for i := range keys {
+		if keyHashes[i], err = common.HashData(keys[i].Bytes()); err != nil {
+			return nil, err
+		}
+		if incarnation == 0 {
+			continue
+		}
+		compositeKey := dbutils.GenerateCompositeStorageKey(addrHash, incarnation, keyHashes[i])
+		proofRl.AddKey(compositeKey)
+		loaderRl.AddKey(compositeKey)
+	}
+
+	tr, err := trie.LoadTrieForProofs("eth_getProof", tx, loaderRl, proofRl, ctx.Done())
+	if err != nil {
+		return nil, err
+	}
+	if root := tr.Hash(); root != header.Root {
+		return nil, fmt.Errorf("mismatch in expected state root computed %x vs %x indicates bug in proof implementation", root, header.Root)
+	}
+
+	result := &ethapi.AccountResult{
+		Address:      address,
+		Balance:      (*hexutil.Big)(new(big.Int)),
+		CodeHash:     common.BytesToHash(crypto.Keccak256(nil)),
+		StorageHash:  trie.EmptyRoot,
+		StorageProof: make([]ethapi.StorageResult, len(storageKeys)),
+	}
+	accountProof, err := tr.Prove(addrHash[:], 0, false)
+	if err != nil {
+		return nil, err
+	}
+	result.AccountProof = encodeProof(accountProof)
+	if acc, ok := tr.GetAccount(addrHash[:]); ok && acc != nil {
+		result.Balance = (*hexutil.Big)(acc.Balance.ToBig())
+		result.Nonce = hexutil.Uint64(acc.Nonce)
+		result.CodeHash = acc.CodeHash
+		result.StorageHash = acc.Root
+	}
+
+	for i, key := range storageKeys {
+		storageResult := ethapi.StorageResult{Key: key, Value: (*hexutil.Big)(new(big.Int)), Proof: []string{}}
+		if incarnation != 0 {
+			storageKey := append(common.CopyBytes(addrHash[:]), keyHashes[i][:]...)
+			storageProof, err := tr.Prove(storageKey, 64, true)
+			if err != nil {
+				return nil, err
+			}
+			storageResult.Proof = encodeProof(storageProof)
+			if value, ok := tr.Get(storageKey); ok && len(value) > 0 {
+				storageResult.Value = (*hexutil.Big)(new(big.Int).SetBytes(value))
+			}
+		}
+		result.StorageProof[i] = storageResult
+	}
+	return result, nil
+}
+
+// decodeStorageKey parses a storage key of eth_getProof: hex of at most 32 bytes, with or without the 0x prefix, an odd
+// number of digits being allowed
+func decodeStorageKey(key string) (common.Hash, error) {
+	s := key
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		s = s[2:]
+	}
+	if len(s)%2 == 1 {
+		s = "0" + s
+	}
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return common.Hash{}, &rpc.CustomError{Code: -32602, Message: fmt.Sprintf("invalid storage key %q: %v", key, err)}
+	}
+	if len(b) > common.HashLength {
+		return common.Hash{}, &rpc.CustomError{Code: -32602, Message: fmt.Sprintf("invalid storage key %q: longer than %d bytes", key, common.HashLength)}
+	}
+	return common.BytesToHash(b), nil
+}
+
+func encodeProof(proof [][]byte) []string {
+	res := make([]string, len(proof))
+	for i, node := range proof {
+		res[i] = hexutil.Encode(node)
+	}
+	return res
 }
 
 // accessListResult returns an optional accesslist
@@ -24,16 +24,22 @@ import (
 	"github.com/ledgerwatch/log/v3"
 )
 
+// BlockOverrides are the fields of the block a bundle is executed in which differ from the block of
+// the previous bundle.
 type BlockOverrides struct {
 	BlockNumber *hexutil.Uint64
 	Coinbase    *common.Address
 	Timestamp   *hexutil.Uint64
 	GasLimit    *hexutil.Uint
-	Difficulty  *hexutil.Uint
+	Difficulty  *hexutil.Big
 	BaseFee     *uint256.Int
 	BlockHash   *map[uint64]common.Hash
 }
 
+// Bundle is a list of calls executed in a synthetic block, on top of the state left by the previous
+// bundles. The first bundle is executed in the block of the StateContext, each of the next ones in the
+// block following the one of the previous bundle: its number and timestamp are increased by one and
+// its other fields are kept, unless they are overridden.
 type Bundle struct {
 	Transactions  []rpcapi.CallArgs
 	BlockOverride BlockOverrides
@@ -49,13 +55,13 @@ func blockHeaderOverride(blockCtx *vm.BlockContext, blockOverride BlockOverrides
 		blockCtx.BlockNumber = uint64(*blockOverride.BlockNumber)
 	}
 	if blockOverride.BaseFee != nil {
-		blockCtx.BaseFee = blockOverride.BaseFee
+		blockCtx.BaseFee = new(uint256.Int).Set(blockOverride.BaseFee)
 	}
 	if blockOverride.Coinbase != nil {
 		blockCtx.Coinbase = *blockOverride.Coinbase
 	}
 	if blockOverride.Difficulty != nil {
-		blockCtx.Difficulty = big.NewInt(int64(*blockOverride.Difficulty))
+		blockCtx.Difficulty = new(big.Int).Set(blockOverride.Difficulty.ToInt())
 	}
 	if blockOverride.Timestamp != nil {
 		blockCtx.Time = uint64(*blockOverride.Timestamp)
@@ -70,6 +76,12 @@ func blockHeaderOverride(blockCtx *vm.BlockContext, blockOverride BlockOverrides
 	}
 }
 
+// nextBlockContext moves the block context to the synthetic block following it.
+func nextBlockContext(blockCtx *vm.BlockContext) {
+	blockCtx.BlockNumber++
+	blockCtx.Time++
+}
+
 func (api *APIImpl) CallMany(ctx context.Context, bundles []Bundle, simulateContext StateContext, stateOverride *rpcapi.StateOverrides, timeoutMilliSecondsPtr *int64) ([][]map[string]interface{}, error) {
 	var (
 		hash               common.Hash
@@ -226,6 +238,9 @@ func (api *APIImpl) CallMany(ctx context.Context, bundles []Bundle, simulateCont
 		if err != nil {
 			return nil, err
 		}
+		if err = evm.IntraBlockState().(*state.IntraBlockState).FinalizeTx(rules, state.NewNoopWriter()); err != nil {
+			return nil, err
+		}
 		// If the timer caused an abort, return an appropriate error message
 		if evm.Cancelled() {
 			return nil, fmt.Errorf("execution aborted (timeout = %v)", timeout)
@@ -245,29 +260,7 @@ func (api *APIImpl) CallMany(ctx context.Context, bundles []Bundle, simulateCont
 
 	for _, bundle := range bundles {
 		// first change blockContext
-		if bundle.BlockOverride.BlockNumber != nil {
-			blockCtx.BlockNumber = uint64(*bundle.BlockOverride.BlockNumber)
-		}
-		if bundle.BlockOverride.BaseFee != nil {
-			blockCtx.BaseFee = bundle.BlockOverride.BaseFee
-		}
-		if bundle.BlockOverride.Coinbase != nil {
-			blockCtx.Coinbase = *bundle.BlockOverride.Coinbase
-		}
-		if bundle.BlockOverride.Difficulty != nil {
-			blockCtx.Difficulty = big.NewInt(int64(*bundle.BlockOverride.Difficulty))
-		}
-		if bundle.BlockOverride.Timestamp != nil {
-			blockCtx.Time = uint64(*bundle.BlockOverride.Timestamp)
-		}
-		if bundle.BlockOverride.GasLimit != nil {
-			blockCtx.GasLimit = uint64(*bundle.BlockOverride.GasLimit)
-		}
-		if bundle.BlockOverride.BlockHash != nil {
-			for blockNum, hash := range *bundle.BlockOverride.BlockHash {
-				overrideBlockHash[blockNum] = hash
-			}
-		}
+		blockHeaderOverride(&blockCtx, bundle.BlockOverride, overrideBlockHash)
 		results := []map[string]interface{}{}
 		for _, txn := range bundle.Transactions {
 			if txn.Gas == nil || *(txn.Gas) == 0 {
@@ -283,6 +276,10 @@ func (api *APIImpl) CallMany(ctx context.Context, bundles []Bundle, simulateCont
 			if err != nil {
 				return nil, err
 			}
+			// the state is carried over to the next calls, like between the transactions of a block
+			if err = evm.IntraBlockState().(*state.IntraBlockState).FinalizeTx(evm.ChainRules(), state.NewNoopWriter()); err != nil {
+				return nil, err
+			}
 			// If the timer caused an abort, return an appropriate error message
 			if evm.Cancelled() {
 				return nil, fmt.Errorf("execution aborted (timeout = %v)", timeout)
@@ -301,8 +298,7 @@ func (api *APIImpl) CallMany(ctx context.Context, bundles []Bundle, simulateCont
 			results = append(results, jsonResult)
 		}
 
-		blockCtx.BlockNumber++
-		blockCtx.Time++
+		nextBlockContext(&blockCtx)
 		ret = append(ret, results)
 	}
 
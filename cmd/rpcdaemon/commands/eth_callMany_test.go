@@ -1,24 +1,31 @@
 package commands
 
 import (
+	"bytes"
 	"context"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"math/big"
 	"strconv"
 	"testing"
 
+	"github.com/holiman/uint256"
+	jsoniter "github.com/json-iterator/go"
 	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
 	"github.com/ledgerwatch/erigon/accounts/abi/bind"
 	"github.com/ledgerwatch/erigon/accounts/abi/bind/backends"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/commands/contracts"
+	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/hexutil"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/tracers"
 	"github.com/ledgerwatch/erigon/internal/ethapi"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/stretchr/testify/require"
 )
 
 // block 1 contains 3 Transactions
@@ -78,7 +85,7 @@ func TestCallMany(t *testing.T) {
 	var secondNonce hexutil.Uint64 = 2
 
 	db := contractBackend.DB()
-	api := NewEthAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), nil, nil, false), db, nil, nil, nil, 5000000)
+	api := NewEthAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), nil, nil, false), db, nil, nil, nil, 5000000, 100_000)
 
 	callArgAddr1 := ethapi.CallArgs{From: &address, To: &tokenAddr, Nonce: &nonce,
 		MaxPriorityFeePerGas: (*hexutil.Big)(big.NewInt(1e9)),
@@ -163,3 +170,78 @@ func TestCallMany(t *testing.T) {
 		t.Errorf("eth_callMany: %s", "balanceUnmatch")
 	}
 }
+
+// the bundles are executed in consecutive blocks, which fields can be overridden, on top of the state
+// left by the previous bundles
+func TestCallManyBlockOverrides(t *testing.T) {
+	config := *params.AllEthashProtocolChanges
+	config.LondonBlock = big.NewInt(0)
+	var (
+		key, _  = crypto.HexToECDSA("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+		address = crypto.PubkeyToAddress(key.PublicKey)
+		gspec   = &core.Genesis{
+			Config:   &config,
+			Alloc:    core.GenesisAlloc{address: {Balance: big.NewInt(9000000000000000000)}},
+			GasLimit: 10000000,
+		}
+		ctx = context.Background()
+
+		blockFields = common.HexToAddress("0x1000")
+		counter     = common.HexToAddress("0x2000")
+	)
+	contractBackend := backends.NewSimulatedBackendWithConfig(gspec.Alloc, gspec.Config, gspec.GasLimit)
+	defer contractBackend.Close()
+	contractBackend.Commit()
+	api := NewEthAPI(NewBaseApi(nil, kvcache.New(kvcache.DefaultCoherentConfig), snapshotsync.NewBlockReader(), nil, nil, false), contractBackend.DB(), nil, nil, nil, 5000000, 100_000)
+
+	// returns NUMBER, TIMESTAMP, COINBASE, DIFFICULTY and BASEFEE
+	blockFieldsCode := hexutil.Bytes(common.FromHex("0x4360005242602052416040524460605248608052" + "60a06000f3"))
+	// increments the slot 0 and returns it
+	counterCode := hexutil.Bytes(common.FromHex("0x6000546001018060005560005260206000f3"))
+	stateOverride := ethapi.StateOverrides{blockFields: {Code: &blockFieldsCode}, counter: {Code: &counterCode}}
+	call := func(to common.Address) ethapi.CallArgs {
+		return ethapi.CallArgs{From: &address, To: &to, MaxFeePerGas: (*hexutil.Big)(big.NewInt(1e10))}
+	}
+
+	head, err := contractBackend.HeaderByNumber(ctx, nil)
+	require.NoError(t, err)
+	number, timestamp := hexutil.Uint64(100), hexutil.Uint64(5000)
+	coinbase := common.HexToAddress("0xc0ffee")
+	difficulty := (*hexutil.Big)(big.NewInt(1234))
+	baseFee := uint256.NewInt(7)
+	timeout := int64(50000)
+	bundles := []Bundle{
+		{Transactions: []ethapi.CallArgs{call(blockFields), call(counter)}},
+		{Transactions: []ethapi.CallArgs{call(blockFields), call(counter)}, BlockOverride: BlockOverrides{BlockNumber: &number, Timestamp: &timestamp, Coinbase: &coinbase, Difficulty: difficulty, BaseFee: baseFee}},
+		{Transactions: []ethapi.CallArgs{call(blockFields), call(counter)}},
+	}
+	res, err := api.CallMany(ctx, bundles, StateContext{BlockNumber: rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)}, &stateOverride, &timeout)
+	require.NoError(t, err)
+	require.Len(t, res, 3)
+
+	word := func(v interface{}) string { return fmt.Sprintf("%064x", v) }
+	blockFieldsOf := func(number, time uint64, coinbase common.Address, difficulty, baseFee *big.Int) string {
+		return word(number) + word(time) + word(coinbase.Hash()) + word(difficulty) + word(baseFee)
+	}
+	require.Equal(t, blockFieldsOf(head.Number.Uint64(), head.Time, head.Coinbase, head.Difficulty, head.BaseFee), res[0][0]["value"])
+	require.Equal(t, blockFieldsOf(100, 5000, coinbase, big.NewInt(1234), big.NewInt(7)), res[1][0]["value"])
+	// the overrides are kept by the next blocks
+	require.Equal(t, blockFieldsOf(101, 5001, coinbase, big.NewInt(1234), big.NewInt(7)), res[2][0]["value"])
+	for i := range res {
+		require.Equal(t, word(i+1), res[i][1]["value"])
+	}
+
+	// the same blocks are traced
+	debugAPI := NewPrivateDebugAPI(api.BaseAPI, contractBackend.DB(), nil, 5000000, 100_000)
+	var buf bytes.Buffer
+	stream := jsoniter.NewStream(jsoniter.ConfigDefault, &buf, 4096)
+	require.NoError(t, debugAPI.TraceCallMany(ctx, bundles, StateContext{BlockNumber: rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber)}, &tracers.TraceConfig{StateOverrides: &stateOverride}, stream))
+	require.NoError(t, stream.Flush())
+	var traces [][]ethapi.ExecutionResult
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &traces))
+	require.Len(t, traces, 3)
+	for i := range traces {
+		require.Equal(t, res[i][0]["value"], traces[i][0].ReturnValue)
+		require.Equal(t, res[i][1]["value"], traces[i][1].ReturnValue)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"math/big"
+	"strings"
 	"testing"
 	"time"
 
@@ -36,7 +37,7 @@ func TestEstimateGas(t *testing.T) {
 	ctx, conn := rpcdaemontest.CreateTestGrpcConn(t, stages.Mock(t))
 	mining := txpool.NewMiningClient(conn)
 	ff := rpchelper.New(ctx, nil, nil, mining, func() {})
-	api := NewEthAPI(NewBaseApi(ff, stateCache, snapshotsync.NewBlockReader(), nil, nil, false), db, nil, nil, nil, 5000000)
+	api := NewEthAPI(NewBaseApi(ff, stateCache, snapshotsync.NewBlockReader(), nil, nil, false), db, nil, nil, nil, 5000000, 100_000)
 	var from = common.HexToAddress("0x71562b71999873db5b286df957af199ec94617f7")
 	var to = common.HexToAddress("0x0d3ab14bbad3d99f4203bd7a11acb94882050e7e")
 	if _, err := api.EstimateGas(context.Background(), &ethapi.CallArgs{
@@ -50,7 +51,7 @@ func TestEstimateGas(t *testing.T) {
 func TestEthCallNonCanonical(t *testing.T) {
 	db := rpcdaemontest.CreateTestKV(t)
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
-	api := NewEthAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), nil, nil, false), db, nil, nil, nil, 5000000)
+	api := NewEthAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), nil, nil, false), db, nil, nil, nil, 5000000, 100_000)
 	var from = common.HexToAddress("0x71562b71999873db5b286df957af199ec94617f7")
 	var to = common.HexToAddress("0x0d3ab14bbad3d99f4203bd7a11acb94882050e7e")
 	if _, err := api.Call(context.Background(), ethapi.CallArgs{
@@ -72,7 +73,7 @@ func TestEthCallToPrunedBlock(t *testing.T) {
 	prune(t, db, pruneTo)
 
 	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
-	api := NewEthAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), nil, nil, false), db, nil, nil, nil, 5000000)
+	api := NewEthAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), nil, nil, false), db, nil, nil, nil, 5000000, 100_000)
 
 	callData := hexutil.MustDecode("0x2e64cec1")
 	callDataBytes := hexutil.Bytes(callData)
@@ -86,6 +87,53 @@ func TestEthCallToPrunedBlock(t *testing.T) {
 	}
 }
 
+func TestGetProof(t *testing.T) {
+	db := rpcdaemontest.CreateTestKV(t)
+	stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
+	api := NewEthAPI(NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), nil, nil, false), db, nil, nil, nil, 5000000, 100_000)
+	address := common.HexToAddress("0x71562b71999873db5b286df957af199ec94617f7")
+
+	ctx := context.Background()
+	var head, old *types.Header
+	if err := db.View(ctx, func(tx kv.Tx) error {
+		head = rawdb.ReadCurrentHeader(tx)
+		old = rawdb.ReadHeaderByNumber(tx, head.Number.Uint64()-3)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// the head block is served from the current state, older blocks require a rewind of the state
+	for _, header := range []*types.Header{head, old} {
+		blockNr := rpc.BlockNumber(header.Number.Int64())
+		proof, err := api.GetProof(ctx, address, []string{"0x0"}, rpc.BlockNumberOrHashWithNumber(blockNr))
+		if err != nil {
+			t.Fatalf("block %d: %v", blockNr, err)
+		}
+		assert.NotEmpty(t, proof.AccountProof)
+		assert.Equal(t, header.Root, crypto.Keccak256Hash(hexutil.MustDecode(proof.AccountProof[0])))
+
+		balance, err := api.GetBalance(ctx, address, rpc.BlockNumberOrHashWithNumber(blockNr))
+		if err != nil {
+			t.Fatal(err)
+		}
+		assert.Equal(t, balance.ToInt(), proof.Balance.ToInt())
+		assert.Equal(t, 1, len(proof.StorageProof))
+	}
+
+	_, err := api.GetProof(ctx, address, nil, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(head.Number.Int64()+1)))
+	assert.NotNil(t, err)
+
+	// malformed and oversized storage keys are invalid params
+	for _, key := range []string{"0xzz", "0x" + strings.Repeat("00", 33)} {
+		_, err = api.GetProof(ctx, address, []string{key}, rpc.BlockNumberOrHashWithNumber(rpc.BlockNumber(head.Number.Int64())))
+		var rpcErr rpc.Error
+		if assert.ErrorAs(t, err, &rpcErr, key) {
+			assert.Equal(t, -32602, rpcErr.ErrorCode(), key)
+		}
+	}
+}
+
 func TestGetBlockByTimestampLatestTime(t *testing.T) {
 	ctx := context.Background()
 	db := rpcdaemontest.CreateTestKV(t)
@@ -5,8 +5,10 @@ import (
 
 	"github.com/ledgerwatch/erigon/common/debug"
 	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/eth/filters"
+	"github.com/ledgerwatch/erigon/ethdb/privateapi"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/log/v3"
@@ -159,8 +161,9 @@ func (api *APIImpl) NewHeads(ctx context.Context) (*rpc.Subscription, error) {
 	return rpcSub, nil
 }
 
-// NewPendingTransactions send a notification each time a new (header) block is appended to the chain.
-func (api *APIImpl) NewPendingTransactions(ctx context.Context) (*rpc.Subscription, error) {
+// NewPendingTransactions send a notification each time a new transaction is added to the pool,
+// with the hash of the transaction, or the full transaction if fullTx is true.
+func (api *APIImpl) NewPendingTransactions(ctx context.Context, fullTx *bool) (*rpc.Subscription, error) {
 	if api.filters == nil {
 		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
 	}
@@ -180,9 +183,22 @@ func (api *APIImpl) NewPendingTransactions(ctx context.Context) (*rpc.Subscripti
 		for {
 			select {
 			case txs, ok := <-txsCh:
-				for _, t := range txs {
+				var rpcTxs []*RPCTransaction
+				if fullTx != nil && *fullTx && len(txs) > 0 {
+					var err error
+					if rpcTxs, err = api.rpcPendingTransactions(ctx, txs); err != nil {
+						log.Warn("error while notifying subscription", "err", err)
+						return
+					}
+				}
+				for i, t := range txs {
 					if t != nil {
-						err := notifier.Notify(rpcSub.ID, t.Hash())
+						var err error
+						if rpcTxs != nil {
+							err = notifier.Notify(rpcSub.ID, rpcTxs[i])
+						} else {
+							err = notifier.Notify(rpcSub.ID, t.Hash())
+						}
 						if err != nil {
 							log.Warn("error while notifying subscription", "err", err)
 							return
@@ -202,6 +218,119 @@ func (api *APIImpl) NewPendingTransactions(ctx context.Context) (*rpc.Subscripti
 	return rpcSub, nil
 }
 
+// rpcPendingTransactions returns the RPC representation of the transactions of the pool.
+func (api *APIImpl) rpcPendingTransactions(ctx context.Context, txs []types.Transaction) ([]*RPCTransaction, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	chainConfig, err := api.chainConfig(tx)
+	if err != nil {
+		return nil, err
+	}
+	curHeader := rawdb.ReadCurrentHeader(tx)
+	rpcTxs := make([]*RPCTransaction, len(txs))
+	for i, t := range txs {
+		if t != nil {
+			rpcTxs[i] = newRPCPendingTransaction(t, curHeader, chainConfig)
+		}
+	}
+	return rpcTxs, nil
+}
+
+// SyncingAPI serves the "syncing" subscription, apart from APIImpl which Syncing method implements eth_syncing.
+type SyncingAPI struct {
+	filters *rpchelper.Filters
+}
+
+func NewSyncingAPI(base *BaseAPI) *SyncingAPI {
+	return &SyncingAPI{filters: base.filters}
+}
+
+// SyncingResult is the notification of the "syncing" subscription while the node is syncing.
+type SyncingResult struct {
+	Syncing bool          `json:"syncing"`
+	Status  SyncingStatus `json:"status"`
+}
+
+type SyncingStatus struct {
+	StartingBlock hexutil.Uint64 `json:"startingBlock"`
+	CurrentBlock  hexutil.Uint64 `json:"currentBlock"`
+	HighestBlock  hexutil.Uint64 `json:"highestBlock"`
+	Stages        []StageStatus  `json:"stages"`
+}
+
+type StageStatus struct {
+	StageName   string         `json:"stage_name"`
+	BlockNumber hexutil.Uint64 `json:"block_number"`
+}
+
+// Syncing send a notification with the sync progress on every sync cycle while the node is
+// syncing, and false once, when it's done.
+func (api *SyncingAPI) Syncing(ctx context.Context) (*rpc.Subscription, error) {
+	if api.filters == nil {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		defer debug.LogPanic()
+		progressCh := make(chan *privateapi.SyncProgress, 1)
+		id := api.filters.SubscribeSyncing(progressCh)
+		defer api.filters.UnsubscribeSyncing(id)
+
+		var syncing bool
+		for {
+			select {
+			case progress, ok := <-progressCh:
+				if progress != nil && (progress.Syncing || syncing) {
+					syncing = progress.Syncing
+					var err error
+					if syncing {
+						err = notifier.Notify(rpcSub.ID, newSyncingResult(progress))
+					} else {
+						err = notifier.Notify(rpcSub.ID, false)
+					}
+					if err != nil {
+						log.Warn("error while notifying subscription", "err", err)
+						return
+					}
+				}
+				if !ok {
+					log.Warn("syncing channel was closed")
+					return
+				}
+			case <-rpcSub.Err():
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+func newSyncingResult(progress *privateapi.SyncProgress) *SyncingResult {
+	result := &SyncingResult{
+		Syncing: true,
+		Status: SyncingStatus{
+			StartingBlock: hexutil.Uint64(progress.StartingBlock),
+			CurrentBlock:  hexutil.Uint64(progress.CurrentBlock),
+			HighestBlock:  hexutil.Uint64(progress.HighestBlock),
+			Stages:        make([]StageStatus, len(progress.Stages)),
+		},
+	}
+	for i, stage := range progress.Stages {
+		result.Status.Stages[i] = StageStatus{StageName: stage.Stage, BlockNumber: hexutil.Uint64(stage.BlockNumber)}
+	}
+	return result
+}
+
 // Logs send a notification each time a new log appears.
 func (api *APIImpl) Logs(ctx context.Context, crit filters.FilterCriteria) (*rpc.Subscription, error) {
 	if api.filters == nil {
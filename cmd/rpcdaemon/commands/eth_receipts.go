@@ -15,20 +15,15 @@ import (
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/dbutils"
 	"github.com/ledgerwatch/erigon/common/hexutil"
-	"github.com/ledgerwatch/erigon/consensus/ethash"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/rawdb"
-	"github.com/ledgerwatch/erigon/core/state"
 	"github.com/ledgerwatch/erigon/core/types"
-	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/eth/filters"
-	"github.com/ledgerwatch/erigon/ethdb"
 	"github.com/ledgerwatch/erigon/ethdb/bitmapdb"
 	"github.com/ledgerwatch/erigon/ethdb/cbor"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
-	"github.com/ledgerwatch/erigon/turbo/transactions"
 )
 
 func (api *BaseAPI) getReceipts(ctx context.Context, tx kv.Tx, chainConfig *params.ChainConfig, block *types.Block, senders []common.Address) (types.Receipts, error) {
@@ -43,32 +38,7 @@ func (api *BaseAPI) getReceipts(ctx context.Context, tx kv.Tx, chainConfig *para
 		}
 		return h
 	}
-	contractHasTEVM := ethdb.GetHasTEVM(tx)
-	_, _, _, ibs, _, err := transactions.ComputeTxEnv(ctx, block, chainConfig, getHeader, contractHasTEVM, ethash.NewFaker(), tx, block.Hash(), 0)
-	if err != nil {
-		return nil, err
-	}
-
-	usedGas := new(uint64)
-	gp := new(core.GasPool).AddGas(block.GasLimit())
-
-	ethashFaker := ethash.NewFaker()
-	noopWriter := state.NewNoopWriter()
-
-	receipts := make(types.Receipts, len(block.Transactions()))
-
-	for i, txn := range block.Transactions() {
-		ibs.Prepare(txn.Hash(), block.Hash(), i)
-		header := block.Header()
-		receipt, _, err := core.ApplyTransaction(chainConfig, core.GetHashFn(header, getHeader), ethashFaker, nil, gp, ibs, noopWriter, header, txn, usedGas, vm.Config{}, contractHasTEVM)
-		if err != nil {
-			return nil, err
-		}
-		receipt.BlockHash = block.Hash()
-		receipts[i] = receipt
-	}
-
-	return receipts, nil
+	return core.ComputeReceipts(ctx, tx, chainConfig, getHeader, block)
 }
 
 // GetLogs implements eth_getLogs. Returns an array of logs matching a given filter object.
@@ -215,7 +185,11 @@ func (api *APIImpl) GetLogs(ctx context.Context, crit filters.FilterCriteria) (t
 		for _, log := range blockLogs {
 			log.BlockNumber = blockNumber
 			log.BlockHash = blockHash
-			log.TxHash = body.Transactions[log.TxIndex].Hash()
+			if log.TxIndex < uint(len(body.Transactions)) {
+				log.TxHash = body.Transactions[log.TxIndex].Hash()
+			} else {
+				log.TxHash = types.ComputeBorTxHash(blockNumber, blockHash) // the state-sync logs of Bor
+			}
 		}
 		logs = append(logs, blockLogs...)
 	}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"fmt"
 	"testing"
+	"time"
 
 	"github.com/ledgerwatch/erigon-lib/direct"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces/sentry"
 	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcservices"
 	"github.com/ledgerwatch/erigon/common"
@@ -61,3 +63,71 @@ func TestEthSubscribe(t *testing.T) {
 		require.Equal(i, header.Number.Uint64())
 	}
 }
+
+func TestEthSubscribeSyncing(t *testing.T) {
+	m, require := stages.Mock(t), require.New(t)
+	chain, err := core.GenerateChain(m.ChainConfig, m.Genesis, m.Engine, m.DB, 7, func(i int, b *core.BlockGen) {
+		b.SetCoinbase(common.Address{1})
+	}, false /* intermediateHashes */)
+	require.NoError(err)
+
+	b, err := rlp.EncodeToBytes(&eth.BlockHeadersPacket66{
+		RequestId:          1,
+		BlockHeadersPacket: chain.Headers,
+	})
+	require.NoError(err)
+
+	m.ReceiveWg.Add(1)
+	for _, err = range m.Send(&sentry.InboundMessage{Id: sentry.MessageId_BLOCK_HEADERS_66, Data: b, PeerId: m.PeerId}) {
+		require.NoError(err)
+	}
+	m.ReceiveWg.Wait() // Wait for all messages to be processed before we proceeed
+
+	ctx := context.Background()
+	backendServer := privateapi.NewEthBackendServer(ctx, nil, m.DB, m.Notifications.Events, snapshotsync.NewBlockReader(), nil, nil, nil, false)
+	backendClient := direct.NewEthBackendClientDirect(backendServer)
+	backend := rpcservices.NewRemoteBackend(backendClient, m.DB, snapshotsync.NewBlockReader())
+	ff := rpchelper.New(ctx, backend, nil, nil, func() {})
+
+	syncing := make(chan *privateapi.SyncProgress, 8)
+	id := ff.SubscribeSyncing(syncing)
+	defer ff.UnsubscribeSyncing(id)
+
+	// the subscriptions not asking for the sync progress don't receive it
+	eventsCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events := make(chan remote.Event, 64)
+	go func() {
+		_ = backend.Subscribe(eventsCtx, func(reply *remote.SubscribeReply) { events <- reply.Type })
+	}()
+
+	highestSeenHeader := chain.TopBlock.NumberU64()
+	if _, err := stages.StageLoopStep(m.Ctx, m.DB, m.Sync, highestSeenHeader, m.Notifications, true /* initialCycle */, m.UpdateHead, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	// the progress sent before the cycle may be missed, the subscription being established meanwhile
+	for {
+		select {
+		case progress := <-syncing:
+			if progress.Syncing {
+				continue
+			}
+			require.Equal(uint64(0), progress.StartingBlock)
+			require.Equal(highestSeenHeader, progress.CurrentBlock)
+			require.Equal(highestSeenHeader, progress.HighestBlock)
+			require.NotEmpty(progress.Stages)
+			for _, stage := range progress.Stages {
+				if stage.Stage == "Execution" {
+					require.Equal(highestSeenHeader, stage.BlockNumber)
+				}
+			}
+			for len(events) > 0 {
+				require.NotEqual(privateapi.EventSyncing, <-events)
+			}
+			return
+		case <-time.After(10 * time.Second):
+			t.Fatal("no sync progress")
+		}
+	}
+}
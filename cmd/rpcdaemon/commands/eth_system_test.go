@@ -44,7 +44,7 @@ func TestGasPrice(t *testing.T) {
 			defer db.Close()
 			stateCache := kvcache.New(kvcache.DefaultCoherentConfig)
 			base := NewBaseApi(nil, stateCache, snapshotsync.NewBlockReader(), nil, nil, false)
-			eth := NewEthAPI(base, db, nil, nil, nil, 5000000)
+			eth := NewEthAPI(base, db, nil, nil, nil, 5000000, 100_000)
 
 			ctx := context.Background()
 			result, err := eth.GasPrice(ctx)
@@ -0,0 +1,110 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon/cmd/state/exec22"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/consensus/parlia"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/rpc"
+)
+
+// maxValidatorActivityRange is the maximum number of blocks scanned by a single parlia_getValidatorActivity call
+const maxValidatorActivityRange = 100_000
+
+// ParliaEpochActivity describes the activity of the validators within an epoch
+type ParliaEpochActivity struct {
+	Epoch      hexutil.Uint64  `json:"epoch"`
+	FromBlock  hexutil.Uint64  `json:"fromBlock"`
+	ToBlock    hexutil.Uint64  `json:"toBlock"`
+	Validators parlia.Activity `json:"validators"`
+}
+
+// GetValidatorActivity reports, per epoch, the blocks sealed in turn and out of turn, the missed turns,
+// the slashes and the distributed rewards of each validator over the given range of blocks. It is
+// computed from the difficulty of the headers and the receipts of the system transactions, so
+// the receipts of the range must not be pruned.
+func (api *ParliaImpl) GetValidatorActivity(ctx context.Context, fromBlock, toBlock rpc.BlockNumber) ([]*ParliaEpochActivity, error) {
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	from, err := api.parliaHeaderByNumber(&fromBlock, tx)
+	if err != nil {
+		return nil, err
+	}
+	to, err := api.parliaHeaderByNumber(&toBlock, tx)
+	if err != nil {
+		return nil, err
+	}
+	start, end := from.Number.Uint64(), to.Number.Uint64()
+	if start == 0 {
+		// the genesis block is not sealed
+		start = 1
+	}
+	if end < start {
+		return nil, fmt.Errorf("invalid block range %d - %d", start, end)
+	}
+	if end-start >= maxValidatorActivityRange {
+		return nil, fmt.Errorf("block range %d - %d exceeds the maximum of %d blocks", start, end, maxValidatorActivityRange)
+	}
+	chainConfig, err := api.chainConfig(tx)
+	if err != nil {
+		return nil, err
+	}
+	chain := exec22.NewChainReader(chainConfig, tx, api._blockReader)
+
+	var (
+		snap   *parlia.Snapshot
+		result []*ParliaEpochActivity
+	)
+	for number := start; number <= end; number++ {
+		if err = ctx.Err(); err != nil {
+			return nil, err
+		}
+		hash, err := rawdb.ReadCanonicalHash(tx, number)
+		if err != nil {
+			return nil, err
+		}
+		block, senders, err := api._blockReader.BlockWithSenders(ctx, tx, hash, number)
+		if err != nil {
+			return nil, err
+		}
+		if block == nil {
+			return nil, fmt.Errorf("block %d not found", number)
+		}
+		// The turn of a block is decided by the snapshot of its parent
+		if snap == nil {
+			if snap, err = api.parliaSnapshot(ctx, tx, number-1, block.ParentHash()); err != nil {
+				return nil, err
+			}
+		}
+		receipts := rawdb.ReadReceipts(tx, block, senders)
+		if receipts == nil {
+			return nil, fmt.Errorf("receipts of block %d are not available", number)
+		}
+		block.SendersToTxs(senders)
+
+		epoch := number / snap.EpochLength()
+		if len(result) == 0 || uint64(result[len(result)-1].Epoch) != epoch {
+			result = append(result, &ParliaEpochActivity{
+				Epoch:      hexutil.Uint64(epoch),
+				FromBlock:  hexutil.Uint64(number),
+				Validators: parlia.Activity{},
+			})
+		}
+		current := result[len(result)-1]
+		current.ToBlock = hexutil.Uint64(number)
+		if err = current.Validators.RecordBlock(block.Header(), snap.InturnValidator(), block.Transactions(), receipts); err != nil {
+			return nil, err
+		}
+		if snap, err = snap.Apply(chain, block.Header()); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
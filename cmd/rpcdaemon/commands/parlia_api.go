@@ -0,0 +1,41 @@
+package commands
+
+import (
+	"context"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/consensus/parlia"
+	"github.com/ledgerwatch/erigon/rpc"
+)
+
+// ParliaAPI Parlia specific routines
+type ParliaAPI interface {
+	// Parlia snapshot related (see ./parlia_snapshot.go)
+	GetSnapshot(number *rpc.BlockNumber) (*parlia.Snapshot, error)
+	GetSnapshotAtHash(hash common.Hash) (*parlia.Snapshot, error)
+	GetValidators(number *rpc.BlockNumber) ([]common.Address, error)
+	GetValidatorsAtHash(hash common.Hash) ([]common.Address, error)
+	GetRecents(number *rpc.BlockNumber) (map[uint64]common.Address, error)
+	GetSigner(number *rpc.BlockNumber) (*ParliaSigner, error)
+	GetEpochValidators(number *rpc.BlockNumber) (*ParliaEpochValidators, error)
+
+	// Validator monitoring (see ./parlia_activity.go)
+	GetValidatorActivity(ctx context.Context, fromBlock, toBlock rpc.BlockNumber) ([]*ParliaEpochActivity, error)
+}
+
+// ParliaImpl is implementation of the ParliaAPI interface
+type ParliaImpl struct {
+	*BaseAPI
+	db       kv.RoDB // the chain db
+	parliaDb kv.RoDB // the consensus db
+}
+
+// NewParliaAPI returns ParliaImpl instance
+func NewParliaAPI(base *BaseAPI, db kv.RoDB, parliaDb kv.RoDB) *ParliaImpl {
+	return &ParliaImpl{
+		BaseAPI:  base,
+		db:       db,
+		parliaDb: parliaDb,
+	}
+}
@@ -0,0 +1,199 @@
+package commands
+
+import (
+	"context"
+	"errors"
+	"math/big"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/cmd/state/exec22"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/consensus/parlia"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/rpc"
+)
+
+// errNoParliaDb is returned when rpcdaemon runs without access to the parlia consensus db
+var errNoParliaDb = errors.New("parlia consensus db is not available, run rpcdaemon with --datadir")
+
+// ParliaSigner describes who sealed a block and whether it was sealed in turn
+type ParliaSigner struct {
+	Number          hexutil.Uint64 `json:"number"`
+	Hash            common.Hash    `json:"hash"`
+	Signer          common.Address `json:"signer"`
+	InturnValidator common.Address `json:"inturnValidator"`
+	Inturn          bool           `json:"inturn"`
+	Difficulty      *hexutil.Big   `json:"difficulty"`
+}
+
+// ParliaEpochValidators describes the validator set announced by an epoch block
+type ParliaEpochValidators struct {
+	Number     hexutil.Uint64   `json:"number"`
+	Hash       common.Hash      `json:"hash"`
+	Validators []common.Address `json:"validators"`
+}
+
+// GetSnapshot retrieves the state snapshot at a given block.
+func (api *ParliaImpl) GetSnapshot(number *rpc.BlockNumber) (*parlia.Snapshot, error) {
+	ctx := context.Background()
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	header, err := api.parliaHeaderByNumber(number, tx)
+	if err != nil {
+		return nil, err
+	}
+	return api.parliaSnapshot(ctx, tx, header.Number.Uint64(), header.Hash())
+}
+
+// GetSnapshotAtHash retrieves the state snapshot at a given block.
+func (api *ParliaImpl) GetSnapshotAtHash(hash common.Hash) (*parlia.Snapshot, error) {
+	ctx := context.Background()
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	header, err := api._blockReader.HeaderByHash(ctx, tx, hash)
+	if err != nil {
+		return nil, err
+	}
+	// Ensure we have an actually valid block
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	return api.parliaSnapshot(ctx, tx, header.Number.Uint64(), header.Hash())
+}
+
+// GetValidators retrieves the list of validators at the specified block.
+func (api *ParliaImpl) GetValidators(number *rpc.BlockNumber) ([]common.Address, error) {
+	snap, err := api.GetSnapshot(number)
+	if err != nil {
+		return nil, err
+	}
+	return snap.SortedValidators(), nil
+}
+
+// GetValidatorsAtHash retrieves the list of validators at the specified block.
+func (api *ParliaImpl) GetValidatorsAtHash(hash common.Hash) ([]common.Address, error) {
+	snap, err := api.GetSnapshotAtHash(hash)
+	if err != nil {
+		return nil, err
+	}
+	return snap.SortedValidators(), nil
+}
+
+// GetRecents retrieves the validators which recently sealed a block and are
+// therefore not allowed to seal again at the specified block.
+func (api *ParliaImpl) GetRecents(number *rpc.BlockNumber) (map[uint64]common.Address, error) {
+	snap, err := api.GetSnapshot(number)
+	if err != nil {
+		return nil, err
+	}
+	return snap.Recents, nil
+}
+
+// GetSigner retrieves the signer of the specified block together with the
+// validator that was expected to seal it in turn.
+func (api *ParliaImpl) GetSigner(number *rpc.BlockNumber) (*ParliaSigner, error) {
+	ctx := context.Background()
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	header, err := api.parliaHeaderByNumber(number, tx)
+	if err != nil {
+		return nil, err
+	}
+	if header.Number.Sign() == 0 {
+		return nil, errors.New("genesis block is not sealed")
+	}
+	// The turn of a block is decided by the snapshot of its parent
+	snap, err := api.parliaSnapshot(ctx, tx, header.Number.Uint64()-1, header.ParentHash)
+	if err != nil {
+		return nil, err
+	}
+	// Parlia enforces the coinbase to be the signer of the block
+	inturn := snap.InturnValidator()
+	return &ParliaSigner{
+		Number:          hexutil.Uint64(header.Number.Uint64()),
+		Hash:            header.Hash(),
+		Signer:          header.Coinbase,
+		InturnValidator: inturn,
+		Inturn:          header.Coinbase == inturn,
+		Difficulty:      (*hexutil.Big)(new(big.Int).Set(header.Difficulty)),
+	}, nil
+}
+
+// GetEpochValidators retrieves the validator set announced by the last epoch block at or before
+// the specified block, as verified against the validator contract when executing it and stored by the PostExec stage.
+func (api *ParliaImpl) GetEpochValidators(number *rpc.BlockNumber) (*ParliaEpochValidators, error) {
+	tx, err := api.db.BeginRo(context.Background())
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	header, err := api.parliaHeaderByNumber(number, tx)
+	if err != nil {
+		return nil, err
+	}
+	epochNum, epochHash, validators, err := rawdb.FindEpochValidatorsBeforeOrEqualNumber(tx, header.Number.Uint64())
+	if err != nil {
+		return nil, err
+	}
+	if validators == nil {
+		return nil, errors.New("epoch validators are not available, PostExec stage has not reached them")
+	}
+	return &ParliaEpochValidators{
+		Number:     hexutil.Uint64(epochNum),
+		Hash:       epochHash,
+		Validators: validators,
+	}, nil
+}
+
+// parliaHeaderByNumber retrieves the requested header (or current if none requested).
+func (api *ParliaImpl) parliaHeaderByNumber(number *rpc.BlockNumber, tx kv.Tx) (*types.Header, error) {
+	var header *types.Header
+	if number == nil || *number == rpc.LatestBlockNumber {
+		header = rawdb.ReadCurrentHeader(tx)
+	} else {
+		var err error
+		if header, err = api.headerByRPCNumber(*number, tx); err != nil {
+			return nil, err
+		}
+	}
+	// Ensure we have an actually valid block
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	return header, nil
+}
+
+// parliaSnapshot retrieves the validator snapshot at a given block from the consensus db.
+func (api *ParliaImpl) parliaSnapshot(ctx context.Context, tx kv.Tx, number uint64, hash common.Hash) (*parlia.Snapshot, error) {
+	if api.parliaDb == nil {
+		return nil, errNoParliaDb
+	}
+	chainConfig, err := api.chainConfig(tx)
+	if err != nil {
+		return nil, err
+	}
+	if chainConfig.Parlia == nil {
+		return nil, errors.New("parlia is not the consensus engine of the chain")
+	}
+	parliaTx, err := api.parliaDb.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer parliaTx.Rollback()
+	return parlia.ReadSnapshot(exec22.NewChainReader(chainConfig, tx, api._blockReader), tx, parliaTx, number, hash)
+}
@@ -16,12 +16,14 @@ import (
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/hexutil"
 	math2 "github.com/ledgerwatch/erigon/common/math"
+	"github.com/ledgerwatch/erigon/consensus/misc"
 	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/state"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/core/types/accounts"
 	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/ethdb"
+	"github.com/ledgerwatch/erigon/rlp"
 	"github.com/ledgerwatch/erigon/rpc"
 	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 	"github.com/ledgerwatch/erigon/turbo/shards"
@@ -1229,8 +1231,67 @@ func (api *TraceAPIImpl) doCallMany(ctx context.Context, dbtx kv.Tx, msgs []type
 	return results, nil
 }
 
-// RawTransaction implements trace_rawTransaction.
-func (api *TraceAPIImpl) RawTransaction(ctx context.Context, txHash common.Hash, traceTypes []string) ([]interface{}, error) {
-	var stub []interface{}
-	return stub, fmt.Errorf(NotImplemented, "trace_rawTransaction")
+// RawTransaction implements trace_rawTransaction. The signed transaction is traced as the first transaction of the
+// block after the given one, on top of its state, the latest by default. Unlike trace_call, the transaction has to pay
+// for its gas, fit in the gas limit of the block and have the right nonce, as it would once broadcast.
+func (api *TraceAPIImpl) RawTransaction(ctx context.Context, encodedTx hexutil.Bytes, traceTypes []string, parentNrOrHash *rpc.BlockNumberOrHash) (*TraceCallResult, error) {
+	txn, err := types.DecodeTransaction(rlp.NewStream(bytes.NewReader(encodedTx), uint64(len(encodedTx))))
+	if err != nil {
+		return nil, err
+	}
+
+	dbtx, err := api.kv.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer dbtx.Rollback()
+
+	chainConfig, err := api.chainConfig(dbtx)
+	if err != nil {
+		return nil, err
+	}
+	if parentNrOrHash == nil {
+		var num = rpc.LatestBlockNumber
+		parentNrOrHash = &rpc.BlockNumberOrHash{BlockNumber: &num}
+	}
+	blockNumber, hash, _, err := rpchelper.GetBlockNumber(*parentNrOrHash, dbtx, api.filters)
+	if err != nil {
+		return nil, err
+	}
+	parentHeader, err := api._blockReader.Header(ctx, dbtx, hash, blockNumber)
+	if err != nil {
+		return nil, err
+	}
+	if parentHeader == nil {
+		return nil, fmt.Errorf("parent header %d(%x) not found", blockNumber, hash)
+	}
+
+	// the next block keeps the gas limit of its parent and is mined at the earliest time it can be
+	header := &types.Header{
+		ParentHash: hash,
+		Number:     new(big.Int).SetUint64(blockNumber + 1),
+		GasLimit:   parentHeader.GasLimit,
+		Time:       parentHeader.Time + 1,
+		Difficulty: parentHeader.Difficulty,
+		Coinbase:   parentHeader.Coinbase,
+	}
+	if chainConfig.IsLondon(blockNumber + 1) {
+		header.Eip1559 = true
+		header.BaseFee = misc.CalcBaseFee(chainConfig, parentHeader)
+	}
+	if txn.GetGas() > header.GasLimit {
+		return nil, fmt.Errorf("%w: tx gas %d, block gas limit %d", core.ErrGasLimitReached, txn.GetGas(), header.GasLimit)
+	}
+	signer := types.MakeSigner(chainConfig, blockNumber+1)
+	msg, err := txn.AsMessage(*signer, header.BaseFee, chainConfig.Rules(blockNumber+1))
+	if err != nil {
+		return nil, fmt.Errorf("convert tx into msg: %w", err)
+	}
+	txHash := txn.Hash()
+	callParams := []TraceCallParam{{txHash: &txHash, traceTypes: traceTypes}}
+	traces, err := api.doCallMany(ctx, dbtx, []types.Message{msg}, callParams, parentNrOrHash, header, false /* gasBailout */, -1 /* all tx indices */)
+	if err != nil {
+		return nil, err
+	}
+	return traces[0], nil
 }
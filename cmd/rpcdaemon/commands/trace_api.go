@@ -19,7 +19,7 @@ type TraceAPI interface {
 	ReplayTransaction(ctx context.Context, txHash common.Hash, traceTypes []string) (*TraceCallResult, error)
 	Call(ctx context.Context, call TraceCallParam, types []string, blockNr *rpc.BlockNumberOrHash) (*TraceCallResult, error)
 	CallMany(ctx context.Context, calls json.RawMessage, blockNr *rpc.BlockNumberOrHash) ([]*TraceCallResult, error)
-	RawTransaction(ctx context.Context, txHash common.Hash, traceTypes []string) ([]interface{}, error)
+	RawTransaction(ctx context.Context, encodedTx hexutil.Bytes, traceTypes []string, blockNr *rpc.BlockNumberOrHash) (*TraceCallResult, error)
 
 	// Filtering (see ./trace_filtering.go)
 	Transaction(ctx context.Context, txHash common.Hash) (ParityTraces, error)
@@ -32,16 +32,18 @@ type TraceAPI interface {
 type TraceAPIImpl struct {
 	*BaseAPI
 	kv            kv.RoDB
+	tracesDb      kv.RoDB // traces stored by the Traces stage, nil when there are none
 	maxTraces     uint64
 	gasCap        uint64
 	compatibility bool // Bug for bug compatiblity with OpenEthereum
 }
 
 // NewTraceAPI returns NewTraceAPI instance
-func NewTraceAPI(base *BaseAPI, kv kv.RoDB, cfg *httpcfg.HttpCfg) *TraceAPIImpl {
+func NewTraceAPI(base *BaseAPI, kv kv.RoDB, tracesDb kv.RoDB, cfg *httpcfg.HttpCfg) *TraceAPIImpl {
 	return &TraceAPIImpl{
 		BaseAPI:       base,
 		kv:            kv,
+		tracesDb:      tracesDb,
 		maxTraces:     cfg.MaxTraces,
 		gasCap:        cfg.Gascap,
 		compatibility: cfg.TraceCompatibility,
@@ -64,15 +64,17 @@ func (api *TraceAPIImpl) Transaction(ctx context.Context, txHash common.Hash) (P
 		}
 	}
 	bn := hexutil.Uint64(blockNumber)
-
-	parentNr := bn
-	if parentNr > 0 {
-		parentNr -= 1
-	}
 	hash := block.Hash()
 
+	tracesTx, err := api.beginTracesTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tracesTx != nil {
+		defer tracesTx.Rollback()
+	}
 	// Returns an array of trace arrays, one trace array for each transaction
-	traces, err := api.callManyTransactions(ctx, tx, block.Transactions(), []string{TraceTypeTrace}, block.ParentHash(), rpc.BlockNumber(parentNr), block.Header(), txIndex, types.MakeSigner(chainConfig, blockNumber), chainConfig.Rules(blockNumber))
+	traces, err := api.blockTraces(ctx, tx, tracesTx, block, txIndex, chainConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -144,16 +146,18 @@ func (api *TraceAPIImpl) Block(ctx context.Context, blockNr rpc.BlockNumber) (Pa
 		return nil, fmt.Errorf("could not find block %d", uint64(bn))
 	}
 
-	parentNr := bn
-	if parentNr > 0 {
-		parentNr -= 1
-	}
-
 	chainConfig, err := api.chainConfig(tx)
 	if err != nil {
 		return nil, err
 	}
-	traces, err := api.callManyTransactions(ctx, tx, block.Transactions(), []string{TraceTypeTrace}, block.ParentHash(), rpc.BlockNumber(parentNr), block.Header(), -1 /* all tx indices */, types.MakeSigner(chainConfig, blockNum), chainConfig.Rules(blockNum))
+	tracesTx, err := api.beginTracesTx(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if tracesTx != nil {
+		defer tracesTx.Rollback()
+	}
+	traces, err := api.blockTraces(ctx, tx, tracesTx, block, -1 /* all tx indices */, chainConfig)
 	if err != nil {
 		return nil, err
 	}
@@ -207,7 +211,7 @@ func (api *TraceAPIImpl) Block(ctx context.Context, blockNr rpc.BlockNumber) (Pa
 }
 
 // Filter implements trace_filter
-// NOTE: We do not store full traces - we just store index for each address
+// NOTE: Unless the Traces stage stores full traces, we just store index for each address
 // Pull blocks which have txs with matching address
 func (api *TraceAPIImpl) Filter(ctx context.Context, req TraceFilterRequest, stream *jsoniter.Stream) error {
 	dbtx, err1 := api.kv.BeginRo(ctx)
@@ -293,6 +297,13 @@ func (api *TraceAPIImpl) Filter(ctx context.Context, req TraceFilterRequest, str
 	if err != nil {
 		return err
 	}
+	tracesTx, err := api.beginTracesTx(ctx)
+	if err != nil {
+		return err
+	}
+	if tracesTx != nil {
+		defer tracesTx.Rollback()
+	}
 
 	var json = jsoniter.ConfigCompatibleWithStandardLibrary
 	stream.WriteArrayStart()
@@ -354,7 +365,7 @@ func (api *TraceAPIImpl) Filter(ctx context.Context, req TraceFilterRequest, str
 		blockHash := block.Hash()
 		blockNumber := block.NumberU64()
 		txs := block.Transactions()
-		t, tErr := api.callManyTransactions(ctx, dbtx, txs, []string{TraceTypeTrace}, block.ParentHash(), rpc.BlockNumber(block.NumberU64()-1), block.Header(), -1 /* all tx indices */, types.MakeSigner(chainConfig, b), chainConfig.Rules(b))
+		t, tErr := api.blockTraces(ctx, dbtx, tracesTx, block, -1 /* all tx indices */, chainConfig)
 		if tErr != nil {
 			if first {
 				first = false
@@ -0,0 +1,185 @@
+package commands
+
+import (
+	"context"
+	"math/big"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/cli/httpcfg"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
+	"github.com/ledgerwatch/erigon/ethdb/tracedb"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/services"
+)
+
+// storedTrace is the compact form of a ParityTrace stored by the Traces stage. The fields are
+// shared by the actions and the results of the call, create and suicide traces. The block and
+// transaction of the trace are known from the key it is stored at.
+type storedTrace struct {
+	Type         string
+	CallType     string
+	From         common.Address // Address of the suicide action
+	To           common.Address // created Address of the create result, RefundAddress of the suicide action
+	Gas          uint64
+	Value        *big.Int // Balance of the suicide action
+	Input        []byte   // Init of the create action
+	HasResult    bool
+	GasUsed      uint64
+	Output       []byte // Code of the create result
+	Error        string
+	Subtraces    uint64
+	TraceAddress []uint64
+}
+
+func toStoredTrace(pt *ParityTrace) storedTrace {
+	st := storedTrace{Type: pt.Type, Error: pt.Error, Subtraces: uint64(pt.Subtraces), TraceAddress: make([]uint64, len(pt.TraceAddress))}
+	for i, a := range pt.TraceAddress {
+		st.TraceAddress[i] = uint64(a)
+	}
+	switch action := pt.Action.(type) {
+	case *CallTraceAction:
+		st.CallType, st.From, st.To = action.CallType, action.From, action.To
+		st.Gas, st.Value, st.Input = action.Gas.ToInt().Uint64(), action.Value.ToInt(), action.Input
+	case *CreateTraceAction:
+		st.From = action.From
+		st.Gas, st.Value, st.Input = action.Gas.ToInt().Uint64(), action.Value.ToInt(), action.Init
+	case *SuicideTraceAction:
+		st.From, st.To, st.Value = action.Address, action.RefundAddress, action.Balance.ToInt()
+	}
+	switch result := pt.Result.(type) {
+	case *TraceResult:
+		st.HasResult, st.Output = true, result.Output
+		if result.GasUsed != nil {
+			st.GasUsed = result.GasUsed.ToInt().Uint64()
+		}
+	case *CreateTraceResult:
+		st.HasResult, st.Output = true, result.Code
+		if result.Address != nil {
+			st.To = *result.Address
+		}
+		if result.GasUsed != nil {
+			st.GasUsed = result.GasUsed.ToInt().Uint64()
+		}
+	}
+	return st
+}
+
+func (st *storedTrace) toParityTrace() *ParityTrace {
+	pt := &ParityTrace{Type: st.Type, Error: st.Error, Subtraces: int(st.Subtraces), TraceAddress: make([]int, len(st.TraceAddress))}
+	for i, a := range st.TraceAddress {
+		pt.TraceAddress[i] = int(a)
+	}
+	switch st.Type {
+	case CALL:
+		action := &CallTraceAction{From: st.From, CallType: st.CallType, Input: st.Input, To: st.To}
+		action.Gas.ToInt().SetUint64(st.Gas)
+		action.Value.ToInt().Set(st.Value)
+		pt.Action = action
+		if st.HasResult {
+			pt.Result = &TraceResult{GasUsed: (*hexutil.Big)(new(big.Int).SetUint64(st.GasUsed)), Output: st.Output}
+		}
+	case CREATE:
+		action := &CreateTraceAction{From: st.From, Init: st.Input}
+		action.Gas.ToInt().SetUint64(st.Gas)
+		action.Value.ToInt().Set(st.Value)
+		pt.Action = action
+		if st.HasResult {
+			address := st.To
+			pt.Result = &CreateTraceResult{Address: &address, Code: st.Output, GasUsed: (*hexutil.Big)(new(big.Int).SetUint64(st.GasUsed))}
+		}
+	case SUICIDE:
+		action := &SuicideTraceAction{Address: st.From, RefundAddress: st.To}
+		action.Balance.ToInt().Set(st.Value)
+		pt.Action = action
+	}
+	return pt
+}
+
+func encodeBlockTraces(traces []*TraceCallResult) ([]byte, error) {
+	stored := make([][]storedTrace, len(traces))
+	for i, trace := range traces {
+		stored[i] = make([]storedTrace, len(trace.Trace))
+		for j, pt := range trace.Trace {
+			stored[i][j] = toStoredTrace(pt)
+		}
+	}
+	return rlp.EncodeToBytes(stored)
+}
+
+func decodeBlockTraces(data []byte) ([]*TraceCallResult, error) {
+	var stored [][]storedTrace
+	if err := rlp.DecodeBytes(data, &stored); err != nil {
+		return nil, err
+	}
+	traces := make([]*TraceCallResult, len(stored))
+	for i := range stored {
+		traces[i] = &TraceCallResult{Trace: make([]*ParityTrace, len(stored[i]))}
+		for j := range stored[i] {
+			traces[i].Trace[j] = stored[i][j].toParityTrace()
+		}
+	}
+	return traces, nil
+}
+
+// NewBlockTracer returns the tracer of the Traces stage, which stores the traces served by
+// trace_block, trace_transaction and trace_filter.
+func NewBlockTracer(blockReader services.FullBlockReader) stagedsync.BlockTracer {
+	api := NewTraceAPI(NewBaseApi(nil, kvcache.NewDummy(), blockReader, nil, nil, false), nil, nil, &httpcfg.HttpCfg{})
+	return func(ctx context.Context, tx kv.Tx, block *types.Block) ([]byte, error) {
+		chainConfig, err := api.chainConfig(tx)
+		if err != nil {
+			return nil, err
+		}
+		traces, err := api.traceBlockTransactions(ctx, tx, block, -1 /* all tx indices */, chainConfig)
+		if err != nil {
+			return nil, err
+		}
+		return encodeBlockTraces(traces)
+	}
+}
+
+// beginTracesTx opens a transaction of the database of the stored traces, nil if there is no database.
+func (api *TraceAPIImpl) beginTracesTx(ctx context.Context) (kv.Tx, error) {
+	if api.tracesDb == nil {
+		return nil, nil
+	}
+	return api.tracesDb.BeginRo(ctx)
+}
+
+// blockTraces returns the traces of the transactions of the block stored by the Traces stage,
+// or re-executes the block, tracing the transaction at txIndex or all of them for -1, when the
+// traces are not stored. The stored traces are not bug for bug compatible with OpenEthereum.
+func (api *TraceAPIImpl) blockTraces(ctx context.Context, dbtx kv.Tx, tracesTx kv.Tx, block *types.Block, txIndex int, chainConfig *params.ChainConfig) ([]*TraceCallResult, error) {
+	if tracesTx != nil && !api.compatibility {
+		data, err := tracedb.ReadBlockTraces(tracesTx, block.NumberU64(), block.Hash())
+		if err != nil {
+			return nil, err
+		}
+		if data != nil {
+			traces, err := decodeBlockTraces(data)
+			if err != nil {
+				return nil, err
+			}
+			if len(traces) == len(block.Transactions()) {
+				return traces, nil
+			}
+		}
+	}
+	return api.traceBlockTransactions(ctx, dbtx, block, txIndex, chainConfig)
+}
+
+// traceBlockTransactions re-executes the block, tracing the transaction at txIndex or all of them for -1.
+func (api *TraceAPIImpl) traceBlockTransactions(ctx context.Context, dbtx kv.Tx, block *types.Block, txIndex int, chainConfig *params.ChainConfig) ([]*TraceCallResult, error) {
+	blockNum := block.NumberU64()
+	parentNr := blockNum
+	if parentNr > 0 {
+		parentNr -= 1
+	}
+	return api.callManyTransactions(ctx, dbtx, block.Transactions(), []string{TraceTypeTrace}, block.ParentHash(), rpc.BlockNumber(parentNr), block.Header(), txIndex, types.MakeSigner(chainConfig, blockNum), chainConfig.Rules(blockNum))
+}
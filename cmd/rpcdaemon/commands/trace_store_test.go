@@ -0,0 +1,98 @@
+package commands
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/kvcache"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/cli/httpcfg"
+	"github.com/ledgerwatch/erigon/cmd/rpcdaemon/rpcdaemontest"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	prunemode "github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/ledgerwatch/erigon/ethdb/tracedb"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStoredTraces(t *testing.T) {
+	ctx := context.Background()
+	db := rpcdaemontest.CreateTestKV(t)
+	tracesDB := tracedb.OpenInMem(log.New())
+	defer tracesDB.Close()
+	blockReader := snapshotsync.NewBlockReader()
+	api := NewTraceAPI(NewBaseApi(nil, kvcache.New(kvcache.DefaultCoherentConfig), blockReader, nil, nil, false), db, tracesDB, &httpcfg.HttpCfg{})
+
+	var head uint64
+	require.NoError(t, db.View(ctx, func(tx kv.Tx) (err error) {
+		head, err = stages.GetStageProgress(tx, stages.CallTraces)
+		return err
+	}))
+	// nothing is stored yet, the blocks are re-executed
+	blockTraces := func(blockNum uint64) string {
+		traces, err := api.Block(ctx, rpc.BlockNumber(blockNum))
+		require.NoError(t, err)
+		b, err := json.Marshal(traces)
+		require.NoError(t, err)
+		return string(b)
+	}
+	reexecuted := make([]string, head+1)
+	for blockNum := uint64(1); blockNum <= head; blockNum++ {
+		reexecuted[blockNum] = blockTraces(blockNum)
+	}
+
+	cfg := stagedsync.StageTracesCfg(db, tracesDB, NewBlockTracer(blockReader), prunemode.DefaultMode, blockReader)
+	require.NoError(t, stagedsync.SpawnTraces(&stagedsync.StageState{ID: stages.Traces}, nil, cfg, ctx))
+
+	storedTraces := func(blockNum uint64) (data []byte) {
+		require.NoError(t, db.View(ctx, func(tx kv.Tx) error {
+			hash, err := rawdb.ReadCanonicalHash(tx, blockNum)
+			if err != nil {
+				return err
+			}
+			return tracesDB.View(ctx, func(tracesTx kv.Tx) error {
+				data, err = tracedb.ReadBlockTraces(tracesTx, blockNum, hash)
+				return err
+			})
+		}))
+		return data
+	}
+	var stored []uint64
+	for blockNum := uint64(1); blockNum <= head; blockNum++ {
+		if storedTraces(blockNum) != nil {
+			stored = append(stored, blockNum)
+		}
+		require.JSONEq(t, reexecuted[blockNum], blockTraces(blockNum), "block %d", blockNum)
+	}
+	require.NotEmpty(t, stored)
+
+	// the stored traces are served, instead of re-executing the block
+	blockNum := stored[len(stored)-1]
+	traces, err := decodeBlockTraces(storedTraces(blockNum))
+	require.NoError(t, err)
+	traces[0].Trace[0].Error = "stored"
+	data, err := encodeBlockTraces(traces)
+	require.NoError(t, err)
+	require.NoError(t, db.View(ctx, func(tx kv.Tx) error {
+		hash, err := rawdb.ReadCanonicalHash(tx, blockNum)
+		if err != nil {
+			return err
+		}
+		return tracesDB.Update(ctx, func(tracesTx kv.RwTx) error {
+			return tracedb.WriteBlockTraces(tracesTx, blockNum, hash, data)
+		})
+	}))
+	served, err := api.Block(ctx, rpc.BlockNumber(blockNum))
+	require.NoError(t, err)
+	require.Equal(t, "stored", served[0].Error)
+
+	u := &stagedsync.UnwindState{ID: stages.Traces, UnwindPoint: blockNum - 1}
+	require.NoError(t, stagedsync.UnwindTraces(u, &stagedsync.StageState{ID: stages.Traces, BlockNumber: head}, nil, cfg, ctx))
+	require.Nil(t, storedTraces(blockNum))
+	require.JSONEq(t, reexecuted[blockNum], blockTraces(blockNum))
+}
@@ -403,7 +403,10 @@ func (api *PrivateDebugAPIImpl) TraceCallMany(ctx context.Context, bundles []Bun
 			stream.WriteNil()
 			return err
 		}
-
+		if err = evm.IntraBlockState().(*state.IntraBlockState).FinalizeTx(rules, state.NewNoopWriter()); err != nil {
+			stream.WriteNil()
+			return err
+		}
 	}
 
 	// after replaying the txns, we want to overload the state
@@ -438,6 +441,11 @@ func (api *PrivateDebugAPIImpl) TraceCallMany(ctx context.Context, bundles []Bun
 				stream.WriteNil()
 				return err
 			}
+			// the state is carried over to the next calls, like between the transactions of a block
+			if err = ibs.FinalizeTx(chainConfig.Rules(blockCtx.BlockNumber), state.NewNoopWriter()); err != nil {
+				stream.WriteNil()
+				return err
+			}
 
 			if txn_index < len(bundle.Transactions)-1 {
 				stream.WriteMore()
@@ -448,8 +456,7 @@ func (api *PrivateDebugAPIImpl) TraceCallMany(ctx context.Context, bundles []Bun
 		if bundle_index < len(bundles)-1 {
 			stream.WriteMore()
 		}
-		blockCtx.BlockNumber++
-		blockCtx.Time++
+		nextBlockContext(&blockCtx)
 	}
 	stream.WriteArrayEnd()
 	return nil
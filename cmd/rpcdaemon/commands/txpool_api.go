@@ -4,20 +4,31 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"sort"
 
+	"github.com/holiman/uint256"
+
+	"github.com/ledgerwatch/erigon-lib/common/cmp"
 	"github.com/ledgerwatch/erigon-lib/gointerfaces"
 	proto_txpool "github.com/ledgerwatch/erigon-lib/gointerfaces/txpool"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/consensus/misc"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
 	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/ledgerwatch/erigon/rpc"
+	"github.com/ledgerwatch/erigon/turbo/rpchelper"
 )
 
 // NetAPI the interface for the net_ RPC commands
 type TxPoolAPI interface {
 	Content(ctx context.Context) (map[string]map[string]map[string]*RPCTransaction, error)
+	ContentFrom(ctx context.Context, addr common.Address) (map[string]map[string]*RPCTransaction, error)
+	Inspect(ctx context.Context) (map[string]map[string]map[string]string, error)
+	NotPendingReasons(ctx context.Context) (map[string]map[string]map[string][]string, error)
 }
 
 // TxPoolAPIImpl data structure to store things needed for net_ commands
@@ -37,45 +48,46 @@ func NewTxPoolAPI(base *BaseAPI, db kv.RoDB, pool proto_txpool.TxpoolClient) *Tx
 }
 
 func (api *TxPoolAPIImpl) Content(ctx context.Context) (map[string]map[string]map[string]*RPCTransaction, error) {
-	reply, err := api.pool.All(ctx, &proto_txpool.AllRequest{})
+	subpools, err := api.poolTransactions(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	content := map[string]map[string]map[string]*RPCTransaction{
-		"pending": make(map[string]map[string]*RPCTransaction),
-		"baseFee": make(map[string]map[string]*RPCTransaction),
-		"queued":  make(map[string]map[string]*RPCTransaction),
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	cc, err := api.chainConfig(tx)
+	if err != nil {
+		return nil, err
 	}
 
-	pending := make(map[common.Address][]types.Transaction, 8)
-	baseFee := make(map[common.Address][]types.Transaction, 8)
-	queued := make(map[common.Address][]types.Transaction, 8)
-	for i := range reply.Txs {
-		stream := rlp.NewStream(bytes.NewReader(reply.Txs[i].RlpTx), 0)
-		txn, err := types.DecodeTransaction(stream)
-		if err != nil {
-			return nil, err
-		}
-		addr := gointerfaces.ConvertH160toAddress(reply.Txs[i].Sender)
-		switch reply.Txs[i].TxnType {
-		case proto_txpool.AllReply_PENDING:
-			if _, ok := pending[addr]; !ok {
-				pending[addr] = make([]types.Transaction, 0, 4)
-			}
-			pending[addr] = append(pending[addr], txn)
-		case proto_txpool.AllReply_BASE_FEE:
-			if _, ok := baseFee[addr]; !ok {
-				baseFee[addr] = make([]types.Transaction, 0, 4)
-			}
-			baseFee[addr] = append(baseFee[addr], txn)
-		case proto_txpool.AllReply_QUEUED:
-			if _, ok := queued[addr]; !ok {
-				queued[addr] = make([]types.Transaction, 0, 4)
+	curHeader := rawdb.ReadCurrentHeader(tx)
+	if curHeader == nil {
+		return nil, nil
+	}
+	content := make(map[string]map[string]map[string]*RPCTransaction, len(subpools))
+	for subpool, senders := range subpools {
+		content[subpool] = make(map[string]map[string]*RPCTransaction, len(senders))
+		// Flatten the transactions of the sub-pool
+		for account, txs := range senders {
+			dump := make(map[string]*RPCTransaction)
+			for _, txn := range txs {
+				dump[fmt.Sprintf("%d", txn.GetNonce())] = newRPCPendingTransaction(txn, curHeader, cc)
 			}
-			queued[addr] = append(queued[addr], txn)
+			content[subpool][account.Hex()] = dump
 		}
 	}
+	return content, nil
+}
+
+// ContentFrom returns the transactions of the address in the pool, by sub-pool and nonce.
+func (api *TxPoolAPIImpl) ContentFrom(ctx context.Context, addr common.Address) (map[string]map[string]*RPCTransaction, error) {
+	subpools, err := api.poolTransactions(ctx, &addr)
+	if err != nil {
+		return nil, err
+	}
 
 	tx, err := api.db.BeginRo(ctx)
 	if err != nil {
@@ -91,80 +103,198 @@ func (api *TxPoolAPIImpl) Content(ctx context.Context) (map[string]map[string]ma
 	if curHeader == nil {
 		return nil, nil
 	}
-	// Flatten the pending transactions
-	for account, txs := range pending {
+	content := make(map[string]map[string]*RPCTransaction, len(subpools))
+	for subpool, senders := range subpools {
 		dump := make(map[string]*RPCTransaction)
-		for _, txn := range txs {
+		for _, txn := range senders[addr] {
 			dump[fmt.Sprintf("%d", txn.GetNonce())] = newRPCPendingTransaction(txn, curHeader, cc)
 		}
-		content["pending"][account.Hex()] = dump
+		content[subpool] = dump
 	}
-	// Flatten the baseFee transactions
-	for account, txs := range baseFee {
-		dump := make(map[string]*RPCTransaction)
-		for _, txn := range txs {
-			dump[fmt.Sprintf("%d", txn.GetNonce())] = newRPCPendingTransaction(txn, curHeader, cc)
+	return content, nil
+}
+
+// Inspect retrieves the content of the transaction pool and flattens it into an
+// easily inspectable list.
+func (api *TxPoolAPIImpl) Inspect(ctx context.Context) (map[string]map[string]map[string]string, error) {
+	subpools, err := api.poolTransactions(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Define a formatter to flatten a transaction into a string
+	var format = func(txn types.Transaction) string {
+		if to := txn.GetTo(); to != nil {
+			return fmt.Sprintf("%s: %v wei + %v gas × %v wei", to.Hex(), txn.GetValue(), txn.GetGas(), txn.GetFeeCap())
 		}
-		content["baseFee"][account.Hex()] = dump
+		return fmt.Sprintf("contract creation: %v wei + %v gas × %v wei", txn.GetValue(), txn.GetGas(), txn.GetFeeCap())
 	}
-	// Flatten the queued transactions
-	for account, txs := range queued {
-		dump := make(map[string]*RPCTransaction)
-		for _, txn := range txs {
-			dump[fmt.Sprintf("%d", txn.GetNonce())] = newRPCPendingTransaction(txn, curHeader, cc)
+	content := make(map[string]map[string]map[string]string, len(subpools))
+	for subpool, senders := range subpools {
+		content[subpool] = make(map[string]map[string]string, len(senders))
+		// Flatten the transactions of the sub-pool
+		for account, txs := range senders {
+			dump := make(map[string]string)
+			for _, txn := range txs {
+				dump[fmt.Sprintf("%d", txn.GetNonce())] = format(txn)
+			}
+			content[subpool][account.Hex()] = dump
 		}
-		content["queued"][account.Hex()] = dump
 	}
 	return content, nil
 }
 
-// Status returns the number of pending and queued transaction in the pool.
-func (api *TxPoolAPIImpl) Status(ctx context.Context) (map[string]hexutil.Uint, error) {
-	reply, err := api.pool.Status(ctx, &proto_txpool.StatusRequest{})
+// NotPendingReasons returns the reasons the transactions of the pool which are not pending can't be
+// included in the pending block, by sub-pool, sender and nonce: a nonce gap, an insufficient balance
+// of the sender or a fee cap below the base fee of the pending block. The reasons are empty when the
+// transaction is held back for another reason, like the limits of the pool.
+func (api *TxPoolAPIImpl) NotPendingReasons(ctx context.Context) (map[string]map[string]map[string][]string, error) {
+	subpools, err := api.poolTransactions(ctx, nil)
 	if err != nil {
 		return nil, err
 	}
-	return map[string]hexutil.Uint{
-		"pending": hexutil.Uint(reply.PendingCount),
-		"baseFee": hexutil.Uint(reply.BaseFeeCount),
-		"queued":  hexutil.Uint(reply.QueuedCount),
-	}, nil
-}
 
-/*
+	tx, err := api.db.BeginRo(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer tx.Rollback()
+	cc, err := api.chainConfig(tx)
+	if err != nil {
+		return nil, err
+	}
+	curHeader := rawdb.ReadCurrentHeader(tx)
+	if curHeader == nil {
+		return nil, nil
+	}
+	var baseFee *uint256.Int
+	if cc.IsLondon(curHeader.Number.Uint64() + 1) {
+		baseFee, _ = uint256.FromBig(misc.CalcBaseFee(cc, curHeader))
+	}
+	reader, err := rpchelper.CreateStateReader(ctx, tx, rpc.BlockNumberOrHashWithNumber(rpc.LatestBlockNumber), api.filters, api.stateCache)
+	if err != nil {
+		return nil, err
+	}
 
-// Inspect retrieves the content of the transaction pool and flattens it into an
-// easily inspectable list.
-func (s *PublicTxPoolAPI) Inspect() map[string]map[string]map[string]string {
-	content := map[string]map[string]map[string]string{
-		"pending": make(map[string]map[string]string),
-		"queued":  make(map[string]map[string]string),
+	// the reasons depend on all the transactions of the sender, sorted by nonce
+	bySender := make(map[common.Address][]types.Transaction)
+	for _, senders := range subpools {
+		for account, txs := range senders {
+			bySender[account] = append(bySender[account], txs...)
+		}
+	}
+	reasons := make(map[types.Transaction][]string)
+	for account, txs := range bySender {
+		acc, err := reader.ReadAccountData(account)
+		if err != nil {
+			return nil, err
+		}
+		if acc == nil {
+			acc = &accounts.Account{}
+		}
+		sort.Slice(txs, func(i, j int) bool { return txs[i].GetNonce() < txs[j].GetNonce() })
+		for i, r := range notPendingReasons(txs, acc.Nonce, &acc.Balance, baseFee) {
+			reasons[txs[i]] = r
+		}
 	}
-	pending, queue := s.b.TxPoolContent()
 
-	// Define a formatter to flatten a transaction into a string
-	var format = func(tx *types.Transaction) string {
-		if to := tx.To(); to != nil {
-			return fmt.Sprintf("%s: %v wei + %v gas × %v wei", tx.To().Hex(), tx.Value(), tx.Gas(), tx.GasPrice())
+	content := make(map[string]map[string]map[string][]string, len(subpools)-1)
+	for subpool, senders := range subpools {
+		if subpool == "pending" {
+			continue
 		}
-		return fmt.Sprintf("contract creation: %v wei + %v gas × %v wei", tx.Value(), tx.Gas(), tx.GasPrice())
+		content[subpool] = make(map[string]map[string][]string, len(senders))
+		for account, txs := range senders {
+			dump := make(map[string][]string)
+			for _, txn := range txs {
+				dump[fmt.Sprintf("%d", txn.GetNonce())] = append([]string{}, reasons[txn]...)
+			}
+			content[subpool][account.Hex()] = dump
+		}
+	}
+	return content, nil
+}
+
+// poolTransactions returns the transactions of the pool by sub-pool and sender, only the ones of
+// the sender if it's not nil. The sub-pools are always present, even when they're empty.
+func (api *TxPoolAPIImpl) poolTransactions(ctx context.Context, sender *common.Address) (map[string]map[common.Address][]types.Transaction, error) {
+	reply, err := api.pool.All(ctx, &proto_txpool.AllRequest{})
+	if err != nil {
+		return nil, err
+	}
+
+	subpools := map[string]map[common.Address][]types.Transaction{
+		"pending": make(map[common.Address][]types.Transaction, 8),
+		"baseFee": make(map[common.Address][]types.Transaction, 8),
+		"queued":  make(map[common.Address][]types.Transaction, 8),
 	}
-	// Flatten the pending transactions
-	for account, txs := range pending {
-		dump := make(map[string]string)
-		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = format(tx)
+	for i := range reply.Txs {
+		addr := gointerfaces.ConvertH160toAddress(reply.Txs[i].Sender)
+		if sender != nil && addr != *sender {
+			continue
+		}
+		var subpool string
+		switch reply.Txs[i].TxnType {
+		case proto_txpool.AllReply_PENDING:
+			subpool = "pending"
+		case proto_txpool.AllReply_BASE_FEE:
+			subpool = "baseFee"
+		case proto_txpool.AllReply_QUEUED:
+			subpool = "queued"
+		default:
+			continue
+		}
+		stream := rlp.NewStream(bytes.NewReader(reply.Txs[i].RlpTx), 0)
+		txn, err := types.DecodeTransaction(stream)
+		if err != nil {
+			return nil, err
 		}
-		content["pending"][account.Hex()] = dump
+		subpools[subpool][addr] = append(subpools[subpool][addr], txn)
 	}
-	// Flatten the queued transactions
-	for account, txs := range queue {
-		dump := make(map[string]string)
-		for _, tx := range txs {
-			dump[fmt.Sprintf("%d", tx.Nonce())] = format(tx)
+	return subpools, nil
+}
+
+// notPendingReasons returns the reasons each transaction of a sender, sorted by nonce, can't be
+// included in the pending block, the way the pool sorts them into its sub-pools: a nonce gap
+// from the nonce of the sender or a previous transaction, a balance of the sender below the
+// cost of the transaction and the previous ones, or a fee cap below the base fee, if any.
+func notPendingReasons(txs []types.Transaction, nonce uint64, balance *uint256.Int, baseFee *uint256.Int) [][]string {
+	reasons := make([][]string, len(txs))
+	cost := new(uint256.Int)
+	gap, overflow := false, false
+	for i, txn := range txs {
+		if txn.GetNonce() > nonce {
+			gap = true
+		}
+		nonce = cmp.Max(nonce, txn.GetNonce()+1)
+		if gap {
+			reasons[i] = append(reasons[i], "nonce gap")
+		}
+
+		txCost, overflow1 := new(uint256.Int).MulOverflow(txn.GetFeeCap(), uint256.NewInt(txn.GetGas()))
+		_, overflow2 := txCost.AddOverflow(txCost, txn.GetValue())
+		_, overflow3 := cost.AddOverflow(cost, txCost)
+		overflow = overflow || overflow1 || overflow2 || overflow3
+		if overflow || cost.Gt(balance) {
+			reasons[i] = append(reasons[i], "insufficient balance")
+		}
+
+		if baseFee != nil && txn.GetFeeCap().Lt(baseFee) {
+			reasons[i] = append(reasons[i], "fee cap below base fee")
 		}
-		content["queued"][account.Hex()] = dump
 	}
-	return content
+	return reasons
+}
+
+// Status returns the number of pending and queued transaction in the pool.
+func (api *TxPoolAPIImpl) Status(ctx context.Context) (map[string]hexutil.Uint, error) {
+	reply, err := api.pool.Status(ctx, &proto_txpool.StatusRequest{})
+	if err != nil {
+		return nil, err
+	}
+	return map[string]hexutil.Uint{
+		"pending": hexutil.Uint(reply.PendingCount),
+		"baseFee": hexutil.Uint(reply.BaseFeeCount),
+		"queued":  hexutil.Uint(reply.QueuedCount),
+	}, nil
 }
-*/
@@ -61,4 +61,49 @@ func TestTxPoolContent(t *testing.T) {
 	require.Len(status, 3)
 	require.Equal(status["pending"], hexutil.Uint(1))
 	require.Equal(status["queued"], hexutil.Uint(0))
+
+	// a transaction after a nonce gap is queued
+	txn, err = types.SignTx(types.NewTransaction(2, common.Address{2}, uint256.NewInt(expectValue), params.TxGas, uint256.NewInt(10*params.GWei), nil), *types.LatestSignerForChainID(m.ChainConfig.ChainID), m.Key)
+	require.NoError(err)
+	buf.Reset()
+	require.NoError(txn.MarshalBinary(buf))
+	reply, err = txPool.Add(ctx, &txpool.AddRequest{RlpTxs: [][]byte{buf.Bytes()}})
+	require.NoError(err)
+	require.Equal([]txPoolProto.ImportResult{txPoolProto.ImportResult_SUCCESS}, reply.Imported, fmt.Sprintf("%s", reply.Errors))
+
+	inspect, err := api.Inspect(ctx)
+	require.NoError(err)
+	require.Equal(map[string]string{"0": fmt.Sprintf("%s: 1234 wei + 21000 gas × 10000000000 wei", common.Address{1}.Hex())}, inspect["pending"][sender])
+	require.Equal(map[string]string{"2": fmt.Sprintf("%s: 1234 wei + 21000 gas × 10000000000 wei", common.Address{2}.Hex())}, inspect["queued"][sender])
+	require.Empty(inspect["baseFee"])
+
+	reasons, err := api.NotPendingReasons(ctx)
+	require.NoError(err)
+	require.Equal(map[string]map[string]map[string][]string{
+		"queued":  {sender: {"2": {"nonce gap"}}},
+		"baseFee": {},
+	}, reasons)
+
+	from, err := api.ContentFrom(ctx, m.Address)
+	require.NoError(err)
+	require.Len(from["pending"], 1)
+	require.Equal(txn.Hash(), from["queued"]["2"].Hash)
+	require.Empty(from["baseFee"])
+	from, err = api.ContentFrom(ctx, common.Address{1})
+	require.NoError(err)
+	require.Len(from, 3)
+	require.Empty(from["pending"])
+}
+
+func TestNotPendingReasons(t *testing.T) {
+	tx := func(nonce uint64, value, feeCap uint64) types.Transaction {
+		return types.NewTransaction(nonce, common.Address{1}, uint256.NewInt(value), params.TxGas, uint256.NewInt(feeCap), nil)
+	}
+	// each transaction costs 21000 * fee cap + value
+	txs := []types.Transaction{tx(3, 0, 1), tx(4, 1000, 1), tx(6, 0, 1), tx(7, 0, 10)}
+	require.Equal(t, [][]string{nil, nil, {"nonce gap"}, {"nonce gap", "insufficient balance"}}, notPendingReasons(txs, 3, uint256.NewInt(3*21000+1000), nil))
+	require.Equal(t, [][]string{{"nonce gap"}, {"nonce gap", "insufficient balance"}, {"nonce gap", "insufficient balance"}, {"nonce gap", "insufficient balance"}}, notPendingReasons(txs, 2, uint256.NewInt(21000), nil))
+	require.Equal(t, [][]string{{"fee cap below base fee"}, {"fee cap below base fee"}, {"nonce gap", "fee cap below base fee"}, {"nonce gap", "insufficient balance"}}, notPendingReasons(txs, 3, uint256.NewInt(3*21000+1000), uint256.NewInt(2)))
+	// a transaction below the nonce of the sender doesn't lower the nonce the next ones follow
+	require.Equal(t, [][]string{nil, nil}, notPendingReasons([]types.Transaction{tx(3, 0, 1), tx(5, 0, 1)}, 5, uint256.NewInt(2*21000), nil))
 }
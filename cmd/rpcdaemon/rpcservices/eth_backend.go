@@ -121,7 +121,24 @@ func (back *RemoteBackend) ClientVersion(ctx context.Context) (string, error) {
 }
 
 func (back *RemoteBackend) Subscribe(ctx context.Context, onNewEvent func(*remote.SubscribeReply)) error {
-	subscription, err := back.remoteEthBackend.Subscribe(ctx, &remote.SubscribeRequest{}, grpc.WaitForReady(true))
+	return back.subscribe(ctx, &remote.SubscribeRequest{}, onNewEvent)
+}
+
+// SubscribeSyncing receives the sync progress, which is sent only to the subscriptions asking for it.
+// It returns privateapi.ErrSyncingUnsupported if the backend is too old to serve it.
+func (back *RemoteBackend) SubscribeSyncing(ctx context.Context, onNewEvent func(*remote.SubscribeReply)) error {
+	versionReply, err := back.remoteEthBackend.Version(ctx, &emptypb.Empty{}, grpc.WaitForReady(true))
+	if err != nil {
+		return err
+	}
+	if !privateapi.SupportsSyncing(versionReply) {
+		return privateapi.ErrSyncingUnsupported
+	}
+	return back.subscribe(ctx, &remote.SubscribeRequest{Type: privateapi.EventSyncing}, onNewEvent)
+}
+
+func (back *RemoteBackend) subscribe(ctx context.Context, req *remote.SubscribeRequest, onNewEvent func(*remote.SubscribeReply)) error {
+	subscription, err := back.remoteEthBackend.Subscribe(ctx, req, grpc.WaitForReady(true))
 	if err != nil {
 		if s, ok := status.FromError(err); ok {
 			return errors.New(s.Message())
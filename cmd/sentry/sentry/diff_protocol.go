@@ -0,0 +1,277 @@
+package sentry
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/debug"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/protocols/diff"
+	"github.com/ledgerwatch/erigon/p2p"
+	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/ledgerwatch/log/v3"
+)
+
+const (
+	// maxDiffLayersServe is the maximum number of diff layers served in one response.
+	maxDiffLayersServe = 128
+	// diffRequestPeers is the number of peers asked for the same diff layers.
+	diffRequestPeers = 3
+)
+
+// diffPeerInfo is a peer speaking the `diff` protocol, diffSync is set if the peer
+// wants to receive the diff layers produced by this node.
+type diffPeerInfo struct {
+	*PeerInfo
+	diffSync bool
+}
+
+// EnableDiffSync makes the sentry serve the BSC `diff` protocol, the diff layers received
+// from the peers are added to the store, the ones produced locally are broadcast to the peers.
+func (ss *GrpcServer) EnableDiffSync(store *diff.Store) {
+	ss.DiffProtocol = &p2p.Protocol{
+		Name:    diff.ProtocolName,
+		Version: diff.Diff1,
+		Length:  diff.ProtocolLength,
+		Run: func(peer *p2p.Peer, rw p2p.MsgReadWriter) error {
+			peerID := peer.Pubkey()
+			peerInfo := NewPeerInfo(peer, rw)
+			defer peerInfo.Close()
+
+			diffSync, err := diffHandShake(ss.ctx, rw)
+			if err != nil {
+				return fmt.Errorf("diff handshake to peer %s: %w", peerID, err)
+			}
+			ss.diffPeers.Store(peerID, &diffPeerInfo{PeerInfo: peerInfo, diffSync: diffSync})
+			defer ss.diffPeers.Delete(peerID)
+
+			err = runDiffPeer(ss.ctx, peerID, rw, store, func(msgcode uint64, data []byte) {
+				ss.writeDiffPeer(peerInfo, msgcode, data)
+			}) // runDiffPeer never returns a nil error
+			log.Trace(fmt.Sprintf("[%s] Error while running diff peer: %v", peerID, err))
+			return nil
+		},
+	}
+	store.SetNetwork(ss.requestDiffLayers, ss.broadcastDiffLayer, ss.penalizeDiffPeer)
+}
+
+func diffHandShake(ctx context.Context, rw p2p.MsgReadWriter) (bool, error) {
+	errc := make(chan error, 2)
+	var diffSync bool
+
+	go func() {
+		defer debug.LogPanic()
+		errc <- p2p.Send(rw, diff.DiffCapMsg, &diff.DiffCapPacket{DiffSync: true, Extra: rlp.EmptyString})
+	}()
+
+	go func() {
+		msg, err := rw.ReadMsg()
+		if err != nil {
+			errc <- err
+			return
+		}
+		defer msg.Discard()
+		if msg.Code != diff.DiffCapMsg {
+			errc <- fmt.Errorf("first msg has code %x (!= %x)", msg.Code, diff.DiffCapMsg)
+			return
+		}
+		if msg.Size > diff.ProtocolMaxMsgSize {
+			errc <- fmt.Errorf("message is too large %d, limit %d", msg.Size, diff.ProtocolMaxMsgSize)
+			return
+		}
+		var reply diff.DiffCapPacket
+		if err := msg.Decode(&reply); err != nil {
+			errc <- fmt.Errorf("decode message %v: %w", msg, err)
+			return
+		}
+		diffSync = reply.DiffSync
+		errc <- nil
+	}()
+
+	timeout := time.NewTimer(handshakeTimeout)
+	defer timeout.Stop()
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errc:
+			if err != nil {
+				return false, err
+			}
+		case <-timeout.C:
+			return false, p2p.DiscReadTimeout
+		case <-ctx.Done():
+			return false, ctx.Err()
+		}
+	}
+	return diffSync, nil
+}
+
+func runDiffPeer(
+	ctx context.Context,
+	peerID [64]byte,
+	rw p2p.MsgReadWriter,
+	store *diff.Store,
+	reply func(msgcode uint64, data []byte),
+) error {
+	for {
+		select {
+		case <-ctx.Done():
+			return p2p.ErrShuttingDown
+		default:
+		}
+		msg, err := rw.ReadMsg()
+		if err != nil {
+			return fmt.Errorf("reading message: %w", err)
+		}
+		if msg.Size > diff.ProtocolMaxMsgSize {
+			msg.Discard()
+			return fmt.Errorf("message is too large %d, limit %d", msg.Size, diff.ProtocolMaxMsgSize)
+		}
+		switch msg.Code {
+		case diff.GetDiffLayerMsg:
+			var query diff.GetDiffLayersPacket
+			if err := msg.Decode(&query); err != nil {
+				msg.Discard()
+				return fmt.Errorf("decode GetDiffLayers %v: %w", msg, err)
+			}
+			response := diff.FullDiffLayersPacket{RequestId: query.RequestId}
+			for _, hash := range query.BlockHashes {
+				if len(response.DiffLayersPacket) >= maxDiffLayersServe {
+					break
+				}
+				if layer := store.Get(hash); layer != nil {
+					data, err := rlp.EncodeToBytes(layer)
+					if err != nil {
+						msg.Discard()
+						return fmt.Errorf("encode diff layer %x: %w", hash, err)
+					}
+					response.DiffLayersPacket = append(response.DiffLayersPacket, data)
+				}
+			}
+			b, err := rlp.EncodeToBytes(&response)
+			if err != nil {
+				msg.Discard()
+				return fmt.Errorf("encode FullDiffLayers: %w", err)
+			}
+			reply(diff.FullDiffLayerMsg, b)
+		case diff.DiffLayerMsg:
+			var packet diff.DiffLayersPacket
+			if err := msg.Decode(&packet); err != nil {
+				msg.Discard()
+				return fmt.Errorf("decode DiffLayers %v: %w", msg, err)
+			}
+			if err := addDiffLayers(store, peerID, &packet); err != nil {
+				msg.Discard()
+				return err
+			}
+		case diff.FullDiffLayerMsg:
+			var packet diff.FullDiffLayersPacket
+			if err := msg.Decode(&packet); err != nil {
+				msg.Discard()
+				return fmt.Errorf("decode FullDiffLayers %v: %w", msg, err)
+			}
+			if err := addDiffLayers(store, peerID, &packet.DiffLayersPacket); err != nil {
+				msg.Discard()
+				return err
+			}
+		case diff.DiffCapMsg:
+			msg.Discard()
+			return fmt.Errorf("unexpected DiffCap message after handshake")
+		default:
+			log.Trace("Unknown diff message code", "code", msg.Code)
+		}
+		msg.Discard()
+	}
+}
+
+func addDiffLayers(store *diff.Store, peerID [64]byte, packet *diff.DiffLayersPacket) error {
+	layers, err := packet.Unpack()
+	if err != nil {
+		return err
+	}
+	for _, layer := range layers {
+		store.Add(layer, peerID)
+	}
+	return nil
+}
+
+func (ss *GrpcServer) rangeDiffPeers(f func(peerInfo *diffPeerInfo) bool) {
+	ss.diffPeers.Range(func(key, value interface{}) bool {
+		peerInfo, _ := value.(*diffPeerInfo)
+		if peerInfo == nil {
+			return true
+		}
+		return f(peerInfo)
+	})
+}
+
+func (ss *GrpcServer) writeDiffPeer(peerInfo *PeerInfo, msgcode uint64, data []byte) {
+	peerInfo.Async(func() {
+		err := peerInfo.rw.WriteMsg(p2p.Msg{Code: msgcode, Size: uint32(len(data)), Payload: bytes.NewReader(data)})
+		if err != nil {
+			peerInfo.Remove()
+			ss.diffPeers.Delete(peerInfo.ID())
+			if !errors.Is(err, p2p.ErrShuttingDown) {
+				log.Debug("diff protocol", "msgcode", msgcode, "err", err)
+			}
+		}
+	})
+}
+
+// penalizeDiffPeer disconnects the peer which sent a wrong diff layer.
+func (ss *GrpcServer) penalizeDiffPeer(peerID [64]byte) {
+	if value, ok := ss.diffPeers.LoadAndDelete(peerID); ok {
+		if peerInfo, _ := value.(*diffPeerInfo); peerInfo != nil {
+			peerInfo.Remove()
+		}
+	}
+	ss.removePeer(peerID)
+}
+
+// requestDiffLayers asks a few random diff peers for the diff layers of the blocks.
+func (ss *GrpcServer) requestDiffLayers(hashes []common.Hash) {
+	b, err := rlp.EncodeToBytes(&diff.GetDiffLayersPacket{
+		RequestId:   rand.Uint64(), // nolint: gosec
+		BlockHashes: hashes,
+	})
+	if err != nil {
+		log.Error("requestDiffLayers encode packet failed", "err", err)
+		return
+	}
+	var peers []*diffPeerInfo
+	ss.rangeDiffPeers(func(peerInfo *diffPeerInfo) bool {
+		peers = append(peers, peerInfo)
+		return true
+	})
+	rand.Shuffle(len(peers), func(i, j int) { peers[i], peers[j] = peers[j], peers[i] })
+	if len(peers) > diffRequestPeers {
+		peers = peers[:diffRequestPeers]
+	}
+	for _, peerInfo := range peers {
+		ss.writeDiffPeer(peerInfo.PeerInfo, diff.GetDiffLayerMsg, b)
+	}
+}
+
+// broadcastDiffLayer pushes the locally produced diff layer to the peers which asked for diff sync.
+func (ss *GrpcServer) broadcastDiffLayer(layer *types.DiffLayer) {
+	data, err := rlp.EncodeToBytes(layer)
+	if err != nil {
+		log.Error("broadcastDiffLayer encode diff layer failed", "err", err)
+		return
+	}
+	b, err := rlp.EncodeToBytes(diff.DiffLayersPacket{data})
+	if err != nil {
+		log.Error("broadcastDiffLayer encode packet failed", "err", err)
+		return
+	}
+	ss.rangeDiffPeers(func(peerInfo *diffPeerInfo) bool {
+		if peerInfo.diffSync {
+			ss.writeDiffPeer(peerInfo.PeerInfo, diff.DiffLayerMsg, b)
+		}
+		return true
+	})
+}
@@ -194,7 +194,7 @@ func ConvertH512ToPeerID(h512 *proto_types.H512) [64]byte {
 func makeP2PServer(
 	p2pConfig p2p.Config,
 	genesisHash common.Hash,
-	protocol p2p.Protocol,
+	protocols []p2p.Protocol,
 ) (*p2p.Server, error) {
 	var urls []string
 	chainConfig := params.ChainConfigByGenesisHash(genesisHash)
@@ -209,7 +209,7 @@ func makeP2PServer(
 		p2pConfig.BootstrapNodes = bootstrapNodes
 		p2pConfig.BootstrapNodesV5 = bootstrapNodes
 	}
-	p2pConfig.Protocols = []p2p.Protocol{protocol}
+	p2pConfig.Protocols = protocols
 	return &p2p.Server{Config: p2pConfig}, nil
 }
 
@@ -389,6 +389,7 @@ func runPeer(
 			if !hasSubscribers(eth.ToProto[protocol][msg.Code]) {
 				continue
 			}
+			givePermit = true
 			b := make([]byte, msg.Size)
 			if _, err := io.ReadFull(msg.Payload, b); err != nil {
 				log.Error(fmt.Sprintf("%s: reading msg into bytes: %v", peerID, err))
@@ -589,6 +590,17 @@ type GrpcServer struct {
 	messageStreamsLock   sync.RWMutex
 	peersStreams         *PeersStreams
 	p2p                  *p2p.Config
+	DiffProtocol         *p2p.Protocol // set by EnableDiffSync
+	diffPeers            sync.Map
+}
+
+// Protocols returns the devp2p protocols served by the sentry.
+func (ss *GrpcServer) Protocols() []p2p.Protocol {
+	protocols := []p2p.Protocol{ss.Protocol}
+	if ss.DiffProtocol != nil {
+		protocols = append(protocols, *ss.DiffProtocol)
+	}
+	return protocols
 }
 
 func (ss *GrpcServer) rangePeers(f func(peerInfo *PeerInfo) bool) {
@@ -708,6 +720,7 @@ func (ss *GrpcServer) SendMessageByMinBlock(_ context.Context, inreq *proto_sent
 	msgcode := eth.FromProto[ss.Protocol.Version][inreq.Data.Id]
 	if msgcode != eth.GetBlockHeadersMsg &&
 		msgcode != eth.GetBlockBodiesMsg &&
+		msgcode != eth.GetReceiptsMsg &&
 		msgcode != eth.GetPooledTransactionsMsg {
 		return reply, fmt.Errorf("sendMessageByMinBlock not implemented for message Id: %s", inreq.Data.Id)
 	}
@@ -841,7 +854,7 @@ func (ss *GrpcServer) SetStatus(ctx context.Context, statusData *proto_sentry.St
 			}
 		}
 
-		srv, err := makeP2PServer(*ss.p2p, genesisHash, ss.Protocol)
+		srv, err := makeP2PServer(*ss.p2p, genesisHash, ss.Protocols())
 		if err != nil {
 			return reply, err
 		}
@@ -30,7 +30,9 @@ import (
 
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/core"
 	"github.com/ledgerwatch/erigon/core/forkid"
+	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/eth/ethconfig"
 	"github.com/ledgerwatch/erigon/eth/protocols/eth"
@@ -40,6 +42,7 @@ import (
 	"github.com/ledgerwatch/erigon/turbo/services"
 	"github.com/ledgerwatch/erigon/turbo/stages/bodydownload"
 	"github.com/ledgerwatch/erigon/turbo/stages/headerdownload"
+	"github.com/ledgerwatch/erigon/turbo/stages/receiptsdownload"
 )
 
 type sentryMessageStream grpc.ClientStream
@@ -101,6 +104,7 @@ func (cs *MultiClient) RecvMessageLoop(
 	ids := []proto_sentry.MessageId{
 		eth.ToProto[eth.ETH66][eth.BlockHeadersMsg],
 		eth.ToProto[eth.ETH66][eth.BlockBodiesMsg],
+		eth.ToProto[eth.ETH66][eth.ReceiptsMsg],
 		eth.ToProto[eth.ETH66][eth.NewBlockHashesMsg],
 		eth.ToProto[eth.ETH66][eth.NewBlockMsg],
 	}
@@ -247,6 +251,7 @@ type MultiClient struct {
 	lock          sync.RWMutex
 	Hd            *headerdownload.HeaderDownload
 	Bd            *bodydownload.BodyDownload
+	Rd            *receiptsdownload.ReceiptsDownload
 	IsMock        bool
 	forkValidator *engineapi.ForkValidator
 	nodeName      string
@@ -297,6 +302,7 @@ func NewMultiClient(
 		nodeName:      nodeName,
 		Hd:            hd,
 		Bd:            bd,
+		Rd:            receiptsdownload.NewReceiptsDownload(time.Duration(syncCfg.BodyDownloadTimeoutSeconds) * time.Second),
 		sentries:      sentries,
 		db:            db,
 		Engine:        engine,
@@ -536,7 +542,16 @@ func (cs *MultiClient) blockBodies66(inreq *proto_sentry.InboundMessage, _ direc
 	return nil
 }
 
-func (cs *MultiClient) receipts66(_ context.Context, _ *proto_sentry.InboundMessage, _ direct.SentryClient) error {
+func (cs *MultiClient) receipts66(ctx context.Context, inreq *proto_sentry.InboundMessage, _ direct.SentryClient) error {
+	var response eth.ReceiptsPacket66
+	if err := rlp.DecodeBytes(inreq.Data, &response); err != nil {
+		return fmt.Errorf("decode ReceiptsPacket66: %w", err)
+	}
+	// responses to requests which timed out are dropped, the peers sending invalid receipts are kicked
+	if err := cs.Rd.DeliverReceipts(response.RequestId, response.ReceiptsPacket); errors.Is(err, receiptsdownload.ErrInvalidReceipts) {
+		log.Debug("Kick peer for invalid receipts", "err", err)
+		cs.Penalize(ctx, []headerdownload.PenaltyItem{{Penalty: headerdownload.BadBlockPenalty, PeerID: ConvertH512ToPeerID(inreq.PeerId)}})
+	}
 	return nil
 }
 
@@ -632,7 +647,9 @@ func (cs *MultiClient) getReceipts66(ctx context.Context, inreq *proto_sentry.In
 		return err
 	}
 	defer tx.Rollback()
-	receipts, err := eth.AnswerGetReceiptsQuery(tx, query.GetReceiptsPacket)
+	receipts, err := eth.AnswerGetReceiptsQuery(tx, query.GetReceiptsPacket, func(tx kv.Tx, hash common.Hash, number uint64) (types.Receipts, error) {
+		return cs.computeReceipts(ctx, tx, hash, number)
+	})
 	if err != nil {
 		return err
 	}
@@ -662,6 +679,34 @@ func (cs *MultiClient) getReceipts66(ctx context.Context, inreq *proto_sentry.In
 	return nil
 }
 
+// computeReceipts regenerates the receipts of a block the same way the rpcdaemon does
+// when they are not stored.
+func (cs *MultiClient) computeReceipts(ctx context.Context, tx kv.Tx, hash common.Hash, number uint64) (types.Receipts, error) {
+	var (
+		block *types.Block
+		err   error
+	)
+	if blockReader, ok := cs.blockReader.(services.BlockReader); ok {
+		block, _, err = blockReader.BlockWithSenders(ctx, tx, hash, number)
+	} else {
+		block, _, err = rawdb.ReadBlockWithSenders(tx, hash, number)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if block == nil {
+		return nil, fmt.Errorf("block %d %x not found", number, hash)
+	}
+	getHeader := func(hash common.Hash, number uint64) *types.Header {
+		h, e := cs.blockReader.Header(ctx, tx, hash, number)
+		if e != nil {
+			log.Error("getHeader error", "number", number, "hash", hash, "err", e)
+		}
+		return h
+	}
+	return core.ComputeReceipts(ctx, tx, cs.ChainConfig, getHeader, block)
+}
+
 func makeInboundMessage() *proto_sentry.InboundMessage {
 	return new(proto_sentry.InboundMessage)
 }
@@ -0,0 +1,148 @@
+package commands
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+
+	kv2 "github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/spf13/cobra"
+
+	"github.com/ledgerwatch/erigon/cmd/state/exec22"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/turbo/services"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/ledgerwatch/erigon/turbo/trie"
+)
+
+var witnessFile string
+
+func init() {
+	withBlock(runWitnessCmd)
+	withDataDir(runWitnessCmd)
+	withChain(runWitnessCmd)
+	withSnapshotBlocks(runWitnessCmd)
+	runWitnessCmd.Flags().StringVar(&witnessFile, "witness", "", "file with the witness of the block, as returned by debug_getBlockWitness or binary")
+	must(runWitnessCmd.MarkFlagRequired("witness"))
+	rootCmd.AddCommand(runWitnessCmd)
+}
+
+var runWitnessCmd = &cobra.Command{
+	Use:   "runWitness",
+	Short: "Executes a block on the state of its witness only, and verifies the state root after the block",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		logger := log.New()
+		return RunWitness(cmd.Context(), chainConfig, logger, block, witnessFile)
+	},
+}
+
+// RunWitness executes the block on the trie built from its witness, which has to be the state trie before the block,
+// then compares the root of the trie after the block with the one of the header. The block, the headers of its
+// ancestors and the snapshots of the consensus engine are read from the datadir, the state isn't.
+func RunWitness(ctx context.Context, chainConfig *params.ChainConfig, logger log.Logger, blockNum uint64, witnessFile string) error {
+	if blockNum == 0 {
+		return fmt.Errorf("the genesis block has no witness")
+	}
+	enc, err := os.ReadFile(witnessFile)
+	if err != nil {
+		return err
+	}
+	if hexEnc := strings.Trim(strings.TrimSpace(string(enc)), `"`); strings.HasPrefix(hexEnc, "0x") {
+		if enc, err = hexutil.Decode(hexEnc); err != nil {
+			return fmt.Errorf("decoding the witness: %w", err)
+		}
+	}
+	witness, err := trie.NewWitnessFromReader(bytes.NewReader(enc), false)
+	if err != nil {
+		return fmt.Errorf("reading the witness: %w", err)
+	}
+	stats, err := witness.WriteInto(io.Discard)
+	if err != nil {
+		return err
+	}
+	tr, err := trie.BuildTrieFromWitness(witness, false)
+	if err != nil {
+		return fmt.Errorf("building the trie from the witness: %w", err)
+	}
+
+	db, err := kv2.NewMDBX(logger).Path(chaindata).Readonly().Open()
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	var blockReader services.FullBlockReader
+	var allSnapshots *snapshotsync.RoSnapshots
+	if ethconfig.UseSnapshotsByChainName(chainConfig.ChainName) && snapshotsCli {
+		allSnapshots = snapshotsync.NewRoSnapshots(ethconfig.NewSnapCfg(true, false, true), path.Join(datadir, "snapshots"))
+		defer allSnapshots.Close()
+		if err := allSnapshots.ReopenFolder(); err != nil {
+			return fmt.Errorf("reopen snapshot segments: %w", err)
+		}
+		blockReader = snapshotsync.NewBlockReaderWithSnapshots(allSnapshots)
+	} else {
+		blockReader = snapshotsync.NewBlockReader()
+	}
+	tx, err := db.BeginRo(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	blockHash, err := blockReader.CanonicalHash(ctx, tx, blockNum)
+	if err != nil {
+		return err
+	}
+	b, _, err := blockReader.BlockWithSenders(ctx, tx, blockHash, blockNum)
+	if err != nil {
+		return err
+	}
+	if b == nil {
+		return fmt.Errorf("block %d not found", blockNum)
+	}
+	parent, err := blockReader.Header(ctx, tx, b.ParentHash(), blockNum-1)
+	if err != nil {
+		return err
+	}
+	if parent == nil {
+		return fmt.Errorf("parent of block %d not found", blockNum)
+	}
+	if root := tr.Hash(); root != parent.Root {
+		return fmt.Errorf("the root of the witness %x is not the state root %x before block %d", root, parent.Root, blockNum)
+	}
+
+	engine := initConsensusEngine(chainConfig, logger, allSnapshots)
+	getHeader := func(hash common.Hash, number uint64) *types.Header {
+		h, e := blockReader.Header(ctx, tx, hash, number)
+		if e != nil {
+			log.Error("getHeader error", "number", number, "hash", hash, "err", e)
+		}
+		return h
+	}
+	execute := core.ExecuteBlockEphemerally
+	if _, isPoSA := engine.(consensus.PoSA); isPoSA {
+		execute = core.ExecuteBlockEphemerallyForBSC
+	}
+	stateless := state.NewStateless(tr)
+	if _, err = execute(chainConfig, &vm.Config{}, core.GetHashFn(b.Header(), getHeader), engine, b, stateless, stateless, nil, exec22.NewChainReader(chainConfig, tx, blockReader), nil, false, nil); err != nil {
+		return fmt.Errorf("block %d: %w", blockNum, err)
+	}
+	if root := stateless.Root(); root != b.Root() {
+		return fmt.Errorf("block %d: state root %x computed from the witness, %x in the header", blockNum, root, b.Root())
+	}
+	log.Info("Verified the state root from the witness", "block", blockNum, "root", b.Root(),
+		"witness", stats.BlockWitnessSize(), "hashes", stats.HashesSize(), "codes", stats.CodesSize(), "leaves", stats.LeafKeysSize()+stats.LeafValuesSize())
+	return nil
+}
@@ -47,6 +47,7 @@ import (
 	"github.com/ledgerwatch/erigon/eth/protocols/eth"
 	"github.com/ledgerwatch/erigon/params/networkname"
 
+	"github.com/ledgerwatch/erigon/accounts/keystore"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/paths"
 	"github.com/ledgerwatch/erigon/consensus/ethash"
@@ -120,6 +121,20 @@ var (
 		Name:  "dev.period",
 		Usage: "Block period to use in developer mode (0 = mine only if transaction pending)",
 	}
+	ParliaDevKeystoreFlag = DirectoryFlag{
+		Name:  "parlia-dev.keystore",
+		Usage: "Directory of the validator keys of the parlia-dev chain, shared by its nodes (default = inside datadir)",
+	}
+	ParliaDevValidatorsFlag = cli.IntFlag{
+		Name:  "parlia-dev.validators",
+		Usage: "Number of validator keys generated in an empty parlia-dev keystore",
+		Value: 3,
+	}
+	PasswordFileFlag = cli.StringFlag{
+		Name:  "password",
+		Usage: "Password file to use for non-interactive password input",
+		Value: "",
+	}
 	ChainFlag = cli.StringFlag{
 		Name:  "chain",
 		Usage: "Name of the testnet to join",
@@ -377,6 +392,34 @@ var (
 		Name:  "rpc.streaming.disable",
 		Usage: "Erigon has enalbed json streaming for some heavy endpoints (like trace_*). It's treadoff: greatly reduce amount of RAM (in some cases from 30GB to 30mb), but it produce invalid json format if error happened in the middle of streaming (because json is not streaming-friendly format)",
 	}
+	RpcLimitsRateFlag = cli.IntFlag{
+		Name:  "rpc.limits.rate",
+		Usage: "Cost of the requests each client (IP or API key) may make per second, the trace_ and debug_ methods costing more. 0 - unlimited",
+	}
+	RpcLimitsBurstFlag = cli.IntFlag{
+		Name:  "rpc.limits.burst",
+		Usage: "Cost of the requests each client may make at once. Default: equal to --rpc.limits.rate",
+	}
+	RpcLimitsCostsFlag = cli.StringFlag{
+		Name:  "rpc.limits.costs",
+		Usage: "Comma separated costs of methods or namespaces, overriding the defaults. Example: trace_=20,eth_getLogs=10",
+	}
+	RpcLimitsBatchFlag = cli.IntFlag{
+		Name:  "rpc.limits.batch",
+		Usage: "Maximum number of requests in a batch. 0 - unlimited",
+	}
+	RpcLimitsResponseFlag = cli.IntFlag{
+		Name:  "rpc.limits.response",
+		Usage: "Maximum size of the response to a request, in bytes, the responses being held up to this size until complete. 0 - unlimited",
+	}
+	RpcLimitsAPIKeyHeaderFlag = cli.StringFlag{
+		Name:  "rpc.limits.apikey.header",
+		Usage: "HTTP header of the API keys telling apart the clients, which are otherwise told apart by IP. Example: X-API-Key",
+	}
+	RpcLimitsAPIKeysFlag = cli.StringFlag{
+		Name:  "rpc.limits.apikeys",
+		Usage: "File of the API keys accepted in --rpc.limits.apikey.header, one per line. The requests carrying another key are told apart by IP",
+	}
 	HTTPTraceFlag = cli.BoolFlag{
 		Name:  "http.trace",
 		Usage: "Trace HTTP requests with INFO level",
@@ -396,6 +439,11 @@ var (
 		Usage: "Sets a cap on gas that can be used in eth_call/estimateGas",
 		Value: 50000000,
 	}
+	RpcMaxGetProofRewindBlockCountFlag = cli.IntFlag{
+		Name:  "rpc.maxgetproofrewindblockcount.limit",
+		Usage: "Max GetProof rewind block count",
+		Value: 100_000,
+	}
 	RpcTraceCompatFlag = cli.BoolFlag{
 		Name:  "trace.compat",
 		Usage: "Bug for bug compatibility with OE for trace_ routines",
@@ -722,7 +770,7 @@ var (
 
 	HeimdallURLFlag = cli.StringFlag{
 		Name:  "bor.heimdall",
-		Usage: "URL of Heimdall service",
+		Usage: "URL of Heimdall service. Comma separated URLs are failed over in order, file:// replays recorded Heimdall fixtures",
 		Value: "http://localhost:1317",
 	}
 
@@ -732,6 +780,12 @@ var (
 		Usage: "Run without Heimdall service (for testing purpose)",
 	}
 
+	// DiffSyncFlag enables the BSC diff protocol and the light processing of blocks with known diff layers
+	DiffSyncFlag = cli.BoolFlag{
+		Name:  "diffsync",
+		Usage: "Enable the diff protocol and apply blocks by the state diffs received from the peers (Parlia chains only), for the blocks whose call traces are pruned (e.g. --prune.c.before beyond the head)",
+	}
+
 	ConfigFlag = cli.StringFlag{
 		Name:  "config",
 		Usage: "Sets erigon flags from YAML/TOML file",
@@ -1107,6 +1161,8 @@ func DataDirForNetwork(datadir string, network string) string {
 		return networkDataDirCheckingLegacy(datadir, "sepolia")
 	case networkname.GnosisChainName:
 		return networkDataDirCheckingLegacy(datadir, "gnosis")
+	case networkname.ParliaDevChainName:
+		return networkDataDirCheckingLegacy(datadir, "parlia-dev")
 
 	default:
 		return datadir
@@ -1443,6 +1499,7 @@ func SetEthConfig(ctx *cli.Context, nodeConfig *nodecfg.Config, cfg *ethconfig.C
 	cfg.Sync.UseSnapshots = ctx.GlobalBoolT(SnapshotFlag.Name)
 	cfg.Dirs = nodeConfig.Dirs
 	cfg.MemoryOverlay = ctx.GlobalBool(MemoryOverlayFlag.Name)
+	cfg.DiffSync = ctx.GlobalBool(DiffSyncFlag.Name)
 	cfg.Snapshot.KeepBlocks = ctx.GlobalBool(SnapKeepBlocksFlag.Name)
 	cfg.Snapshot.Produce = !ctx.GlobalBool(SnapStopFlag.Name)
 	cfg.Snapshot.NoDownloader = ctx.GlobalBool(NoDownloaderFlag.Name)
@@ -1557,6 +1614,52 @@ func SetEthConfig(ctx *cli.Context, nodeConfig *nodecfg.Config, cfg *ethconfig.C
 		if !ctx.GlobalIsSet(MinerGasPriceFlag.Name) {
 			cfg.Miner.GasPrice = big.NewInt(1)
 		}
+	case networkname.ParliaDevChainName:
+		if !ctx.GlobalIsSet(NetworkIdFlag.Name) {
+			cfg.NetworkID = params.NetworkIDByChainName(chain)
+		}
+
+		// The validator set is given by the keys of the keystore shared by the nodes of the devnet
+		keystoreDir := filepath.Join(cfg.Dirs.DataDir, "keystore")
+		if ctx.GlobalIsSet(ParliaDevKeystoreFlag.Name) {
+			keystoreDir = ctx.GlobalString(ParliaDevKeystoreFlag.Name)
+		}
+		passphrase := ""
+		if list := MakePasswordList(ctx); len(list) > 0 {
+			passphrase = list[0]
+		}
+		ks := keystore.NewKeyStore(keystoreDir, keystore.LightScryptN, keystore.LightScryptP)
+		if len(ks.Accounts()) == 0 {
+			for i := 0; i < ctx.GlobalInt(ParliaDevValidatorsFlag.Name); i++ {
+				if _, err := ks.NewAccount(passphrase); err != nil {
+					Fatalf("Failed to create the %s validator: %v", chain, err)
+				}
+			}
+		}
+		validators := ks.Accounts()
+		if len(validators) == 0 {
+			Fatalf("No validator keys in the %s keystore %s", chain, keystoreDir)
+		}
+		period := params.ParliaDevChainConfig.Parlia.Period
+		if ctx.GlobalIsSet(DeveloperPeriodFlag.Name) {
+			period = uint64(ctx.GlobalInt(DeveloperPeriodFlag.Name))
+		}
+		var err error
+		if cfg.Genesis, err = core.ParliaDevGenesisBlock(period, validators); err != nil {
+			Fatalf("Failed to create the %s genesis: %v", chain, err)
+		}
+
+		// Sign with the key of the etherbase, the first validator unless specified
+		if cfg.Miner.SigKey == nil {
+			if cfg.Miner.Etherbase == (common.Address{}) {
+				cfg.Miner.Etherbase = validators[0]
+			}
+			if err := ks.Unlock(cfg.Miner.Etherbase, passphrase); err != nil {
+				Fatalf("Failed to unlock the validator %x: %v", cfg.Miner.Etherbase, err)
+			}
+			cfg.Miner.SignHash = ks.SignHash
+		}
+		log.Info("Using parlia-dev validators", "keystore", keystoreDir, "validators", len(validators), "etherbase", cfg.Miner.Etherbase, "period", period)
 	}
 
 	if ctx.GlobalIsSet(OverrideTerminalTotalDifficulty.Name) {
@@ -1612,6 +1715,24 @@ func MakeConsolePreloads(ctx *cli.Context) []string {
 	return preloads
 }
 
+// MakePasswordList reads password lines from the file specified by the global --password flag.
+func MakePasswordList(ctx *cli.Context) []string {
+	path := ctx.GlobalString(PasswordFileFlag.Name)
+	if path == "" {
+		return nil
+	}
+	text, err := os.ReadFile(path)
+	if err != nil {
+		Fatalf("Failed to read password file: %v", err)
+	}
+	lines := strings.Split(string(text), "\n")
+	// Sanitise DOS line endings.
+	for i := range lines {
+		lines[i] = strings.TrimRight(lines[i], "\r")
+	}
+	return lines
+}
+
 func CobraFlags(cmd *cobra.Command, urfaveCliFlags []cli.Flag) {
 	flags := cmd.PersistentFlags()
 	for _, flag := range urfaveCliFlags {
@@ -238,7 +238,7 @@ func New(
 	db kv.RwDB,
 	heimdallURL string,
 	withoutHeimdall bool,
-) *Bor {
+) (*Bor, error) {
 	// get bor config
 	borConfig := chainConfig.Bor
 
@@ -252,7 +252,10 @@ func New(
 	signatures, _ := lru.NewARC(inmemorySignatures)
 	vABI, _ := abi.JSON(strings.NewReader(validatorsetABI))
 	sABI, _ := abi.JSON(strings.NewReader(stateReceiverABI))
-	heimdallClient, _ := NewHeimdallClient(heimdallURL)
+	heimdallClient, err := newHeimdallClient(heimdallURL, db)
+	if err != nil && !withoutHeimdall {
+		return nil, fmt.Errorf("heimdall client of %s: %w", heimdallURL, err)
+	}
 	genesisContractsClient := NewGenesisContractsClient(chainConfig, borConfig.ValidatorContract, borConfig.StateReceiverContract)
 	c := &Bor{
 		chainConfig:            chainConfig,
@@ -277,7 +280,24 @@ func New(
 		}
 	}
 
-	return c
+	return c, nil
+}
+
+// newHeimdallClient creates the client of the comma separated Heimdall URLs, caching the spans
+// and the state-sync events in the Bor database. A file:// URL replays the fixtures of the file.
+func newHeimdallClient(heimdallURL string, db kv.RwDB) (IHeimdallClient, error) {
+	if path := strings.TrimPrefix(heimdallURL, "file://"); path != heimdallURL {
+		fixtures, err := LoadHeimdallFixtures(path)
+		if err != nil {
+			return nil, err
+		}
+		return NewReplayHeimdallClient(fixtures), nil
+	}
+	client, err := NewHeimdallClient(heimdallURL)
+	if err != nil {
+		return nil, err
+	}
+	return NewCachedHeimdallClient(client, db), nil
 }
 
 // Type returns underlying consensus engine
@@ -707,39 +727,49 @@ func (c *Bor) Prepare(chain consensus.ChainHeaderReader, header *types.Header, s
 // Finalize implements consensus.Engine, ensuring no uncles are set, nor block
 // rewards given.
 func (c *Bor) Finalize(config *params.ChainConfig, header *types.Header, state *state.IntraBlockState, txs types.Transactions, uncles []*types.Header, r types.Receipts, e consensus.EpochReader, chain consensus.ChainHeaderReader, syscall consensus.SystemCall) (types.Transactions, types.Receipts, error) {
-	var err error
+	if _, err := c.finalize(header, state, chain, syscall); err != nil {
+		return nil, types.Receipts{}, err
+	}
+
+	// No block rewards in PoA, so the state remains as is and uncles are dropped
+	// header.Root = state.IntermediateRoot(chain.Config().IsSpuriousDragon(header.Number.Uint64()))
+	header.UncleHash = types.CalcUncleHash(nil)
+
+	// Set state sync data to blockchain
+	// bc := chain.(*core.BlockChain)
+	// bc.SetStateSync(stateSyncData)
+	return nil, types.Receipts{}, nil
+}
+
+// finalize commits the span and the state-sync events at the end of a sprint, and changes the code of
+// the contracts at the blocks of the config, returning the committed state-sync events.
+func (c *Bor) finalize(header *types.Header, state *state.IntraBlockState, chain consensus.ChainHeaderReader, syscall consensus.SystemCall) ([]*types.StateSyncData, error) {
+	var stateSyncs []*types.StateSyncData
 	headerNumber := header.Number.Uint64()
 	if headerNumber%c.config.Sprint == 0 {
 		cx := chainContext{Chain: chain, Bor: c}
 		// check and commit span
 		if err := c.checkAndCommitSpan(state, header, cx, syscall); err != nil {
 			log.Error("Error while committing span", "err", err)
-			return nil, types.Receipts{}, err
+			return nil, err
 		}
 
 		if !c.WithoutHeimdall {
 			// commit states
-			_, err = c.CommitStates(state, header, cx, syscall)
+			var err error
+			stateSyncs, err = c.CommitStates(state, header, cx, syscall)
 			if err != nil {
 				log.Error("Error while committing states", "err", err)
-				return nil, types.Receipts{}, err
+				return nil, err
 			}
 		}
 	}
 
-	if err = c.changeContractCodeIfNeeded(headerNumber, state); err != nil {
+	if err := c.changeContractCodeIfNeeded(headerNumber, state); err != nil {
 		log.Error("Error changing contract code", "err", err)
-		return nil, types.Receipts{}, err
+		return nil, err
 	}
-
-	// No block rewards in PoA, so the state remains as is and uncles are dropped
-	// header.Root = state.IntermediateRoot(chain.Config().IsSpuriousDragon(header.Number.Uint64()))
-	header.UncleHash = types.CalcUncleHash(nil)
-
-	// Set state sync data to blockchain
-	// bc := chain.(*core.BlockChain)
-	// bc.SetStateSync(stateSyncData)
-	return nil, types.Receipts{}, nil
+	return stateSyncs, nil
 }
 
 func decodeGenesisAlloc(i interface{}) (core.GenesisAlloc, error) {
@@ -1065,16 +1095,12 @@ func (c *Bor) getSpanForBlock(blockNum uint64) (*HeimdallSpan, error) {
 			spanID = c.spanCache.Max().(*HeimdallSpan).ID + 1
 		}
 		for span == nil || span.EndBlock < blockNum {
-			var heimdallSpan HeimdallSpan
 			log.Info("Span with high enough block number is not loaded", "fetching span", spanID)
-			response, err := c.HeimdallClient.FetchWithRetry(c.execCtx, fmt.Sprintf("bor/span/%d", spanID), "")
+			heimdallSpan, err := c.HeimdallClient.Span(c.execCtx, spanID)
 			if err != nil {
 				return nil, err
 			}
-			if err := json.Unmarshal(response.Result, &heimdallSpan); err != nil {
-				return nil, err
-			}
-			span = &heimdallSpan
+			span = heimdallSpan
 			c.spanCache.ReplaceOrInsert(span)
 			spanID++
 		}
@@ -1082,16 +1108,12 @@ func (c *Bor) getSpanForBlock(blockNum uint64) (*HeimdallSpan, error) {
 		for span.StartBlock > blockNum {
 			// Span wit low enough block number is not loaded
 			var spanID = span.ID - 1
-			var heimdallSpan HeimdallSpan
 			log.Info("Span with low enough block number is not loaded", "fetching span", spanID)
-			response, err := c.HeimdallClient.FetchWithRetry(c.execCtx, fmt.Sprintf("bor/span/%d", spanID), "")
+			heimdallSpan, err := c.HeimdallClient.Span(c.execCtx, spanID)
 			if err != nil {
 				return nil, err
 			}
-			if err := json.Unmarshal(response.Result, &heimdallSpan); err != nil {
-				return nil, err
-			}
-			span = &heimdallSpan
+			span = heimdallSpan
 			c.spanCache.ReplaceOrInsert(span)
 		}
 	}
@@ -1117,14 +1139,11 @@ func (c *Bor) fetchAndCommitSpan(
 		}
 		heimdallSpan = *s
 	} else {
-		response, err := c.HeimdallClient.FetchWithRetry(c.execCtx, fmt.Sprintf("bor/span/%d", newSpanID), "")
+		s, err := c.HeimdallClient.Span(c.execCtx, newSpanID)
 		if err != nil {
 			return err
 		}
-
-		if err := json.Unmarshal(response.Result, &heimdallSpan); err != nil {
-			return err
-		}
+		heimdallSpan = *s
 	}
 
 	// check if chain id matches with heimdall span
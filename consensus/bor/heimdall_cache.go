@@ -0,0 +1,196 @@
+package bor
+
+import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/log/v3"
+)
+
+var ErrNotInHeimdallCache = errors.New("not in the Heimdall cache")
+
+var (
+	heimdallSpanPrefix  = []byte("heimdall-span-")
+	heimdallEventPrefix = []byte("heimdall-event-")
+)
+
+func heimdallSpanKey(spanID uint64) []byte {
+	k := make([]byte, len(heimdallSpanPrefix)+8)
+	copy(k, heimdallSpanPrefix)
+	binary.BigEndian.PutUint64(k[len(heimdallSpanPrefix):], spanID)
+	return k
+}
+
+func heimdallEventKey(eventID uint64) []byte {
+	k := make([]byte, len(heimdallEventPrefix)+8)
+	copy(k, heimdallEventPrefix)
+	binary.BigEndian.PutUint64(k[len(heimdallEventPrefix):], eventID)
+	return k
+}
+
+// CachedHeimdallClient persists the spans and the state-sync events fetched from Heimdall in
+// the Bor database, so that they are not fetched again, e.g. when the blocks are re-executed
+// after an unwind or a restart, and the node keeps syncing what it has seen while Heimdall is down.
+type CachedHeimdallClient struct {
+	IHeimdallClient
+	db kv.RwDB
+}
+
+func NewCachedHeimdallClient(client IHeimdallClient, db kv.RwDB) *CachedHeimdallClient {
+	return &CachedHeimdallClient{IHeimdallClient: client, db: db}
+}
+
+func (h *CachedHeimdallClient) Span(ctx context.Context, spanID uint64) (*HeimdallSpan, error) {
+	var span *HeimdallSpan
+	if err := h.db.View(ctx, func(tx kv.Tx) (err error) {
+		span, err = readCachedSpan(tx, spanID)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	if span != nil {
+		return span, nil
+	}
+
+	span, err := h.IHeimdallClient.Span(ctx, spanID)
+	if err != nil {
+		return nil, err
+	}
+	v, err := json.Marshal(span)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.db.Update(ctx, func(tx kv.RwTx) error {
+		return tx.Put(kv.BorSeparate, heimdallSpanKey(spanID), v)
+	}); err != nil {
+		log.Warn("Failed to cache Heimdall span", "id", spanID, "err", err)
+	}
+	return span, nil
+}
+
+// FetchStateSyncEvents returns the cached events from fromID on, and fetches the rest from Heimdall.
+// Event records are immutable and recorded in the order of their ids, so the cached events are complete
+// once one is recorded at or after the given time.
+func (h *CachedHeimdallClient) FetchStateSyncEvents(ctx context.Context, fromID uint64, to int64) ([]*EventRecordWithTime, error) {
+	var (
+		events   []*EventRecordWithTime
+		complete bool
+	)
+	if err := h.db.View(ctx, func(tx kv.Tx) (err error) {
+		events, complete, err = readCachedEvents(tx, fromID, to)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	if complete {
+		return events, nil
+	}
+
+	fetched, err := h.IHeimdallClient.FetchStateSyncEvents(ctx, fromID+uint64(len(events)), to)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.db.Update(ctx, func(tx kv.RwTx) error {
+		for _, event := range fetched {
+			v, err := json.Marshal(event)
+			if err != nil {
+				return err
+			}
+			if err := tx.Put(kv.BorSeparate, heimdallEventKey(event.ID), v); err != nil {
+				return err
+			}
+		}
+		return nil
+	}); err != nil {
+		log.Warn("Failed to cache Heimdall state-sync events", "from", fromID, "err", err)
+	}
+	return append(events, fetched...), nil
+}
+
+// readCachedSpan returns nil if the span isn't cached.
+func readCachedSpan(tx kv.Tx, spanID uint64) (*HeimdallSpan, error) {
+	v, err := tx.GetOne(kv.BorSeparate, heimdallSpanKey(spanID))
+	if err != nil || v == nil {
+		return nil, err
+	}
+	span := new(HeimdallSpan)
+	if err := json.Unmarshal(v, span); err != nil {
+		return nil, err
+	}
+	return span, nil
+}
+
+// readCachedEvents returns the cached events from fromID on recorded before to, and whether they are
+// all the events recorded before to.
+func readCachedEvents(tx kv.Tx, fromID uint64, to int64) (events []*EventRecordWithTime, complete bool, err error) {
+	for id := fromID; ; id++ {
+		v, err := tx.GetOne(kv.BorSeparate, heimdallEventKey(id))
+		if err != nil || v == nil {
+			return events, false, err
+		}
+		event := new(EventRecordWithTime)
+		if err := json.Unmarshal(v, event); err != nil {
+			return nil, false, err
+		}
+		if event.Time.Unix() >= to {
+			return events, true, nil
+		}
+		events = append(events, event)
+	}
+}
+
+// StoredHeimdallClient answers from the spans and the state-sync events cached by a CachedHeimdallClient,
+// without reaching Heimdall, to re-execute the blocks offline. It fails with ErrNotInHeimdallCache when the
+// cache can't tell which events a block commits, so that the block is not verified against missing events.
+type StoredHeimdallClient struct {
+	db kv.RoDB
+}
+
+func NewStoredHeimdallClient(db kv.RoDB) *StoredHeimdallClient {
+	return &StoredHeimdallClient{db: db}
+}
+
+func (h *StoredHeimdallClient) Span(ctx context.Context, spanID uint64) (*HeimdallSpan, error) {
+	var span *HeimdallSpan
+	if err := h.db.View(ctx, func(tx kv.Tx) (err error) {
+		span, err = readCachedSpan(tx, spanID)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	if span == nil {
+		return nil, fmt.Errorf("%w: span %d", ErrNotInHeimdallCache, spanID)
+	}
+	return span, nil
+}
+
+// FetchStateSyncEvents returns the cached events from fromID on recorded before to, once an event recorded
+// at or after to is cached too, as otherwise the events following the cached ones are unknown.
+func (h *StoredHeimdallClient) FetchStateSyncEvents(ctx context.Context, fromID uint64, to int64) ([]*EventRecordWithTime, error) {
+	var (
+		events   []*EventRecordWithTime
+		complete bool
+	)
+	if err := h.db.View(ctx, func(tx kv.Tx) (err error) {
+		events, complete, err = readCachedEvents(tx, fromID, to)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	if !complete {
+		return nil, fmt.Errorf("%w: state-sync events from %d, %d cached before %d", ErrNotInHeimdallCache, fromID, len(events), to)
+	}
+	return events, nil
+}
+
+func (h *StoredHeimdallClient) Fetch(ctx context.Context, path string, query string) (*ResponseWithHeight, error) {
+	return nil, fmt.Errorf("%w: %s", ErrNotInHeimdallCache, path)
+}
+
+func (h *StoredHeimdallClient) FetchWithRetry(ctx context.Context, path string, query string) (*ResponseWithHeight, error) {
+	return h.Fetch(ctx, path, query)
+}
@@ -0,0 +1,241 @@
+package bor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var ErrNoHeimdallFixture = errors.New("no Heimdall fixture")
+
+// HeimdallFixtures are spans and state-sync events recorded from Heimdall, which can be replayed
+// by a ReplayHeimdallClient, or served to a HeimdallClient by Handler, so that Bor syncs offline.
+type HeimdallFixtures struct {
+	Spans  []*HeimdallSpan        `json:"spans"`
+	Events []*EventRecordWithTime `json:"events"`
+	// The events with an ID from EventsFromID on and a time before EventsTo are all recorded,
+	// the others can't be replayed.
+	EventsFromID uint64 `json:"eventsFromId"`
+	EventsTo     int64  `json:"eventsTo"`
+}
+
+// LoadHeimdallFixtures reads the fixtures from a json file written by HeimdallFixtures.Save.
+func LoadHeimdallFixtures(path string) (*HeimdallFixtures, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f := new(HeimdallFixtures)
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("heimdall fixtures %s: %w", path, err)
+	}
+	f.sort()
+	return f, nil
+}
+
+// Save writes the fixtures into a json file.
+func (f *HeimdallFixtures) Save(path string) error {
+	f.sort()
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func (f *HeimdallFixtures) sort() {
+	sort.Slice(f.Spans, func(i, j int) bool { return f.Spans[i].ID < f.Spans[j].ID })
+	sort.Slice(f.Events, func(i, j int) bool { return f.Events[i].ID < f.Events[j].ID })
+}
+
+func (f *HeimdallFixtures) span(spanID uint64) (*HeimdallSpan, error) {
+	i := sort.Search(len(f.Spans), func(i int) bool { return f.Spans[i].ID >= spanID })
+	if i == len(f.Spans) || f.Spans[i].ID != spanID {
+		return nil, fmt.Errorf("%w: span %d", ErrNoHeimdallFixture, spanID)
+	}
+	return f.Spans[i], nil
+}
+
+// events returns at most limit events from fromID on recorded before to, all of them if limit is 0.
+// It fails if the events of the range weren't all recorded.
+func (f *HeimdallFixtures) events(fromID uint64, to int64, limit int) ([]*EventRecordWithTime, error) {
+	if fromID < f.EventsFromID || to > f.EventsTo {
+		return nil, fmt.Errorf("%w: events from %d before %d, recorded from %d before %d", ErrNoHeimdallFixture, fromID, to, f.EventsFromID, f.EventsTo)
+	}
+	var events []*EventRecordWithTime
+	for i := sort.Search(len(f.Events), func(i int) bool { return f.Events[i].ID >= fromID }); i < len(f.Events); i++ {
+		if f.Events[i].Time.Unix() >= to || (limit > 0 && len(events) == limit) {
+			break
+		}
+		events = append(events, f.Events[i])
+	}
+	return events, nil
+}
+
+// Handler serves the fixtures on the Heimdall REST endpoints used by Bor.
+func (f *HeimdallFixtures) Handler() http.Handler {
+	mux := http.NewServeMux()
+	write := func(w http.ResponseWriter, result interface{}) {
+		data, err := json.Marshal(result)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(ResponseWithHeight{Height: "0", Result: data})
+	}
+	mux.HandleFunc("/bor/span/", func(w http.ResponseWriter, r *http.Request) {
+		spanID, err := strconv.ParseUint(strings.TrimPrefix(r.URL.Path, "/bor/span/"), 10, 64)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		span, err := f.span(spanID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		write(w, span)
+	})
+	mux.HandleFunc("/clerk/event-record/list", func(w http.ResponseWriter, r *http.Request) {
+		q := r.URL.Query()
+		fromID, err := strconv.ParseUint(q.Get("from-id"), 10, 64)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		to, err := strconv.ParseInt(q.Get("to-time"), 10, 64)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		limit, _ := strconv.Atoi(q.Get("limit"))
+		events, err := f.events(fromID, to, limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if len(events) == 0 {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+		write(w, events)
+	})
+	return mux
+}
+
+// ReplayHeimdallClient is an IHeimdallClient answering from HeimdallFixtures, failing on what
+// wasn't recorded instead of retrying.
+type ReplayHeimdallClient struct {
+	fixtures *HeimdallFixtures
+}
+
+func NewReplayHeimdallClient(fixtures *HeimdallFixtures) *ReplayHeimdallClient {
+	fixtures.sort()
+	return &ReplayHeimdallClient{fixtures: fixtures}
+}
+
+func (h *ReplayHeimdallClient) Span(_ context.Context, spanID uint64) (*HeimdallSpan, error) {
+	return h.fixtures.span(spanID)
+}
+
+func (h *ReplayHeimdallClient) FetchStateSyncEvents(_ context.Context, fromID uint64, to int64) ([]*EventRecordWithTime, error) {
+	return h.fixtures.events(fromID, to, 0)
+}
+
+func (h *ReplayHeimdallClient) Fetch(ctx context.Context, path string, query string) (*ResponseWithHeight, error) {
+	if !strings.HasPrefix(path, "bor/span/") {
+		return nil, fmt.Errorf("%w: %s", ErrNoHeimdallFixture, path)
+	}
+	spanID, err := strconv.ParseUint(strings.TrimPrefix(path, "bor/span/"), 10, 64)
+	if err != nil {
+		return nil, err
+	}
+	span, err := h.Span(ctx, spanID)
+	if err != nil {
+		return nil, err
+	}
+	result, err := json.Marshal(span)
+	if err != nil {
+		return nil, err
+	}
+	return &ResponseWithHeight{Height: "0", Result: result}, nil
+}
+
+func (h *ReplayHeimdallClient) FetchWithRetry(ctx context.Context, path string, query string) (*ResponseWithHeight, error) {
+	return h.Fetch(ctx, path, query)
+}
+
+// RecordingHeimdallClient records the spans and the state-sync events returned by a client,
+// to be saved as fixtures.
+type RecordingHeimdallClient struct {
+	IHeimdallClient
+	lock   sync.Mutex
+	spans  map[uint64]*HeimdallSpan
+	events map[uint64]*EventRecordWithTime
+	// range of the recorded events, Bor fetches them in order
+	eventsRecorded bool
+	eventsFromID   uint64
+	eventsTo       int64
+}
+
+func NewRecordingHeimdallClient(client IHeimdallClient) *RecordingHeimdallClient {
+	return &RecordingHeimdallClient{
+		IHeimdallClient: client,
+		spans:           map[uint64]*HeimdallSpan{},
+		events:          map[uint64]*EventRecordWithTime{},
+	}
+}
+
+func (h *RecordingHeimdallClient) Span(ctx context.Context, spanID uint64) (*HeimdallSpan, error) {
+	span, err := h.IHeimdallClient.Span(ctx, spanID)
+	if err != nil {
+		return nil, err
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.spans[spanID] = span
+	return span, nil
+}
+
+func (h *RecordingHeimdallClient) FetchStateSyncEvents(ctx context.Context, fromID uint64, to int64) ([]*EventRecordWithTime, error) {
+	events, err := h.IHeimdallClient.FetchStateSyncEvents(ctx, fromID, to)
+	if err != nil {
+		return nil, err
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	for _, event := range events {
+		h.events[event.ID] = event
+	}
+	if !h.eventsRecorded || fromID < h.eventsFromID {
+		h.eventsFromID = fromID
+	}
+	if !h.eventsRecorded || to > h.eventsTo {
+		h.eventsTo = to
+	}
+	h.eventsRecorded = true
+	return events, nil
+}
+
+// Fixtures returns what has been recorded so far.
+func (h *RecordingHeimdallClient) Fixtures() *HeimdallFixtures {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	f := &HeimdallFixtures{EventsFromID: h.eventsFromID, EventsTo: h.eventsTo}
+	for _, span := range h.spans {
+		f.Spans = append(f.Spans, span)
+	}
+	for _, event := range h.events {
+		f.Events = append(f.Events, event)
+	}
+	f.sort()
+	return f
+}
@@ -0,0 +1,131 @@
+package bor
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
+)
+
+func testHeimdallFixtures() *HeimdallFixtures {
+	f := &HeimdallFixtures{EventsFromID: 1, EventsTo: 100}
+	for id := uint64(0); id < 3; id++ {
+		f.Spans = append(f.Spans, &HeimdallSpan{Span: Span{ID: id, StartBlock: id * 6400, EndBlock: (id+1)*6400 - 1}, ChainID: "137"})
+	}
+	for id := uint64(1); id <= 3; id++ {
+		f.Events = append(f.Events, &EventRecordWithTime{EventRecord: EventRecord{ID: id, ChainID: "137"}, Time: time.Unix(int64(id*10), 0).UTC()})
+	}
+	return f
+}
+
+// countingHeimdallClient counts the calls which reach Heimdall.
+type countingHeimdallClient struct {
+	IHeimdallClient
+	spans, events int
+}
+
+func (h *countingHeimdallClient) Span(ctx context.Context, spanID uint64) (*HeimdallSpan, error) {
+	h.spans++
+	return h.IHeimdallClient.Span(ctx, spanID)
+}
+
+func (h *countingHeimdallClient) FetchStateSyncEvents(ctx context.Context, fromID uint64, to int64) ([]*EventRecordWithTime, error) {
+	h.events++
+	return h.IHeimdallClient.FetchStateSyncEvents(ctx, fromID, to)
+}
+
+func TestHeimdallClientFailover(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "down", http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	up := httptest.NewServer(testHeimdallFixtures().Handler())
+	defer up.Close()
+
+	client, err := NewHeimdallClient(down.URL + ", " + up.URL)
+	require.NoError(t, err)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	span, err := client.Span(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(6400), span.StartBlock)
+	require.Equal(t, uint32(1), client.current, "sticks to the url which responded")
+
+	events, err := client.FetchStateSyncEvents(ctx, 2, 40)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, uint64(2), events[0].ID)
+
+	_, err = NewHeimdallClient(" , ")
+	require.Error(t, err)
+}
+
+func TestCachedHeimdallClient(t *testing.T) {
+	ctx := context.Background()
+	heimdall := &countingHeimdallClient{IHeimdallClient: NewReplayHeimdallClient(testHeimdallFixtures())}
+	client := NewCachedHeimdallClient(heimdall, memdb.NewTestDB(t))
+
+	for i := 0; i < 2; i++ {
+		span, err := client.Span(ctx, 2)
+		require.NoError(t, err)
+		require.Equal(t, uint64(2), span.ID)
+	}
+	require.Equal(t, 1, heimdall.spans)
+
+	events, err := client.FetchStateSyncEvents(ctx, 1, 40)
+	require.NoError(t, err)
+	require.Len(t, events, 3)
+	require.Equal(t, 1, heimdall.events)
+
+	// the cache holds the event recorded after the time, nothing is fetched
+	events, err = client.FetchStateSyncEvents(ctx, 1, 25)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, 1, heimdall.events)
+
+	// the events after the cached ones are fetched
+	events, err = client.FetchStateSyncEvents(ctx, 2, 50)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.Equal(t, 2, heimdall.events)
+}
+
+func TestReplayRecordedHeimdallFixtures(t *testing.T) {
+	ctx := context.Background()
+	recorder := NewRecordingHeimdallClient(NewReplayHeimdallClient(testHeimdallFixtures()))
+	_, err := recorder.Span(ctx, 1)
+	require.NoError(t, err)
+	_, err = recorder.FetchStateSyncEvents(ctx, 1, 25)
+	require.NoError(t, err)
+
+	path := filepath.Join(t.TempDir(), "heimdall.json")
+	require.NoError(t, recorder.Fixtures().Save(path))
+	fixtures, err := LoadHeimdallFixtures(path)
+	require.NoError(t, err)
+	replay := NewReplayHeimdallClient(fixtures)
+
+	span, err := replay.Span(ctx, 1)
+	require.NoError(t, err)
+	require.Equal(t, uint64(12799), span.EndBlock)
+	_, err = replay.Span(ctx, 0)
+	require.ErrorIs(t, err, ErrNoHeimdallFixture)
+
+	response, err := replay.FetchWithRetry(ctx, "bor/span/1", "")
+	require.NoError(t, err)
+	require.Contains(t, string(response.Result), `"span_id":1`)
+
+	events, err := replay.FetchStateSyncEvents(ctx, 1, 25)
+	require.NoError(t, err)
+	require.Len(t, events, 2)
+	require.True(t, events[1].Time.Equal(time.Unix(20, 0)))
+	_, err = replay.FetchStateSyncEvents(ctx, 1, 100)
+	require.ErrorIs(t, err, ErrNoHeimdallFixture, "events after the recorded time")
+	_, err = replay.FetchStateSyncEvents(ctx, 0, 25)
+	require.ErrorIs(t, err, ErrNoHeimdallFixture, "events before the recorded ones")
+}
@@ -8,6 +8,8 @@ import (
 	"net/http"
 	"net/url"
 	"sort"
+	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/ledgerwatch/log/v3"
@@ -28,23 +30,53 @@ type IHeimdallClient interface {
 	Fetch(ctx context.Context, path string, query string) (*ResponseWithHeight, error)
 	FetchWithRetry(ctx context.Context, path string, query string) (*ResponseWithHeight, error)
 	FetchStateSyncEvents(ctx context.Context, fromID uint64, to int64) ([]*EventRecordWithTime, error)
+	// Span returns the span of the given id, retrying until Heimdall has it
+	Span(ctx context.Context, spanID uint64) (*HeimdallSpan, error)
 }
 
+// HeimdallClient fetches from the first of its Heimdall URLs which responds, starting from
+// the last one which did.
 type HeimdallClient struct {
-	urlString string
-	client    http.Client
+	urls    []*url.URL
+	current uint32 // index of the last url which responded
+	client  http.Client
 }
 
+// NewHeimdallClient creates a client of the comma separated list of Heimdall URLs.
 func NewHeimdallClient(urlString string) (*HeimdallClient, error) {
 	h := &HeimdallClient{
-		urlString: urlString,
 		client: http.Client{
 			Timeout: 5 * time.Second,
 		},
 	}
+	for _, s := range strings.Split(urlString, ",") {
+		if s = strings.TrimSpace(s); s == "" {
+			continue
+		}
+		u, err := url.Parse(s)
+		if err != nil {
+			return nil, err
+		}
+		h.urls = append(h.urls, u)
+	}
+	if len(h.urls) == 0 {
+		return nil, fmt.Errorf("no Heimdall URL in %q", urlString)
+	}
 	return h, nil
 }
 
+func (h *HeimdallClient) Span(ctx context.Context, spanID uint64) (*HeimdallSpan, error) {
+	response, err := h.FetchWithRetry(ctx, fmt.Sprintf("bor/span/%d", spanID), "")
+	if err != nil {
+		return nil, err
+	}
+	var span HeimdallSpan
+	if err := json.Unmarshal(response.Result, &span); err != nil {
+		return nil, err
+	}
+	return &span, nil
+}
+
 func (h *HeimdallClient) FetchStateSyncEvents(ctx context.Context, fromID uint64, to int64) ([]*EventRecordWithTime, error) {
 	eventRecords := make([]*EventRecordWithTime, 0)
 	for {
@@ -74,35 +106,40 @@ func (h *HeimdallClient) FetchStateSyncEvents(ctx context.Context, fromID uint64
 	return eventRecords, nil
 }
 
-// Fetch fetches response from heimdall
+// Fetch fetches response from heimdall, failing over to the next URL when one doesn't respond
 func (h *HeimdallClient) Fetch(ctx context.Context, rawPath string, rawQuery string) (*ResponseWithHeight, error) {
-	u, err := url.Parse(h.urlString)
-	if err != nil {
-		return nil, err
+	var err error
+	current := atomic.LoadUint32(&h.current)
+	for i := 0; i < len(h.urls); i++ {
+		idx := (int(current) + i) % len(h.urls)
+		u := *h.urls[idx]
+		u.Path = rawPath
+		u.RawQuery = rawQuery
+
+		var res *ResponseWithHeight
+		if res, err = h.internalFetch(ctx, &u); err == nil {
+			if idx != int(current) {
+				log.Warn("Failed over to Heimdall", "url", h.urls[idx].Host)
+				atomic.StoreUint32(&h.current, uint32(idx))
+			}
+			return res, nil
+		}
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		log.Debug("Heimdall fetch failed", "url", h.urls[idx].Host, "path", rawPath, "err", err)
 	}
-
-	u.Path = rawPath
-	u.RawQuery = rawQuery
-
-	return h.internalFetch(ctx, u)
+	return nil, err
 }
 
 // FetchWithRetry returns data from heimdall with retry
 func (h *HeimdallClient) FetchWithRetry(ctx context.Context, rawPath string, rawQuery string) (*ResponseWithHeight, error) {
-	u, err := url.Parse(h.urlString)
-	if err != nil {
-		return nil, err
-	}
-
-	u.Path = rawPath
-	u.RawQuery = rawQuery
-
 	for {
-		res, err := h.internalFetch(ctx, u)
+		res, err := h.Fetch(ctx, rawPath, rawQuery)
 		if err == nil && res != nil {
 			return res, nil
 		}
-		log.Info("Retrying again in 5 seconds for next Heimdall span", "path", u.Path)
+		log.Info("Retrying again in 5 seconds for next Heimdall span", "path", rawPath, "err", err)
 		select {
 		case <-ctx.Done():
 			return nil, ctx.Err()
@@ -0,0 +1,153 @@
+package bor
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"golang.org/x/exp/slices"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/core/vm"
+)
+
+// StateSyncCheck is the outcome of the re-execution of the state-sync of a block.
+type StateSyncCheck struct {
+	Block      uint64
+	Events     []uint64 // ids of the state-sync events committed by the re-execution
+	Mismatches []string // differences with what the node stored, none if the state-sync is verified
+}
+
+// VerifyStateSync re-executes a block on its historical state, then the system calls of its finalization,
+// which commit the span and the state-sync events returned by the Heimdall client of the engine, a
+// StoredHeimdallClient not to trust Heimdall. The state-sync logs are compared with the stored bor receipt,
+// and the state written by the block with the history of the state, which must not be pruned. The code of
+// the contracts isn't compared: the account change sets are written without code hashes, and the ones read
+// from the history are restored from PlainContractCode, i.e. the latest code of the incarnation.
+func (c *Bor) VerifyStateSync(tx kv.Tx, block *types.Block, chain consensus.ChainHeaderReader) (*StateSyncCheck, error) {
+	header := block.Header()
+	number := header.Number.Uint64()
+	ibs := state.New(state.NewPlainState(tx, number))
+	getHeader := func(hash common.Hash, number uint64) *types.Header { return chain.GetHeader(hash, number) }
+	blockHashFunc := core.GetHashFn(header, getHeader)
+	gp := new(core.GasPool).AddGas(block.GasLimit())
+	usedGas := new(uint64)
+	var txLogs int
+	for i, txn := range block.Transactions() {
+		ibs.Prepare(txn.Hash(), block.Hash(), i)
+		receipt, _, err := core.ApplyTransaction(c.chainConfig, blockHashFunc, c, nil, gp, ibs, state.NewNoopWriter(), header, txn, usedGas, vm.Config{}, nil)
+		if err != nil {
+			return nil, fmt.Errorf("could not apply tx %d from block %d [%x]: %w", i, number, txn.Hash(), err)
+		}
+		txLogs += len(receipt.Logs)
+	}
+
+	syscall := func(contract common.Address, data []byte) ([]byte, error) {
+		return core.SysCallContract(contract, data, *c.chainConfig, ibs, header, c)
+	}
+	stateSyncs, err := c.finalize(header, ibs, chain, syscall)
+	if err != nil {
+		return nil, fmt.Errorf("finalizing block %d: %w", number, err)
+	}
+	check := &StateSyncCheck{Block: number}
+	for _, stateSync := range stateSyncs {
+		check.Events = append(check.Events, stateSync.ID)
+	}
+
+	// the logs emitted by the system calls follow the ones of the transactions, as in ExecuteBlockEphemerally
+	var stateSyncLogs, storedLogs []*types.Log
+	blockLogs := ibs.Logs()
+	slices.SortStableFunc(blockLogs, func(i, j *types.Log) bool { return i.Index < j.Index })
+	if len(blockLogs) > txLogs {
+		stateSyncLogs = blockLogs[txLogs:]
+	}
+	if receipt := rawdb.ReadRawBorReceipt(tx, block.Hash(), number); receipt != nil {
+		storedLogs = receipt.Logs
+	}
+	check.Mismatches = compareStateSyncLogs(stateSyncLogs, storedLogs)
+
+	history := &historyComparer{history: state.NewPlainState(tx, number+1)}
+	if err := ibs.CommitBlock(c.chainConfig.Rules(number), history); err != nil {
+		return nil, fmt.Errorf("committing block %d failed: %w", number, err)
+	}
+	check.Mismatches = append(check.Mismatches, history.mismatches...)
+	return check, nil
+}
+
+func compareStateSyncLogs(logs, stored []*types.Log) []string {
+	var mismatches []string
+	if len(logs) != len(stored) {
+		mismatches = append(mismatches, fmt.Sprintf("%d state-sync logs, %d in the bor receipt", len(logs), len(stored)))
+	}
+	for i := 0; i < len(logs) && i < len(stored); i++ {
+		equal := logs[i].Address == stored[i].Address && bytes.Equal(logs[i].Data, stored[i].Data) && len(logs[i].Topics) == len(stored[i].Topics)
+		for j := 0; equal && j < len(logs[i].Topics); j++ {
+			equal = logs[i].Topics[j] == stored[i].Topics[j]
+		}
+		if !equal {
+			mismatches = append(mismatches, fmt.Sprintf("state-sync log %d of %x differs from the bor receipt", i, logs[i].Address))
+		}
+	}
+	return mismatches
+}
+
+// historyComparer is a StateWriter recording the differences between what is written and the history.
+type historyComparer struct {
+	history    state.StateReader
+	mismatches []string
+}
+
+func (h *historyComparer) UpdateAccountData(address common.Address, original, account *accounts.Account) error {
+	stored, err := h.history.ReadAccountData(address)
+	if err != nil {
+		return err
+	}
+	switch {
+	case stored == nil:
+		h.mismatches = append(h.mismatches, fmt.Sprintf("account %x: written, absent from the history", address))
+	case stored.Nonce != account.Nonce:
+		h.mismatches = append(h.mismatches, fmt.Sprintf("account %x: nonce %d, %d in the history", address, account.Nonce, stored.Nonce))
+	case !stored.Balance.Eq(&account.Balance):
+		h.mismatches = append(h.mismatches, fmt.Sprintf("account %x: balance %d, %d in the history", address, &account.Balance, &stored.Balance))
+	case stored.Incarnation != account.Incarnation:
+		h.mismatches = append(h.mismatches, fmt.Sprintf("account %x: incarnation %d, %d in the history", address, account.Incarnation, stored.Incarnation))
+	}
+	return nil
+}
+
+func (h *historyComparer) UpdateAccountCode(address common.Address, incarnation uint64, codeHash common.Hash, code []byte) error {
+	return nil
+}
+
+func (h *historyComparer) DeleteAccount(address common.Address, original *accounts.Account) error {
+	stored, err := h.history.ReadAccountData(address)
+	if err != nil {
+		return err
+	}
+	if stored != nil {
+		h.mismatches = append(h.mismatches, fmt.Sprintf("account %x: deleted, present in the history", address))
+	}
+	return nil
+}
+
+func (h *historyComparer) WriteAccountStorage(address common.Address, incarnation uint64, key *common.Hash, original, value *uint256.Int) error {
+	enc, err := h.history.ReadAccountStorage(address, incarnation, key)
+	if err != nil {
+		return err
+	}
+	if stored := new(uint256.Int).SetBytes(enc); !stored.Eq(value) {
+		h.mismatches = append(h.mismatches, fmt.Sprintf("storage %x %x: %x, %x in the history", address, *key, value.Bytes(), stored.Bytes()))
+	}
+	return nil
+}
+
+func (h *historyComparer) CreateContract(address common.Address) error {
+	return nil
+}
@@ -0,0 +1,114 @@
+package bor
+
+import (
+	"context"
+	"encoding/json"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
+)
+
+func TestVerifyStateSync(t *testing.T) {
+	genesis := core.DefaultBorDevnetGenesisBlock()
+	// the receiver of the events logs the calls of the state receiver
+	receiver := common.HexToAddress("0x2000")
+	genesis.Alloc[receiver] = core.GenesisAccount{Balance: new(big.Int), Code: common.FromHex("366000600037366000a000")}
+	db := memdb.NewTestDB(t)
+	chainConfig, genesisBlock, err := core.CommitGenesisBlock(db, genesis)
+	require.NoError(t, err)
+
+	// the events are committed at the end of the first sprint if they were recorded before the genesis
+	borDB := memdb.NewTestDB(t)
+	storeEvent := func(id uint64, data []byte, recorded uint64) {
+		event := &EventRecordWithTime{
+			EventRecord: EventRecord{ID: id, Contract: receiver, Data: data, ChainID: chainConfig.ChainID.String()},
+			Time:        time.Unix(int64(recorded), 0).UTC(),
+		}
+		v, err := json.Marshal(event)
+		require.NoError(t, err)
+		require.NoError(t, borDB.Update(context.Background(), func(tx kv.RwTx) error {
+			return tx.Put(kv.BorSeparate, heimdallEventKey(id), v)
+		}))
+	}
+	// the genesis span ends before the end of the first sprint, the next span is committed with the events
+	validator := NewValidator(common.HexToAddress("0x67b1d87101671b127f5f8714789C7192f7ad340e"), 10000)
+	span, err := json.Marshal(&HeimdallSpan{
+		Span:              Span{ID: 1, StartBlock: 256, EndBlock: 6655},
+		ValidatorSet:      *NewValidatorSet([]*Validator{validator}),
+		SelectedProducers: []Validator{*validator},
+		ChainID:           chainConfig.ChainID.String(),
+	})
+	require.NoError(t, err)
+	require.NoError(t, borDB.Update(context.Background(), func(tx kv.RwTx) error {
+		return tx.Put(kv.BorSeparate, heimdallSpanKey(1), span)
+	}))
+	storeEvent(1, []byte{0x01}, genesisBlock.Time()-20)
+	storeEvent(2, []byte{0x02}, genesisBlock.Time()-10)
+	storeEvent(3, []byte{0x03}, genesisBlock.Time()+10)
+
+	_, err = New(chainConfig, borDB, " , ", false)
+	require.Error(t, err, "no Heimdall url")
+	engine, err := New(chainConfig, borDB, "", true)
+	require.NoError(t, err)
+	engine.WithoutHeimdall = false
+	engine.SetHeimdallClient(NewStoredHeimdallClient(borDB))
+
+	tx, err := db.BeginRw(context.Background())
+	require.NoError(t, err)
+	defer tx.Rollback()
+	chain := stagedsync.ChainReader{Cfg: *chainConfig, Db: tx}
+	sprint := chainConfig.Bor.Sprint
+	header := &types.Header{
+		ParentHash:  genesisBlock.Hash(),
+		Number:      new(big.Int).SetUint64(sprint),
+		Time:        genesisBlock.Time() + sprint*5,
+		GasLimit:    genesisBlock.GasLimit(),
+		Difficulty:  big.NewInt(1),
+		BaseFee:     genesisBlock.BaseFee(),
+		ReceiptHash: types.EmptyRootHash,
+	}
+	block := types.NewBlockWithHeader(header)
+
+	// the state before the block is the one of the genesis, the blocks of the sprint being empty
+	w := state.NewPlainStateWriter(tx, tx, sprint)
+	res, err := core.ExecuteBlockEphemerally(chainConfig, &vm.Config{}, core.GetHashFn(header, chain.GetHeader), engine, block, state.NewPlainStateReader(tx), w, nil, chain, nil, false, nil)
+	require.NoError(t, err)
+	require.NoError(t, w.WriteHistory())
+	require.NotNil(t, res.ReceiptForStorage)
+	require.NoError(t, rawdb.WriteBorReceipt(tx, block.Hash(), sprint, res.ReceiptForStorage))
+
+	check, err := engine.VerifyStateSync(tx, block, chain)
+	require.NoError(t, err)
+	require.Equal(t, &StateSyncCheck{Block: sprint, Events: []uint64{1, 2}}, check)
+
+	storeEvent(2, []byte{0x04}, genesisBlock.Time()-10)
+	check, err = engine.VerifyStateSync(tx, block, chain)
+	require.NoError(t, err)
+	require.Equal(t, []uint64{1, 2}, check.Events)
+	require.Equal(t, []string{"state-sync log 1 of 0000000000000000000000000000000000002000 differs from the bor receipt"}, check.Mismatches)
+
+	// without the second event, or without one recorded after the sprint, the events of the block are unknown
+	require.NoError(t, borDB.Update(context.Background(), func(tx kv.RwTx) error {
+		return tx.Delete(kv.BorSeparate, heimdallEventKey(2))
+	}))
+	_, err = engine.VerifyStateSync(tx, block, chain)
+	require.ErrorIs(t, err, ErrNotInHeimdallCache)
+	storeEvent(2, []byte{0x02}, genesisBlock.Time()-10)
+	require.NoError(t, borDB.Update(context.Background(), func(tx kv.RwTx) error {
+		return tx.Delete(kv.BorSeparate, heimdallEventKey(3))
+	}))
+	_, err = engine.VerifyStateSync(tx, block, chain)
+	require.ErrorIs(t, err, ErrNotInHeimdallCache)
+}
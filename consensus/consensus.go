@@ -161,6 +161,24 @@ type PoSA interface {
 	EnoughDistance(chain ChainReader, header *types.Header) bool
 	IsLocalBlock(header *types.Header) bool
 	AllowLightProcess(chain ChainReader, currentHeader *types.Header) bool
+
+	// EpochValidators returns the validators announced by an epoch header, or nil if the header doesn't
+	// start an epoch.
+	EpochValidators(header *types.Header) ([]common.Address, error)
+	// VerifyEpochValidators checks the validators announced by an epoch header against the validator
+	// contract on the given state of its parent.
+	VerifyEpochValidators(chain ChainHeaderReader, header *types.Header, ibs *state.IntraBlockState) error
+	// VerifySystemReceipts checks the outcome of the system transactions of an executed block, as the
+	// engine requires it when executing the block.
+	VerifySystemReceipts(header *types.Header, txs types.Transactions, receipts types.Receipts) error
+	// RecordActivity adds the activity of the validators recorded by a canonical block at the tip of the
+	// chain to the metrics.
+	RecordActivity(chain ChainHeaderReader, header *types.Header, txs types.Transactions, receipts types.Receipts) error
+	// UnwindActivity accounts the activity added by RecordActivity for a canonical block being unwound as reorged.
+	UnwindActivity(chain ChainHeaderReader, header *types.Header, txs types.Transactions, receipts types.Receipts) error
+	// Finality returns the highest finalized and safe blocks of the chain ending at the header,
+	// nil for the ones which aren't known.
+	Finality(chain ChainHeaderReader, header *types.Header) (finalized, safe *types.Header, err error)
 }
 
 type AsyncEngine interface {
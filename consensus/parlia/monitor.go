@@ -0,0 +1,243 @@
+package parlia
+
+import (
+	"bytes"
+	"fmt"
+	"math/big"
+	"sync"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/holiman/uint256"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/systemcontracts"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/params"
+)
+
+var (
+	slashMethodID   = crypto.Keccak256([]byte("slash(address)"))[:4]
+	depositMethodID = crypto.Keccak256([]byte("deposit(address)"))[:4]
+)
+
+// ValidatorActivity is the work of a validator over a range of blocks, as recorded
+// by the difficulty of the headers and the system transactions of the blocks.
+type ValidatorActivity struct {
+	Inturn          hexutil.Uint64 `json:"inturn"`          // blocks sealed in turn
+	Outturn         hexutil.Uint64 `json:"outturn"`         // blocks sealed out of turn
+	MissedTurns     hexutil.Uint64 `json:"missedTurns"`     // in-turn blocks sealed by another validator
+	Slashes         hexutil.Uint64 `json:"slashes"`         // slash system transactions issued against the validator
+	FailedSlashes   hexutil.Uint64 `json:"failedSlashes"`   // slash system transactions which reverted, e.g. with a disabled slash channel
+	ValidatorReward *hexutil.Big   `json:"validatorReward"` // fees deposited to the validator contract by distributeToValidator
+	SystemReward    *hexutil.Big   `json:"systemReward"`    // fees sent to the system reward contract by distributeToSystem
+}
+
+// Activity is the activity of the validators over a range of blocks
+type Activity map[common.Address]*ValidatorActivity
+
+func (a Activity) validator(addr common.Address) *ValidatorActivity {
+	v, ok := a[addr]
+	if !ok {
+		v = &ValidatorActivity{ValidatorReward: (*hexutil.Big)(new(big.Int)), SystemReward: (*hexutil.Big)(new(big.Int))}
+		a[addr] = v
+	}
+	return v
+}
+
+// RecordBlock adds the activity recorded by a block to the activity of its validators.
+// inturn is the validator expected to seal the block, as given by the snapshot of its
+// parent; missed turns are not accounted when it is the zero address. The senders of
+// the transactions must be known, and receipts must be in the order of the transactions.
+func (a Activity) RecordBlock(header *types.Header, inturn common.Address, txs types.Transactions, receipts types.Receipts) error {
+	if len(txs) != len(receipts) {
+		return fmt.Errorf("block %d has %d transactions but %d receipts", header.Number.Uint64(), len(txs), len(receipts))
+	}
+	// Parlia enforces the coinbase to be the signer of the block
+	signer := a.validator(header.Coinbase)
+	if header.Difficulty.Cmp(diffInTurn) == 0 {
+		signer.Inturn++
+	} else {
+		signer.Outturn++
+		if inturn != (common.Address{}) && inturn != header.Coinbase {
+			a.validator(inturn).MissedTurns++
+		}
+	}
+	for i, tx := range txs {
+		to := tx.GetTo()
+		if to == nil || !isToSystemContract(*to) || !tx.GetPrice().IsZero() {
+			continue
+		}
+		sender, ok := tx.GetSender()
+		if !ok {
+			return fmt.Errorf("sender of tx %x in block %d is unknown", tx.Hash(), header.Number.Uint64())
+		}
+		if sender != header.Coinbase {
+			continue
+		}
+		data, success := tx.GetData(), receipts[i].Status == types.ReceiptStatusSuccessful
+		switch {
+		case *to == systemcontracts.SlashContract && bytes.HasPrefix(data, slashMethodID) && len(data) >= 4+32:
+			slashed := a.validator(common.BytesToAddress(data[4 : 4+32]))
+			slashed.Slashes++
+			if !success {
+				slashed.FailedSlashes++
+			}
+		case *to == systemcontracts.ValidatorContract && bytes.HasPrefix(data, depositMethodID) && success:
+			signer.ValidatorReward.ToInt().Add(signer.ValidatorReward.ToInt(), valueOf(tx).ToBig())
+		case *to == systemcontracts.SystemRewardContract && len(data) == 0 && success:
+			signer.SystemReward.ToInt().Add(signer.SystemReward.ToInt(), valueOf(tx).ToBig())
+		}
+	}
+	return nil
+}
+
+// activityMonitor exports the activity of the validators recorded by the canonical blocks at the tip of the chain.
+// The parlia_* counters only go up: the activity of the unwound blocks is added to the parlia_*_reorged counters,
+// which are subtracted from them to get the activity of the canonical chain. The parlia_epoch_* gauges hold the
+// activity of the blocks of the current epoch.
+type activityMonitor struct {
+	mu sync.Mutex
+	// recordedFrom and recordedTo are the range of consecutive canonical blocks whose activity was recorded,
+	// 0 if there is none. Only these blocks are accounted as reorged when unwound.
+	recordedFrom, recordedTo uint64
+	epoch                    uint64              // epoch of the head of the recorded blocks
+	epochs                   map[uint64]Activity // activity of the recorded blocks of the epoch and of the previous one
+	epochMetrics             *metrics.Set        // parlia_epoch gauges, registered on the first recorded block
+}
+
+// record adds the activity of a canonical block to the metrics
+func (m *activityMonitor) record(number, epochLength uint64, blockActivity func() (Activity, error)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	activity, err := blockActivity()
+	if err != nil {
+		return err
+	}
+	if m.recordedTo == 0 || number != m.recordedTo+1 {
+		// the activity of the blocks skipped since the last recorded one isn't known
+		m.recordedFrom, m.epochs = number, nil
+	}
+	m.recordedTo = number
+	m.setEpoch(number / epochLength)
+	activity.updateMetrics("")
+	m.epochs[m.epoch].add(activity, false)
+	m.registerEpochGauges(activity)
+	return nil
+}
+
+// unwind accounts the activity of a canonical block being unwound as reorged. The blocks are unwound from the
+// head down; the ones whose activity wasn't recorded are skipped.
+func (m *activityMonitor) unwind(number, epochLength uint64, blockActivity func() (Activity, error)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.recordedTo == 0 || number != m.recordedTo {
+		return nil
+	}
+	activity, err := blockActivity()
+	if err != nil {
+		return err
+	}
+	activity.updateMetrics("_reorged")
+	m.epochs[m.epoch].add(activity, true)
+	if m.recordedTo = number - 1; m.recordedTo < m.recordedFrom {
+		m.recordedFrom, m.recordedTo = 0, 0
+	}
+	m.setEpoch((number - 1) / epochLength)
+	return nil
+}
+
+// setEpoch makes the epoch the current one of the gauges, keeping the activity of the previous epoch for unwinds
+func (m *activityMonitor) setEpoch(epoch uint64) {
+	if m.epochs == nil {
+		m.epochs = make(map[uint64]Activity)
+	}
+	m.epoch = epoch
+	if _, ok := m.epochs[epoch]; !ok {
+		m.epochs[epoch] = Activity{}
+	}
+	for e := range m.epochs {
+		if e != epoch && e+1 != epoch {
+			delete(m.epochs, e)
+		}
+	}
+}
+
+// registerEpochGauges creates the parlia_epoch gauges of the validators of the activity
+func (m *activityMonitor) registerEpochGauges(activity Activity) {
+	if m.epochMetrics == nil {
+		m.epochMetrics = metrics.NewSet()
+		m.epochMetrics.GetOrCreateGauge("parlia_epoch", func() float64 {
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			return float64(m.epoch)
+		})
+		metrics.RegisterSet(m.epochMetrics)
+	}
+	for addr := range activity {
+		addr := addr
+		gauge := func(name string, value func(v *ValidatorActivity) float64) {
+			m.epochMetrics.GetOrCreateGauge(fmt.Sprintf(name, addr), func() float64 {
+				m.mu.Lock()
+				defer m.mu.Unlock()
+				if v, ok := m.epochs[m.epoch][addr]; ok {
+					return value(v)
+				}
+				return 0
+			})
+		}
+		gauge(`parlia_epoch_blocks{validator="0x%x",turn="in"}`, func(v *ValidatorActivity) float64 { return float64(v.Inturn) })
+		gauge(`parlia_epoch_blocks{validator="0x%x",turn="out"}`, func(v *ValidatorActivity) float64 { return float64(v.Outturn) })
+		gauge(`parlia_epoch_missed_turns{validator="0x%x"}`, func(v *ValidatorActivity) float64 { return float64(v.MissedTurns) })
+		gauge(`parlia_epoch_slashes{validator="0x%x"}`, func(v *ValidatorActivity) float64 { return float64(v.Slashes) })
+		gauge(`parlia_epoch_slashes_failed{validator="0x%x"}`, func(v *ValidatorActivity) float64 { return float64(v.FailedSlashes) })
+		gauge(`parlia_epoch_rewards{validator="0x%x",to="validator"}`, func(v *ValidatorActivity) float64 { return toEther(v.ValidatorReward.ToInt()) })
+		gauge(`parlia_epoch_rewards{validator="0x%x",to="system"}`, func(v *ValidatorActivity) float64 { return toEther(v.SystemReward.ToInt()) })
+	}
+}
+
+// add adds the activity of a block to the activity, or subtracts it when the block is unwound
+func (a Activity) add(block Activity, unwind bool) {
+	for addr, b := range block {
+		v := a.validator(addr)
+		if unwind {
+			v.Inturn, v.Outturn, v.MissedTurns = v.Inturn-b.Inturn, v.Outturn-b.Outturn, v.MissedTurns-b.MissedTurns
+			v.Slashes, v.FailedSlashes = v.Slashes-b.Slashes, v.FailedSlashes-b.FailedSlashes
+			v.ValidatorReward.ToInt().Sub(v.ValidatorReward.ToInt(), b.ValidatorReward.ToInt())
+			v.SystemReward.ToInt().Sub(v.SystemReward.ToInt(), b.SystemReward.ToInt())
+		} else {
+			v.Inturn, v.Outturn, v.MissedTurns = v.Inturn+b.Inturn, v.Outturn+b.Outturn, v.MissedTurns+b.MissedTurns
+			v.Slashes, v.FailedSlashes = v.Slashes+b.Slashes, v.FailedSlashes+b.FailedSlashes
+			v.ValidatorReward.ToInt().Add(v.ValidatorReward.ToInt(), b.ValidatorReward.ToInt())
+			v.SystemReward.ToInt().Add(v.SystemReward.ToInt(), b.SystemReward.ToInt())
+		}
+	}
+}
+
+// updateMetrics adds the activity to the parlia counters, the ones of the reorged activity given the "_reorged" suffix
+func (a Activity) updateMetrics(suffix string) {
+	for addr, v := range a {
+		metrics.GetOrCreateCounter(fmt.Sprintf(`parlia_blocks%s{validator="0x%x",turn="in"}`, suffix, addr)).Add(int(v.Inturn))
+		metrics.GetOrCreateCounter(fmt.Sprintf(`parlia_blocks%s{validator="0x%x",turn="out"}`, suffix, addr)).Add(int(v.Outturn))
+		metrics.GetOrCreateCounter(fmt.Sprintf(`parlia_missed_turns%s{validator="0x%x"}`, suffix, addr)).Add(int(v.MissedTurns))
+		metrics.GetOrCreateCounter(fmt.Sprintf(`parlia_slashes%s{validator="0x%x"}`, suffix, addr)).Add(int(v.Slashes))
+		metrics.GetOrCreateCounter(fmt.Sprintf(`parlia_slashes_failed%s{validator="0x%x"}`, suffix, addr)).Add(int(v.FailedSlashes))
+		metrics.GetOrCreateFloatCounter(fmt.Sprintf(`parlia_rewards%s{validator="0x%x",to="validator"}`, suffix, addr)).Add(toEther(v.ValidatorReward.ToInt()))
+		metrics.GetOrCreateFloatCounter(fmt.Sprintf(`parlia_rewards%s{validator="0x%x",to="system"}`, suffix, addr)).Add(toEther(v.SystemReward.ToInt()))
+	}
+}
+
+// toEther converts an amount of wei to a floating point amount of BNB
+func toEther(wei *big.Int) float64 {
+	f, _ := new(big.Float).Quo(new(big.Float).SetInt(wei), new(big.Float).SetUint64(params.Ether)).Float64()
+	return f
+}
+
+// valueOf returns the value of a system transaction, treating a nil value as zero
+func valueOf(tx types.Transaction) *uint256.Int {
+	if v := tx.GetValue(); v != nil {
+		return v
+	}
+	return new(uint256.Int)
+}
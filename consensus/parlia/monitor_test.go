@@ -0,0 +1,138 @@
+package parlia
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/holiman/uint256"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/systemcontracts"
+	"github.com/ledgerwatch/erigon/core/types"
+)
+
+func TestRecordBlockActivity(t *testing.T) {
+	signer, inturn, user := randomAddress(), randomAddress(), randomAddress()
+	newTx := func(from common.Address, to common.Address, value uint64, gasPrice uint64, data []byte) types.Transaction {
+		tx := types.NewTransaction(0, to, uint256.NewInt(value), 21000, uint256.NewInt(gasPrice), data)
+		tx.SetSender(from)
+		return tx
+	}
+	receipt := func(status uint64) *types.Receipt {
+		return &types.Receipt{Status: status}
+	}
+	slashData := append(common.CopyBytes(slashMethodID), common.LeftPadBytes(inturn[:], 32)...)
+	depositData := append(common.CopyBytes(depositMethodID), common.LeftPadBytes(signer[:], 32)...)
+
+	activity := Activity{}
+	// an in-turn block without transactions
+	inturnHeader := &types.Header{Number: big.NewInt(1), Coinbase: inturn, Difficulty: diffInTurn}
+	require.NoError(t, activity.RecordBlock(inturnHeader, inturn, nil, nil))
+
+	// an out-of-turn block, slashing the in-turn validator
+	header := &types.Header{Number: big.NewInt(2), Coinbase: signer, Difficulty: diffNoTurn}
+	txs := types.Transactions{
+		newTx(user, systemcontracts.ValidatorContract, 5, 1, depositData), // user transaction
+		newTx(signer, systemcontracts.SlashContract, 0, 0, slashData),
+		newTx(signer, systemcontracts.SlashContract, 0, 0, slashData),
+		newTx(signer, systemcontracts.SystemRewardContract, 10, 0, nil),
+		newTx(signer, systemcontracts.ValidatorContract, 150, 0, depositData),
+	}
+	receipts := types.Receipts{
+		receipt(types.ReceiptStatusSuccessful),
+		receipt(types.ReceiptStatusSuccessful),
+		receipt(types.ReceiptStatusFailed),
+		receipt(types.ReceiptStatusSuccessful),
+		receipt(types.ReceiptStatusSuccessful),
+	}
+	require.NoError(t, activity.RecordBlock(header, inturn, txs, receipts))
+	require.Error(t, activity.RecordBlock(header, inturn, txs, receipts[1:]))
+
+	require.Len(t, activity, 2)
+	require.EqualValues(t, 1, activity[inturn].Inturn)
+	require.EqualValues(t, 0, activity[inturn].Outturn)
+	require.EqualValues(t, 1, activity[inturn].MissedTurns)
+	require.EqualValues(t, 2, activity[inturn].Slashes)
+	require.EqualValues(t, 1, activity[inturn].FailedSlashes)
+	require.EqualValues(t, 0, activity[signer].Inturn)
+	require.EqualValues(t, 1, activity[signer].Outturn)
+	require.EqualValues(t, 0, activity[signer].Slashes)
+	require.Equal(t, big.NewInt(150), activity[signer].ValidatorReward.ToInt())
+	require.Equal(t, big.NewInt(10), activity[signer].SystemReward.ToInt())
+	require.Equal(t, new(big.Int), activity[inturn].ValidatorReward.ToInt())
+
+	// a transaction with an unknown sender can't be attributed
+	unsigned := types.NewTransaction(0, systemcontracts.SlashContract, new(uint256.Int), 21000, new(uint256.Int), slashData)
+	require.Error(t, Activity{}.RecordBlock(header, inturn, types.Transactions{unsigned}, types.Receipts{receipt(types.ReceiptStatusSuccessful)}))
+
+	activity.updateMetrics("")
+	activity.updateMetrics("")
+	require.EqualValues(t, 2, metrics.GetOrCreateCounter(`parlia_missed_turns{validator="0x`+common.Bytes2Hex(inturn[:])+`"}`).Get())
+	require.EqualValues(t, 4, metrics.GetOrCreateCounter(`parlia_slashes{validator="0x`+common.Bytes2Hex(inturn[:])+`"}`).Get())
+	require.InDelta(t, toEther(big.NewInt(300)), metrics.GetOrCreateFloatCounter(`parlia_rewards{validator="0x`+common.Bytes2Hex(signer[:])+`",to="validator"}`).Get(), 1e-30)
+
+	// the activity of an unwound block goes to the reorged counters
+	activity.updateMetrics("_reorged")
+	require.EqualValues(t, 2, metrics.GetOrCreateCounter(`parlia_missed_turns{validator="0x`+common.Bytes2Hex(inturn[:])+`"}`).Get())
+	require.EqualValues(t, 1, metrics.GetOrCreateCounter(`parlia_missed_turns_reorged{validator="0x`+common.Bytes2Hex(inturn[:])+`"}`).Get())
+	require.EqualValues(t, 2, metrics.GetOrCreateCounter(`parlia_slashes_reorged{validator="0x`+common.Bytes2Hex(inturn[:])+`"}`).Get())
+	require.InDelta(t, toEther(big.NewInt(150)), metrics.GetOrCreateFloatCounter(`parlia_rewards_reorged{validator="0x`+common.Bytes2Hex(signer[:])+`",to="validator"}`).Get(), 1e-30)
+}
+
+func TestUnwindActivity(t *testing.T) {
+	validator := randomAddress()
+	counter := func(name string) *metrics.Counter {
+		return metrics.GetOrCreateCounter(name + `{validator="0x` + common.Bytes2Hex(validator[:]) + `",turn="in"}`)
+	}
+	block := func() (Activity, error) {
+		return Activity{validator: {Inturn: 1, ValidatorReward: (*hexutil.Big)(new(big.Int)), SystemReward: (*hexutil.Big)(new(big.Int))}}, nil
+	}
+	canonical := func() uint64 { return counter("parlia_blocks").Get() - counter("parlia_blocks_reorged").Get() }
+
+	var m activityMonitor
+	defer metrics.UnregisterSet(m.epochMetrics)
+	epochGauge := func(name string) float64 {
+		return m.epochMetrics.GetOrCreateGauge(name, nil).Get()
+	}
+	epochBlocks := func() float64 {
+		return epochGauge(`parlia_epoch_blocks{validator="0x` + common.Bytes2Hex(validator[:]) + `",turn="in"}`)
+	}
+
+	// blocks 8-12 are recorded, the process started after block 7
+	for number := uint64(8); number <= 12; number++ {
+		require.NoError(t, m.record(number, 5, block))
+	}
+	require.EqualValues(t, 5, canonical())
+	require.EqualValues(t, 2, epochGauge("parlia_epoch"))
+	require.EqualValues(t, 3, epochBlocks(), "blocks 10-12")
+
+	// a reorg unwinds blocks 12-6, only the recorded ones are accounted as reorged
+	for number := uint64(12); number >= 6; number-- {
+		require.NoError(t, m.unwind(number, 5, block))
+	}
+	require.EqualValues(t, 5, counter("parlia_blocks").Get())
+	require.EqualValues(t, 0, canonical())
+	require.EqualValues(t, 1, epochGauge("parlia_epoch"))
+	require.EqualValues(t, 0, epochBlocks())
+
+	// the new blocks are recorded once
+	for number := uint64(6); number <= 13; number++ {
+		require.NoError(t, m.record(number, 5, block))
+	}
+	require.EqualValues(t, 8, canonical())
+	for number := uint64(13); number >= 9; number-- {
+		require.NoError(t, m.unwind(number, 5, block))
+	}
+	require.EqualValues(t, 3, canonical())
+	require.EqualValues(t, 1, epochGauge("parlia_epoch"))
+	require.EqualValues(t, 3, epochBlocks(), "blocks 6-8 of the previous epoch are kept")
+
+	// the blocks skipped since the last recorded one start a new range
+	require.NoError(t, m.record(20, 5, block))
+	require.NoError(t, m.unwind(20, 5, block))
+	require.NoError(t, m.unwind(8, 5, block))
+	require.EqualValues(t, 3, canonical(), "block 8 isn't in the recorded range anymore")
+}
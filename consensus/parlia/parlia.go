@@ -139,6 +139,10 @@ var (
 	// errRecentlySigned is returned if a header is signed by an authorized entity
 	// that already signed a header recently, thus is temporarily not allowed to.
 	errRecentlySigned = errors.New("recently signed")
+
+	// errSystemTxFailed is returned if a system transaction of an executed block
+	// didn't have the expected outcome.
+	errSystemTxFailed = errors.New("system transaction failed")
 )
 
 // SignFn is a signer callback function to request a header to be signed by a
@@ -219,7 +223,7 @@ type Parlia struct {
 	chainConfig *params.ChainConfig  // Chain config
 	config      *params.ParliaConfig // Consensus engine configuration parameters for parlia consensus
 	genesisHash common.Hash
-	db          kv.RwDB // Database to store and retrieve snapshot checkpoints
+	DB          kv.RwDB // Database to store and retrieve snapshot checkpoints
 	chainDb     kv.RwDB
 
 	recentSnaps *lru.ARCCache // Snapshots for recent block to speed up
@@ -235,6 +239,8 @@ type Parlia struct {
 	validatorSetABI abi.ABI
 	slashABI        abi.ABI
 
+	monitor activityMonitor // Metrics of the validator activity recorded by the canonical blocks
+
 	// The fields below are for testing only
 	fakeDiff  bool     // Skip difficulty verifications
 	forks     []uint64 // Forks extracted from the chainConfig
@@ -276,7 +282,7 @@ func New(
 	c := &Parlia{
 		chainConfig:     chainConfig,
 		config:          parliaConfig,
-		db:              db,
+		DB:              db,
 		chainDb:         chainDb,
 		recentSnaps:     recentSnaps,
 		signatures:      signatures,
@@ -520,8 +526,10 @@ func (p *Parlia) SignRecently(chain consensus.ChainHeaderReader, parent *types.H
 func (p *Parlia) snapshot(chain consensus.ChainHeaderReader, number uint64, hash common.Hash, parents []*types.Header, verify bool) (*Snapshot, error) {
 	// Search for a snapshot in memory or on disk for checkpoints
 	var (
-		headers []*types.Header
-		snap    *Snapshot
+		headers    []*types.Header
+		snap       *Snapshot
+		seed       *epochSeed
+		seedLooked bool
 	)
 
 	for snap == nil {
@@ -530,16 +538,45 @@ func (p *Parlia) snapshot(chain consensus.ChainHeaderReader, number uint64, hash
 			snap = s.(*Snapshot)
 			break
 		}
+		if !seedLooked {
+			seedLooked = true
+			var err error
+			if seed, err = p.findEpochSeed(number); err != nil {
+				return nil, err
+			}
+		}
+		// The validators stored for the epoch blocks seed the snapshot, the headers above it being applied
+		if seed != nil && number == seed.number && len(parents) == 0 {
+			s, err := seedSnapshot(p.config, p.signatures, chain, seed, hash)
+			if err != nil {
+				return nil, err
+			}
+			if s != nil {
+				snap = s
+				break
+			}
+		}
 
 		// If an on-disk checkpoint snapshot can be found, use that
 		if number%checkpointInterval == 0 {
-			if s, err := loadSnapshot(p.config, p.signatures, p.db, number, hash); err == nil {
+			if s, err := loadSnapshot(p.config, p.signatures, p.DB, number, hash); err == nil {
 				//log.Trace("Loaded snapshot from disk", "number", number, "hash", hash)
 				snap = s
 				if !verify || snap != nil {
 					break
 				}
 			}
+			// Checkpoints retired from the database are frozen in the snapshot segments
+			if p.snapshots != nil {
+				if blob, ok := p.snapshots.ParliaSnapshot(number, hash); ok {
+					s, err := decodeSnapshot(p.config, p.signatures, blob)
+					if err != nil {
+						return nil, err
+					}
+					snap = s
+					break
+				}
+			}
 		}
 		if (verify && number%p.config.Epoch == 0) || number == 0 {
 			if (p.snapshots != nil && number <= p.snapshots.BlocksAvailable()) || number == 0 {
@@ -596,7 +633,7 @@ func (p *Parlia) snapshot(chain consensus.ChainHeaderReader, number uint64, hash
 
 	// If we've generated a new checkpoint snapshot, save to disk
 	if snap.Number%checkpointInterval == 0 && len(headers) > 0 {
-		if err = snap.store(p.db); err != nil {
+		if err = snap.store(p.DB); err != nil {
 			return nil, err
 		}
 		//log.Trace("Stored snapshot to disk", "number", snap.Number, "hash", snap.Hash)
@@ -604,6 +641,22 @@ func (p *Parlia) snapshot(chain consensus.ChainHeaderReader, number uint64, hash
 	return snap, err
 }
 
+// findEpochSeed returns the last block at or before the given one whose snapshot can be rebuilt from the validators
+// stored for the epoch blocks in the chain database, nil if there is none or the engine has no chain database.
+func (p *Parlia) findEpochSeed(number uint64) (*epochSeed, error) {
+	if p.chainDb == nil {
+		return nil, nil
+	}
+	var seed *epochSeed
+	if err := p.chainDb.View(context.Background(), func(tx kv.Tx) (err error) {
+		seed, err = findEpochSeed(p.config, tx, number)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	return seed, nil
+}
+
 // VerifyUncles verifies that the given block's uncles conform to the consensus
 // rules of a given engine.
 func (p *Parlia) VerifyUncles(chain consensus.ChainReader, header *types.Header, uncles []*types.Header) error {
@@ -730,21 +783,9 @@ func (p *Parlia) finalize(header *types.Header, state *state.IntraBlockState, tx
 	// The verification can only be done when the state is ready, it can't be done in VerifyHeader.
 	if number%p.config.Epoch == 0 {
 		parentHeader := chain.GetHeader(header.ParentHash, number-1)
-		newValidators, err := p.getCurrentValidators(parentHeader, state)
-		if err != nil {
+		if _, err := p.verifyEpochValidators(header, parentHeader, state); err != nil {
 			return nil, nil, err
 		}
-		// sort validator by address
-		sort.Sort(validatorsAscending(newValidators))
-		validatorsBytes := make([]byte, len(newValidators)*validatorBytesLength)
-		for i, validator := range newValidators {
-			copy(validatorsBytes[i*validatorBytesLength:], validator.Bytes())
-		}
-
-		extraSuffix := len(header.Extra) - extraSeal
-		if !bytes.Equal(header.Extra[extraVanity:extraSuffix], validatorsBytes) {
-			return nil, nil, errMismatchingEpochValidators
-		}
 	}
 	// No block rewards in PoA, so the state remains as is and uncles are dropped
 	if number == 1 {
@@ -966,6 +1007,107 @@ func (p *Parlia) IsSystemContract(to *common.Address) bool {
 	return isToSystemContract(*to)
 }
 
+// EpochValidators returns the sorted validators of the new epoch from the extra data of an epoch
+// header, or nil if the header doesn't start an epoch.
+func (p *Parlia) EpochValidators(header *types.Header) ([]common.Address, error) {
+	if header.Number.Uint64()%p.config.Epoch != 0 {
+		return nil, nil
+	}
+	if len(header.Extra) < extraVanity+extraSeal {
+		return nil, errMissingSignature
+	}
+	return ParseValidators(header.Extra[extraVanity : len(header.Extra)-extraSeal])
+}
+
+// VerifyEpochValidators checks the validators of an epoch header against the ones returned by the
+// validator contract on the given state of its parent.
+func (p *Parlia) VerifyEpochValidators(chain consensus.ChainHeaderReader, header *types.Header, ibs *state.IntraBlockState) error {
+	number := header.Number.Uint64()
+	if number%p.config.Epoch != 0 {
+		return nil
+	}
+	if len(header.Extra) < extraVanity+extraSeal {
+		return errMissingSignature
+	}
+	parent := chain.GetHeader(header.ParentHash, number-1)
+	if parent == nil {
+		return consensus.ErrUnknownAncestor
+	}
+	_, err := p.verifyEpochValidators(header, parent, ibs)
+	return err
+}
+
+func (p *Parlia) verifyEpochValidators(header, parent *types.Header, ibs *state.IntraBlockState) ([]common.Address, error) {
+	newValidators, err := p.getCurrentValidators(parent, ibs)
+	if err != nil {
+		return nil, err
+	}
+	// sort validator by address
+	sort.Sort(validatorsAscending(newValidators))
+	validatorsBytes := make([]byte, len(newValidators)*validatorBytesLength)
+	for i, validator := range newValidators {
+		copy(validatorsBytes[i*validatorBytesLength:], validator.Bytes())
+	}
+
+	extraSuffix := len(header.Extra) - extraSeal
+	if !bytes.Equal(header.Extra[extraVanity:extraSuffix], validatorsBytes) {
+		return nil, errMismatchingEpochValidators
+	}
+	return newValidators, nil
+}
+
+// VerifySystemReceipts checks the outcome of the system transactions of a block: all of them
+// but slashing must succeed, a successful slashing must have slashed the validator and the
+// reward distribution must have been deposited to the validator contract.
+func (p *Parlia) VerifySystemReceipts(header *types.Header, txs types.Transactions, receipts types.Receipts) error {
+	if len(txs) != len(receipts) {
+		return fmt.Errorf("block %d has %d transactions but %d receipts", header.Number.Uint64(), len(txs), len(receipts))
+	}
+	slashMethod := p.slashABI.Methods["slash"].ID
+	depositMethod := p.validatorSetABI.Methods["deposit"].ID
+	for i, tx := range txs {
+		isSystemTx, err := p.IsSystemTransaction(tx, header)
+		if err != nil {
+			return err
+		}
+		if !isSystemTx {
+			continue
+		}
+		receipt := receipts[i]
+		isSlash := *tx.GetTo() == systemcontracts.SlashContract && bytes.HasPrefix(tx.GetData(), slashMethod)
+		if receipt.Status != types.ReceiptStatusSuccessful {
+			// slashing is allowed to fail, e.g. when the slash channel is disabled
+			if isSlash {
+				continue
+			}
+			return fmt.Errorf("%w: tx %x to %s", errSystemTxFailed, tx.Hash(), tx.GetTo())
+		}
+		switch {
+		case isSlash:
+			if !hasSystemEvent(receipt.Logs, systemcontracts.SlashContract, p.slashABI, "validatorSlashed") {
+				return fmt.Errorf("%w: slash tx %x didn't slash the validator", errSystemTxFailed, tx.Hash())
+			}
+		case *tx.GetTo() == systemcontracts.ValidatorContract && bytes.HasPrefix(tx.GetData(), depositMethod):
+			// rewards of validators which left the set are kept by the contract as deprecated deposits
+			if !hasSystemEvent(receipt.Logs, systemcontracts.ValidatorContract, p.validatorSetABI, "validatorDeposit") &&
+				!hasSystemEvent(receipt.Logs, systemcontracts.ValidatorContract, p.validatorSetABI, "deprecatedDeposit") {
+				return fmt.Errorf("%w: distribute tx %x didn't deposit the reward", errSystemTxFailed, tx.Hash())
+			}
+		}
+	}
+	return nil
+}
+
+func hasSystemEvent(logs []*types.Log, contract common.Address, contractABI abi.ABI, event string) bool {
+	id := contractABI.Events[event].ID
+	for _, l := range logs {
+		if l.Address == contract && len(l.Topics) > 0 && l.Topics[0] == id {
+			return true
+		}
+	}
+	return false
+}
+
 func (p *Parlia) shouldWaitForCurrentBlockProcess(chainDb kv.RwDB, header *types.Header, snap *Snapshot) bool {
 	if header.Difficulty.Cmp(diffInTurn) == 0 {
 		return false
@@ -978,6 +1120,9 @@ func (p *Parlia) shouldWaitForCurrentBlockProcess(chainDb kv.RwDB, header *types
 	defer roTx.Rollback()
 	hash := rawdb.ReadHeadHeaderHash(roTx)
 	number := rawdb.ReadHeaderNumber(roTx, hash)
+	if number == nil {
+		return false
+	}
 
 	highestVerifiedHeader := rawdb.ReadHeader(roTx, hash, *number)
 	if highestVerifiedHeader == nil {
@@ -998,11 +1143,58 @@ func (p *Parlia) EnoughDistance(chain consensus.ChainReader, header *types.Heade
 	return snap.enoughDistance(p.val, header)
 }
 
+// RecordActivity adds the activity of the validators recorded by a canonical block at the tip of the chain to the
+// parlia metrics. The senders of the transactions must be known.
+func (p *Parlia) RecordActivity(chain consensus.ChainHeaderReader, header *types.Header, txs types.Transactions, receipts types.Receipts) error {
+	return p.monitor.record(header.Number.Uint64(), p.config.Epoch, func() (Activity, error) {
+		return p.blockActivity(chain, header, txs, receipts)
+	})
+}
+
+// UnwindActivity adds the activity of a canonical block being unwound to the reorged parlia metrics. The blocks
+// are unwound from the head down; the ones whose activity wasn't recorded by RecordActivity are skipped.
+func (p *Parlia) UnwindActivity(chain consensus.ChainHeaderReader, header *types.Header, txs types.Transactions, receipts types.Receipts) error {
+	return p.monitor.unwind(header.Number.Uint64(), p.config.Epoch, func() (Activity, error) {
+		return p.blockActivity(chain, header, txs, receipts)
+	})
+}
+
+// blockActivity returns the activity of the validators recorded by a block
+func (p *Parlia) blockActivity(chain consensus.ChainHeaderReader, header *types.Header, txs types.Transactions, receipts types.Receipts) (Activity, error) {
+	// The turn of a block is decided by the snapshot of its parent
+	snap, err := p.snapshot(chain, header.Number.Uint64()-1, header.ParentHash, nil, false /* verify */)
+	if err != nil {
+		return nil, err
+	}
+	activity := Activity{}
+	if err = activity.RecordBlock(header, snap.supposeValidator(), txs, receipts); err != nil {
+		return nil, err
+	}
+	return activity, nil
+}
+
+// Finality returns the highest finalized and safe blocks of the chain ending at the header, which
+// are sealed on by a majority and by more than a third of the validators of the header's snapshot.
+// A nil header is returned for a threshold which isn't reached.
+func (p *Parlia) Finality(chain consensus.ChainHeaderReader, header *types.Header) (finalized, safe *types.Header, err error) {
+	snap, err := p.snapshot(chain, header.Number.Uint64(), header.Hash(), nil, false /* verify */)
+	if err != nil {
+		return nil, nil, err
+	}
+	finalized, safe = snap.finality(chain, header)
+	return finalized, safe, nil
+}
+
 func (p *Parlia) IsLocalBlock(header *types.Header) bool {
 	return p.val == header.Coinbase
 }
 
+// AllowLightProcess returns whether the block may be applied from a diff layer, which skips the checks of
+// Finalize. The epoch blocks are always executed, so that their validators are checked against the contract.
 func (p *Parlia) AllowLightProcess(chain consensus.ChainReader, currentHeader *types.Header) bool {
+	if currentHeader.Number.Uint64()%p.config.Epoch == 0 {
+		return false
+	}
 	snap, err := p.snapshot(chain, currentHeader.Number.Uint64()-1, currentHeader.ParentHash, nil, false /* verify */)
 	if err != nil {
 		return true
@@ -1138,6 +1330,11 @@ func (p *Parlia) initContract(state *state.IntraBlockState, header *types.Header
 		return nil, nil, nil, err
 	}
 	for _, c := range contracts {
+		if p.alreadyInit(c, state, header) {
+			// a devnet genesis holds the initialized validator set in the storage of the validator contract
+			log.Info("[parlia] contract already initialized", "block hash", header.Hash(), "contract", c)
+			continue
+		}
 		log.Info("[parlia] init contract", "block hash", header.Hash(), "contract", c)
 		var tx types.Transaction
 		var receipt *types.Receipt
@@ -1150,6 +1347,24 @@ func (p *Parlia) initContract(state *state.IntraBlockState, header *types.Header
 	return txs, systemTxs, receipts, nil
 }
 
+// alreadyInit reports whether the init method of the system contract has already run, or its storage was
+// initialized in the genesis.
+func (p *Parlia) alreadyInit(contract common.Address, ibs *state.IntraBlockState, header *types.Header) bool {
+	data, err := p.validatorSetABI.Pack("alreadyInit")
+	if err != nil {
+		return false
+	}
+	_, returnData, err := p.systemCall(header.Coinbase, contract, data, ibs, header, u256.Num0)
+	if err != nil {
+		return false
+	}
+	var initialized bool
+	if err := p.validatorSetABI.UnpackIntoInterface(&initialized, "alreadyInit", returnData); err != nil {
+		return false
+	}
+	return initialized
+}
+
 func (p *Parlia) distributeToSystem(amount *uint256.Int, state *state.IntraBlockState, header *types.Header,
 	txIndex int, systemTxs types.Transactions,
 	usedGas *uint64, mining bool,
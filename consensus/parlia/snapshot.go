@@ -32,6 +32,7 @@ import (
 
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/params"
 )
@@ -101,10 +102,20 @@ func loadSnapshot(config *params.ParliaConfig, sigCache *lru.ARCCache, db kv.RwD
 		return nil, err
 	}
 	defer tx.Rollback()
+	return readSnapshot(config, sigCache, tx, num, hash)
+}
+
+// readSnapshot loads an existing snapshot within the given transaction.
+func readSnapshot(config *params.ParliaConfig, sigCache *lru.ARCCache, tx kv.Tx, num uint64, hash common.Hash) (*Snapshot, error) {
 	blob, err := tx.GetOne(kv.ParliaSnapshot, SnapshotFullKey(num, hash))
 	if err != nil {
 		return nil, err
 	}
+	return decodeSnapshot(config, sigCache, blob)
+}
+
+// decodeSnapshot decodes a snapshot as stored in the database or frozen in the snapshot segments.
+func decodeSnapshot(config *params.ParliaConfig, sigCache *lru.ARCCache, blob []byte) (*Snapshot, error) {
 	snap := new(Snapshot)
 	if err := json.Unmarshal(blob, snap); err != nil {
 		return nil, err
@@ -114,6 +125,134 @@ func loadSnapshot(config *params.ParliaConfig, sigCache *lru.ARCCache, db kv.RwD
 	return snap, nil
 }
 
+// epochSeed is a block whose snapshot is rebuilt from the validators the PostExec stage stored for the epoch blocks,
+// which the execution checked against the validator contract
+type epochSeed struct {
+	number     uint64
+	epoch      uint64 // the epoch block announcing the validators
+	epochHash  common.Hash
+	validators []common.Address
+}
+
+// findEpochSeed returns the last block at or before the given one whose snapshot can be rebuilt from the stored
+// epoch validators, nil if there is none. The validators announced by an epoch block replace the previous ones
+// len(previous)/2 blocks later; once they have sealed len(validators) more blocks, the recent validators and fork
+// hashes of the snapshot only come from the headers they sealed.
+func findEpochSeed(config *params.ParliaConfig, tx kv.Tx, number uint64) (*epochSeed, error) {
+	for n, i := number, 0; i < 2; i++ {
+		epoch, epochHash, validators, err := rawdb.FindEpochValidatorsBeforeOrEqualNumber(tx, n)
+		if err != nil || len(validators) == 0 || epoch < config.Epoch {
+			return nil, err
+		}
+		prevEpoch, _, prevValidators, err := rawdb.FindEpochValidatorsBeforeOrEqualNumber(tx, epoch-1)
+		if err != nil || len(prevValidators) == 0 || prevEpoch != epoch-config.Epoch {
+			return nil, err
+		}
+		if seed := epoch + uint64(len(prevValidators)/2+len(validators)); seed <= number {
+			return &epochSeed{number: seed, epoch: epoch, epochHash: epochHash, validators: validators}, nil
+		}
+		n = epoch - 1
+	}
+	return nil, nil
+}
+
+// seedSnapshot rebuilds the snapshot of the seed block of the chain whose header at the seed has the given hash,
+// the recent validators and fork hashes being read from the last headers. It returns nil if the chain doesn't go
+// through the epoch block of the seed.
+func seedSnapshot(config *params.ParliaConfig, sigCache *lru.ARCCache, chain consensus.ChainHeaderReader, seed *epochSeed, hash common.Hash) (*Snapshot, error) {
+	snap := newSnapshot(config, sigCache, seed.number, hash, seed.validators)
+	recentsLimit, forkHashesLimit := uint64(len(seed.validators)/2+1), uint64(len(seed.validators))
+	for number := seed.number; number > seed.epoch; number-- {
+		header := chain.GetHeader(hash, number)
+		if header == nil {
+			return nil, consensus.ErrUnknownAncestor
+		}
+		if seed.number-number < recentsLimit {
+			validator, err := ecrecover(header, sigCache, chain.Config().ChainID)
+			if err != nil {
+				return nil, err
+			}
+			snap.Recents[number] = validator
+		}
+		if seed.number-number < forkHashesLimit {
+			snap.RecentForkHashes[number] = hex.EncodeToString(header.Extra[extraVanity-nextForkHashSize : extraVanity])
+		}
+		hash = header.ParentHash
+	}
+	if hash != seed.epochHash {
+		return nil, nil
+	}
+	return snap, nil
+}
+
+// ReadSnapshot retrieves the validator snapshot at a given block without
+// persisting anything. It starts from the closest checkpoint stored in the
+// consensus database, the snapshot seeded by the validators stored for the
+// epoch blocks in the chain database, or the genesis header, and applies the
+// headers on top of it, which makes it usable from processes that only have
+// read-only access, e.g. a standalone rpcdaemon. As in Parlia.snapshot, the
+// walk back is bounded: an epoch header more than FullImmutabilityThreshold
+// blocks back is trusted as a checkpoint.
+func ReadSnapshot(chain consensus.ChainHeaderReader, chainTx kv.Tx, tx kv.Tx, number uint64, hash common.Hash) (*Snapshot, error) {
+	if chain.Config().Parlia == nil {
+		return nil, errors.New("parlia config not found")
+	}
+	// Local copy, the chain config being shared
+	config := *chain.Config().Parlia
+	if config.Epoch == 0 {
+		config.Epoch = defaultEpochLength
+	}
+	sigCache, err := lru.NewARC(inMemorySignatures)
+	if err != nil {
+		return nil, err
+	}
+
+	seed, err := findEpochSeed(&config, chainTx, number)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		headers []*types.Header
+		snap    *Snapshot
+	)
+	for snap == nil {
+		if number%checkpointInterval == 0 {
+			if s, err := readSnapshot(&config, sigCache, tx, number, hash); err == nil {
+				snap = s
+				break
+			}
+		}
+		if seed != nil && number == seed.number {
+			if snap, err = seedSnapshot(&config, sigCache, chain, seed, hash); err != nil {
+				return nil, err
+			}
+			if snap != nil {
+				break
+			}
+		}
+		header := chain.GetHeader(hash, number)
+		if header == nil {
+			return nil, consensus.ErrUnknownAncestor
+		}
+		if number == 0 || (number%config.Epoch == 0 && len(headers) >= params.FullImmutabilityThreshold) {
+			validators, err := ParseValidators(header.Extra[extraVanity : len(header.Extra)-extraSeal])
+			if err != nil {
+				return nil, err
+			}
+			snap = newSnapshot(&config, sigCache, number, hash, validators)
+			break
+		}
+		headers = append(headers, header)
+		number, hash = number-1, header.ParentHash
+	}
+
+	for i := 0; i < len(headers)/2; i++ {
+		headers[i], headers[len(headers)-1-i] = headers[len(headers)-1-i], headers[i]
+	}
+	return snap.apply(headers, chain, nil, chain.Config().ChainID)
+}
+
 // store inserts the snapshot into the database.
 func (s *Snapshot) store(db kv.RwDB) error {
 	blob, err := json.Marshal(s)
@@ -246,6 +385,16 @@ func (s *Snapshot) apply(headers []*types.Header, chain consensus.ChainHeaderRea
 	return snap, nil
 }
 
+// Apply creates a new snapshot by applying the given consecutive headers to the original one.
+func (s *Snapshot) Apply(chain consensus.ChainHeaderReader, headers ...*types.Header) (*Snapshot, error) {
+	return s.apply(headers, chain, nil, chain.Config().ChainID)
+}
+
+// EpochLength returns the number of blocks between validator set updates.
+func (s *Snapshot) EpochLength() uint64 {
+	return s.config.Epoch
+}
+
 // validators retrieves the list of validators in ascending order.
 func (s *Snapshot) validators() []common.Address {
 	validators := make([]common.Address, 0, len(s.Validators))
@@ -256,6 +405,16 @@ func (s *Snapshot) validators() []common.Address {
 	return validators
 }
 
+// SortedValidators retrieves the list of validators in ascending order.
+func (s *Snapshot) SortedValidators() []common.Address {
+	return s.validators()
+}
+
+// InturnValidator returns the validator expected to seal the block following the snapshot.
+func (s *Snapshot) InturnValidator() common.Address {
+	return s.supposeValidator()
+}
+
 // inturn returns if a validator at a given block height is in-turn or not.
 func (s *Snapshot) inturn(validator common.Address) bool {
 	validators := s.validators()
@@ -283,6 +442,30 @@ func (s *Snapshot) enoughDistance(validator common.Address, header *types.Header
 	}
 }
 
+// finality walks back the chain from the header of the snapshot and returns the highest block on
+// which ⌊N/2⌋+1 distinct validators of the snapshot sealed blocks, so that it can't be reorganised
+// without a majority of the validators sealing a competing chain, and the highest block on which
+// ⌊N/3⌋+1 of them did, which takes more than a third of them to revert. The walk is bounded by twice
+// the number of validators, nil is returned for a threshold which isn't reached within it.
+func (s *Snapshot) finality(chain consensus.ChainHeaderReader, header *types.Header) (finalized, safe *types.Header) {
+	finalizedQuorum, safeQuorum := len(s.Validators)/2+1, len(s.Validators)/3+1
+	sealers := make(map[common.Address]struct{}, finalizedQuorum)
+	for depth := 0; depth < 2*len(s.Validators) && header != nil && header.Number.Uint64() > 0; depth++ {
+		if _, ok := s.Validators[header.Coinbase]; ok {
+			sealers[header.Coinbase] = struct{}{}
+		}
+		parent := chain.GetHeader(header.ParentHash, header.Number.Uint64()-1)
+		if safe == nil && len(sealers) >= safeQuorum {
+			safe = parent
+		}
+		if len(sealers) >= finalizedQuorum {
+			return parent, safe
+		}
+		header = parent
+	}
+	return nil, safe
+}
+
 func (s *Snapshot) indexOfVal(validator common.Address) int {
 	validators := s.validators()
 	for idx, val := range validators {
@@ -2,13 +2,24 @@ package parlia
 
 import (
 	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"math/big"
 	"math/rand"
 	"sort"
 	"testing"
 
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 
 	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/params"
 )
 
 func TestValidatorSetSort(t *testing.T) {
@@ -28,3 +39,229 @@ func randomAddress() common.Address {
 	rand.Read(addrBytes)
 	return common.BytesToAddress(addrBytes)
 }
+
+type testChainReader struct {
+	config  *params.ChainConfig
+	headers map[common.Hash]*types.Header
+}
+
+func (cr testChainReader) Config() *params.ChainConfig  { return cr.config }
+func (cr testChainReader) CurrentHeader() *types.Header { return nil }
+func (cr testChainReader) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return cr.headers[hash]
+}
+func (cr testChainReader) GetHeaderByNumber(number uint64) *types.Header {
+	for _, h := range cr.headers {
+		if h.Number.Uint64() == number {
+			return h
+		}
+	}
+	return nil
+}
+func (cr testChainReader) GetHeaderByHash(hash common.Hash) *types.Header { return cr.headers[hash] }
+func (cr testChainReader) GetTd(hash common.Hash, number uint64) *big.Int { return nil }
+
+func TestReadSnapshot(t *testing.T) {
+	config := &params.ChainConfig{ChainID: big.NewInt(1337), Parlia: &params.ParliaConfig{Period: 3, Epoch: 200}}
+	keys := make([]*ecdsa.PrivateKey, 2)
+	validators := make([]common.Address, 2)
+	for i := range keys {
+		keys[i], _ = crypto.GenerateKey()
+		validators[i] = crypto.PubkeyToAddress(keys[i].PublicKey)
+	}
+	sort.Sort(validatorsAscending(validators))
+
+	extra := make([]byte, extraVanity+2*validatorBytesLength+extraSeal)
+	for i, v := range validators {
+		copy(extra[extraVanity+i*validatorBytesLength:], v[:])
+	}
+	genesis := &types.Header{Number: big.NewInt(0), Difficulty: big.NewInt(1), Extra: extra}
+	chain := testChainReader{config: config, headers: map[common.Hash]*types.Header{genesis.Hash(): genesis}}
+
+	parent := genesis
+	for i := uint64(1); i <= 5; i++ {
+		signer := validators[i%2]
+		header := &types.Header{
+			ParentHash: parent.Hash(),
+			Coinbase:   signer,
+			Number:     new(big.Int).SetUint64(i),
+			Difficulty: new(big.Int).Set(diffInTurn),
+			Time:       i * 3,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		for _, key := range keys {
+			if crypto.PubkeyToAddress(key.PublicKey) == signer {
+				sig, err := crypto.Sign(SealHash(header, config.ChainID).Bytes(), key)
+				require.NoError(t, err)
+				copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+			}
+		}
+		chain.headers[header.Hash()] = header
+		parent = header
+	}
+
+	db := memdb.NewTestDB(t)
+	tx, err := db.BeginRo(context.Background())
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	snap, err := ReadSnapshot(chain, tx, tx, parent.Number.Uint64(), parent.Hash())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(5), snap.Number)
+	assert.Equal(t, parent.Hash(), snap.Hash)
+	assert.Equal(t, validators, snap.SortedValidators())
+	assert.Equal(t, validators[0], snap.InturnValidator())
+	assert.Equal(t, map[uint64]common.Address{4: validators[0], 5: validators[1]}, snap.Recents)
+
+	// The default epoch isn't written into the shared chain config
+	config.Parlia.Epoch = 0
+	_, err = ReadSnapshot(chain, tx, tx, parent.Number.Uint64(), parent.Hash())
+	require.NoError(t, err)
+	assert.Zero(t, config.Parlia.Epoch)
+}
+
+func TestReadSnapshotFromEpochValidators(t *testing.T) {
+	config := &params.ChainConfig{ChainID: big.NewInt(1337), Parlia: &params.ParliaConfig{Period: 3, Epoch: 4}}
+	keys := make(map[common.Address]*ecdsa.PrivateKey, 2)
+	validators := make([]common.Address, 2)
+	for i := range validators {
+		key, _ := crypto.GenerateKey()
+		validators[i] = crypto.PubkeyToAddress(key.PublicKey)
+		keys[validators[i]] = key
+	}
+	sort.Sort(validatorsAscending(validators))
+
+	extra := make([]byte, extraVanity+len(validators)*validatorBytesLength+extraSeal)
+	for i, v := range validators {
+		copy(extra[extraVanity+i*validatorBytesLength:], v[:])
+	}
+	genesis := &types.Header{Number: big.NewInt(0), Difficulty: big.NewInt(1), Extra: extra}
+	chain := testChainReader{config: config, headers: map[common.Hash]*types.Header{}}
+	headers := []*types.Header{genesis}
+	for i := uint64(1); i <= 9; i++ {
+		signer := validators[i%uint64(len(validators))]
+		header := &types.Header{
+			ParentHash: headers[i-1].Hash(),
+			Coinbase:   signer,
+			Number:     new(big.Int).SetUint64(i),
+			Difficulty: new(big.Int).Set(diffInTurn),
+			Time:       i * 3,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		if i%config.Parlia.Epoch == 0 {
+			header.Extra = common.CopyBytes(extra)
+		}
+		sig, err := crypto.Sign(SealHash(header, config.ChainID).Bytes(), keys[signer])
+		require.NoError(t, err)
+		copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+		chain.headers[header.Hash()] = header
+		headers = append(headers, header)
+	}
+
+	db := memdb.NewTestDB(t)
+	tx, err := db.BeginRw(context.Background())
+	require.NoError(t, err)
+	defer tx.Rollback()
+
+	// without the genesis header, the snapshot can only come from the validators stored for the epoch blocks
+	_, err = ReadSnapshot(chain, tx, tx, 9, headers[9].Hash())
+	require.ErrorIs(t, err, consensus.ErrUnknownAncestor)
+
+	require.NoError(t, rawdb.WriteEpochValidators(tx, 0, genesis.Hash(), validators))
+	require.NoError(t, rawdb.WriteEpochValidators(tx, 4, headers[4].Hash(), validators))
+	// the validators announced by block 4 sealed 2 blocks after replacing the previous ones at block 5
+	seed, err := findEpochSeed(config.Parlia, tx, 9)
+	require.NoError(t, err)
+	require.NotNil(t, seed)
+	assert.Equal(t, uint64(7), seed.number)
+
+	snap, err := ReadSnapshot(chain, tx, tx, 9, headers[9].Hash())
+	require.NoError(t, err)
+	assert.Equal(t, uint64(9), snap.Number)
+	assert.Equal(t, headers[9].Hash(), snap.Hash)
+	assert.Equal(t, validators, snap.SortedValidators())
+	assert.Equal(t, map[uint64]common.Address{8: validators[0], 9: validators[1]}, snap.Recents)
+
+	// a fork which doesn't go through the stored epoch block isn't seeded
+	sigCache, err := lru.NewARC(inMemorySignatures)
+	require.NoError(t, err)
+	seed.epochHash = common.Hash{0x01}
+	snap, err = seedSnapshot(config.Parlia, sigCache, chain, seed, headers[7].Hash())
+	require.NoError(t, err)
+	assert.Nil(t, snap)
+}
+
+func TestFinality(t *testing.T) {
+	config := &params.ChainConfig{ChainID: big.NewInt(1337), Parlia: &params.ParliaConfig{Period: 3, Epoch: 200}}
+	keys := make(map[common.Address]*ecdsa.PrivateKey, 4)
+	validators := make([]common.Address, 4)
+	for i := range validators {
+		key, _ := crypto.GenerateKey()
+		validators[i] = crypto.PubkeyToAddress(key.PublicKey)
+		keys[validators[i]] = key
+	}
+	sort.Sort(validatorsAscending(validators))
+
+	extra := make([]byte, extraVanity+len(validators)*validatorBytesLength+extraSeal)
+	for i, v := range validators {
+		copy(extra[extraVanity+i*validatorBytesLength:], v[:])
+	}
+	genesis := &types.Header{Number: big.NewInt(0), Difficulty: big.NewInt(1), Extra: extra}
+	chain := testChainReader{config: config, headers: map[common.Hash]*types.Header{genesis.Hash(): genesis}}
+	headers := []*types.Header{genesis}
+	for i := uint64(1); i <= 8; i++ {
+		signer := validators[i%uint64(len(validators))]
+		header := &types.Header{
+			ParentHash: headers[i-1].Hash(),
+			Coinbase:   signer,
+			Number:     new(big.Int).SetUint64(i),
+			Difficulty: new(big.Int).Set(diffInTurn),
+			Time:       i * 3,
+			Extra:      make([]byte, extraVanity+extraSeal),
+		}
+		sig, err := crypto.Sign(SealHash(header, config.ChainID).Bytes(), keys[signer])
+		require.NoError(t, err)
+		copy(header.Extra[len(header.Extra)-extraSeal:], sig)
+		chain.headers[header.Hash()] = header
+		headers = append(headers, header)
+	}
+
+	engine := New(config, memdb.NewTestDB(t), nil, memdb.NewTestDB(t))
+	// 3 of the 4 validators sealed blocks 6, 7 and 8 on top of block 5, 2 of them sealed blocks 7 and 8
+	finalized, safe, err := engine.Finality(chain, headers[8])
+	require.NoError(t, err)
+	assert.Equal(t, headers[5].Hash(), finalized.Hash())
+	assert.Equal(t, headers[6].Hash(), safe.Hash())
+
+	// there aren't enough blocks to finalize any
+	finalized, safe, err = engine.Finality(chain, headers[2])
+	require.NoError(t, err)
+	assert.Nil(t, finalized)
+	assert.Equal(t, genesis.Hash(), safe.Hash())
+}
+
+func TestEpochValidators(t *testing.T) {
+	p := &Parlia{config: &params.ParliaConfig{Period: 3, Epoch: 200}}
+	validators := []common.Address{{0x01}, {0x02}}
+	extra := make([]byte, extraVanity+len(validators)*validatorBytesLength+extraSeal)
+	for i, v := range validators {
+		copy(extra[extraVanity+i*validatorBytesLength:], v[:])
+	}
+
+	got, err := p.EpochValidators(&types.Header{Number: big.NewInt(400), Extra: extra})
+	require.NoError(t, err)
+	assert.Equal(t, validators, got)
+
+	got, err = p.EpochValidators(&types.Header{Number: big.NewInt(401), Extra: make([]byte, extraVanity+extraSeal)})
+	require.NoError(t, err)
+	assert.Nil(t, got)
+
+	_, err = p.EpochValidators(&types.Header{Number: big.NewInt(400), Extra: make([]byte, extraVanity)})
+	require.Error(t, err)
+}
+
+func TestAllowLightProcessEpochBlock(t *testing.T) {
+	p := &Parlia{config: &params.ParliaConfig{Period: 3, Epoch: 200}}
+	// the validators of an epoch block are only checked by executing it
+	assert.False(t, p.AllowLightProcess(nil, &types.Header{Number: big.NewInt(400)}))
+}
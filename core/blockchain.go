@@ -209,6 +209,7 @@ func ExecuteBlockEphemerallyForBSC(
 		TxRoot:      types.DeriveSha(includedTxs),
 		ReceiptRoot: receiptSha,
 		Bloom:       bloom,
+		LogsHash:    rlpHash(ibs.Logs()),
 		Receipts:    receipts,
 		Difficulty:  (*math.HexOrDecimal256)(block.Header().Difficulty),
 		GasUsed:     math.HexOrDecimal64(*usedGas),
@@ -334,8 +335,9 @@ func ExecuteBlockEphemerally(
 			types.DeriveFieldsForBorLogs(stateSyncLogs, block.Hash(), block.NumberU64(), uint(len(receipts)), uint(len(logs)))
 
 			stateSyncReceipt = &types.ReceiptForStorage{
-				Status: types.ReceiptStatusSuccessful, // make receipt status successful
-				Logs:   stateSyncLogs,
+				Status:           types.ReceiptStatusSuccessful, // make receipt status successful
+				Logs:             stateSyncLogs,
+				TransactionIndex: uint(len(receipts)), // the state-sync logs are stored after the ones of the transactions
 			}
 		}
 	}
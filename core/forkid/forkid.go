@@ -249,6 +249,10 @@ func GatherForks(config *params.ChainConfig) []uint64 {
 			forks = append(forks, rule.Uint64())
 		}
 	}
+	// System contract forks declared by the chain spec are not fields of the config
+	for _, block := range config.SystemContractForkBlocks() {
+		forks = append(forks, block.Uint64())
+	}
 	// Sort the fork block numbers to permit chronological XOR
 	slices.Sort(forks)
 	// Deduplicate block numbers applying multiple forks
@@ -19,6 +19,8 @@ package forkid
 import (
 	"bytes"
 	"math"
+	"math/big"
+	"reflect"
 	"testing"
 
 	"github.com/ledgerwatch/erigon/common"
@@ -257,3 +259,19 @@ func TestEncoding(t *testing.T) {
 		}
 	}
 }
+
+// TestGatherSystemContractForks tests that the system contract forks declared by the
+// chain spec are part of the fork id.
+func TestGatherSystemContractForks(t *testing.T) {
+	config := &params.ChainConfig{
+		HomesteadBlock: big.NewInt(0),
+		NielsBlock:     big.NewInt(10),
+		SystemContractForks: []*params.SystemContractFork{
+			{Name: "a", Block: big.NewInt(10)},
+			{Name: "b", Block: big.NewInt(15)},
+		},
+	}
+	if forks := GatherForks(config); !reflect.DeepEqual(forks, []uint64{10, 15}) {
+		t.Errorf("forks mismatch: have %v, want %v", forks, []uint64{10, 15})
+	}
+}
@@ -26,6 +26,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"sort"
 	"sync"
 
 	"github.com/c2h5oh/datasize"
@@ -39,6 +40,7 @@ import (
 	"github.com/ledgerwatch/erigon/consensus/serenity"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/systemcontracts"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/crypto"
 	"github.com/ledgerwatch/erigon/params"
@@ -766,6 +768,55 @@ func DeveloperGenesisBlock(period uint64, faucet common.Address) *Genesis {
 	}
 }
 
+// ParliaDevGenesisBlock returns the genesis block of a local Parlia devnet sealed by the given
+// validators, which are pre-funded. The genesis system contracts are the ones of Chapel, upgraded
+// by the hard forks active at genesis, with the validator contract initialized with the devnet
+// validators in its storage.
+func ParliaDevGenesisBlock(period uint64, validators []common.Address) (*Genesis, error) {
+	// Override the default period to the user requested one
+	config := *params.ParliaDevChainConfig
+	parliaConfig := *config.Parlia
+	parliaConfig.Period = period
+	config.Parlia = &parliaConfig
+
+	validators = append([]common.Address{}, validators...)
+	sort.Slice(validators, func(i, j int) bool { return bytes.Compare(validators[i][:], validators[j][:]) < 0 })
+	extra := make([]byte, 32, 32+len(validators)*common.AddressLength+crypto.SignatureLength)
+	alloc := make(GenesisAlloc)
+	for addr, account := range readPrealloc("allocs/chapel.json") {
+		// keep the system contracts only
+		if len(account.Code) > 0 {
+			alloc[addr] = account
+		}
+	}
+	codes, err := systemcontracts.DevnetUpgradedCode(&config)
+	if err != nil {
+		return nil, err
+	}
+	for addr, code := range codes {
+		account := alloc[addr]
+		account.Code = code
+		alloc[addr] = account
+	}
+	validatorContract := alloc[systemcontracts.ValidatorContract]
+	validatorContract.Storage = systemcontracts.DevnetValidatorContractStorage(validators)
+	alloc[systemcontracts.ValidatorContract] = validatorContract
+	for _, validator := range validators {
+		extra = append(extra, validator[:]...)
+		alloc[validator] = GenesisAccount{Balance: new(big.Int).Mul(big.NewInt(1_000_000), big.NewInt(params.Ether))}
+	}
+	extra = append(extra, make([]byte, crypto.SignatureLength)...)
+
+	return &Genesis{
+		Config:     &config,
+		ExtraData:  extra,
+		GasLimit:   0x2625a00,
+		Difficulty: big.NewInt(1),
+		Coinbase:   common.HexToAddress("0xffffFFFfFFffffffffffffffFfFFFfffFFFfFFfE"),
+		Alloc:      alloc,
+	}, nil
+}
+
 func DefaultKilnDevnetGenesisBlock() *Genesis {
 	return &Genesis{
 		Config:     params.KilnDevnetChainConfig,
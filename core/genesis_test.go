@@ -2,10 +2,18 @@ package core
 
 import (
 	"context"
+	"math/big"
+	"strings"
 	"testing"
 
+	"github.com/holiman/uint256"
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/accounts/abi"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/systemcontracts"
+	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/params/networkname"
 	"github.com/stretchr/testify/require"
@@ -46,3 +54,45 @@ func TestCommitGenesisIdempotency(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, uint64(2), seq)
 }
+
+func TestParliaDevGenesisValidators(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	validators := []common.Address{{3}, {1}, {2}}
+	genesis, err := ParliaDevGenesisBlock(3, validators)
+	require.NoError(t, err)
+	_, _, err = WriteGenesisBlock(tx, genesis, nil, nil)
+	require.NoError(t, err)
+
+	validatorSetABI, err := abi.JSON(strings.NewReader(`[
+		{"name": "getValidators", "type": "function", "inputs": [], "outputs": [{"name": "", "type": "address[]"}]},
+		{"name": "getMiningValidators", "type": "function", "inputs": [], "outputs": [{"name": "", "type": "address[]"}]},
+		{"name": "alreadyInit", "type": "function", "inputs": [], "outputs": [{"name": "", "type": "bool"}]}
+	]`))
+	require.NoError(t, err)
+	blockContext := vm.BlockContext{
+		CanTransfer:     CanTransfer,
+		Transfer:        Transfer,
+		ContractHasTEVM: func(common.Hash) (bool, error) { return false, nil },
+		BlockNumber:     1,
+		Difficulty:      big.NewInt(1),
+		GasLimit:        genesis.GasLimit,
+	}
+	evm := vm.NewEVM(blockContext, vm.TxContext{GasPrice: big.NewInt(0)}, state.New(state.NewPlainStateReader(tx)), genesis.Config, vm.Config{})
+	call := func(method string, out interface{}) {
+		input, err := validatorSetABI.Pack(method)
+		require.NoError(t, err)
+		ret, _, err := evm.Call(vm.AccountRef(common.Address{}), systemcontracts.ValidatorContract, input, genesis.GasLimit, uint256.NewInt(0), false)
+		require.NoError(t, err, method)
+		require.NoError(t, validatorSetABI.UnpackIntoInterface(out, method, ret), method)
+	}
+
+	var initialized bool
+	call("alreadyInit", &initialized)
+	require.True(t, initialized, "the engine doesn't init the validator contract")
+	sorted := []common.Address{{1}, {2}, {3}}
+	for _, method := range []string{"getValidators", "getMiningValidators"} {
+		var set []common.Address
+		call(method, &set)
+		require.Equal(t, sorted, set, method)
+	}
+}
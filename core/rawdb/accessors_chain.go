@@ -220,6 +220,44 @@ func WriteForkchoiceFinalized(db kv.Putter, hash common.Hash) {
 	}
 }
 
+// ReadPoSAFinalized retrieves the hash of the last block finalized by the validators of a PoSA engine.
+func ReadPoSAFinalized(db kv.Getter) common.Hash {
+	data, err := db.GetOne(kv.LastForkchoice, []byte("posaFinalizedBlockHash"))
+	if err != nil {
+		log.Error("ReadPoSAFinalized failed", "err", err)
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// WritePoSAFinalized stores the hash of the last block finalized by the validators of a PoSA engine.
+func WritePoSAFinalized(db kv.Putter, hash common.Hash) error {
+	return db.Put(kv.LastForkchoice, []byte("posaFinalizedBlockHash"), hash[:])
+}
+
+// ReadPoSASafe retrieves the hash of the last block made safe by the validators of a PoSA engine.
+func ReadPoSASafe(db kv.Getter) common.Hash {
+	data, err := db.GetOne(kv.LastForkchoice, []byte("posaSafeBlockHash"))
+	if err != nil {
+		log.Error("ReadPoSASafe failed", "err", err)
+		return common.Hash{}
+	}
+	return common.BytesToHash(data)
+}
+
+// WritePoSASafe stores the hash of the last block made safe by the validators of a PoSA engine.
+func WritePoSASafe(db kv.Putter, hash common.Hash) error {
+	return db.Put(kv.LastForkchoice, []byte("posaSafeBlockHash"), hash[:])
+}
+
+// DeletePoSAFinality removes the finalized and safe blocks of a PoSA engine.
+func DeletePoSAFinality(db kv.Deleter) error {
+	if err := db.Delete(kv.LastForkchoice, []byte("posaFinalizedBlockHash")); err != nil {
+		return err
+	}
+	return db.Delete(kv.LastForkchoice, []byte("posaSafeBlockHash"))
+}
+
 // ReadHeaderRLP retrieves a block header in its raw RLP database encoding.
 func ReadHeaderRLP(db kv.Getter, hash common.Hash, number uint64) rlp.RawValue {
 	data, err := db.GetOne(kv.Headers, dbutils.HeaderKey(number, hash))
@@ -943,7 +981,10 @@ func ReadRawReceipts(db kv.Tx, blockNum uint64) types.Receipts {
 			return fmt.Errorf("receipt unmarshal failed:  %w", err)
 		}
 
-		receipts[binary.BigEndian.Uint32(k[8:])].Logs = logs
+		// the state-sync logs of Bor follow the ones of the transactions, they are read by ReadBorReceipt
+		if txIndex := binary.BigEndian.Uint32(k[8:]); int(txIndex) < len(receipts) {
+			receipts[txIndex].Logs = logs
+		}
 		return nil
 	}); err != nil {
 		log.Error("logs fetching failed", "err", err)
@@ -1565,6 +1606,52 @@ func WriteEpoch(tx kv.RwTx, blockNum uint64, blockHash common.Hash, transitionPr
 	return tx.Put(kv.Epoch, k, transitionProof)
 }
 
+// WriteEpochValidators stores the validator set announced by an epoch block. Engines keeping
+// validators in system contracts (Parlia) use it as the transition proof of the epoch.
+func WriteEpochValidators(tx kv.RwTx, blockNum uint64, blockHash common.Hash, validators []common.Address) error {
+	enc := make([]byte, len(validators)*common.AddressLength)
+	for i, validator := range validators {
+		copy(enc[i*common.AddressLength:], validator[:])
+	}
+	return WriteEpoch(tx, blockNum, blockHash, enc)
+}
+
+// ReadEpochValidators retrieves the validator set announced by an epoch block, nil if it wasn't stored.
+func ReadEpochValidators(tx kv.Tx, blockNum uint64, blockHash common.Hash) ([]common.Address, error) {
+	enc, err := ReadEpoch(tx, blockNum, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	return decodeEpochValidators(enc)
+}
+
+// FindEpochValidatorsBeforeOrEqualNumber retrieves the validator set announced by the last epoch block
+// at or before the given block number.
+func FindEpochValidatorsBeforeOrEqualNumber(tx kv.Tx, n uint64) (blockNum uint64, blockHash common.Hash, validators []common.Address, err error) {
+	blockNum, blockHash, enc, err := FindEpochBeforeOrEqualNumber(tx, n)
+	if err != nil {
+		return 0, common.Hash{}, nil, err
+	}
+	if validators, err = decodeEpochValidators(enc); err != nil {
+		return 0, common.Hash{}, nil, err
+	}
+	return blockNum, blockHash, validators, nil
+}
+
+func decodeEpochValidators(enc []byte) ([]common.Address, error) {
+	if len(enc) == 0 {
+		return nil, nil
+	}
+	if len(enc)%common.AddressLength != 0 {
+		return nil, fmt.Errorf("invalid epoch validators length %d", len(enc))
+	}
+	validators := make([]common.Address, len(enc)/common.AddressLength)
+	for i := range validators {
+		copy(validators[i][:], enc[i*common.AddressLength:])
+	}
+	return validators, nil
+}
+
 func ReadPendingEpoch(tx kv.Tx, blockNum uint64, blockHash common.Hash) (transitionProof []byte, err error) {
 	k := make([]byte, 8+32)
 	binary.BigEndian.PutUint64(k, blockNum)
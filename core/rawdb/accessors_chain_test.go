@@ -434,6 +434,34 @@ func TestBlockReceiptStorage(t *testing.T) {
 	}
 }
 
+// Tests that epoch validators are stored and looked up by the closest epoch.
+func TestEpochValidatorsStorage(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	require := require.New(t)
+
+	validators := []common.Address{{0x01}, {0x02}, {0x03}}
+	require.NoError(WriteEpochValidators(tx, 200, common.Hash{0xaa}, validators[:2]))
+	require.NoError(WriteEpochValidators(tx, 400, common.Hash{0xbb}, validators))
+
+	stored, err := ReadEpochValidators(tx, 400, common.Hash{0xbb})
+	require.NoError(err)
+	require.Equal(validators, stored)
+	stored, err = ReadEpochValidators(tx, 400, common.Hash{0xcc})
+	require.NoError(err)
+	require.Nil(stored)
+
+	blockNum, blockHash, stored, err := FindEpochValidatorsBeforeOrEqualNumber(tx, 399)
+	require.NoError(err)
+	require.Equal(uint64(200), blockNum)
+	require.Equal(common.Hash{0xaa}, blockHash)
+	require.Equal(validators[:2], stored)
+
+	require.NoError(DeleteNewerEpochs(tx, 201))
+	_, _, stored, err = FindEpochValidatorsBeforeOrEqualNumber(tx, 1000)
+	require.NoError(err)
+	require.Equal(validators[:2], stored)
+}
+
 func checkReceiptsRLP(have, want types.Receipts) error {
 	if len(have) != len(want) {
 		return fmt.Errorf("receipts sizes mismatch: have %d, want %d", len(have), len(want))
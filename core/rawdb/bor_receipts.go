@@ -2,6 +2,7 @@ package rawdb
 
 import (
 	"bytes"
+	"encoding/binary"
 	"errors"
 
 	"github.com/ledgerwatch/erigon-lib/kv"
@@ -40,20 +41,59 @@ func ReadBorReceiptRLP(db kv.Getter, hash common.Hash, number uint64) rlp.RawVal
 // The receipt metadata fields are not guaranteed to be populated, so they
 // should not be used. Use ReadBorReceipt instead if the metadata is needed.
 func ReadRawBorReceipt(db kv.Tx, hash common.Hash, number uint64) *types.Receipt {
+	receipt, _ := readRawBorReceipt(db, hash, number)
+	return receipt
+}
+
+// readRawBorReceipt returns the bor receipt of a block and the key of its logs in kv.Log, nil if
+// they are stored in the receipt. The receipt is stored with CBOR, without its logs, which are the
+// last entry of the block in kv.Log: after the logs of the transactions, or at index 0 when written
+// by the versions which didn't set the TransactionIndex of the bor receipt, then the only entry of
+// the block. The receipts stored by the older versions are RLP encoded with their logs.
+func readRawBorReceipt(db kv.Tx, hash common.Hash, number uint64) (*types.Receipt, []byte) {
 	// Retrieve the flattened receipt slice
 	data := ReadBorReceiptRLP(db, hash, number)
 	if len(data) == 0 {
-		return nil
+		return nil, nil
 	}
 
 	// Convert the receipts from their storage form to their internal representation
 	var storageReceipt types.ReceiptForStorage
-	if err := rlp.DecodeBytes(data, &storageReceipt); err != nil {
-		log.Error("Invalid receipt array RLP", "hash", hash, "err", err)
-		return nil
+	if err := cbor.Unmarshal(&storageReceipt, bytes.NewReader(data)); err != nil {
+		if errRlp := rlp.DecodeBytes(data, &storageReceipt); errRlp != nil {
+			log.Error("Invalid bor receipt", "hash", hash, "err", err, "rlpErr", errRlp)
+			return nil, nil
+		}
+		return (*types.Receipt)(&storageReceipt), nil
 	}
 
-	return (*types.Receipt)(&storageReceipt)
+	c, err := db.Cursor(kv.Log)
+	if err != nil {
+		log.Error("ReadRawBorReceipt failed", "err", err)
+		return nil, nil
+	}
+	defer c.Close()
+	k, v, err := c.Seek(dbutils.LogKey(number+1, 0))
+	if err == nil {
+		if k == nil {
+			k, v, err = c.Last()
+		} else {
+			k, v, err = c.Prev()
+		}
+	}
+	if err != nil {
+		log.Error("ReadRawBorReceipt failed", "err", err)
+		return nil, nil
+	}
+	if k == nil || binary.BigEndian.Uint64(k) != number {
+		return (*types.Receipt)(&storageReceipt), nil
+	}
+	if err := cbor.Unmarshal(&storageReceipt.Logs, bytes.NewReader(v)); err != nil {
+		log.Error("Invalid bor receipt logs", "hash", hash, "err", err)
+		return nil, nil
+	}
+	storageReceipt.TransactionIndex = uint(binary.BigEndian.Uint32(k[8:]))
+	return (*types.Receipt)(&storageReceipt), common.CopyBytes(k)
 }
 
 // ReadBorReceipt retrieves all the bor block receipts belonging to a block, including
@@ -106,9 +146,8 @@ func DeleteBorReceipt(tx kv.RwTx, hash common.Hash, number uint64) {
 	key := borReceiptKey(number)
 
 	// we delete Bor Receipt log too
-	borReceipt := ReadBorReceipt(tx, hash, number)
-	if borReceipt != nil {
-		if err := tx.Delete(kv.Log, dbutils.LogKey(number, uint32(borReceipt.TransactionIndex))); err != nil {
+	if _, logKey := readRawBorReceipt(tx, hash, number); logKey != nil {
+		if err := tx.Delete(kv.Log, logKey); err != nil {
 			log.Crit("Failed to delete bor log", "err", err)
 		}
 	}
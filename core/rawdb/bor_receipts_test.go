@@ -0,0 +1,62 @@
+package rawdb
+
+import (
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBorReceiptFormats(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	txLog := &types.Log{Address: common.Address{1}, Topics: []common.Hash{{1}}, Data: []byte{1}}
+	stateSyncLog := &types.Log{Address: common.Address{2}, Topics: []common.Hash{{2}}, Data: []byte{2}}
+	txReceipts := types.Receipts{
+		{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 21000, Logs: []*types.Log{txLog}},
+		{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 42000, Logs: []*types.Log{}},
+	}
+
+	// the logs of the bor receipt are stored after the ones of the transactions
+	require.NoError(t, AppendReceipts(tx, 1, txReceipts))
+	require.NoError(t, WriteBorReceipt(tx, common.Hash{1}, 1, &types.ReceiptForStorage{
+		Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{stateSyncLog}, TransactionIndex: uint(len(txReceipts)),
+	}))
+	receipt := ReadRawBorReceipt(tx, common.Hash{1}, 1)
+	require.NotNil(t, receipt)
+	require.Equal(t, uint(2), receipt.TransactionIndex)
+	require.Len(t, receipt.Logs, 1)
+	require.Equal(t, stateSyncLog.Address, receipt.Logs[0].Address)
+	require.Equal(t, txLog.Address, ReadRawReceipts(tx, 1)[0].Logs[0].Address)
+
+	// the older versions stored them at index 0, in the blocks without logs of the transactions
+	require.NoError(t, WriteBorReceipt(tx, common.Hash{2}, 2, &types.ReceiptForStorage{
+		Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{stateSyncLog},
+	}))
+	receipt = ReadRawBorReceipt(tx, common.Hash{2}, 2)
+	require.NotNil(t, receipt)
+	require.Len(t, receipt.Logs, 1)
+	require.Equal(t, stateSyncLog.Address, receipt.Logs[0].Address)
+	DeleteBorReceipt(tx, common.Hash{2}, 2)
+	require.Nil(t, ReadRawBorReceipt(tx, common.Hash{2}, 2))
+	has, err := tx.Has(kv.Log, dbutils.LogKey(2, 0))
+	require.NoError(t, err)
+	require.False(t, has)
+
+	// or RLP encoded with their logs, the logs of the transactions being left alone by the deletion
+	require.NoError(t, AppendReceipts(tx, 3, txReceipts))
+	enc, err := rlp.EncodeToBytes(&types.ReceiptForStorage{Status: types.ReceiptStatusSuccessful, Logs: []*types.Log{stateSyncLog}})
+	require.NoError(t, err)
+	require.NoError(t, tx.Put(kv.BorReceipts, borReceiptKey(3), enc))
+	receipt = ReadRawBorReceipt(tx, common.Hash{3}, 3)
+	require.NotNil(t, receipt)
+	require.Len(t, receipt.Logs, 1)
+	require.Equal(t, stateSyncLog.Address, receipt.Logs[0].Address)
+	DeleteBorReceipt(tx, common.Hash{3}, 3)
+	require.Nil(t, ReadRawBorReceipt(tx, common.Hash{3}, 3))
+	require.Equal(t, txLog.Address, ReadRawReceipts(tx, 3)[0].Logs[0].Address)
+}
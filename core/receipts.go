@@ -0,0 +1,48 @@
+package core
+
+import (
+	"context"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/consensus/ethash"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/ethdb"
+	"github.com/ledgerwatch/erigon/params"
+)
+
+// ComputeReceipts regenerates the receipts of a block which are not stored, e.g. pruned ones,
+// by re-executing its transactions on top of the historical state.
+func ComputeReceipts(ctx context.Context, tx kv.Tx, cfg *params.ChainConfig, getHeader func(hash common.Hash, number uint64) *types.Header, block *types.Block) (types.Receipts, error) {
+	contractHasTEVM := ethdb.GetHasTEVM(tx)
+	ibs := state.New(state.NewPlainState(tx, block.NumberU64()))
+
+	usedGas := new(uint64)
+	gp := new(GasPool).AddGas(block.GasLimit())
+
+	ethashFaker := ethash.NewFaker()
+	noopWriter := state.NewNoopWriter()
+
+	receipts := make(types.Receipts, len(block.Transactions()))
+
+	header := block.Header()
+	for i, txn := range block.Transactions() {
+		select {
+		default:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+		ibs.Prepare(txn.Hash(), block.Hash(), i)
+		receipt, _, err := ApplyTransaction(cfg, GetHashFn(header, getHeader), ethashFaker, nil, gp, ibs, noopWriter, header, txn, usedGas, vm.Config{}, contractHasTEVM)
+		if err != nil {
+			return nil, err
+		}
+		receipt.BlockHash = block.Hash()
+		receipts[i] = receipt
+	}
+
+	return receipts, nil
+}
@@ -86,11 +86,19 @@ func accountsEqual(a1, a2 *accounts.Account) bool {
 func (w *ChangeSetWriter) UpdateAccountData(address common.Address, original, account *accounts.Account) error {
 	//fmt.Printf("balance,%x,%d\n", address, &account.Balance)
 	if !accountsEqual(original, account) || w.storageChanged[address] {
-		w.accountChanges[address] = originalAccountData(original, true /*omitHashes*/)
+		w.accountChanges[address] = originalAccountData(original, !codeReplaced(original, account) /*omitHashes*/)
 	}
 	return nil
 }
 
+// codeReplaced tells whether the code of the contract is replaced without a new incarnation, as done by the
+// upgrades of the system contracts. The code hash is kept in the change set then, because the code hash of the
+// incarnation in PlainContractCode is the replacing one.
+func codeReplaced(original, account *accounts.Account) bool {
+	return original.Initialised && original.Incarnation > 0 && original.Incarnation == account.Incarnation &&
+		!original.IsEmptyCodeHash() && original.CodeHash != account.CodeHash
+}
+
 func (w *ChangeSetWriter) UpdateAccountCode(address common.Address, incarnation uint64, codeHash common.Hash, code []byte) error {
 	//fmt.Printf("code,%x,%x\n", address, code)
 	return nil
@@ -129,13 +129,12 @@ func NewDumper(db kv.Tx, blockNumber uint64) *Dumper {
 	}
 }
 
+// DumpToCollector walks the accounts of the state after the block, giving each of them to the collector as soon as
+// its code and storage are read, the storage of an account being read as of the same block as the account
 func (d *Dumper) DumpToCollector(c DumpCollector, excludeCode, excludeStorage bool, startAddress common.Address, maxResults int) ([]byte, error) {
 	var nextKey []byte
 	var emptyCodeHash = crypto.Keccak256Hash(nil)
 	var emptyHash = common.Hash{}
-	var accountList []*DumpAccount
-	var incarnationList []uint64
-	var addrList []common.Address
 
 	c.OnRoot(emptyHash) // We do not calculate the root
 
@@ -157,6 +156,7 @@ func (d *Dumper) DumpToCollector(c DumpCollector, excludeCode, excludeStorage bo
 		if e := acc.DecodeForStorage(v); e != nil {
 			return false, fmt.Errorf("decoding %x for %x: %w", v, k, e)
 		}
+		addr := common.BytesToAddress(k)
 		account := DumpAccount{
 			Balance:  acc.Balance.ToBig().String(),
 			Nonce:    acc.Nonce,
@@ -164,37 +164,24 @@ func (d *Dumper) DumpToCollector(c DumpCollector, excludeCode, excludeStorage bo
 			CodeHash: hexutil.Bytes(emptyCodeHash[:]),
 			Storage:  make(map[string]string),
 		}
-		accountList = append(accountList, &account)
-		addrList = append(addrList, common.BytesToAddress(k))
-		incarnationList = append(incarnationList, acc.Incarnation)
-
-		numberOfResults++
-		return true, nil
-	}); err != nil {
-		return nil, err
-	}
-
-	for i, addr := range addrList {
-		account := accountList[i]
-		incarnation := incarnationList[i]
-		storagePrefix := dbutils.PlainGenerateStoragePrefix(addr[:], incarnation)
-		if incarnation > 0 {
-			codeHash, err := d.db.GetOne(kv.PlainContractCode, storagePrefix)
-			if err != nil {
-				return nil, fmt.Errorf("getting code hash for %x: %w", addr, err)
+		if acc.Incarnation > 0 {
+			// as PlainState.ReadAccountData does, the code hash left out of the change sets is the one of the incarnation
+			codeHash := acc.CodeHash[:]
+			if acc.IsEmptyCodeHash() {
+				var err error
+				if codeHash, err = d.db.GetOne(kv.PlainContractCode, dbutils.PlainGenerateStoragePrefix(addr[:], acc.Incarnation)); err != nil {
+					return false, fmt.Errorf("getting code hash for %x: %w", addr, err)
+				}
 			}
-			if codeHash != nil {
-				account.CodeHash = codeHash
-			} else {
-				account.CodeHash = emptyCodeHash[:]
+			if len(codeHash) > 0 {
+				account.CodeHash = common.CopyBytes(codeHash)
 			}
-
-			if !excludeCode && codeHash != nil && !bytes.Equal(codeHash, emptyCodeHash[:]) {
-				var code []byte
-				if code, err = d.db.GetOne(kv.Code, codeHash); err != nil {
-					return nil, err
+			if !excludeCode && !bytes.Equal(account.CodeHash, emptyCodeHash[:]) {
+				code, err := d.db.GetOne(kv.Code, account.CodeHash)
+				if err != nil {
+					return false, err
 				}
-				account.Code = code
+				account.Code = common.CopyBytes(code)
 			}
 		}
 
@@ -202,20 +189,25 @@ func (d *Dumper) DumpToCollector(c DumpCollector, excludeCode, excludeStorage bo
 			t := trie.New(common.Hash{})
 			if err := WalkAsOfStorage(d.db,
 				addr,
-				incarnation,
+				acc.Incarnation,
 				common.Hash{}, /* startLocation */
-				d.blockNumber,
+				d.blockNumber+1,
 				func(_, loc, vs []byte) (bool, error) {
 					account.Storage[common.BytesToHash(loc).String()] = common.Bytes2Hex(vs)
 					h, _ := common.HashData(loc)
 					t.Update(h.Bytes(), common.CopyBytes(vs))
 					return true, nil
 				}); err != nil {
-				return nil, fmt.Errorf("walking over storage for %x: %w", addr, err)
+				return false, fmt.Errorf("walking over storage for %x: %w", addr, err)
 			}
 			account.Root = t.Hash().Bytes()
 		}
-		c.OnAccount(addr, *account)
+		c.OnAccount(addr, account)
+
+		numberOfResults++
+		return true, nil
+	}); err != nil {
+		return nil, err
 	}
 
 	return nextKey, nil
@@ -390,3 +390,53 @@ func TestDump(t *testing.T) {
 		t.Fatalf("dump mismatch:\ngot: %s\nwant: %s\n", got, want)
 	}
 }
+
+func TestDumpAsOfBlock(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	addr := toAddr([]byte{0x01})
+	key := common.HexToHash("0x01")
+	code := []byte{3, 3, 3}
+
+	// block 1 creates the contract, block 2 changes its balance and storage
+	for blockNum := uint64(1); blockNum <= 2; blockNum++ {
+		state := New(NewPlainStateReader(tx))
+		obj := state.GetOrNewStateObject(addr)
+		if blockNum == 1 {
+			obj.SetCode(crypto.Keccak256Hash(code), code)
+			obj.setIncarnation(1)
+		}
+		obj.SetBalance(uint256.NewInt(10 * blockNum))
+		state.SetState(addr, &key, *uint256.NewInt(blockNum))
+
+		blockWriter := NewPlainStateWriter(tx, tx, blockNum)
+		if err := state.CommitBlock(&params.Rules{}, blockWriter); err != nil {
+			t.Fatal(err)
+		}
+		if err := blockWriter.WriteChangeSets(); err != nil {
+			t.Fatal(err)
+		}
+		if err := blockWriter.WriteHistory(); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if accounts := NewDumper(tx, 0).DefaultRawDump().Accounts; len(accounts) != 0 {
+		t.Fatalf("accounts before the creation of the contract: %v", accounts)
+	}
+	// the account and its storage are both the ones after the block
+	for blockNum, want := range map[uint64]struct{ balance, value string }{1: {"10", "01"}, 2: {"20", "02"}} {
+		account, ok := NewDumper(tx, blockNum).DefaultRawDump().Accounts[addr]
+		if !ok {
+			t.Fatalf("contract missing from the dump of block %d", blockNum)
+		}
+		if account.Balance != want.balance {
+			t.Errorf("balance after block %d: have %s, want %s", blockNum, account.Balance, want.balance)
+		}
+		if value := account.Storage[key.String()]; value != want.value {
+			t.Errorf("storage after block %d: have %s, want %s", blockNum, value, want.value)
+		}
+		if !bytes.Equal(account.Code, code) {
+			t.Errorf("code after block %d: have %x, want %x", blockNum, account.Code, code)
+		}
+	}
+}
@@ -0,0 +1,374 @@
+package state
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"sort"
+
+	"github.com/holiman/uint256"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/turbo/trie"
+)
+
+// WitnessRecorder is a wrapper for an instance of type StateReader which records the accounts,
+// storage items and codes read by the execution of a block, the content of the witness of the block.
+// It is also the StateWriter of the execution, recording the keys written and the ones deleted,
+// whose deletion may need more nodes of the trie.
+type WitnessRecorder struct {
+	r            StateReader
+	incarnations map[common.Hash]uint64                   // touched accounts, by address hash
+	storage      map[common.Hash]map[common.Hash]struct{} // touched storage items, by address hash and key hash
+	codes        map[common.Hash][]byte                   // codes read, by address hash
+	deleted      map[string]struct{}                      // trie keys of the deleted accounts and storage items
+}
+
+// NewWitnessRecorder wraps the reader of the state before a block
+func NewWitnessRecorder(r StateReader) *WitnessRecorder {
+	return &WitnessRecorder{
+		r:            r,
+		incarnations: make(map[common.Hash]uint64),
+		storage:      make(map[common.Hash]map[common.Hash]struct{}),
+		codes:        make(map[common.Hash][]byte),
+		deleted:      make(map[string]struct{}),
+	}
+}
+
+func (wr *WitnessRecorder) touchAccount(address common.Address) common.Hash {
+	addrHash := crypto.Keccak256Hash(address[:])
+	if _, ok := wr.incarnations[addrHash]; !ok {
+		wr.incarnations[addrHash] = 0
+	}
+	return addrHash
+}
+
+func (wr *WitnessRecorder) touchStorage(address common.Address, key *common.Hash) (common.Hash, common.Hash) {
+	addrHash := wr.touchAccount(address)
+	keyHash := crypto.Keccak256Hash(key[:])
+	if _, ok := wr.storage[addrHash]; !ok {
+		wr.storage[addrHash] = make(map[common.Hash]struct{})
+	}
+	wr.storage[addrHash][keyHash] = struct{}{}
+	return addrHash, keyHash
+}
+
+func (wr *WitnessRecorder) ReadAccountData(address common.Address) (*accounts.Account, error) {
+	a, err := wr.r.ReadAccountData(address)
+	if err != nil {
+		return nil, err
+	}
+	addrHash := wr.touchAccount(address)
+	if a != nil {
+		wr.incarnations[addrHash] = a.Incarnation
+	}
+	return a, nil
+}
+
+func (wr *WitnessRecorder) ReadAccountStorage(address common.Address, incarnation uint64, key *common.Hash) ([]byte, error) {
+	wr.touchStorage(address, key)
+	return wr.r.ReadAccountStorage(address, incarnation, key)
+}
+
+func (wr *WitnessRecorder) ReadAccountCode(address common.Address, incarnation uint64, codeHash common.Hash) ([]byte, error) {
+	code, err := wr.r.ReadAccountCode(address, incarnation, codeHash)
+	if err != nil {
+		return nil, err
+	}
+	if len(code) > 0 {
+		wr.codes[wr.touchAccount(address)] = code
+	}
+	return code, nil
+}
+
+// ReadAccountCodeSize reads the whole code, the witness only has the sizes of the codes it contains
+func (wr *WitnessRecorder) ReadAccountCodeSize(address common.Address, incarnation uint64, codeHash common.Hash) (int, error) {
+	code, err := wr.ReadAccountCode(address, incarnation, codeHash)
+	return len(code), err
+}
+
+func (wr *WitnessRecorder) ReadAccountIncarnation(address common.Address) (uint64, error) {
+	return wr.r.ReadAccountIncarnation(address)
+}
+
+func (wr *WitnessRecorder) UpdateAccountData(address common.Address, original, account *accounts.Account) error {
+	wr.touchAccount(address)
+	return nil
+}
+
+func (wr *WitnessRecorder) UpdateAccountCode(address common.Address, incarnation uint64, codeHash common.Hash, code []byte) error {
+	return nil
+}
+
+func (wr *WitnessRecorder) DeleteAccount(address common.Address, original *accounts.Account) error {
+	addrHash := wr.touchAccount(address)
+	wr.deleted[string(addrHash[:])] = struct{}{}
+	return nil
+}
+
+func (wr *WitnessRecorder) WriteAccountStorage(address common.Address, incarnation uint64, key *common.Hash, original, value *uint256.Int) error {
+	addrHash, keyHash := wr.touchStorage(address, key)
+	if value.IsZero() {
+		wr.deleted[string(dbutils.GenerateCompositeTrieKey(addrHash, keyHash))] = struct{}{}
+	}
+	return nil
+}
+
+func (wr *WitnessRecorder) CreateContract(address common.Address) error {
+	wr.touchAccount(address)
+	return nil
+}
+
+func (wr *WitnessRecorder) WriteChangeSets() error {
+	return nil
+}
+
+func (wr *WitnessRecorder) WriteHistory() error {
+	return nil
+}
+
+// LoaderKeys returns the keys of the touched accounts and storage items in the encoding of the trie loader:
+// address hashes, and address hash + incarnation + key hash for the storage items.
+func (wr *WitnessRecorder) LoaderKeys() [][]byte {
+	var keys [][]byte
+	for addrHash, incarnation := range wr.incarnations {
+		keys = append(keys, common.CopyBytes(addrHash[:]))
+		if incarnation == 0 {
+			// the contracts only have storage items
+			continue
+		}
+		for keyHash := range wr.storage[addrHash] {
+			keys = append(keys, dbutils.GenerateCompositeStorageKey(addrHash, incarnation, keyHash))
+		}
+	}
+	return keys
+}
+
+// TrieKeys returns the keys of the touched accounts and storage items in the encoding of the trie:
+// address hashes, and address hash + key hash for the storage items.
+func (wr *WitnessRecorder) TrieKeys() [][]byte {
+	var keys [][]byte
+	for addrHash := range wr.incarnations {
+		keys = append(keys, common.CopyBytes(addrHash[:]))
+		for keyHash := range wr.storage[addrHash] {
+			keys = append(keys, dbutils.GenerateCompositeTrieKey(addrHash, keyHash))
+		}
+	}
+	return keys
+}
+
+// DeletionSiblings returns the hex prefixes of the nodes of the trie of the state before the block which
+// have to be expanded for the deletions of the block, in the encodings of the trie loader and of the trie.
+func (wr *WitnessRecorder) DeletionSiblings(t *trie.Trie) (loaderHexes, trieHexes [][]byte) {
+	keys := make([][]byte, 0, len(wr.deleted))
+	for key := range wr.deleted {
+		keys = append(keys, []byte(key))
+	}
+	trieHexes = t.DeletionSiblings(keys)
+	loaderHexes = make([][]byte, len(trieHexes))
+	for i, hex := range trieHexes {
+		if len(hex) <= 2*common.HashLength {
+			loaderHexes[i] = hex
+			continue
+		}
+		// the incarnation goes between the address hash and the key hash
+		var addrHash common.Hash
+		for j := range addrHash {
+			addrHash[j] = hex[2*j]<<4 | hex[2*j+1]
+		}
+		var incarnation [common.IncarnationLength]byte
+		binary.BigEndian.PutUint64(incarnation[:], wr.incarnations[addrHash])
+		loaderHex := make([]byte, 0, len(hex)+2*len(incarnation))
+		loaderHex = append(loaderHex, hex[:2*common.HashLength]...)
+		for _, b := range incarnation {
+			loaderHex = append(loaderHex, b>>4, b&0x0f)
+		}
+		loaderHexes[i] = append(loaderHex, hex[2*common.HashLength:]...)
+	}
+	return loaderHexes, trieHexes
+}
+
+// AttachCodes attaches the codes read to the accounts of the trie, and adds them to the code touches of rl
+func (wr *WitnessRecorder) AttachCodes(t *trie.Trie, rl *trie.RetainList) error {
+	for addrHash, code := range wr.codes {
+		if err := t.UpdateAccountCode(addrHash[:], code); err != nil {
+			return err
+		}
+		rl.AddCodeTouch(crypto.Keccak256Hash(code))
+	}
+	return nil
+}
+
+// Stateless is a StateReader and a StateWriter backed by the trie built from the witness of a block only.
+// The writes are applied to the trie by Root, once the block is executed, because the storage items of
+// an account are written before the account itself.
+type Stateless struct {
+	t        *trie.Trie
+	accounts map[common.Hash]*accounts.Account // updated accounts, nil for the deleted ones
+	wiped    map[common.Hash]struct{}          // accounts deleted or created, without their former storage
+	storage  map[common.Hash]map[common.Hash][]byte
+}
+
+// NewStateless creates a stateless reader and writer over the trie of a witness
+func NewStateless(t *trie.Trie) *Stateless {
+	return &Stateless{
+		t:        t,
+		accounts: make(map[common.Hash]*accounts.Account),
+		wiped:    make(map[common.Hash]struct{}),
+		storage:  make(map[common.Hash]map[common.Hash][]byte),
+	}
+}
+
+func (s *Stateless) ReadAccountData(address common.Address) (*accounts.Account, error) {
+	acc, ok := s.t.GetAccount(crypto.Keccak256(address[:]))
+	if !ok {
+		return nil, fmt.Errorf("account %x is not in the witness", address)
+	}
+	return acc, nil
+}
+
+func (s *Stateless) ReadAccountStorage(address common.Address, incarnation uint64, key *common.Hash) ([]byte, error) {
+	enc, ok := s.t.Get(dbutils.GenerateCompositeTrieKey(crypto.Keccak256Hash(address[:]), crypto.Keccak256Hash(key[:])))
+	if !ok {
+		return nil, fmt.Errorf("storage %x %x is not in the witness", address, *key)
+	}
+	if len(enc) == 0 {
+		return nil, nil
+	}
+	return enc, nil
+}
+
+func (s *Stateless) ReadAccountCode(address common.Address, incarnation uint64, codeHash common.Hash) ([]byte, error) {
+	if bytes.Equal(codeHash[:], emptyCodeHash) {
+		return nil, nil
+	}
+	code, ok := s.t.GetAccountCode(crypto.Keccak256(address[:]))
+	if !ok || code == nil {
+		return nil, fmt.Errorf("code of %x is not in the witness", address)
+	}
+	return code, nil
+}
+
+func (s *Stateless) ReadAccountCodeSize(address common.Address, incarnation uint64, codeHash common.Hash) (int, error) {
+	if bytes.Equal(codeHash[:], emptyCodeHash) {
+		return 0, nil
+	}
+	codeSize, ok := s.t.GetAccountCodeSize(crypto.Keccak256(address[:]))
+	if !ok {
+		return 0, fmt.Errorf("code size of %x is not in the witness", address)
+	}
+	return codeSize, nil
+}
+
+// ReadAccountIncarnation returns 0, the incarnations are not part of the trie
+func (s *Stateless) ReadAccountIncarnation(address common.Address) (uint64, error) {
+	return 0, nil
+}
+
+func (s *Stateless) UpdateAccountData(address common.Address, original, account *accounts.Account) error {
+	var acc accounts.Account
+	acc.Copy(account)
+	s.accounts[crypto.Keccak256Hash(address[:])] = &acc
+	return nil
+}
+
+func (s *Stateless) UpdateAccountCode(address common.Address, incarnation uint64, codeHash common.Hash, code []byte) error {
+	return nil
+}
+
+func (s *Stateless) DeleteAccount(address common.Address, original *accounts.Account) error {
+	addrHash := crypto.Keccak256Hash(address[:])
+	s.accounts[addrHash] = nil
+	s.wiped[addrHash] = struct{}{}
+	delete(s.storage, addrHash)
+	return nil
+}
+
+func (s *Stateless) WriteAccountStorage(address common.Address, incarnation uint64, key *common.Hash, original, value *uint256.Int) error {
+	addrHash := crypto.Keccak256Hash(address[:])
+	if _, ok := s.storage[addrHash]; !ok {
+		s.storage[addrHash] = make(map[common.Hash][]byte)
+	}
+	s.storage[addrHash][crypto.Keccak256Hash(key[:])] = value.Bytes()
+	return nil
+}
+
+func (s *Stateless) CreateContract(address common.Address) error {
+	addrHash := crypto.Keccak256Hash(address[:])
+	s.wiped[addrHash] = struct{}{}
+	delete(s.storage, addrHash)
+	return nil
+}
+
+func (s *Stateless) WriteChangeSets() error {
+	return nil
+}
+
+func (s *Stateless) WriteHistory() error {
+	return nil
+}
+
+// Root applies the writes to the trie, then returns its root hash
+func (s *Stateless) Root() common.Hash {
+	addrHashes := make([]common.Hash, 0, len(s.accounts)+len(s.wiped))
+	for addrHash := range s.accounts {
+		addrHashes = append(addrHashes, addrHash)
+	}
+	for addrHash := range s.wiped {
+		if _, ok := s.accounts[addrHash]; !ok {
+			addrHashes = append(addrHashes, addrHash)
+		}
+	}
+	sortHashes(addrHashes)
+	for _, addrHash := range addrHashes {
+		acc, updated := s.accounts[addrHash]
+		if updated && acc == nil {
+			s.t.Delete(addrHash[:])
+			continue
+		}
+		if _, ok := s.wiped[addrHash]; ok {
+			if existing, _ := s.t.GetAccount(addrHash[:]); existing != nil {
+				s.t.DeleteSubtree(addrHash[:])
+			}
+		}
+		if updated {
+			s.t.UpdateAccount(addrHash[:], acc)
+		}
+	}
+
+	addrHashes = addrHashes[:0]
+	for addrHash := range s.storage {
+		addrHashes = append(addrHashes, addrHash)
+	}
+	sortHashes(addrHashes)
+	for _, addrHash := range addrHashes {
+		if acc, updated := s.accounts[addrHash]; updated && acc == nil {
+			continue
+		}
+		keyHashes := make([]common.Hash, 0, len(s.storage[addrHash]))
+		for keyHash := range s.storage[addrHash] {
+			keyHashes = append(keyHashes, keyHash)
+		}
+		sortHashes(keyHashes)
+		for _, keyHash := range keyHashes {
+			key := dbutils.GenerateCompositeTrieKey(addrHash, keyHash)
+			if value := s.storage[addrHash][keyHash]; len(value) > 0 {
+				s.t.Update(key, value)
+			} else {
+				s.t.Delete(key)
+			}
+		}
+	}
+
+	s.accounts = make(map[common.Hash]*accounts.Account)
+	s.wiped = make(map[common.Hash]struct{})
+	s.storage = make(map[common.Hash]map[common.Hash][]byte)
+	return s.t.Hash()
+}
+
+func sortHashes(hashes []common.Hash) {
+	sort.Slice(hashes, func(i, j int) bool { return bytes.Compare(hashes[i][:], hashes[j][:]) < 0 })
+}
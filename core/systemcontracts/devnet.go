@@ -0,0 +1,86 @@
+package systemcontracts
+
+import (
+	"encoding/hex"
+	"fmt"
+	"math/big"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/params/networkname"
+)
+
+// devnetVotingPower is the voting power of each devnet validator, the one of the genesis validators of Chapel
+const devnetVotingPower = 0x048c27395000
+
+// The storage slots of the validator contract holding its validator set. They are fixed: the upgrades of
+// the contract replace its code and keep its storage.
+var (
+	// alreadyInit, set by init
+	validatorAlreadyInitSlot = common.BigToHash(big.NewInt(0))
+	// currentValidatorSet, an array of Validator structs of validatorSlots slots each
+	validatorSetSlot = common.BigToHash(big.NewInt(1))
+	// expireTimeSecondGap, set by init
+	validatorExpireTimeSecondGapSlot = common.BigToHash(big.NewInt(2))
+	// currentValidatorSetMap, the index+1 in currentValidatorSet of each consensus address
+	validatorSetMapSlot = common.BigToHash(big.NewInt(4))
+)
+
+const (
+	// validatorSlots is the number of storage slots of a Validator struct: the consensus address, the fee
+	// address, the BBC fee address packed with the voting power and jailed, and the incoming
+	validatorSlots = 4
+	// validatorExpireTimeSecondGap is the value set by init
+	validatorExpireTimeSecondGap = 1000
+)
+
+// DevnetUpgradedCode returns the code of the system contracts upgraded by the hard forks which the config
+// activates at genesis, as they are upgraded on Chapel. The upgrades of the forks at genesis are never applied
+// by the execution, so a devnet deploys the upgraded code in its genesis instead.
+func DevnetUpgradedCode(config *params.ChainConfig) (map[common.Address][]byte, error) {
+	chapel := *config
+	chapel.ChainName = networkname.ChapelChainName
+	_, upgrades := upgradesAt(&chapel, big.NewInt(0))
+	codes := make(map[common.Address][]byte)
+	for _, upgrade := range upgrades {
+		if upgrade == nil {
+			continue
+		}
+		for _, cfg := range upgrade.Configs {
+			code, err := hex.DecodeString(cfg.Code)
+			if err != nil {
+				return nil, fmt.Errorf("%s upgrade of %x: %w", upgrade.UpgradeName, cfg.ContractAddr, err)
+			}
+			codes[cfg.ContractAddr] = code
+		}
+	}
+	return codes, nil
+}
+
+// DevnetValidatorContractStorage returns the genesis storage of the validator contract initialized with the
+// given validators, as init leaves it with a validator set holding them. Each validator is its own fee
+// address. The contract is marked initialized, so the engine doesn't call init on it at block 1, which would
+// load the validator set of the network the contract was compiled for.
+func DevnetValidatorContractStorage(validators []common.Address) map[common.Hash]common.Hash {
+	storage := map[common.Hash]common.Hash{
+		validatorAlreadyInitSlot:         common.BigToHash(big.NewInt(1)),
+		validatorSetSlot:                 common.BigToHash(big.NewInt(int64(len(validators)))),
+		validatorExpireTimeSecondGapSlot: common.BigToHash(big.NewInt(validatorExpireTimeSecondGap)),
+	}
+	// the elements of a dynamic array start at the hash of its slot
+	first := new(big.Int).SetBytes(crypto.Keccak256(validatorSetSlot[:]))
+	for i, validator := range validators {
+		slot := new(big.Int).Add(first, big.NewInt(int64(i*validatorSlots)))
+		storage[common.BigToHash(slot)] = validator.Hash()
+		storage[common.BigToHash(slot.Add(slot, common.Big1))] = validator.Hash()
+		bbcFee := new(big.Int).Lsh(big.NewInt(devnetVotingPower), 8*common.AddressLength)
+		bbcFee.Or(bbcFee, validator.Hash().Big())
+		storage[common.BigToHash(slot.Add(slot, common.Big1))] = common.BigToHash(bbcFee)
+
+		// the value of a mapping key is at the hash of the key followed by the slot of the mapping
+		key := validator.Hash()
+		storage[crypto.Keccak256Hash(key[:], validatorSetMapSlot[:])] = common.BigToHash(big.NewInt(int64(i + 1)))
+	}
+	return storage
+}
@@ -0,0 +1,179 @@
+package systemcontracts
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"sort"
+
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/kv"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/params"
+)
+
+// ContractUpgrade is the change of the code of a system contract by an upgrade of a hard fork.
+type ContractUpgrade struct {
+	Block       uint64
+	Upgrade     string
+	Contract    common.Address
+	CommitUrl   string
+	OldCodeHash common.Hash // zero if the contract had no code before the block
+	OldCodeSize int
+	NewCodeHash common.Hash
+	NewCodeSize int
+	Changed     bool // whether the history of the state records a change of the code or incarnation at the block
+
+	// names of the hooks configured to run before and after the code is replaced, if any
+	ConfiguredBeforeUpgrade string
+	ConfiguredAfterUpgrade  string
+}
+
+// UpgradeBlocks returns the sorted blocks the system contracts are upgraded at, by the built-in hard
+// forks or the ones declared by the chain spec. The genesis is not executed, so it's never upgraded.
+func UpgradeBlocks(config *params.ChainConfig) []uint64 {
+	forks := []*big.Int{config.RamanujanBlock, config.NielsBlock, config.MirrorSyncBlock, config.BrunoBlock, config.EulerBlock}
+	for _, fork := range config.SystemContractForks {
+		forks = append(forks, fork.Block)
+	}
+	seen := map[uint64]bool{}
+	var blocks []uint64
+	for _, fork := range forks {
+		if fork == nil || fork.Sign() == 0 || !fork.IsUint64() || seen[fork.Uint64()] {
+			continue
+		}
+		seen[fork.Uint64()] = true
+		blocks = append(blocks, fork.Uint64())
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i] < blocks[j] })
+	return blocks
+}
+
+// ReportUpgrades returns the upgrades of the system contracts applied at the blocks in [from, to], in the
+// order they were applied. The code of the contracts before and after each block is read from the history of
+// the state, so the blocks must be executed and their history not pruned.
+//
+// The account change sets keep the code hash of a contract only when it is replaced in the incarnation (see
+// ChangeSetWriter.UpdateAccountData), otherwise it's the one of the incarnation in PlainContractCode, which is the
+// latest. So the code as of a block is the one replaced by the next upgrade of the contract, if any. The blocks
+// executed before the change sets kept the replaced code hashes report the latest code instead.
+func ReportUpgrades(tx kv.Tx, config *params.ChainConfig, from, to uint64) ([]ContractUpgrade, error) {
+	r, err := newHistoryReader(tx, UpgradeBlocks(config))
+	if err != nil {
+		return nil, err
+	}
+	defer r.changes.Close()
+	var report []ContractUpgrade
+	for _, blockNum := range r.upgradeBlocks {
+		if blockNum > to {
+			break
+		}
+		if blockNum < from {
+			continue
+		}
+		_, upgrades := upgradesAt(config, new(big.Int).SetUint64(blockNum))
+		for _, upgrade := range upgrades {
+			if upgrade == nil {
+				continue
+			}
+			for _, cfg := range upgrade.Configs {
+				newCode, err := hex.DecodeString(cfg.Code)
+				if err != nil {
+					return nil, fmt.Errorf("decoding the code of %x upgraded by %s: %w", cfg.ContractAddr, upgrade.UpgradeName, err)
+				}
+				// the upgrades run at the beginning of the block, before its transactions
+				before, err := r.readAccount(cfg.ContractAddr, blockNum)
+				if err != nil {
+					return nil, err
+				}
+				after, err := r.readAccount(cfg.ContractAddr, blockNum+1)
+				if err != nil {
+					return nil, err
+				}
+				u := ContractUpgrade{
+					Block:                   blockNum,
+					Upgrade:                 upgrade.UpgradeName,
+					Contract:                cfg.ContractAddr,
+					CommitUrl:               cfg.CommitUrl,
+					NewCodeHash:             crypto.Keccak256Hash(newCode),
+					NewCodeSize:             len(newCode),
+					Changed:                 before.Incarnation != after.Incarnation || before.CodeHash != after.CodeHash,
+					ConfiguredBeforeUpgrade: cfg.BeforeUpgradeName,
+					ConfiguredAfterUpgrade:  cfg.AfterUpgradeName,
+				}
+				if !before.IsEmptyCodeHash() {
+					u.OldCodeHash = before.CodeHash
+					if u.OldCodeSize, err = r.state.ReadAccountCodeSize(cfg.ContractAddr, before.Incarnation, before.CodeHash); err != nil {
+						return nil, err
+					}
+				}
+				report = append(report, u)
+			}
+		}
+	}
+	return report, nil
+}
+
+// historyReader reads the accounts of the system contracts as of the blocks, with the code they had then.
+type historyReader struct {
+	upgradeBlocks []uint64
+	state         *state.PlainState
+	changes       kv.CursorDupSort
+}
+
+func newHistoryReader(tx kv.Tx, upgradeBlocks []uint64) (*historyReader, error) {
+	changes, err := tx.CursorDupSort(kv.AccountChangeSet)
+	if err != nil {
+		return nil, err
+	}
+	return &historyReader{upgradeBlocks: upgradeBlocks, state: state.NewPlainState(tx, 0), changes: changes}, nil
+}
+
+// readAccount returns the account as of the block, an empty one if there was none. Its code is the one replaced
+// in the incarnation by the first upgrade from the block on, read from its change set, or the latest one.
+func (r *historyReader) readAccount(addr common.Address, blockNum uint64) (*accounts.Account, error) {
+	r.state.SetBlockNr(blockNum)
+	account, err := r.state.ReadAccountData(addr)
+	if err != nil || account == nil {
+		empty := accounts.NewAccount()
+		return &empty, err
+	}
+	if account.Incarnation == 0 {
+		return account, nil
+	}
+	for _, upgradeBlock := range r.upgradeBlocks {
+		if upgradeBlock < blockNum {
+			continue
+		}
+		v, err := r.changes.SeekBothRange(dbutils.EncodeBlockNumber(upgradeBlock), addr[:])
+		if err != nil {
+			return nil, err
+		}
+		if !bytes.HasPrefix(v, addr[:]) {
+			continue
+		}
+		enc := v[length.Addr:]
+		if len(enc) == 0 {
+			// the account was created by the upgrade, after being deleted
+			break
+		}
+		var original accounts.Account
+		if err = original.DecodeForStorage(enc); err != nil {
+			return nil, err
+		}
+		if original.Incarnation != account.Incarnation {
+			break
+		}
+		if !original.IsEmptyCodeHash() {
+			account.CodeHash = original.CodeHash
+			break
+		}
+	}
+	return account, nil
+}
@@ -0,0 +1,99 @@
+package systemcontracts
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/params"
+)
+
+func TestReportUpgrades(t *testing.T) {
+	RegisterUpgradeHook("reportHook", func(*big.Int, common.Address, *state.IntraBlockState) error { return nil })
+	config := &params.ChainConfig{
+		ChainName: "private",
+		SystemContractForks: []*params.SystemContractFork{
+			{Name: "first", Block: big.NewInt(2), Contracts: []*params.SystemContractUpgrade{
+				{Address: ValidatorContract, Code: []byte{0x01, 0x02}, CommitUrl: "https://example.com/first"},
+				{Address: SlashContract, Code: []byte{0x03}, AfterUpgrade: "reportHook"},
+			}},
+			{Name: "second", Block: big.NewInt(4), Contracts: []*params.SystemContractUpgrade{
+				{Address: ValidatorContract, Code: []byte{0x04, 0x05, 0x06}},
+				{Address: SystemRewardContract, Code: []byte{0x07}, BeforeUpgrade: "reportHook"},
+				{Address: SlashContract, Code: []byte{0x03}},
+			}},
+		},
+	}
+	require.NoError(t, ValidateSystemContractForks(config))
+	require.Equal(t, []uint64{2, 4}, UpgradeBlocks(config))
+
+	// execute the blocks, writing the history of the state, the contracts are deployed by transactions
+	_, tx := memdb.NewTestTx(t)
+	for blockNum := uint64(0); blockNum <= 5; blockNum++ {
+		ibs := state.New(state.NewPlainStateReader(tx))
+		switch blockNum {
+		case 0:
+			ibs.CreateAccount(ValidatorContract, true)
+			ibs.SetCode(ValidatorContract, []byte{0xaa})
+		case 1:
+			ibs.CreateAccount(SlashContract, true)
+			ibs.SetCode(SlashContract, []byte{0xbb})
+		case 3:
+			ibs.AddBalance(ValidatorContract, uint256.NewInt(1))
+		}
+		UpgradeBuildInSystemContract(config, new(big.Int).SetUint64(blockNum), ibs)
+		w := state.NewPlainStateWriter(tx, tx, blockNum)
+		require.NoError(t, ibs.CommitBlock(&params.Rules{}, w))
+		require.NoError(t, w.WriteChangeSets())
+		require.NoError(t, w.WriteHistory())
+	}
+
+	// the change sets keep the code replaced by an upgrade, not the code of the other changes
+	account, err := state.NewPlainState(tx, 4).ReadAccountData(ValidatorContract)
+	require.NoError(t, err)
+	require.Equal(t, crypto.Keccak256Hash([]byte{0x01, 0x02}), account.CodeHash)
+	account, err = state.NewPlainState(tx, 3).ReadAccountData(ValidatorContract)
+	require.NoError(t, err)
+	require.Equal(t, crypto.Keccak256Hash([]byte{0x04, 0x05, 0x06}), account.CodeHash)
+
+	report, err := ReportUpgrades(tx, config, 0, 5)
+	require.NoError(t, err)
+	second := []ContractUpgrade{
+		{
+			Block: 4, Upgrade: "second", Contract: ValidatorContract,
+			OldCodeHash: crypto.Keccak256Hash([]byte{0x01, 0x02}), OldCodeSize: 2,
+			NewCodeHash: crypto.Keccak256Hash([]byte{0x04, 0x05, 0x06}), NewCodeSize: 3, Changed: true,
+		},
+		{
+			Block: 4, Upgrade: "second", Contract: SystemRewardContract,
+			NewCodeHash: crypto.Keccak256Hash([]byte{0x07}), NewCodeSize: 1, Changed: true, ConfiguredBeforeUpgrade: "reportHook",
+		},
+		{
+			Block: 4, Upgrade: "second", Contract: SlashContract,
+			OldCodeHash: crypto.Keccak256Hash([]byte{0x03}), OldCodeSize: 1,
+			NewCodeHash: crypto.Keccak256Hash([]byte{0x03}), NewCodeSize: 1,
+		},
+	}
+	require.Equal(t, append([]ContractUpgrade{
+		{
+			Block: 2, Upgrade: "first", Contract: ValidatorContract, CommitUrl: "https://example.com/first",
+			OldCodeHash: crypto.Keccak256Hash([]byte{0xaa}), OldCodeSize: 1,
+			NewCodeHash: crypto.Keccak256Hash([]byte{0x01, 0x02}), NewCodeSize: 2, Changed: true,
+		},
+		{
+			Block: 2, Upgrade: "first", Contract: SlashContract,
+			OldCodeHash: crypto.Keccak256Hash([]byte{0xbb}), OldCodeSize: 1,
+			NewCodeHash: crypto.Keccak256Hash([]byte{0x03}), NewCodeSize: 1, Changed: true, ConfiguredAfterUpgrade: "reportHook",
+		},
+	}, second...), report)
+
+	report, err = ReportUpgrades(tx, config, 3, 5)
+	require.NoError(t, err)
+	require.Equal(t, second, report)
+}
@@ -0,0 +1,133 @@
+package systemcontracts
+
+import (
+	"errors"
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/params"
+)
+
+func TestDeclaredSystemContractForks(t *testing.T) {
+	var calls []string
+	RegisterUpgradeHook("testBefore", func(blockNumber *big.Int, contractAddr common.Address, statedb *state.IntraBlockState) error {
+		calls = append(calls, "before:"+common.Bytes2Hex(statedb.GetCode(contractAddr)))
+		return nil
+	})
+	RegisterUpgradeHook("testAfter", func(blockNumber *big.Int, contractAddr common.Address, statedb *state.IntraBlockState) error {
+		calls = append(calls, "after:"+common.Bytes2Hex(statedb.GetCode(contractAddr)))
+		statedb.SetBalance(contractAddr, uint256.NewInt(blockNumber.Uint64()))
+		return nil
+	})
+	RegisterUpgradeHook("testFail", func(*big.Int, common.Address, *state.IntraBlockState) error {
+		return errors.New("hook failed")
+	})
+	require.Panics(t, func() { RegisterUpgradeHook("testFail", nil) })
+
+	config := &params.ChainConfig{
+		ChainName: "private",
+		SystemContractForks: []*params.SystemContractFork{
+			{Name: "first", Block: big.NewInt(5), Contracts: []*params.SystemContractUpgrade{
+				{Address: ValidatorContract, Code: []byte{0x01}},
+				{Address: SlashContract, Code: []byte{0x02}, BeforeUpgrade: "testBefore", AfterUpgrade: "testAfter"},
+			}},
+			{Name: "second", Block: big.NewInt(7), Contracts: []*params.SystemContractUpgrade{
+				{Address: SlashContract, Code: []byte{0x03}, BeforeUpgrade: "testBefore"},
+			}},
+			{Name: "broken", Block: big.NewInt(9), Contracts: []*params.SystemContractUpgrade{
+				{Address: SlashContract, Code: []byte{0x04}, AfterUpgrade: "testFail"},
+			}},
+		},
+	}
+	require.NoError(t, ValidateSystemContractForks(config))
+
+	_, tx := memdb.NewTestTx(t)
+	ibs := state.New(state.NewPlainStateReader(tx))
+	UpgradeBuildInSystemContract(config, big.NewInt(4), ibs)
+	require.Empty(t, ibs.GetCode(ValidatorContract))
+
+	UpgradeBuildInSystemContract(config, big.NewInt(5), ibs)
+	require.Equal(t, []byte{0x01}, ibs.GetCode(ValidatorContract))
+	require.Equal(t, []byte{0x02}, ibs.GetCode(SlashContract))
+	require.Equal(t, uint64(5), ibs.GetBalance(SlashContract).Uint64())
+
+	UpgradeBuildInSystemContract(config, big.NewInt(6), ibs)
+	UpgradeBuildInSystemContract(config, big.NewInt(7), ibs)
+	require.Equal(t, []byte{0x03}, ibs.GetCode(SlashContract))
+	require.Equal(t, []string{"before:", "after:02", "before:02"}, calls)
+
+	require.Panics(t, func() { UpgradeBuildInSystemContract(config, big.NewInt(9), ibs) })
+}
+
+func TestValidateSystemContractForks(t *testing.T) {
+	fork := func(name string, block int64, contracts ...*params.SystemContractUpgrade) *params.SystemContractFork {
+		f := &params.SystemContractFork{Name: name, Contracts: contracts}
+		if block >= 0 {
+			f.Block = big.NewInt(block)
+		}
+		return f
+	}
+	code := &params.SystemContractUpgrade{Address: ValidatorContract, Code: []byte{0x01}}
+	for name, test := range map[string]struct {
+		forks []*params.SystemContractFork
+		file  string
+		valid bool
+	}{
+		"empty":            {valid: true},
+		"valid":            {forks: []*params.SystemContractFork{fork("a", 1, code), fork("b", 1, code), fork("c", 2, code)}, valid: true},
+		"unresolved file":  {file: "forks.json"},
+		"no name":          {forks: []*params.SystemContractFork{fork("", 1, code)}},
+		"duplicate name":   {forks: []*params.SystemContractFork{fork("a", 1, code), fork("a", 2, code)}},
+		"no block":         {forks: []*params.SystemContractFork{fork("a", -1, code)}},
+		"genesis":          {forks: []*params.SystemContractFork{fork("a", 0, code)}},
+		"unordered":        {forks: []*params.SystemContractFork{fork("a", 2, code), fork("b", 1, code)}},
+		"no contracts":     {forks: []*params.SystemContractFork{fork("a", 1)}},
+		"no address":       {forks: []*params.SystemContractFork{fork("a", 1, &params.SystemContractUpgrade{Code: []byte{0x01}})}},
+		"no code":          {forks: []*params.SystemContractFork{fork("a", 1, &params.SystemContractUpgrade{Address: ValidatorContract})}},
+		"unresolved code":  {forks: []*params.SystemContractFork{fork("a", 1, &params.SystemContractUpgrade{Address: ValidatorContract, Code: []byte{0x01}, CodeFile: "a.hex"})}},
+		"unknown hook":     {forks: []*params.SystemContractFork{fork("a", 1, &params.SystemContractUpgrade{Address: ValidatorContract, Code: []byte{0x01}, AfterUpgrade: "unknown"})}},
+		"unknown pre hook": {forks: []*params.SystemContractFork{fork("a", 1, &params.SystemContractUpgrade{Address: ValidatorContract, Code: []byte{0x01}, BeforeUpgrade: "unknown"})}},
+	} {
+		err := ValidateSystemContractForks(&params.ChainConfig{SystemContractForks: test.forks, SystemContractForksFile: test.file})
+		if test.valid {
+			require.NoError(t, err, name)
+		} else {
+			require.Error(t, err, name)
+		}
+	}
+	for _, config := range []*params.ChainConfig{params.BSCChainConfig, params.ChapelChainConfig, params.RialtoChainConfig} {
+		require.NoError(t, ValidateSystemContractForks(config), config.ChainName)
+	}
+}
+
+func TestBuiltInSystemContractForks(t *testing.T) {
+	for network, forks := range map[string][]string{
+		mainNet:   {"mirror", "bruno", "euler"},
+		chapelNet: {"ramanujan", "niels", "mirror", "bruno", "euler"},
+		rialtoNet: {"ramanujan", "mirror", "bruno", "euler"},
+	} {
+		require.Len(t, builtInUpgrades[network], len(forks), network)
+		for _, name := range forks {
+			upgrade := builtInUpgrades[network][name]
+			require.NotNil(t, upgrade, network+" "+name)
+			require.Equal(t, name, upgrade.UpgradeName)
+			require.NotEmpty(t, upgrade.Configs, network+" "+name)
+			for _, config := range upgrade.Configs {
+				require.NotEmpty(t, config.Code, network+" "+name)
+				require.NotEmpty(t, config.CommitUrl, network+" "+name)
+			}
+		}
+	}
+
+	_, upgrades := upgradesAt(params.ChapelChainConfig, params.ChapelChainConfig.EulerBlock)
+	require.Equal(t, []*Upgrade{builtInUpgrades[chapelNet]["euler"]}, upgrades)
+	_, upgrades = upgradesAt(params.BSCChainConfig, params.BSCChainConfig.RamanujanBlock)
+	// ramanujan and niels, activated together on the mainnet, don't upgrade its system contracts
+	require.Equal(t, []*Upgrade{nil, nil}, upgrades)
+}
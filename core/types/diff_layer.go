@@ -0,0 +1,94 @@
+package types
+
+import (
+	"io"
+	"math/big"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/rlp"
+)
+
+// DiffLayer is the set of state changes made by a block, together with its receipts. It is
+// propagated by the BSC `diff` protocol, so that followers can apply a block without executing it.
+type DiffLayer struct {
+	BlockHash common.Hash
+	Number    uint64
+	Receipts  Receipts // Receipts are duplicated to allow applying the block without execution
+	Codes     []DiffCode
+	Destructs []common.Address
+	Accounts  []DiffAccount
+	Storages  []DiffStorage
+}
+
+// extDiffLayer is the network encoding of a diff layer, receipts are flattened into their storage form.
+type extDiffLayer struct {
+	BlockHash common.Hash
+	Number    uint64
+	Receipts  []*ReceiptForStorage
+	Codes     []DiffCode
+	Destructs []common.Address
+	Accounts  []DiffAccount
+	Storages  []DiffStorage
+}
+
+// DiffCode is a contract code deployed by the block.
+type DiffCode struct {
+	Hash common.Hash
+	Code []byte
+}
+
+// DiffAccount is the state of an account after the block, Blob is the slim account encoding
+// (see DiffAccountData).
+type DiffAccount struct {
+	Account common.Address
+	Blob    []byte
+}
+
+// DiffStorage is the set of storage slots of an account changed by the block. Vals are RLP
+// encoded with the leading zeros trimmed, an empty value means that the slot was cleared.
+type DiffStorage struct {
+	Account common.Address
+	Keys    []string
+	Vals    [][]byte
+}
+
+// DiffAccountData is the slim account encoding used by diff layers, the empty storage root and
+// code hash are encoded as empty byte slices.
+type DiffAccountData struct {
+	Nonce    uint64
+	Balance  *big.Int
+	Root     []byte
+	CodeHash []byte
+}
+
+// EncodeRLP implements rlp.Encoder.
+func (d *DiffLayer) EncodeRLP(w io.Writer) error {
+	receipts := make([]*ReceiptForStorage, len(d.Receipts))
+	for i, receipt := range d.Receipts {
+		receipts[i] = (*ReceiptForStorage)(receipt)
+	}
+	return rlp.Encode(w, extDiffLayer{
+		BlockHash: d.BlockHash,
+		Number:    d.Number,
+		Receipts:  receipts,
+		Codes:     d.Codes,
+		Destructs: d.Destructs,
+		Accounts:  d.Accounts,
+		Storages:  d.Storages,
+	})
+}
+
+// DecodeRLP implements rlp.Decoder.
+func (d *DiffLayer) DecodeRLP(s *rlp.Stream) error {
+	var ed extDiffLayer
+	if err := s.Decode(&ed); err != nil {
+		return err
+	}
+	d.BlockHash, d.Number, d.Codes, d.Destructs, d.Accounts, d.Storages =
+		ed.BlockHash, ed.Number, ed.Codes, ed.Destructs, ed.Accounts, ed.Storages
+	d.Receipts = make(Receipts, len(ed.Receipts))
+	for i, receipt := range ed.Receipts {
+		d.Receipts[i] = (*Receipt)(receipt)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/rlp"
+)
+
+func TestDiffLayerRLP(t *testing.T) {
+	diff := &DiffLayer{
+		BlockHash: common.Hash{0x01},
+		Number:    100,
+		Receipts: Receipts{
+			{
+				Status:            ReceiptStatusSuccessful,
+				CumulativeGasUsed: 21000,
+				Logs: []*Log{{
+					Address: common.Address{0x02},
+					Topics:  []common.Hash{{0x03}},
+					Data:    []byte{0x04},
+				}},
+			},
+			{Status: ReceiptStatusFailed, CumulativeGasUsed: 42000, Logs: []*Log{}},
+		},
+		Codes:     []DiffCode{{Hash: common.Hash{0x05}, Code: []byte{0x60, 0x00}}},
+		Destructs: []common.Address{{0x06}},
+		Accounts:  []DiffAccount{{Account: common.Address{0x07}, Blob: []byte{0xc1, 0x80}}},
+		Storages:  []DiffStorage{{Account: common.Address{0x08}, Keys: []string{string(common.Hash{0x09}.Bytes())}, Vals: [][]byte{{0x0a}}}},
+	}
+	enc, err := rlp.EncodeToBytes(diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded DiffLayer
+	if err = rlp.DecodeBytes(enc, &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(diff, &decoded) {
+		t.Fatalf("diff layer mismatch\nhave %+v\nwant %+v", &decoded, diff)
+	}
+}
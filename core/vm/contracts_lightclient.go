@@ -2,6 +2,7 @@ package vm
 
 import (
 	"encoding/binary"
+	"errors"
 	"fmt"
 
 	"github.com/ledgerwatch/erigon/core/vm/lightclient"
@@ -17,13 +18,22 @@ const (
 	merkleProofValidateResultLength      uint64 = 32
 )
 
+// ErrLightClientInternal is wrapped by the errors of the light client precompiles which recovered from a
+// panic while processing their input. Malformed input is expected to be rejected before it gets to panic,
+// so the fuzzers report these errors as crashes.
+var ErrLightClientInternal = errors.New("internal error")
+
 // input:
 // consensus state length | consensus state | tendermint header |
 // 32 bytes               |                 |                   |
 func decodeTendermintHeaderValidationInput(input []byte) (*lightclient.ConsensusState, *lightclient.Header, error) {
+	if uint64(len(input)) <= consensusStateLengthBytesLength {
+		return nil, nil, fmt.Errorf("expected payload size greater than %d, actual size: %d", consensusStateLengthBytesLength, len(input))
+	}
 	csLen := binary.BigEndian.Uint64(input[consensusStateLengthBytesLength-uint64TypeLength : consensusStateLengthBytesLength])
-	if uint64(len(input)) <= consensusStateLengthBytesLength+csLen {
-		return nil, nil, fmt.Errorf("expected payload size %d, actual size: %d", consensusStateLengthBytesLength+csLen, len(input))
+	// compared without adding the lengths, which could overflow
+	if uint64(len(input))-consensusStateLengthBytesLength <= csLen {
+		return nil, nil, fmt.Errorf("consensus state length %d exceeds the payload size %d", csLen, uint64(len(input))-consensusStateLengthBytesLength)
 	}
 
 	cs, err := lightclient.DecodeConsensusState(input[consensusStateLengthBytesLength : consensusStateLengthBytesLength+csLen])
@@ -48,7 +58,7 @@ func (c *tmHeaderValidate) RequiredGas(input []byte) uint64 {
 func (c *tmHeaderValidate) Run(input []byte) (result []byte, err error) {
 	defer func() {
 		if r := recover(); r != nil {
-			err = fmt.Errorf("internal error: %v", r)
+			err = fmt.Errorf("%w: %v", ErrLightClientInternal, r)
 		}
 	}()
 
@@ -106,7 +116,7 @@ func (c *iavlMerkleProofValidate) RequiredGas(input []byte) uint64 {
 func (c *iavlMerkleProofValidate) Run(input []byte) (result []byte, err error) {
 	defer func() {
 		if r := recover(); r != nil {
-			err = fmt.Errorf("internal error: %v", r)
+			err = fmt.Errorf("%w: %v", ErrLightClientInternal, r)
 		}
 	}()
 
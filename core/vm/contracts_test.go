@@ -65,6 +65,8 @@ var allPrecompiles = map[common.Address]PrecompiledContract{
 	common.BytesToAddress([]byte{16}):   &bls12381Pairing{},
 	common.BytesToAddress([]byte{17}):   &bls12381MapG1{},
 	common.BytesToAddress([]byte{18}):   &bls12381MapG2{},
+	common.BytesToAddress([]byte{100}):  &tmHeaderValidate{},
+	common.BytesToAddress([]byte{101}):  &iavlMerkleProofValidate{},
 }
 
 // EIP-152 test vectors
@@ -333,6 +335,23 @@ func TestPrecompiledBLS12381PairingFail(t *testing.T)    { testJsonFail("blsPair
 func TestPrecompiledBLS12381MapG1Fail(t *testing.T)      { testJsonFail("blsMapG1", "11", t) }
 func TestPrecompiledBLS12381MapG2Fail(t *testing.T)      { testJsonFail("blsMapG2", "12", t) }
 
+// The light client vectors are Binance Chain mainnet consensus states, headers and proofs as relayed
+// to BSC, the failure ones are malformed variants of them which must be rejected without panicking.
+func TestPrecompiledTmHeaderValidate(t *testing.T) { testJson("tmHeaderValidate", "64", t) }
+func TestPrecompiledIAVLMerkleProofValidate(t *testing.T) {
+	testJson("iavlMerkleProofValidate", "65", t)
+}
+
+func TestPrecompiledTmHeaderValidateFail(t *testing.T) { testJsonFail("tmHeaderValidate", "64", t) }
+func TestPrecompiledIAVLMerkleProofValidateFail(t *testing.T) {
+	testJsonFail("iavlMerkleProofValidate", "65", t)
+}
+
+func BenchmarkPrecompiledTmHeaderValidate(b *testing.B) { benchJson("tmHeaderValidate", "64", b) }
+func BenchmarkPrecompiledIAVLMerkleProofValidate(b *testing.B) {
+	benchJson("iavlMerkleProofValidate", "65", b)
+}
+
 func loadJson(name string) ([]precompiledTest, error) {
 	data, err := os.ReadFile(fmt.Sprintf("testdata/precompiles/%v.json", name))
 	if err != nil {
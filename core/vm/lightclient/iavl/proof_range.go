@@ -92,10 +92,10 @@ func (proof *RangeProof) LeftIndex() int64 {
 // Verify that a key has some value.
 // Does not assume that the proof itself is valid, call Verify() first.
 func (proof *RangeProof) VerifyItem(key, value []byte) error {
-	leaves := proof.Leaves
 	if proof == nil {
 		return cmn.ErrorWrap(ErrInvalidProof, "proof is nil")
 	}
+	leaves := proof.Leaves
 	if !proof.rootVerified {
 		return cmn.NewError("must call Verify(root) first.")
 	}
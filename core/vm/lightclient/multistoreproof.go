@@ -75,6 +75,9 @@ func MultiStoreProofOpDecoder(pop merkle.ProofOp) (merkle.ProofOperator, error)
 	if err != nil {
 		return nil, cmn.ErrorWrap(err, "decoding ProofOp.Data into MultiStoreProofOp")
 	}
+	if op.Proof == nil {
+		return nil, cmn.NewError("decoding ProofOp.Data into MultiStoreProofOp: proof is nil")
+	}
 
 	return NewMultiStoreProofOp(pop.Key, op.Proof), nil
 }
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"encoding/binary"
 	"fmt"
+	"math"
 
 	"github.com/tendermint/tendermint/crypto/ed25519"
 	"github.com/tendermint/tendermint/crypto/merkle"
@@ -139,6 +140,9 @@ func (cs *ConsensusState) ApplyHeader(header *Header) (bool, error) {
 	}
 
 	trustedNextHash := cs.NextValidatorSet.Hash()
+	if err := checkTotalVotingPower(cs.NextValidatorSet); err != nil {
+		return false, err
+	}
 	if cs.Height == uint64(header.Height-1) {
 		if !bytes.Equal(trustedNextHash, header.ValidatorsHash) {
 			return false, lerr.ErrUnexpectedValidators(header.ValidatorsHash, trustedNextHash)
@@ -148,6 +152,9 @@ func (cs *ConsensusState) ApplyHeader(header *Header) (bool, error) {
 			return false, err
 		}
 	} else {
+		if err := checkTotalVotingPower(header.ValidatorSet); err != nil {
+			return false, err
+		}
 		err := cs.NextValidatorSet.VerifyFutureCommit(header.ValidatorSet, cs.ChainID,
 			header.Commit.BlockID, header.Height, header.Commit)
 		if err != nil {
@@ -167,6 +174,28 @@ func (cs *ConsensusState) ApplyHeader(header *Header) (bool, error) {
 	return validatorSetChanged, nil
 }
 
+// checkTotalVotingPower returns an error for the validator sets which total voting power the commit
+// verification panics on, summing the voting powers the same way.
+func checkTotalVotingPower(vals *tmtypes.ValidatorSet) error {
+	sum := int64(0)
+	for _, val := range vals.Validators {
+		if val == nil {
+			return fmt.Errorf("invalid validator set: nil validator")
+		}
+		if val.VotingPower > 0 && sum > math.MaxInt64-val.VotingPower {
+			sum = math.MaxInt64
+		} else if val.VotingPower < 0 && sum < math.MinInt64-val.VotingPower {
+			sum = math.MinInt64
+		} else {
+			sum += val.VotingPower
+		}
+		if sum > tmtypes.MaxTotalVotingPower {
+			return fmt.Errorf("invalid validator set: total voting power exceeds %d", tmtypes.MaxTotalVotingPower)
+		}
+	}
+	return nil
+}
+
 type Header struct {
 	tmtypes.SignedHeader
 	ValidatorSet     *tmtypes.ValidatorSet `json:"validator_set"`
@@ -249,7 +278,8 @@ func DecodeKeyValueMerkleProof(input []byte) (*KeyValueMerkleProof, error) {
 	keyLength := binary.BigEndian.Uint64(input[pos+keyLengthBytesLength-8 : pos+keyLengthBytesLength])
 	pos += keyLengthBytesLength
 
-	if inputLength <= storeNameLengthBytesLength+keyLengthBytesLength+keyLength+valueLengthBytesLength {
+	// the lengths are compared to the rest of the input, adding them to the position could overflow
+	if keyLength >= inputLength-pos-valueLengthBytesLength {
 		return nil, fmt.Errorf("invalid input, keyLength %d is too long", keyLength)
 	}
 	key := input[pos : pos+keyLength]
@@ -258,7 +288,7 @@ func DecodeKeyValueMerkleProof(input []byte) (*KeyValueMerkleProof, error) {
 	valueLength := binary.BigEndian.Uint64(input[pos+valueLengthBytesLength-8 : pos+valueLengthBytesLength])
 	pos += valueLengthBytesLength
 
-	if inputLength <= storeNameLengthBytesLength+keyLengthBytesLength+keyLength+valueLengthBytesLength+valueLength+appHashLength {
+	if inputLength-pos <= appHashLength || valueLength >= inputLength-pos-appHashLength {
 		return nil, fmt.Errorf("invalid input, valueLength %d is too long", valueLength)
 	}
 	value := input[pos : pos+valueLength]
@@ -231,6 +231,9 @@ type Config struct {
 
 	// No heimdall service
 	WithoutHeimdall bool
+
+	// Enable the BSC diff protocol and the light processing of blocks
+	DiffSync bool
 	// Ethstats service
 	Ethstats string
 
@@ -248,6 +251,11 @@ type Sync struct {
 
 	BlockDownloaderWindow      int
 	BodyDownloadTimeoutSeconds int // TODO: change to duration
+
+	// ReceiptsDownload enables the stage downloading the receipts not written by the execution
+	ReceiptsDownload bool
+	// Traces enables the stage storing the traces of the blocks for the trace_ RPC methods
+	Traces bool
 }
 
 // Chains where snapshots are enabled by default
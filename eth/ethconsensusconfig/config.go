@@ -58,12 +58,21 @@ func CreateConsensusEngine(chainConfig *params.ChainConfig, logger log.Logger, c
 		}
 	case *params.ParliaConfig:
 		if chainConfig.Parlia != nil {
-			eng = parlia.New(chainConfig, db.OpenDatabase(consensusCfg.DBPath, logger, consensusCfg.InMemory, readonly), snapshots, chainDb[0])
+			// the chain db is used for mining only, the tools don't pass it
+			var parliaChainDb kv.RwDB
+			if len(chainDb) > 0 {
+				parliaChainDb = chainDb[0]
+			}
+			eng = parlia.New(chainConfig, db.OpenDatabase(consensusCfg.DBPath, logger, consensusCfg.InMemory, readonly), snapshots, parliaChainDb)
 		}
 	case *params.BorConfig:
 		if chainConfig.Bor != nil {
 			borDbPath := filepath.Join(datadir, "bor") // bor consensus path: datadir/bor
-			eng = bor.New(chainConfig, db.OpenDatabase(borDbPath, logger, false, readonly), HeimdallURL, WithoutHeimdall)
+			var err error
+			eng, err = bor.New(chainConfig, db.OpenDatabase(borDbPath, logger, false, readonly), HeimdallURL, WithoutHeimdall)
+			if err != nil {
+				panic(err)
+			}
 		}
 	}
 
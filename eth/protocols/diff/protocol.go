@@ -0,0 +1,93 @@
+package diff
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/rlp"
+)
+
+// Constants to match up protocol versions and messages
+const (
+	Diff1 = 1
+)
+
+// ProtocolName is the official short name of the `diff` protocol used during
+// devp2p capability negotiation.
+const ProtocolName = "diff"
+
+// ProtocolVersions are the supported versions of the `diff` protocol (first
+// is primary).
+var ProtocolVersions = []uint{Diff1}
+
+// ProtocolLength is the number of implemented message corresponding to
+// different protocol versions.
+const ProtocolLength = 4
+
+// maxMessageSize is the maximum cap on the size of a protocol message.
+const maxMessageSize = 10 * 1024 * 1024
+const ProtocolMaxMsgSize = maxMessageSize
+
+const (
+	DiffCapMsg       = 0x00
+	GetDiffLayerMsg  = 0x01
+	DiffLayerMsg     = 0x02
+	FullDiffLayerMsg = 0x03
+)
+
+var errDecode = errors.New("invalid message")
+
+// Packet represents a p2p message in the `diff` protocol.
+type Packet interface {
+	Name() string // Name returns a string corresponding to the message type.
+	Kind() byte   // Kind returns the message type.
+}
+
+// DiffCapPacket is the network packet for the diff capability message, it is
+// exchanged once during the handshake.
+type DiffCapPacket struct {
+	DiffSync bool
+	Extra    rlp.RawValue // for extension
+}
+
+// DiffLayersPacket is the network packet for pushing diff layers to the peers.
+type DiffLayersPacket []rlp.RawValue
+
+// Unpack decodes the diff layers of the packet.
+func (p *DiffLayersPacket) Unpack() ([]*types.DiffLayer, error) {
+	diffs := make([]*types.DiffLayer, 0, len(*p))
+	for _, data := range *p {
+		diff := new(types.DiffLayer)
+		if err := rlp.DecodeBytes(data, diff); err != nil {
+			return nil, fmt.Errorf("%w: diff layer %v", errDecode, err)
+		}
+		diffs = append(diffs, diff)
+	}
+	return diffs, nil
+}
+
+// GetDiffLayersPacket represents a diff layers query.
+type GetDiffLayersPacket struct {
+	RequestId   uint64
+	BlockHashes []common.Hash
+}
+
+// FullDiffLayersPacket is the response to GetDiffLayersPacket.
+type FullDiffLayersPacket struct {
+	RequestId uint64
+	DiffLayersPacket
+}
+
+func (*DiffCapPacket) Name() string { return "DiffCap" }
+func (*DiffCapPacket) Kind() byte   { return DiffCapMsg }
+
+func (*GetDiffLayersPacket) Name() string { return "GetDiffLayers" }
+func (*GetDiffLayersPacket) Kind() byte   { return GetDiffLayerMsg }
+
+func (*DiffLayersPacket) Name() string { return "DiffLayers" }
+func (*DiffLayersPacket) Kind() byte   { return DiffLayerMsg }
+
+func (*FullDiffLayersPacket) Name() string { return "FullDiffLayers" }
+func (*FullDiffLayersPacket) Kind() byte   { return FullDiffLayerMsg }
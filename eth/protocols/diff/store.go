@@ -0,0 +1,171 @@
+package diff
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
+)
+
+// DefaultStoreLimit is the number of recent diff layers kept in memory.
+const DefaultStoreLimit = 256
+
+// Store keeps the recent diff layers, received from the peers or produced by the local
+// execution, it connects the sentry serving the `diff` protocol with the Execution stage.
+type Store struct {
+	layers   *lru.Cache // block hash -> *storedLayer
+	applied  *lru.Cache // block hash -> struct{}, blocks whose state was written from their diff layer
+	rejected *lru.Cache // block hash -> struct{}, blocks whose diff layer turned out to be wrong
+
+	lock      sync.RWMutex
+	request   func(hashes []common.Hash)
+	broadcast func(diff *types.DiffLayer)
+	penalize  func(peerID [64]byte)
+}
+
+// storedLayer is a diff layer with the peer it was received from, local is set for the ones
+// produced by the local execution.
+type storedLayer struct {
+	layer *types.DiffLayer
+	peer  [64]byte
+	local bool
+}
+
+func NewStore(limit int) *Store {
+	s := &Store{}
+	var err error
+	if s.layers, err = lru.New(limit); err != nil {
+		panic(err)
+	}
+	if s.applied, err = lru.New(limit); err != nil {
+		panic(err)
+	}
+	if s.rejected, err = lru.New(limit); err != nil {
+		panic(err)
+	}
+	return s
+}
+
+// SetNetwork connects the store to the peers, request asks them for the missing diff
+// layers, broadcast propagates the locally produced ones and penalize disconnects a peer
+// which sent a wrong diff layer.
+func (s *Store) SetNetwork(request func(hashes []common.Hash), broadcast func(diff *types.DiffLayer), penalize func(peerID [64]byte)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.request, s.broadcast, s.penalize = request, broadcast, penalize
+}
+
+// Add stores a diff layer received from a peer, unless the diff layer of the block was rejected.
+func (s *Store) Add(diff *types.DiffLayer, peerID [64]byte) {
+	if s.rejected.Contains(diff.BlockHash) {
+		return
+	}
+	s.layers.ContainsOrAdd(diff.BlockHash, &storedLayer{layer: diff, peer: peerID})
+}
+
+// Produce stores a diff layer produced by the local execution and propagates it to the peers.
+func (s *Store) Produce(diff *types.DiffLayer) {
+	s.rejected.Remove(diff.BlockHash)
+	s.layers.Add(diff.BlockHash, &storedLayer{layer: diff, local: true})
+	s.lock.RLock()
+	broadcast := s.broadcast
+	s.lock.RUnlock()
+	if broadcast != nil {
+		broadcast(diff)
+	}
+}
+
+// Get retrieves the diff layer of the block, nil if it is not known.
+func (s *Store) Get(blockHash common.Hash) *types.DiffLayer {
+	if v, ok := s.layers.Get(blockHash); ok {
+		return v.(*storedLayer).layer
+	}
+	return nil
+}
+
+// Has returns whether the diff layer of the block is known.
+func (s *Store) Has(blockHash common.Hash) bool {
+	return s.layers.Contains(blockHash)
+}
+
+// MarkApplied records that the state of the block was written from its diff layer, instead of
+// being executed, so that a wrong state root can be blamed on the diff layer.
+func (s *Store) MarkApplied(blockHash common.Hash) {
+	s.applied.Add(blockHash, struct{}{})
+}
+
+// Applied returns whether the state of the block was written from its diff layer.
+func (s *Store) Applied(blockHash common.Hash) bool {
+	return s.applied.Contains(blockHash)
+}
+
+// Reject forgets the diff layer of the block, because applying it failed or led to a wrong
+// state root, and disconnects the peer which sent it. The diff layer of the block is neither
+// requested nor accepted again, the block is executed instead.
+func (s *Store) Reject(blockHash common.Hash) {
+	s.applied.Remove(blockHash)
+	s.rejected.Add(blockHash, struct{}{})
+	v, ok := s.layers.Peek(blockHash)
+	if !ok {
+		return
+	}
+	s.layers.Remove(blockHash)
+	stored := v.(*storedLayer)
+	if stored.local {
+		return
+	}
+	s.lock.RLock()
+	penalize := s.penalize
+	s.lock.RUnlock()
+	if penalize != nil {
+		penalize(stored.peer)
+	}
+}
+
+// Request asks the peers for the diff layers of the blocks which are not known yet,
+// the responses are added to the store asynchronously.
+func (s *Store) Request(blockHashes []common.Hash) {
+	missing := make([]common.Hash, 0, len(blockHashes))
+	for _, hash := range blockHashes {
+		if !s.Has(hash) && !s.rejected.Contains(hash) {
+			missing = append(missing, hash)
+		}
+	}
+	s.lock.RLock()
+	request := s.request
+	s.lock.RUnlock()
+	if request != nil && len(missing) > 0 {
+		request(missing)
+	}
+}
+
+// WaitFor blocks until the diff layers of all the blocks are known, or the timeout expires.
+func (s *Store) WaitFor(ctx context.Context, blockHashes []common.Hash, timeout time.Duration) bool {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	poll := time.NewTicker(10 * time.Millisecond)
+	defer poll.Stop()
+	for {
+		known := true
+		for _, hash := range blockHashes {
+			if !s.Has(hash) {
+				known = false
+				break
+			}
+		}
+		if known {
+			return true
+		}
+		select {
+		case <-ctx.Done():
+			return false
+		case <-deadline.C:
+			return false
+		case <-poll.C:
+		}
+	}
+}
@@ -46,6 +46,11 @@ const (
 	// containing 200+ transactions nowadays, the practical limit will always
 	// be softResponseLimit.
 	maxReceiptsServe = 1024
+
+	// maxReceiptsRegenerate is the maximum number of blocks re-executed to serve
+	// the receipts which are not stored, as re-execution is much heavier than a
+	// disk lookup.
+	maxReceiptsRegenerate = 16
 )
 
 // NodeInfo represents a short summary of the `eth` sub-protocol metadata
@@ -128,6 +128,77 @@ func TestGetBlockReceipts(t *testing.T) {
 	}
 }
 
+func TestGetPrunedBlockReceipts(t *testing.T) {
+	acc1Key, _ := crypto.HexToECDSA("8a1f9a8f95be41cd7ccb6168179afb4504aefe388d1e14474d32c45c72ce7b7a")
+	acc1Addr := crypto.PubkeyToAddress(acc1Key.PublicKey)
+
+	signer := types.LatestSignerForChainID(nil)
+	m := mockWithGenerator(t, 3, func(i int, block *core.BlockGen) {
+		tx, _ := types.SignTx(types.NewTransaction(block.TxNonce(testAddr), acc1Addr, uint256.NewInt(1000), params.TxGas, nil, nil), *signer, testKey)
+		block.AddTx(tx)
+	})
+	if m.HistoryV2 {
+		t.Skip("GetReceiptsMsg disabled for historyV2")
+	}
+
+	var (
+		hashes   []common.Hash
+		receipts []rlp.RawValue
+	)
+	err := m.DB.View(m.Ctx, func(tx kv.Tx) error {
+		for i := uint64(1); i <= rawdb.ReadCurrentHeader(tx).Number.Uint64(); i++ {
+			hash, err := rawdb.ReadCanonicalHash(tx, i)
+			if err != nil {
+				return err
+			}
+			r, err := rawdb.ReadReceiptsByHash(tx, hash)
+			if err != nil {
+				return err
+			}
+			require.Len(t, r, 1)
+			encoded, err := rlp.EncodeToBytes(r)
+			require.NoError(t, err)
+			hashes = append(hashes, hash)
+			receipts = append(receipts, encoded)
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	// prune the receipts, they are regenerated to answer the request
+	err = m.DB.Update(m.Ctx, func(tx kv.RwTx) error {
+		return rawdb.TruncateReceipts(tx, 1)
+	})
+	require.NoError(t, err)
+
+	b, err := rlp.EncodeToBytes(eth.GetReceiptsPacket66{RequestId: 1, GetReceiptsPacket: hashes})
+	require.NoError(t, err)
+
+	m.StreamWg.Wait()
+
+	m.ReceiveWg.Add(1)
+	for _, err = range m.Send(&sentry.InboundMessage{Id: eth.ToProto[eth.ETH66][eth.GetReceiptsMsg], Data: b, PeerId: m.PeerId}) {
+		require.NoError(t, err)
+	}
+
+	expect, err := rlp.EncodeToBytes(eth.ReceiptsRLPPacket66{RequestId: 1, ReceiptsRLPPacket: receipts})
+	require.NoError(t, err)
+	m.ReceiveWg.Wait()
+	sent := m.SentMessage(0)
+	require.Equal(t, eth.ToProto[m.SentryClient.Protocol()][eth.ReceiptsMsg], sent.Id)
+	require.Equal(t, expect, sent.Data)
+
+	// receipts which don't match the header are not served
+	err = m.DB.View(m.Ctx, func(tx kv.Tx) error {
+		answer, err := eth.AnswerGetReceiptsQuery(tx, hashes, func(kv.Tx, common.Hash, uint64) (types.Receipts, error) {
+			return types.Receipts{{Status: types.ReceiptStatusFailed, CumulativeGasUsed: params.TxGas}}, nil
+		})
+		require.NoError(t, err)
+		require.Empty(t, answer)
+		return nil
+	})
+	require.NoError(t, err)
+}
+
 // newTestBackend creates a chain with a number of explicitly defined blocks and
 // wraps it into a mock backend.
 func mockWithGenerator(t *testing.T, blocks int, generator func(int, *core.BlockGen)) *stages.MockSentry {
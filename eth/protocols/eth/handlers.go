@@ -164,11 +164,17 @@ func AnswerGetBlockBodiesQuery(db kv.Tx, query GetBlockBodiesPacket) []rlp.RawVa
 	return bodies
 }
 
-func AnswerGetReceiptsQuery(db kv.Tx, query GetReceiptsPacket) ([]rlp.RawValue, error) { //nolint:unparam
+// ReceiptsRegenerator regenerates the receipts of a canonical block which are not stored.
+type ReceiptsRegenerator func(db kv.Tx, hash common.Hash, number uint64) (types.Receipts, error)
+
+// AnswerGetReceiptsQuery gathers the receipts of the requested blocks, regenerating the ones
+// which are not stored, e.g. pruned, with regenerate unless it's nil.
+func AnswerGetReceiptsQuery(db kv.Tx, query GetReceiptsPacket, regenerate ReceiptsRegenerator) ([]rlp.RawValue, error) { //nolint:unparam
 	// Gather state data until the fetch or network limits is reached
 	var (
-		bytes    int
-		receipts []rlp.RawValue
+		bytes       int
+		receipts    []rlp.RawValue
+		regenerated int
 	)
 	for lookups, hash := range query {
 		if bytes >= softResponseLimit || len(receipts) >= maxReceiptsServe ||
@@ -185,9 +191,31 @@ func AnswerGetReceiptsQuery(db kv.Tx, query GetReceiptsPacket) ([]rlp.RawValue,
 			if err != nil {
 				return nil, err
 			}
-			if header == nil || header.ReceiptHash != types.EmptyRootHash {
+			if header == nil {
 				continue
 			}
+			if header.ReceiptHash != types.EmptyRootHash {
+				if regenerate == nil || regenerated >= maxReceiptsRegenerate {
+					continue
+				}
+				canonical, err := rawdb.ReadCanonicalHash(db, header.Number.Uint64())
+				if err != nil {
+					return nil, err
+				}
+				if canonical != hash {
+					continue
+				}
+				regenerated++
+				if results, err = regenerate(db, hash, header.Number.Uint64()); err != nil {
+					log.Debug("Failed to regenerate receipts", "number", header.Number.Uint64(), "hash", hash, "err", err)
+					continue
+				}
+				// e.g. the system transactions of some consensus engines aren't executed as regular ones
+				if receiptHash := types.DeriveSha(results); receiptHash != header.ReceiptHash {
+					log.Debug("Regenerated receipts don't match the header", "number", header.Number.Uint64(), "hash", hash, "receiptHash", receiptHash, "expected", header.ReceiptHash)
+					continue
+				}
+			}
 		}
 		// If known, encode and queue for response packet
 		if encoded, err := rlp.EncodeToBytes(results); err != nil {
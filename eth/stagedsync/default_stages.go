@@ -8,7 +8,8 @@ import (
 	"github.com/ledgerwatch/erigon/ethdb/prune"
 )
 
-func DefaultStages(ctx context.Context, sm prune.Mode, headers HeadersCfg, cumulativeIndex CumulativeIndexCfg, blockHashCfg BlockHashesCfg, bodies BodiesCfg, issuance IssuanceCfg, senders SendersCfg, exec ExecuteBlockCfg, trans TranspileCfg, hashState HashStateCfg, trieCfg TrieCfg, history HistoryCfg, logIndex LogIndexCfg, callTraces CallTracesCfg, txLookup TxLookupCfg, finish FinishCfg, test bool) []*Stage {
+func DefaultStages(ctx context.Context, sm prune.Mode, headers HeadersCfg, cumulativeIndex CumulativeIndexCfg, blockHashCfg BlockHashesCfg, bodies BodiesCfg, issuance IssuanceCfg, senders SendersCfg, exec ExecuteBlockCfg, receipts ReceiptsDownloadCfg, trans TranspileCfg, postExec PostExecCfg, hashState HashStateCfg, trieCfg TrieCfg, history HistoryCfg, logIndex LogIndexCfg, callTraces CallTracesCfg, traces TracesCfg, txLookup TxLookupCfg, finish FinishCfg, test bool) []*Stage {
+	exec.keepReceipts = receipts.enabled && !bodies.historyV2
 	return []*Stage{
 		{
 			ID:          stages.Headers,
@@ -91,6 +92,21 @@ func DefaultStages(ctx context.Context, sm prune.Mode, headers HeadersCfg, cumul
 				return PruneExecutionStage(p, tx, exec, ctx, firstCycle)
 			},
 		},
+		{
+			ID:                  stages.ReceiptsDownload,
+			Description:         "Download receipts not written by the execution",
+			Disabled:            !receipts.enabled || bodies.historyV2,
+			DisabledDescription: "Enable by --sync.receipts.download",
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx) error {
+				return SpawnReceiptsDownload(s, tx, receipts, ctx)
+			},
+			Unwind: func(firstCycle bool, u *UnwindState, s *StageState, tx kv.RwTx) error {
+				return UnwindReceiptsDownload(u, tx, receipts, ctx)
+			},
+			Prune: func(firstCycle bool, p *PruneState, tx kv.RwTx) error {
+				return nil
+			},
+		},
 		{
 			ID:                  stages.Translation,
 			Description:         "Transpile marked EVM contracts to TEVM",
@@ -106,6 +122,19 @@ func DefaultStages(ctx context.Context, sm prune.Mode, headers HeadersCfg, cumul
 				return PruneTranspileStage(p, tx, trans, firstCycle, ctx)
 			},
 		},
+		{
+			ID:          stages.PostExec,
+			Description: "Verify the validators and system transactions",
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx) error {
+				return SpawnPostExecStage(s, u, tx, postExec, ctx)
+			},
+			Unwind: func(firstCycle bool, u *UnwindState, s *StageState, tx kv.RwTx) error {
+				return UnwindPostExecStage(u, s, tx, postExec, ctx)
+			},
+			Prune: func(firstCycle bool, p *PruneState, tx kv.RwTx) error {
+				return nil
+			},
+		},
 		{
 			ID:          stages.HashState,
 			Description: "Hash the key in the state",
@@ -190,6 +219,21 @@ func DefaultStages(ctx context.Context, sm prune.Mode, headers HeadersCfg, cumul
 				return PruneLogIndex(p, tx, logIndex, ctx)
 			},
 		},
+		{
+			ID:                  stages.Traces,
+			Description:         "Store the traces of the blocks",
+			Disabled:            !traces.enabled() || bodies.historyV2,
+			DisabledDescription: "Enable by --sync.traces",
+			Forward: func(firstCycle bool, badBlockUnwind bool, s *StageState, u Unwinder, tx kv.RwTx) error {
+				return SpawnTraces(s, tx, traces, ctx)
+			},
+			Unwind: func(firstCycle bool, u *UnwindState, s *StageState, tx kv.RwTx) error {
+				return UnwindTraces(u, s, tx, traces, ctx)
+			},
+			Prune: func(firstCycle bool, p *PruneState, tx kv.RwTx) error {
+				return PruneTraces(p, tx, traces, ctx)
+			},
+		},
 		{
 			ID:          stages.TxLookup,
 			Description: "Generate tx lookup index",
@@ -317,13 +361,16 @@ var DefaultForwardOrder = UnwindOrder{
 	// Stages below don't use Internet
 	stages.Senders,
 	stages.Execution,
+	stages.ReceiptsDownload,
 	stages.Translation,
+	stages.PostExec,
 	stages.HashState,
 	stages.IntermediateHashes,
 	stages.CallTraces,
 	stages.AccountHistoryIndex,
 	stages.StorageHistoryIndex,
 	stages.LogIndex,
+	stages.Traces,
 	stages.TxLookup,
 	stages.Finish,
 }
@@ -338,6 +385,7 @@ type PruneOrder []stages.SyncStage
 var DefaultUnwindOrder = UnwindOrder{
 	stages.Finish,
 	stages.TxLookup,
+	stages.Traces,
 	stages.LogIndex,
 	stages.StorageHistoryIndex,
 	stages.AccountHistoryIndex,
@@ -347,7 +395,9 @@ var DefaultUnwindOrder = UnwindOrder{
 	stages.HashState,
 	stages.IntermediateHashes,
 
+	stages.PostExec,
 	stages.Translation,
+	stages.ReceiptsDownload,
 	stages.Execution,
 	stages.Senders,
 
@@ -370,6 +420,7 @@ var StateUnwindOrder = UnwindOrder{
 var DefaultPruneOrder = PruneOrder{
 	stages.Finish,
 	stages.TxLookup,
+	stages.Traces,
 	stages.LogIndex,
 	stages.StorageHistoryIndex,
 	stages.AccountHistoryIndex,
@@ -379,7 +430,9 @@ var DefaultPruneOrder = PruneOrder{
 	stages.HashState,
 	stages.IntermediateHashes,
 
+	stages.PostExec,
 	stages.Translation,
+	stages.ReceiptsDownload,
 	stages.Execution,
 	stages.Senders,
 
@@ -33,6 +33,7 @@ import (
 	"github.com/ledgerwatch/erigon/core/types/accounts"
 	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/eth/calltracer"
+	"github.com/ledgerwatch/erigon/eth/protocols/diff"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/ethdb"
 	"github.com/ledgerwatch/erigon/ethdb/olddb"
@@ -80,6 +81,8 @@ type ExecuteBlockCfg struct {
 	genesis      *core.Genesis
 	agg          *libstate.Aggregator22
 	txNums       *exec22.TxNums
+	diffs        *diff.Store // diff layers of the BSC diffsync, nil if it is disabled
+	keepReceipts bool        // the receipts of the pruned blocks are downloaded by the ReceiptsDownload stage
 }
 
 func StageExecuteBlocksCfg(
@@ -102,6 +105,7 @@ func StageExecuteBlocksCfg(
 	workersCount int,
 	txNums *exec22.TxNums,
 	agg *libstate.Aggregator22,
+	diffs *diff.Store,
 ) ExecuteBlockCfg {
 	return ExecuteBlockCfg{
 		db:            db,
@@ -122,6 +126,7 @@ func StageExecuteBlocksCfg(
 		workersCount:  workersCount,
 		txNums:        txNums,
 		agg:           agg,
+		diffs:         diffs,
 	}
 }
 
@@ -192,6 +197,16 @@ func executeBlock(
 			cfg.changeSetHook(blockNum, hasChangeSet.ChangeSetWriter())
 		}
 	}
+	// Blocks executed at the tip of the chain are propagated to the diffsync peers
+	if cfg.diffs != nil && isPoSa && !initialCycle {
+		if hasChangeSet, ok := stateWriter.(HasChangeSetWriter); ok {
+			diffLayer, err := makeDiffLayer(block, receipts, hasChangeSet.ChangeSetWriter(), stateReader)
+			if err != nil {
+				return err
+			}
+			cfg.diffs.Produce(diffLayer)
+		}
+	}
 	if writeCallTraces {
 		return callTracer.WriteToDb(tx, block, *cfg.vmConfig)
 	}
@@ -391,6 +406,12 @@ func SpawnExecuteBlocksStage(s *StageState, u Unwinder, tx kv.RwTx, toBlock uint
 		log.Info(fmt.Sprintf("[%s] Blocks execution", logPrefix), "from", s.BlockNumber, "to", to)
 	}
 
+	if cfg.diffs != nil && !initialCycle && to <= s.BlockNumber+diffSyncMaxBlocks && callTracesPruned(cfg, to, to) {
+		if err = fetchDiffLayers(ctx, tx, cfg, s.BlockNumber+1, to); err != nil {
+			return err
+		}
+	}
+
 	startTime := time.Now()
 
 	var batch ethdb.DbWithPendingMutations
@@ -436,7 +457,7 @@ Loop:
 		if err != nil {
 			return err
 		}
-		block, _, err := cfg.blockReader.BlockWithSenders(ctx, tx, blockHash, blockNum)
+		block, senders, err := cfg.blockReader.BlockWithSenders(ctx, tx, blockHash, blockNum)
 		if err != nil {
 			return err
 		}
@@ -457,18 +478,33 @@ Loop:
 		writeChangeSets := nextStagesExpectData || blockNum > cfg.prune.History.PruneTo(to)
 		writeReceipts := nextStagesExpectData || blockNum > cfg.prune.Receipts.PruneTo(to)
 		writeCallTraces := nextStagesExpectData || blockNum > cfg.prune.CallTraces.PruneTo(to)
-		if err = executeBlock(block, tx, batch, cfg, *cfg.vmConfig, writeChangeSets, writeReceipts, writeCallTraces, contractHasTEVM, initialCycle, effectiveEngine); err != nil {
-			if !errors.Is(err, context.Canceled) {
-				log.Warn(fmt.Sprintf("[%s] Execution failed", logPrefix), "block", blockNum, "hash", block.Hash().String(), "err", err)
-				if cfg.hd != nil {
-					cfg.hd.ReportBadHeaderPoS(blockHash, block.ParentHash())
+		lightProcessed := false
+		if cfg.diffs != nil && to-blockNum < diffSyncMaxBlocks && callTracesPruned(cfg, blockNum, to) {
+			if diffLayer := cfg.diffs.Get(blockHash); diffLayer != nil && canLightProcess(cfg, tx, block.Header()) {
+				if err = lightExecuteBlock(block, senders, diffLayer, tx, batch, cfg, writeChangeSets, writeReceipts, initialCycle); err != nil {
+					// the diff layer is validated before it is written, so the block can still be executed
+					log.Warn(fmt.Sprintf("[%s] Light processing failed", logPrefix), "block", blockNum, "hash", blockHash, "err", err)
+					cfg.diffs.Reject(blockHash)
+				} else {
+					cfg.diffs.MarkApplied(blockHash)
+					lightProcessed = true
 				}
-				if cfg.badBlockHalt {
-					return err
+			}
+		}
+		if !lightProcessed {
+			if err = executeBlock(block, tx, batch, cfg, *cfg.vmConfig, writeChangeSets, writeReceipts, writeCallTraces, contractHasTEVM, initialCycle, effectiveEngine); err != nil {
+				if !errors.Is(err, context.Canceled) {
+					log.Warn(fmt.Sprintf("[%s] Execution failed", logPrefix), "block", blockNum, "hash", block.Hash().String(), "err", err)
+					if cfg.hd != nil {
+						cfg.hd.ReportBadHeaderPoS(blockHash, block.ParentHash())
+					}
+					if cfg.badBlockHalt {
+						return err
+					}
 				}
+				u.UnwindTo(blockNum-1, block.Hash())
+				break Loop
 			}
-			u.UnwindTo(blockNum-1, block.Hash())
-			break Loop
 		}
 		stageProgress = blockNum
 
@@ -634,10 +670,17 @@ func unwindExecutionStage(u *UnwindState, s *StageState, tx kv.RwTx, ctx context
 					return err
 				}
 
+				// the change set keeps the code hash when the account was deleted or its code replaced in the incarnation
+				codeHashKept := acc.Incarnation > 0 && !acc.IsEmptyCodeHash()
 				// Fetch the code hash
 				recoverCodeHashPlain(&acc, tx, k)
 				var address commonold.Address
 				copy(address[:], k)
+				if codeHashKept {
+					if err := tx.Put(kv.PlainContractCode, dbutils.PlainGenerateStoragePrefix(address[:], acc.Incarnation), acc.CodeHash[:]); err != nil {
+						return fmt.Errorf("restore code hash of %x: %w", address, err)
+					}
+				}
 
 				// cleanup contract code bucket
 				original, err := state.NewPlainStateReader(tx).ReadAccountData(address)
@@ -765,7 +808,7 @@ func PruneExecutionStage(s *PruneState, tx kv.RwTx, cfg ExecuteBlockCfg, ctx con
 		}
 	}
 
-	if cfg.prune.Receipts.Enabled() {
+	if cfg.prune.Receipts.Enabled() && !cfg.keepReceipts {
 		if err = rawdb.PruneTable(tx, kv.Receipts, cfg.prune.Receipts.PruneTo(s.ForwardProgress), ctx, math.MaxInt32); err != nil {
 			return err
 		}
@@ -0,0 +1,421 @@
+package stagedsync
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/common/length"
+	"github.com/ledgerwatch/erigon-lib/kv"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/eth/protocols/diff"
+	"github.com/ledgerwatch/erigon/ethdb"
+	"github.com/ledgerwatch/erigon/rlp"
+)
+
+// BSC nodes propagate the state changes of the blocks as diff layers (see eth/protocols/diff), so that
+// nodes which are not validators can apply a block without executing it. The light-applied state is
+// checked against the header by the IntermediateHashes stage, like the executed one: on a wrong root
+// the diff layers are rejected and the blocks are executed again, see rejectDiffLayers.
+// The call traces can't be restored from a diff layer, so only the blocks whose call traces are pruned
+// anyway, e.g. by --prune.c.before beyond the head of the chain, are light processed.
+// Light processing skips the checks of Finalize: the system transactions and the slashing of the
+// spoiled validator are only covered by the state root, and the epoch blocks, whose validators Finalize
+// checks against the validator contract, are always executed (see consensus.PoSA.AllowLightProcess).
+// It's limited to the last diffSyncMaxBlocks blocks of the execution, i.e. to following the tip.
+
+const (
+	diffSyncMaxBlocks = 16                     // light processing is only tried when following the tip of the chain
+	diffWaitTimeout   = 200 * time.Millisecond // how long to wait for the requested diff layers
+)
+
+// fetchDiffLayers asks the peers for the diff layers of blocks [from, to] which are not known yet
+// and waits for them for a short while.
+func fetchDiffLayers(ctx context.Context, tx kv.RwTx, cfg ExecuteBlockCfg, from, to uint64) error {
+	hashes := make([]common.Hash, 0, to-from+1)
+	for blockNum := from; blockNum <= to; blockNum++ {
+		hash, err := rawdb.ReadCanonicalHash(tx, blockNum)
+		if err != nil {
+			return err
+		}
+		hashes = append(hashes, hash)
+	}
+	header, err := cfg.blockReader.Header(ctx, tx, hashes[len(hashes)-1], to)
+	if err != nil {
+		return err
+	}
+	if header == nil || !canLightProcess(cfg, tx, header) {
+		return nil
+	}
+	cfg.diffs.Request(hashes)
+	cfg.diffs.WaitFor(ctx, hashes, diffWaitTimeout)
+	return nil
+}
+
+// callTracesPruned returns whether the call traces of the block are pruned as soon as they are indexed.
+func callTracesPruned(cfg ExecuteBlockCfg, blockNum, to uint64) bool {
+	return cfg.prune.CallTraces.Enabled() && blockNum <= cfg.prune.CallTraces.PruneTo(to)
+}
+
+// rejectDiffLayers rejects the diff layers of the blocks [from, to] whose state was written from them,
+// which disconnects the peers they came from, and returns the first of those blocks, 0 if there is none.
+func rejectDiffLayers(tx kv.Tx, diffs *diff.Store, from, to uint64) (uint64, error) {
+	if diffs == nil {
+		return 0, nil
+	}
+	var first uint64
+	for blockNum := from; blockNum <= to; blockNum++ {
+		hash, err := rawdb.ReadCanonicalHash(tx, blockNum)
+		if err != nil {
+			return 0, err
+		}
+		if !diffs.Applied(hash) {
+			continue
+		}
+		diffs.Reject(hash)
+		if first == 0 {
+			first = blockNum
+		}
+	}
+	return first, nil
+}
+
+// canLightProcess returns whether the block may be applied from a diff layer, instead of being executed.
+func canLightProcess(cfg ExecuteBlockCfg, tx kv.RwTx, header *types.Header) bool {
+	if cfg.diffs == nil {
+		return false
+	}
+	posa, ok := cfg.engine.(consensus.PoSA)
+	if !ok {
+		return false
+	}
+	return posa.AllowLightProcess(ChainReader{Cfg: *cfg.chainConfig, Db: tx}, header)
+}
+
+// lightExecuteBlock applies the diff layer of the block received from the peers, writing the state,
+// the change sets and the receipts the same way as executeBlock does, but not the call traces.
+func lightExecuteBlock(
+	block *types.Block,
+	senders []common.Address,
+	diff *types.DiffLayer,
+	tx kv.RwTx,
+	batch ethdb.Database,
+	cfg ExecuteBlockCfg,
+	writeChangesets bool,
+	writeReceipts bool,
+	initialCycle bool,
+) error {
+	receipts, err := diffReceipts(block, senders, diff)
+	if err != nil {
+		return err
+	}
+	stateReader, stateWriter, err := newStateReaderWriter(batch, tx, block, writeChangesets, cfg.accumulator, initialCycle, cfg.stateStream)
+	if err != nil {
+		return err
+	}
+	if err = applyDiffLayer(diff, stateReader, stateWriter); err != nil {
+		return err
+	}
+	if err = stateWriter.WriteChangeSets(); err != nil {
+		return fmt.Errorf("writing changesets for block %d failed: %w", block.NumberU64(), err)
+	}
+	if writeReceipts {
+		if err = rawdb.AppendReceipts(tx, block.NumberU64(), receipts); err != nil {
+			return err
+		}
+	}
+	if cfg.changeSetHook != nil {
+		if hasChangeSet, ok := stateWriter.(HasChangeSetWriter); ok {
+			cfg.changeSetHook(block.NumberU64(), hasChangeSet.ChangeSetWriter())
+		}
+	}
+	return nil
+}
+
+// diffReceipts restores the receipts of the diff layer and checks them against the header.
+func diffReceipts(block *types.Block, senders []common.Address, diff *types.DiffLayer) (types.Receipts, error) {
+	txs := block.Transactions()
+	if diff.BlockHash != block.Hash() || diff.Number != block.NumberU64() {
+		return nil, fmt.Errorf("diff layer of block %d(%x) doesn't belong to block %d(%x)", diff.Number, diff.BlockHash, block.NumberU64(), block.Hash())
+	}
+	if len(diff.Receipts) != len(txs) {
+		return nil, fmt.Errorf("diff layer of block %d has %d receipts for %d transactions", block.NumberU64(), len(diff.Receipts), len(txs))
+	}
+	receipts := make(types.Receipts, len(diff.Receipts))
+	for i, r := range diff.Receipts {
+		receipt := r.Copy()
+		receipt.Type = txs[i].Type()
+		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
+		receipts[i] = receipt
+	}
+	if err := receipts.DeriveFields(block.Hash(), block.NumberU64(), txs, senders); err != nil {
+		return nil, err
+	}
+	if receiptSha := types.DeriveSha(receipts); receiptSha != block.ReceiptHash() {
+		return nil, fmt.Errorf("mismatched receipt headers for block %d (%s != %s)", block.NumberU64(), receiptSha.Hex(), block.ReceiptHash().Hex())
+	}
+	if len(receipts) > 0 && receipts[len(receipts)-1].CumulativeGasUsed != block.GasUsed() {
+		return nil, fmt.Errorf("gas used by diff layer: %d, in header: %d", receipts[len(receipts)-1].CumulativeGasUsed, block.GasUsed())
+	}
+	if bloom := types.CreateBloom(receipts); bloom != block.Bloom() {
+		return nil, fmt.Errorf("bloom computed by diff layer: %x, in header: %x", bloom, block.Bloom())
+	}
+	return receipts, nil
+}
+
+// applyDiffLayer writes the state changes of the diff layer through the state writer, the original
+// values are read from the state reader so that the change sets are the same as of the execution.
+// The diff layer is fully validated before the first write, so a malformed one leaves the state intact.
+func applyDiffLayer(diff *types.DiffLayer, stateReader state.StateReader, stateWriter state.WriterWithChangeSets) error {
+	codes := make(map[common.Hash][]byte, len(diff.Codes))
+	for _, code := range diff.Codes {
+		codes[code.Hash] = code.Code
+	}
+	hasStorage := make(map[common.Address]bool, len(diff.Storages))
+	for _, storage := range diff.Storages {
+		hasStorage[storage.Account] = true
+	}
+	destructed := make(map[common.Address]bool, len(diff.Destructs))
+	for _, address := range diff.Destructs {
+		destructed[address] = true
+	}
+
+	type accountUpdate struct {
+		address           common.Address
+		original, account *accounts.Account
+		create, newCode   bool
+	}
+	updates := make([]accountUpdate, 0, len(diff.Accounts))
+	incarnations := make(map[common.Address]uint64, len(diff.Accounts))
+	for _, diffAccount := range diff.Accounts {
+		address := diffAccount.Account
+		var data types.DiffAccountData
+		if err := rlp.DecodeBytes(diffAccount.Blob, &data); err != nil {
+			return fmt.Errorf("decoding account %x: %w", address, err)
+		}
+		original, err := stateReader.ReadAccountData(address)
+		if err != nil {
+			return err
+		}
+		account := accounts.NewAccount()
+		account.Nonce = data.Nonce
+		if data.Balance != nil {
+			if overflow := account.Balance.SetFromBig(data.Balance); overflow {
+				return fmt.Errorf("balance of account %x overflows", address)
+			}
+		}
+		if len(data.CodeHash) > 0 {
+			account.CodeHash = common.BytesToHash(data.CodeHash)
+		}
+		update := accountUpdate{address: address, original: original, account: &account}
+		if original == nil {
+			update.original = &accounts.Account{}
+		} else if !destructed[address] {
+			account.Incarnation = original.Incarnation
+		}
+		// Like in IntraBlockState.CommitBlock, recreated contracts are not deleted but get a new incarnation
+		update.newCode = !account.IsEmptyCodeHash() && (original == nil || destructed[address] || original.CodeHash != account.CodeHash)
+		if account.Incarnation == 0 && (update.newCode || hasStorage[address] || destructed[address]) {
+			prevIncarnation, err := stateReader.ReadAccountIncarnation(address)
+			if err != nil {
+				return err
+			}
+			if original != nil && original.Incarnation > prevIncarnation {
+				prevIncarnation = original.Incarnation
+			}
+			account.Incarnation = prevIncarnation + 1
+			update.create = true
+		}
+		if _, ok := codes[account.CodeHash]; update.newCode && !ok {
+			return fmt.Errorf("missing code %x of account %x", account.CodeHash, address)
+		}
+		incarnations[address] = account.Incarnation
+		updates = append(updates, update)
+	}
+
+	type storageUpdate struct {
+		address         common.Address
+		incarnation     uint64
+		key             common.Hash
+		original, value uint256.Int
+	}
+	var storageUpdates []storageUpdate
+	for _, storage := range diff.Storages {
+		address := storage.Account
+		if len(storage.Keys) != len(storage.Vals) {
+			return fmt.Errorf("storage of account %x has %d keys but %d values", address, len(storage.Keys), len(storage.Vals))
+		}
+		incarnation, ok := incarnations[address]
+		if !ok {
+			account, err := stateReader.ReadAccountData(address)
+			if err != nil {
+				return err
+			}
+			if account == nil || destructed[address] {
+				return fmt.Errorf("storage of missing account %x", address)
+			}
+			incarnation = account.Incarnation
+		}
+		for i, k := range storage.Keys {
+			if len(k) != length.Hash {
+				return fmt.Errorf("invalid storage key %x of account %x", k, address)
+			}
+			update := storageUpdate{address: address, incarnation: incarnation, key: common.BytesToHash([]byte(k))}
+			enc, err := stateReader.ReadAccountStorage(address, incarnation, &update.key)
+			if err != nil {
+				return err
+			}
+			update.original.SetBytes(enc)
+			if len(storage.Vals[i]) > 0 {
+				var val []byte
+				if err = rlp.DecodeBytes(storage.Vals[i], &val); err != nil {
+					return fmt.Errorf("decoding storage %x of account %x: %w", update.key, address, err)
+				}
+				if len(val) > length.Hash {
+					return fmt.Errorf("invalid storage %x of account %x", update.key, address)
+				}
+				update.value.SetBytes(val)
+			}
+			storageUpdates = append(storageUpdates, update)
+		}
+	}
+
+	for _, address := range diff.Destructs {
+		if _, ok := incarnations[address]; ok {
+			continue
+		}
+		original, err := stateReader.ReadAccountData(address)
+		if err != nil {
+			return err
+		}
+		if original == nil {
+			continue
+		}
+		if err = stateWriter.DeleteAccount(address, original); err != nil {
+			return err
+		}
+	}
+	for _, update := range updates {
+		if !update.create {
+			continue
+		}
+		if err := stateWriter.CreateContract(update.address); err != nil {
+			return err
+		}
+	}
+	// Storage is written before the accounts, as IntraBlockState.CommitBlock does
+	for i := range storageUpdates {
+		update := &storageUpdates[i]
+		if err := stateWriter.WriteAccountStorage(update.address, update.incarnation, &update.key, &update.original, &update.value); err != nil {
+			return err
+		}
+	}
+	for _, update := range updates {
+		if err := stateWriter.UpdateAccountData(update.address, update.original, update.account); err != nil {
+			return err
+		}
+		if !update.newCode {
+			continue
+		}
+		if err := stateWriter.UpdateAccountCode(update.address, update.account.Incarnation, update.account.CodeHash, codes[update.account.CodeHash]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// makeDiffLayer builds the diff layer of an executed block from its change sets, the values after
+// the block are read from the state reader.
+func makeDiffLayer(block *types.Block, receipts types.Receipts, csw *state.ChangeSetWriter, stateReader state.StateReader) (*types.DiffLayer, error) {
+	accountChanges, err := csw.GetAccountChanges()
+	if err != nil {
+		return nil, err
+	}
+	storageChanges, err := csw.GetStorageChanges()
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(accountChanges)
+	sort.Sort(storageChanges)
+
+	diff := &types.DiffLayer{BlockHash: block.Hash(), Number: block.NumberU64(), Receipts: receipts}
+	incarnations := make(map[common.Address]uint64, accountChanges.Len())
+	for _, change := range accountChanges.Changes {
+		address := common.BytesToAddress(change.Key)
+		account, err := stateReader.ReadAccountData(address)
+		if err != nil {
+			return nil, err
+		}
+		var original accounts.Account
+		if len(change.Value) > 0 {
+			if err = original.DecodeForStorage(change.Value); err != nil {
+				return nil, err
+			}
+		}
+		if account == nil {
+			diff.Destructs = append(diff.Destructs, address)
+			continue
+		}
+		if len(change.Value) > 0 && original.Incarnation > 0 && original.Incarnation != account.Incarnation {
+			// the contract was destructed and created again
+			diff.Destructs = append(diff.Destructs, address)
+		}
+		incarnations[address] = account.Incarnation
+
+		data := types.DiffAccountData{Nonce: account.Nonce, Balance: account.Balance.ToBig()}
+		if !account.IsEmptyCodeHash() {
+			data.CodeHash = account.CodeHash.Bytes()
+		}
+		blob, err := rlp.EncodeToBytes(&data)
+		if err != nil {
+			return nil, err
+		}
+		diff.Accounts = append(diff.Accounts, types.DiffAccount{Account: address, Blob: blob})
+
+		// the change set keeps the code hash when the code is replaced in the incarnation, by a system contract upgrade
+		codeReplaced := !original.IsEmptyCodeHash() && original.CodeHash != account.CodeHash
+		if !account.IsEmptyCodeHash() && (len(change.Value) == 0 || original.Incarnation != account.Incarnation || codeReplaced) {
+			code, err := stateReader.ReadAccountCode(address, account.Incarnation, account.CodeHash)
+			if err != nil {
+				return nil, err
+			}
+			diff.Codes = append(diff.Codes, types.DiffCode{Hash: account.CodeHash, Code: code})
+		}
+	}
+
+	for _, change := range storageChanges.Changes {
+		address := common.BytesToAddress(change.Key[:length.Addr])
+		incarnation, ok := incarnations[address]
+		if !ok || incarnation != binary.BigEndian.Uint64(change.Key[length.Addr:]) {
+			// storage of a destructed contract
+			continue
+		}
+		key := common.BytesToHash(change.Key[length.Addr+length.Incarnation:])
+		value, err := stateReader.ReadAccountStorage(address, incarnation, &key)
+		if err != nil {
+			return nil, err
+		}
+		var val []byte
+		if len(value) > 0 {
+			if val, err = rlp.EncodeToBytes(common.TrimLeftZeroes(value)); err != nil {
+				return nil, err
+			}
+		}
+		if n := len(diff.Storages); n == 0 || diff.Storages[n-1].Account != address {
+			diff.Storages = append(diff.Storages, types.DiffStorage{Account: address})
+		}
+		storage := &diff.Storages[len(diff.Storages)-1]
+		storage.Keys = append(storage.Keys, string(key.Bytes()))
+		storage.Vals = append(storage.Vals, val)
+	}
+	return diff, nil
+}
@@ -0,0 +1,158 @@
+package stagedsync
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/eth/protocols/diff"
+	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyDiffLayer(t *testing.T) {
+	require := require.New(t)
+	_, tx1 := memdb.NewTestTx(t)
+	_, tx2 := memdb.NewTestTx(t)
+
+	eoa, fresh := common.Address{0x01}, common.Address{0x02}
+	changed, destructed, recreated := common.Address{0x03}, common.Address{0x04}, common.Address{0x05}
+	upgraded := common.Address{0x06}
+	slot1, slot2 := common.Hash{0x10}, common.Hash{0x20}
+
+	newContract := func(balance uint64, incarnation uint64, code []byte) *accounts.Account {
+		acc := accounts.NewAccount()
+		acc.Initialised = true
+		acc.Balance.SetUint64(balance)
+		acc.Incarnation = incarnation
+		if code != nil {
+			acc.CodeHash, _ = common.HashData(code)
+		}
+		return &acc
+	}
+	deploy := func(w *state.PlainStateWriter, address common.Address, acc *accounts.Account, code []byte, storage map[common.Hash]uint64) {
+		require.NoError(w.CreateContract(address))
+		for key, value := range storage {
+			key := key
+			require.NoError(w.WriteAccountStorage(address, acc.Incarnation, &key, new(uint256.Int), uint256.NewInt(value)))
+		}
+		require.NoError(w.UpdateAccountData(address, &accounts.Account{}, acc))
+		require.NoError(w.UpdateAccountCode(address, acc.Incarnation, acc.CodeHash, code))
+	}
+
+	// block 1 is the same on both sides
+	eoa1, changed1 := newContract(10, 0, nil), newContract(0, 1, []byte("changed"))
+	destructed1, recreated1 := newContract(0, 1, []byte("destructed")), newContract(0, 1, []byte("recreated-1"))
+	upgraded1 := newContract(0, 1, []byte("upgraded-1"))
+	for _, tx := range []kv.RwTx{tx1, tx2} {
+		w := state.NewPlainStateWriter(tx, tx, 1)
+		require.NoError(w.UpdateAccountData(eoa, &accounts.Account{}, eoa1))
+		deploy(w, changed, changed1, []byte("changed"), map[common.Hash]uint64{slot1: 5, slot2: 6})
+		deploy(w, destructed, destructed1, []byte("destructed"), map[common.Hash]uint64{slot1: 7})
+		deploy(w, recreated, recreated1, []byte("recreated-1"), map[common.Hash]uint64{slot1: 8})
+		deploy(w, upgraded, upgraded1, []byte("upgraded-1"), nil)
+		require.NoError(w.WriteChangeSets())
+	}
+
+	// block 2 is executed on the first side
+	w := state.NewPlainStateWriter(tx1, tx1, 2)
+	eoa2 := eoa1.SelfCopy()
+	eoa2.Nonce = 1
+	eoa2.Balance.SetUint64(8)
+	require.NoError(w.UpdateAccountData(eoa, eoa1, eoa2))
+	require.NoError(w.UpdateAccountData(fresh, &accounts.Account{}, newContract(2, 0, nil)))
+	require.NoError(w.WriteAccountStorage(changed, 1, &slot1, uint256.NewInt(5), new(uint256.Int)))
+	require.NoError(w.WriteAccountStorage(changed, 1, &slot2, uint256.NewInt(6), uint256.NewInt(0x100)))
+	changed2 := changed1.SelfCopy()
+	changed2.Balance.SetUint64(1)
+	require.NoError(w.UpdateAccountData(changed, changed1, changed2))
+	require.NoError(w.DeleteAccount(destructed, destructed1))
+	require.NoError(w.CreateContract(recreated))
+	require.NoError(w.WriteAccountStorage(recreated, 2, &slot1, new(uint256.Int), uint256.NewInt(9)))
+	recreated2 := newContract(0, 2, []byte("recreated-2"))
+	require.NoError(w.UpdateAccountData(recreated, recreated1, recreated2))
+	require.NoError(w.UpdateAccountCode(recreated, 2, recreated2.CodeHash, []byte("recreated-2")))
+	// the code of a system contract is replaced in its incarnation
+	upgraded2 := newContract(0, 1, []byte("upgraded-2"))
+	require.NoError(w.UpdateAccountData(upgraded, upgraded1, upgraded2))
+	require.NoError(w.UpdateAccountCode(upgraded, 1, upgraded2.CodeHash, []byte("upgraded-2")))
+
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(2)})
+	diff, err := makeDiffLayer(block, nil, w.ChangeSetWriter(), state.NewPlainStateReader(tx1))
+	require.NoError(err)
+	require.NoError(w.WriteChangeSets())
+	require.Equal([]common.Address{destructed, recreated}, diff.Destructs)
+	require.Len(diff.Accounts, 5)
+	require.Len(diff.Codes, 2)
+
+	enc, err := rlp.EncodeToBytes(diff)
+	require.NoError(err)
+	decoded := new(types.DiffLayer)
+	require.NoError(rlp.DecodeBytes(enc, decoded))
+
+	// a diff layer with a missing code is rejected before anything is written
+	broken := *decoded
+	broken.Codes = nil
+	w = state.NewPlainStateWriter(tx2, tx2, 2)
+	require.Error(applyDiffLayer(&broken, state.NewPlainStateReader(tx2), w))
+	require.NoError(w.WriteChangeSets())
+	acc, err := state.NewPlainStateReader(tx2).ReadAccountData(eoa)
+	require.NoError(err)
+	require.Equal(uint64(10), acc.Balance.Uint64())
+	var changes int
+	require.NoError(tx2.ForEach(kv.AccountChangeSet, nil, func(k, v []byte) error {
+		changes++
+		return nil
+	}))
+	require.Equal(5, changes) // only block 1
+
+	// block 2 is applied from the diff layer on the second side
+	w = state.NewPlainStateWriter(tx2, tx2, 2)
+	require.NoError(applyDiffLayer(decoded, state.NewPlainStateReader(tx2), w))
+	require.NoError(w.WriteChangeSets())
+
+	compareCurrentState(t, tx1, tx2, kv.PlainState, kv.PlainContractCode, kv.Code, kv.IncarnationMap, kv.AccountChangeSet, kv.StorageChangeSet)
+}
+
+func TestRejectDiffLayers(t *testing.T) {
+	require := require.New(t)
+	_, tx := memdb.NewTestTx(t)
+
+	var penalized [][64]byte
+	store := diff.NewStore(diff.DefaultStoreLimit)
+	store.SetNetwork(nil, nil, func(peerID [64]byte) { penalized = append(penalized, peerID) })
+
+	peer := [64]byte{0x01}
+	hashes := make([]common.Hash, 4)
+	for i := range hashes {
+		hashes[i] = common.Hash{byte(i + 1)}
+		require.NoError(rawdb.WriteCanonicalHash(tx, hashes[i], uint64(i+1)))
+		store.Add(&types.DiffLayer{BlockHash: hashes[i], Number: uint64(i + 1)}, peer)
+	}
+	store.MarkApplied(hashes[1])
+	store.MarkApplied(hashes[2])
+
+	first, err := rejectDiffLayers(tx, store, 1, 4)
+	require.NoError(err)
+	require.Equal(uint64(2), first)
+	require.Equal([][64]byte{peer, peer}, penalized)
+	require.Nil(store.Get(hashes[1]))
+	require.False(store.Applied(hashes[1]))
+	require.NotNil(store.Get(hashes[0]))
+
+	// a rejected diff layer is not accepted again, the block is executed instead
+	store.Add(&types.DiffLayer{BlockHash: hashes[1], Number: 2}, peer)
+	require.Nil(store.Get(hashes[1]))
+
+	first, err = rejectDiffLayers(tx, store, 1, 4)
+	require.NoError(err)
+	require.Zero(first)
+	require.Len(penalized, 2)
+}
@@ -51,7 +51,6 @@ func TestUnwindExecutionStagePlainWithIncarnationChanges(t *testing.T) {
 }
 
 func TestUnwindExecutionStagePlainWithCodeChanges(t *testing.T) {
-	t.Skip("not supported yet, to be restored")
 	ctx := context.Background()
 	_, tx1 := memdb.NewTestTx(t)
 	_, tx2 := memdb.NewTestTx(t)
@@ -17,8 +17,11 @@ import (
 	"github.com/ledgerwatch/erigon/common/dbutils"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/ethdb/cbor"
+	"github.com/ledgerwatch/erigon/ethdb/privateapi"
 	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rlp"
 	"github.com/ledgerwatch/erigon/turbo/engineapi"
 	"github.com/ledgerwatch/log/v3"
 )
@@ -129,6 +132,47 @@ func PruneFinish(u *PruneState, tx kv.RwTx, cfg FinishCfg, ctx context.Context)
 	return nil
 }
 
+// NotifySyncProgress sends the sync progress, as reported by eth_syncing, to the rpcdaemon
+// "syncing" subscriptions. The starting block is the one of the first cycle of the sync, kept
+// until the node catches up.
+func NotifySyncProgress(notifications *Notifications, tx kv.Tx) error {
+	if notifications == nil || notifications.Events == nil {
+		return nil
+	}
+	highestBlock, err := stages.GetStageProgress(tx, stages.Headers)
+	if err != nil {
+		return err
+	}
+	currentBlock, err := stages.GetStageProgress(tx, stages.Finish)
+	if err != nil {
+		return err
+	}
+	syncing := currentBlock == 0 || currentBlock < highestBlock
+	if syncing && !notifications.syncing {
+		notifications.startingBlock = currentBlock
+	}
+	notifications.syncing = syncing
+	progress := privateapi.SyncProgress{
+		Syncing:       syncing,
+		StartingBlock: notifications.startingBlock,
+		CurrentBlock:  currentBlock,
+		HighestBlock:  highestBlock,
+		Stages:        make([]privateapi.StageProgress, len(stages.AllStages)),
+	}
+	for i, stage := range stages.AllStages {
+		if progress.Stages[i].BlockNumber, err = stages.GetStageProgress(tx, stage); err != nil {
+			return err
+		}
+		progress.Stages[i].Stage = string(stage)
+	}
+	progressRlp, err := rlp.EncodeToBytes(&progress)
+	if err != nil {
+		return err
+	}
+	notifications.Events.OnSyncProgress(progressRlp)
+	return nil
+}
+
 func NotifyNewHeaders(ctx context.Context, finishStageBeforeSync uint64, finishStageAfterSync uint64, unwindTo *uint64, notifier ChainEventNotifier, tx kv.Tx) error {
 	t := time.Now()
 	if notifier == nil {
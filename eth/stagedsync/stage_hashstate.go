@@ -475,17 +475,19 @@ func getCodeUnwindExtractFunc(db kv.Tx, changeSetBucket string) etl.ExtractFunc
 		var (
 			newK     []byte
 			codeHash []byte
+			acc      accounts.Account
 		)
-		incarnation, err := accounts.DecodeIncarnationFromStorage(v)
-		if err != nil {
+		if err = acc.DecodeForStorage(v); err != nil {
 			return err
 		}
-		if incarnation == 0 {
+		if acc.Incarnation == 0 {
 			return nil
 		}
-		plainKey := dbutils.PlainGenerateStoragePrefix(k, incarnation)
-		codeHash, err = db.GetOne(kv.PlainContractCode, plainKey)
-		if err != nil {
+		plainKey := dbutils.PlainGenerateStoragePrefix(k, acc.Incarnation)
+		if !acc.IsEmptyCodeHash() {
+			// the change set keeps the code hash when the account was deleted or its code replaced in the incarnation
+			codeHash = acc.CodeHash.Bytes()
+		} else if codeHash, err = db.GetOne(kv.PlainContractCode, plainKey); err != nil {
 			return fmt.Errorf("getCodeUnwindExtractFunc: %w, key=%x", err, plainKey)
 		}
 		newK, err = transformContractCodeKey(plainKey)
@@ -18,7 +18,9 @@ import (
 	"github.com/ledgerwatch/erigon/common/math"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/eth/protocols/diff"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/node/nodecfg/datadir"
 	"github.com/ledgerwatch/erigon/turbo/services"
 	"github.com/ledgerwatch/erigon/turbo/stages/headerdownload"
 	"github.com/ledgerwatch/erigon/turbo/trie"
@@ -34,6 +36,7 @@ type TrieCfg struct {
 	saveNewHashesToDB bool // no reason to save changes when calculating root for mining
 	blockReader       services.FullBlockReader
 	hd                *headerdownload.HeaderDownload
+	diffs             *diff.Store // diff layers of the BSC diffsync, nil if it is disabled
 
 	historyV2 bool
 	txNums    *exec22.TxNums
@@ -41,7 +44,7 @@ type TrieCfg struct {
 }
 
 func StageTrieCfg(db kv.RwDB, checkRoot, saveNewHashesToDB, badBlockHalt bool, tmpDir string, blockReader services.FullBlockReader, hd *headerdownload.HeaderDownload,
-	historyV2 bool, txNums *exec22.TxNums, agg *state.Aggregator22) TrieCfg {
+	diffs *diff.Store, historyV2 bool, txNums *exec22.TxNums, agg *state.Aggregator22) TrieCfg {
 	return TrieCfg{
 		db:                db,
 		checkRoot:         checkRoot,
@@ -50,6 +53,7 @@ func StageTrieCfg(db kv.RwDB, checkRoot, saveNewHashesToDB, badBlockHalt bool, t
 		badBlockHalt:      badBlockHalt,
 		blockReader:       blockReader,
 		hd:                hd,
+		diffs:             diffs,
 
 		historyV2: historyV2,
 		txNums:    txNums,
@@ -112,16 +116,25 @@ func SpawnIntermediateHashesStage(s *StageState, u Unwinder, tx kv.RwTx, cfg Tri
 
 	if cfg.checkRoot && root != expectedRootHash {
 		log.Error(fmt.Sprintf("[%s] Wrong trie root of block %d: %x, expected (from header): %x. Block hash: %x", logPrefix, to, root, expectedRootHash, headerHash))
-		if cfg.badBlockHalt {
-			return trie.EmptyRoot, fmt.Errorf("wrong trie root")
-		}
-		if cfg.hd != nil {
-			cfg.hd.ReportBadHeaderPoS(headerHash, syncHeadHeader.ParentHash)
+		// The blocks applied from the diff layers of the peers are executed again before blaming the header
+		var lightFrom uint64
+		if lightFrom, err = rejectDiffLayers(tx, cfg.diffs, s.BlockNumber+1, to); err != nil {
+			return trie.EmptyRoot, err
 		}
-		if to > s.BlockNumber {
-			unwindTo := (to + s.BlockNumber) / 2 // Binary search for the correct block, biased to the lower numbers
-			log.Warn("Unwinding due to incorrect root hash", "to", unwindTo)
-			u.UnwindTo(unwindTo, headerHash)
+		if lightFrom > 0 {
+			log.Warn("Unwinding to execute the blocks applied from diff layers", "to", lightFrom-1)
+			u.UnwindTo(lightFrom-1, common.Hash{})
+		} else if cfg.badBlockHalt {
+			return trie.EmptyRoot, fmt.Errorf("wrong trie root")
+		} else {
+			if cfg.hd != nil {
+				cfg.hd.ReportBadHeaderPoS(headerHash, syncHeadHeader.ParentHash)
+			}
+			if to > s.BlockNumber {
+				unwindTo := (to + s.BlockNumber) / 2 // Binary search for the correct block, biased to the lower numbers
+				log.Warn("Unwinding due to incorrect root hash", "to", unwindTo)
+				u.UnwindTo(unwindTo, headerHash)
+			}
 		}
 	} else if err = s.Update(tx, to); err != nil {
 		return trie.EmptyRoot, err
@@ -696,6 +709,37 @@ func unwindIntermediateHashesStageImpl(logPrefix string, u *UnwindState, s *Stag
 	return nil
 }
 
+// UnwindHashedStateForTrieLoader unwinds HashedAccounts and HashedStorage in the given transaction
+// (usually an in-memory batch over a read-only transaction) from block `from` down to block `to`.
+// Intermediate hashes are left untouched, instead the returned retain list marks all keys changed
+// in the unwound range, so that a trie loader created with it doesn't use the stale hashes on their paths
+// and calculates the state root of block `to`.
+func UnwindHashedStateForTrieLoader(logPrefix string, tx kv.RwTx, from, to uint64, tmpDir string, quit <-chan struct{}) (*trie.RetainList, error) {
+	s := &StageState{ID: stages.HashState, BlockNumber: from}
+	u := &UnwindState{ID: stages.HashState, UnwindPoint: to}
+	prom := NewPromoter(tx, datadir.Dirs{Tmp: tmpDir}, quit)
+	if err := prom.Unwind(logPrefix, s, u, false /* storage */, false /* codes */); err != nil {
+		return nil, err
+	}
+	if err := prom.Unwind(logPrefix, s, u, true /* storage */, false /* codes */); err != nil {
+		return nil, err
+	}
+
+	rl := trie.NewRetainList(0)
+	collect := func(k, v []byte, _ etl.CurrentTableReader, _ etl.LoadNextFunc) error {
+		rl.AddKeyWithMarker(k, len(v) == 0)
+		return nil
+	}
+	p := NewHashPromoter(tx, tmpDir, quit, logPrefix)
+	if err := p.Unwind(logPrefix, s, u, false /* storage */, collect); err != nil {
+		return nil, err
+	}
+	if err := p.Unwind(logPrefix, s, u, true /* storage */, collect); err != nil {
+		return nil, err
+	}
+	return rl, nil
+}
+
 func ResetHashState(tx kv.RwTx) error {
 	if err := tx.ClearBucket(kv.HashedAccounts); err != nil {
 		return err
@@ -1,6 +1,7 @@
 package stagedsync
 
 import (
+	"bytes"
 	"encoding/binary"
 	"testing"
 
@@ -10,6 +11,7 @@ import (
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/common/dbutils"
 	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/crypto"
 	"github.com/ledgerwatch/erigon/params"
 	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
 	"github.com/ledgerwatch/erigon/turbo/trie"
@@ -72,7 +74,7 @@ func TestAccountAndStorageTrie(t *testing.T) {
 
 	historyV2 := false
 	blockReader := snapshotsync.NewBlockReader()
-	cfg := StageTrieCfg(nil, false, true, false, t.TempDir(), blockReader, nil, historyV2, nil, nil)
+	cfg := StageTrieCfg(nil, false, true, false, t.TempDir(), blockReader, nil, nil, historyV2, nil, nil)
 	_, err := RegenerateIntermediateHashes("IH", tx, cfg, common.Hash{} /* expectedRootHash */, nil /* quit */)
 	assert.Nil(t, err)
 
@@ -193,7 +195,7 @@ func TestAccountTrieAroundExtensionNode(t *testing.T) {
 	assert.Nil(t, tx.Put(kv.HashedAccounts, hash6[:], encoded))
 
 	blockReader := snapshotsync.NewBlockReader()
-	_, err := RegenerateIntermediateHashes("IH", tx, StageTrieCfg(nil, false, true, false, t.TempDir(), blockReader, nil, historyV2, nil, nil), common.Hash{} /* expectedRootHash */, nil /* quit */)
+	_, err := RegenerateIntermediateHashes("IH", tx, StageTrieCfg(nil, false, true, false, t.TempDir(), blockReader, nil, nil, historyV2, nil, nil), common.Hash{} /* expectedRootHash */, nil /* quit */)
 	assert.Nil(t, err)
 
 	accountTrie := make(map[string][]byte)
@@ -255,7 +257,7 @@ func TestStorageDeletion(t *testing.T) {
 	// ----------------------------------------------------------------
 	historyV2 := false
 	blockReader := snapshotsync.NewBlockReader()
-	cfg := StageTrieCfg(nil, false, true, false, t.TempDir(), blockReader, nil, historyV2, nil, nil)
+	cfg := StageTrieCfg(nil, false, true, false, t.TempDir(), blockReader, nil, nil, historyV2, nil, nil)
 	_, err = RegenerateIntermediateHashes("IH", tx, cfg, common.Hash{} /* expectedRootHash */, nil /* quit */)
 	assert.Nil(t, err)
 
@@ -303,3 +305,88 @@ func TestStorageDeletion(t *testing.T) {
 
 	assert.Equal(t, 0, len(storageTrieB))
 }
+
+func TestLoadTrieForProofs(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+
+	hash1 := common.HexToHash("0xB000000000000000000000000000000000000000000000000000000000000000")
+	assert.Nil(t, addTestAccount(tx, hash1, 3*params.Ether, 0))
+
+	hash2 := common.HexToHash("0xB040000000000000000000000000000000000000000000000000000000000000")
+	assert.Nil(t, addTestAccount(tx, hash2, 1*params.Ether, 0))
+
+	incarnation := uint64(1)
+	hash3 := common.HexToHash("0xB041000000000000000000000000000000000000000000000000000000000000")
+	assert.Nil(t, addTestAccount(tx, hash3, 2*params.Ether, incarnation))
+
+	// real storage keys are hashed, so the storage trie doesn't have nodes shorter than a hash
+	loc1 := crypto.Keccak256Hash([]byte{1})
+	loc2 := crypto.Keccak256Hash([]byte{2})
+	loc3 := crypto.Keccak256Hash([]byte{3})
+	loc4 := crypto.Keccak256Hash([]byte{4})
+
+	assert.Nil(t, tx.Put(kv.HashedStorage, dbutils.GenerateCompositeStorageKey(hash3, incarnation, loc1), common.FromHex("0x42")))
+	assert.Nil(t, tx.Put(kv.HashedStorage, dbutils.GenerateCompositeStorageKey(hash3, incarnation, loc2), common.FromHex("0x01")))
+	assert.Nil(t, tx.Put(kv.HashedStorage, dbutils.GenerateCompositeStorageKey(hash3, incarnation, loc3), common.FromHex("0x127a89")))
+	assert.Nil(t, tx.Put(kv.HashedStorage, dbutils.GenerateCompositeStorageKey(hash3, incarnation, loc4), common.FromHex("0x05")))
+
+	hash4 := common.HexToHash("0xB1A0000000000000000000000000000000000000000000000000000000000000")
+	assert.Nil(t, addTestAccount(tx, hash4, 4*params.Ether, 0))
+
+	hash5 := common.HexToHash("0xB310000000000000000000000000000000000000000000000000000000000000")
+	assert.Nil(t, addTestAccount(tx, hash5, 8*params.Ether, 0))
+
+	historyV2 := false
+	blockReader := snapshotsync.NewBlockReader()
+	cfg := StageTrieCfg(nil, false, true, false, t.TempDir(), blockReader, nil, nil, historyV2, nil, nil)
+	expectedRoot, err := RegenerateIntermediateHashes("IH", tx, cfg, common.Hash{} /* expectedRootHash */, nil /* quit */)
+	assert.Nil(t, err)
+
+	// the loader and the aggregator consume their retain lists independently, so both need own instances
+	loaderRl, proofRl := trie.NewRetainList(0), trie.NewRetainList(0)
+	for _, k := range [][]byte{
+		hash3[:],
+		hash5[:],
+		dbutils.GenerateCompositeStorageKey(hash3, incarnation, loc1),
+		dbutils.GenerateCompositeStorageKey(hash3, incarnation, loc3),
+	} {
+		loaderRl.AddKey(k)
+		proofRl.AddKey(k)
+	}
+	tr, err := trie.LoadTrieForProofs("IH", tx, loaderRl, proofRl, nil /* quit */)
+	assert.Nil(t, err)
+	assert.Equal(t, expectedRoot, tr.Hash())
+
+	checkProof := func(proof [][]byte, root common.Hash) {
+		assert.NotEmpty(t, proof)
+		assert.Equal(t, root, crypto.Keccak256Hash(proof[0]))
+		for i := 1; i < len(proof); i++ {
+			child := proof[i]
+			if len(child) >= length.Hash {
+				child = crypto.Keccak256(child)
+			}
+			assert.True(t, bytes.Contains(proof[i-1], child), "node %d is not referenced by its parent", i)
+		}
+	}
+
+	for _, hash := range []common.Hash{hash3, hash5} {
+		proof, err := tr.Prove(hash[:], 0, false)
+		assert.Nil(t, err)
+		checkProof(proof, expectedRoot)
+	}
+	acc, ok := tr.GetAccount(hash5[:])
+	assert.True(t, ok)
+	assert.Equal(t, uint64(8*params.Ether), acc.Balance.Uint64())
+
+	acc, ok = tr.GetAccount(hash3[:])
+	assert.True(t, ok)
+	for _, loc := range []common.Hash{loc1, loc3} {
+		key := append(common.CopyBytes(hash3[:]), loc[:]...)
+		proof, err := tr.Prove(key, 64, true)
+		assert.Nil(t, err)
+		checkProof(proof, acc.Root)
+	}
+	value, ok := tr.Get(append(common.CopyBytes(hash3[:]), loc3[:]...))
+	assert.True(t, ok)
+	assert.Equal(t, common.FromHex("0x127a89"), value)
+}
@@ -1,28 +1,55 @@
 package stagedsync
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"time"
 
+	"github.com/VictoriaMetrics/metrics"
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/length"
 	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/changeset"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/consensus"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/turbo/services"
 )
 
 // PostExec stage is run after execution stage to peform extra verifications that are only possible when state is available.
-// It is used for consensus engines which keep validators inside smart contracts (Bor, AuRa)
+// It is used for consensus engines which keep validators inside smart contracts (Bor, AuRa, Parlia)
 
 type PostExecCfg struct {
-	db    kv.RwDB
-	borDb kv.RwDB
+	db          kv.RwDB
+	borDb       kv.RwDB
+	prune       prune.Mode
+	chainConfig *params.ChainConfig
+	engine      consensus.Engine
+	blockReader services.FullBlockReader
 }
 
-func StagePostExecCfg(db kv.RwDB, borDb kv.RwDB) PostExecCfg {
+func StagePostExecCfg(db kv.RwDB, borDb kv.RwDB, pm prune.Mode, chainConfig *params.ChainConfig, engine consensus.Engine, blockReader services.FullBlockReader) PostExecCfg {
 	return PostExecCfg{
-		db:    db,
-		borDb: borDb,
+		db:          db,
+		borDb:       borDb,
+		prune:       pm,
+		chainConfig: chainConfig,
+		engine:      engine,
+		blockReader: blockReader,
 	}
 }
 
-func SpawnPostExecStage(s *StageState, tx kv.RwTx, cfg PostExecCfg, ctx context.Context) error {
+func SpawnPostExecStage(s *StageState, u Unwinder, tx kv.RwTx, cfg PostExecCfg, ctx context.Context) error {
 	useExternalTx := tx != nil
 	if !useExternalTx {
 		var err error
@@ -33,7 +60,7 @@ func SpawnPostExecStage(s *StageState, tx kv.RwTx, cfg PostExecCfg, ctx context.
 		defer tx.Rollback()
 	}
 
-	//logPrefix := s.LogPrefix()
+	logPrefix := s.LogPrefix()
 	to, err := s.ExecutionAt(tx)
 	if err != nil {
 		return err
@@ -45,7 +72,21 @@ func SpawnPostExecStage(s *StageState, tx kv.RwTx, cfg PostExecCfg, ctx context.
 		return nil
 	}
 	if s.BlockNumber > to {
-		return fmt.Errorf("hashstate: promotion backwards from %d to %d", s.BlockNumber, to)
+		return fmt.Errorf("postexec: verification backwards from %d to %d", s.BlockNumber, to)
+	}
+
+	if posa, ok := cfg.engine.(consensus.PoSA); ok {
+		badBlock, badHash, err := verifyPoSABlocks(logPrefix, s.BlockNumber, to, tx, cfg, posa, ctx)
+		if err != nil {
+			return err
+		}
+		if badBlock > 0 {
+			u.UnwindTo(badBlock-1, badHash)
+			return nil
+		}
+		if err = updatePoSAFinality(tx, cfg, posa, to, ctx); err != nil {
+			return err
+		}
 	}
 
 	if err = s.Update(tx, to); err != nil {
@@ -60,6 +101,368 @@ func SpawnPostExecStage(s *StageState, tx kv.RwTx, cfg PostExecCfg, ctx context.
 	return nil
 }
 
+// unexpectedSystemTxBlocks counts the blocks whose system transactions didn't have the expected outcome
+var unexpectedSystemTxBlocks = metrics.GetOrCreateCounter("posa_unexpected_system_txs_blocks")
+
+// activityTipDepth is how many blocks below the head of the headers the activity of the validators is recorded to the
+// metrics. The blocks of the initial sync aren't recorded, parlia_getValidatorActivity serves the historical ranges.
+const activityTipDepth = 128
+
+// epochBlock is an epoch block of the verified range, with the validators it announces
+type epochBlock struct {
+	header     *types.Header
+	hash       common.Hash
+	validators []common.Address
+}
+
+// verifyPoSABlocks checks the outcomes of the system transactions of blocks (from, to] and the validators announced by
+// their epoch blocks, records the activity of their validators at the tip of the chain, and stores the validators of
+// the epoch blocks once checked.
+// The validators of an epoch block are checked against the validator contract on the state at the beginning of the block,
+// rebuilt from the change sets written by the execution of the range. The epoch blocks whose change sets are pruned
+// (--prune.h) aren't checked again, only by the engine when executing them.
+// The system transactions which didn't have the expected outcome make the block bad, as the engine rejects them
+// when executing the block: they can only come from the receipts of a block light processed from its diff layer.
+// It returns the number and hash of the lowest bad block, 0 if there is none.
+func verifyPoSABlocks(logPrefix string, from, to uint64, tx kv.RwTx, cfg PostExecCfg, posa consensus.PoSA, ctx context.Context) (badBlock uint64, badHash common.Hash, err error) {
+	if to > from+16 {
+		log.Info(fmt.Sprintf("[%s] Verifying system contracts", logPrefix), "from", from, "to", to)
+	}
+
+	headersProgress, err := stages.GetStageProgress(tx, stages.Headers)
+	if err != nil {
+		return 0, common.Hash{}, err
+	}
+	logEvery := time.NewTicker(logInterval)
+	defer logEvery.Stop()
+	// the stage progress isn't saved on a bad block or an error, so the blocks will be verified again
+	recorded := from
+	defer func() {
+		if err != nil || badBlock > 0 {
+			if unwindErr := unwindPoSAActivity(logPrefix, recorded, from, tx, cfg, posa, ctx); unwindErr != nil {
+				log.Warn(fmt.Sprintf("[%s] Failed to unwind validator activity", logPrefix), "err", unwindErr)
+			}
+		}
+	}()
+	var epochs []epochBlock
+	for blockNum := from + 1; blockNum <= to; blockNum++ {
+		select {
+		case <-ctx.Done():
+			return 0, common.Hash{}, libcommon.ErrStopped
+		case <-logEvery.C:
+			log.Info(fmt.Sprintf("[%s] Verifying system contracts", logPrefix), "block", blockNum)
+		default:
+		}
+
+		blockHash, err := rawdb.ReadCanonicalHash(tx, blockNum)
+		if err != nil {
+			return 0, common.Hash{}, err
+		}
+		block, senders, err := cfg.blockReader.BlockWithSenders(ctx, tx, blockHash, blockNum)
+		if err != nil {
+			return 0, common.Hash{}, err
+		}
+		if block == nil {
+			return 0, common.Hash{}, fmt.Errorf("block %d(%x) not found", blockNum, blockHash)
+		}
+		block.SendersToTxs(senders)
+		header := block.Header()
+
+		// receipts are not written for the pruned blocks, nor for the blocks without transactions
+		if receipts := rawdb.ReadRawReceipts(tx, blockNum); receipts != nil || block.Transactions().Len() == 0 {
+			if err = posa.VerifySystemReceipts(header, block.Transactions(), receipts); err != nil {
+				unexpectedSystemTxBlocks.Inc()
+				log.Warn(fmt.Sprintf("[%s] Unexpected outcome of system transactions", logPrefix), "block", blockNum, "hash", blockHash, "err", err)
+				badBlock, badHash = blockNum, blockHash
+				break
+			}
+			// accounted as reorged by unwindPoSAActivity if the block is unwound
+			if blockNum+activityTipDepth > headersProgress {
+				if err = posa.RecordActivity(ChainReader{Cfg: *cfg.chainConfig, Db: tx}, header, block.Transactions(), receipts); err != nil {
+					log.Warn(fmt.Sprintf("[%s] Failed to record validator activity", logPrefix), "block", blockNum, "hash", blockHash, "err", err)
+				}
+			}
+		}
+		recorded = blockNum
+
+		validators, err := posa.EpochValidators(header)
+		if err != nil {
+			log.Warn(fmt.Sprintf("[%s] Malformed epoch validators", logPrefix), "block", blockNum, "hash", blockHash, "err", err)
+			badBlock, badHash = blockNum, blockHash
+			break
+		}
+		if validators != nil {
+			epochs = append(epochs, epochBlock{header: header, hash: blockHash, validators: validators})
+		}
+	}
+
+	// the epoch blocks are all below the bad block, if any
+	if epochBad, epochHash, err := verifyEpochBlocks(logPrefix, epochs, to, tx, cfg, posa, ctx); err != nil {
+		return 0, common.Hash{}, err
+	} else if epochBad > 0 {
+		return epochBad, epochHash, nil
+	}
+	if badBlock > 0 {
+		return badBlock, badHash, nil
+	}
+	for _, epoch := range epochs {
+		if err = rawdb.WriteEpochValidators(tx, epoch.header.Number.Uint64(), epoch.hash, epoch.validators); err != nil {
+			return 0, common.Hash{}, err
+		}
+	}
+	return 0, common.Hash{}, nil
+}
+
+// verifyEpochBlocks checks the validators of the given epoch blocks of the range ending at the head of the execution
+// against the validator contract, from the highest down, moving the state back through the change sets.
+// It returns the number and hash of the lowest epoch block announcing wrong validators, 0 if there is none.
+func verifyEpochBlocks(logPrefix string, epochs []epochBlock, to uint64, tx kv.Tx, cfg PostExecCfg, posa consensus.PoSA, ctx context.Context) (badBlock uint64, badHash common.Hash, err error) {
+	// the execution writes the change sets of the blocks above the pruned history only
+	changeSetsFrom := cfg.prune.History.PruneTo(to) + 1
+	reader := newBatchStateReader(tx, to)
+	defer reader.Close()
+	for i := len(epochs) - 1; i >= 0; i-- {
+		epoch := epochs[i]
+		blockNum := epoch.header.Number.Uint64()
+		if blockNum < changeSetsFrom {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return 0, common.Hash{}, libcommon.ErrStopped
+		default:
+		}
+		if err = reader.SetBlockNumber(blockNum); err != nil {
+			return 0, common.Hash{}, err
+		}
+		if err = posa.VerifyEpochValidators(ChainReader{Cfg: *cfg.chainConfig, Db: tx}, epoch.header, state.New(reader)); err != nil {
+			log.Warn(fmt.Sprintf("[%s] Epoch validators verification failed", logPrefix), "block", blockNum, "hash", epoch.hash, "err", err)
+			badBlock, badHash = blockNum, epoch.hash
+		}
+	}
+	return badBlock, badHash, nil
+}
+
+// unwindPoSAActivity accounts the activity of the validators recorded by verifyPoSABlocks for the blocks
+// (unwindPoint, progress] as reorged, from the head down. The blocks and their receipts are still readable, as the stages
+// before this one are unwound after it. It isn't interrupted, so that the metrics stay consistent.
+func unwindPoSAActivity(logPrefix string, progress, unwindPoint uint64, tx kv.RwTx, cfg PostExecCfg, posa consensus.PoSA, ctx context.Context) error {
+	for blockNum := progress; blockNum > unwindPoint; blockNum-- {
+		blockHash, err := rawdb.ReadCanonicalHash(tx, blockNum)
+		if err != nil {
+			return err
+		}
+		block, senders, err := cfg.blockReader.BlockWithSenders(ctx, tx, blockHash, blockNum)
+		if err != nil {
+			return err
+		}
+		if block == nil {
+			return fmt.Errorf("block %d(%x) not found", blockNum, blockHash)
+		}
+		receipts := rawdb.ReadRawReceipts(tx, blockNum)
+		if receipts == nil && block.Transactions().Len() > 0 {
+			// the activity of the pruned blocks isn't recorded
+			continue
+		}
+		block.SendersToTxs(senders)
+		if err = posa.UnwindActivity(ChainReader{Cfg: *cfg.chainConfig, Db: tx}, block.Header(), block.Transactions(), receipts); err != nil {
+			log.Warn(fmt.Sprintf("[%s] Failed to unwind validator activity", logPrefix), "block", blockNum, "hash", blockHash, "err", err)
+		}
+	}
+	return nil
+}
+
+// batchStateReader reads the state at the beginning of the blocks executed by the last batch, from its head down,
+// which the history indices don't cover yet. It keeps the original values of the accounts read, and of their storage,
+// changed since the block: an item changed since has the original value recorded by its lowest change set, the others
+// are read from the plain state. The change sets of each block are sought once per account read, when the account is
+// first read or the reader moves below the block.
+type batchStateReader struct {
+	tx       kv.Tx
+	blockNum uint64
+	to       uint64
+	plain    *state.PlainStateReader
+	accounts map[common.Address]*originalAccount
+
+	accountChanges kv.CursorDupSort
+	storageChanges kv.Cursor
+}
+
+// originalAccount is the original account and storage items of an account changed since the block of the reader
+type originalAccount struct {
+	enc     []byte
+	changed bool
+	storage map[string][]byte // by incarnation and location
+}
+
+// newBatchStateReader returns a reader of the state at the end of the given block, the head of the execution.
+func newBatchStateReader(tx kv.Tx, to uint64) *batchStateReader {
+	return &batchStateReader{
+		tx:       tx,
+		blockNum: to + 1,
+		to:       to,
+		plain:    state.NewPlainStateReader(tx),
+		accounts: make(map[common.Address]*originalAccount),
+	}
+}
+
+func (r *batchStateReader) Close() {
+	if r.accountChanges != nil {
+		r.accountChanges.Close()
+	}
+	if r.storageChanges != nil {
+		r.storageChanges.Close()
+	}
+}
+
+// SetBlockNumber moves the reader to the beginning of the given block, which can't be above the current one.
+func (r *batchStateReader) SetBlockNumber(blockNum uint64) error {
+	if blockNum > r.blockNum {
+		return fmt.Errorf("batch state reader moving up from %d to %d", r.blockNum, blockNum)
+	}
+	for ; r.blockNum > blockNum; r.blockNum-- {
+		for address, account := range r.accounts {
+			if err := r.loadChanges(r.blockNum-1, address, account); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// account returns the original values of the account, reading them from the change sets on the first read.
+func (r *batchStateReader) account(address common.Address) (*originalAccount, error) {
+	if account, ok := r.accounts[address]; ok {
+		return account, nil
+	}
+	account := &originalAccount{storage: make(map[string][]byte)}
+	// from the head down, so that the lowest change set is kept
+	for blockNum := r.to; blockNum >= r.blockNum && blockNum > 0; blockNum-- {
+		if err := r.loadChanges(blockNum, address, account); err != nil {
+			return nil, err
+		}
+	}
+	r.accounts[address] = account
+	return account, nil
+}
+
+// loadChanges records the original values of the account and its storage changed by the block.
+func (r *batchStateReader) loadChanges(blockNum uint64, address common.Address, account *originalAccount) (err error) {
+	if r.accountChanges == nil {
+		if r.accountChanges, err = r.tx.CursorDupSort(kv.AccountChangeSet); err != nil {
+			return err
+		}
+		if r.storageChanges, err = r.tx.Cursor(kv.StorageChangeSet); err != nil {
+			return err
+		}
+	}
+	v, err := r.accountChanges.SeekBothRange(dbutils.EncodeBlockNumber(blockNum), address[:])
+	if err != nil {
+		return err
+	}
+	if bytes.HasPrefix(v, address[:]) {
+		account.enc, account.changed = common.CopyBytes(v[length.Addr:]), true
+	}
+	// the storage change sets are keyed by the block, the address and the incarnation
+	prefix := append(dbutils.EncodeBlockNumber(blockNum), address[:]...)
+	for k, v, err := r.storageChanges.Seek(prefix); k != nil; k, v, err = r.storageChanges.Next() {
+		if err != nil {
+			return err
+		}
+		if !bytes.HasPrefix(k, prefix) {
+			break
+		}
+		_, key, value, err := changeset.DecodeStorage(k, v)
+		if err != nil {
+			return err
+		}
+		account.storage[string(key[length.Addr:])] = common.CopyBytes(value)
+	}
+	return nil
+}
+
+func (r *batchStateReader) ReadAccountData(address common.Address) (*accounts.Account, error) {
+	account, err := r.account(address)
+	if err != nil {
+		return nil, err
+	}
+	if !account.changed {
+		return r.plain.ReadAccountData(address)
+	}
+	if len(account.enc) == 0 {
+		return nil, nil
+	}
+	var a accounts.Account
+	if err = a.DecodeForStorage(account.enc); err != nil {
+		return nil, err
+	}
+	// the change sets don't keep the code hash of the contracts, which is restored as in state.FindByHistory
+	if a.Incarnation > 0 && a.IsEmptyCodeHash() {
+		codeHash, err := r.tx.GetOne(kv.PlainContractCode, dbutils.PlainGenerateStoragePrefix(address[:], a.Incarnation))
+		if err != nil {
+			return nil, err
+		}
+		if len(codeHash) > 0 {
+			a.CodeHash.SetBytes(codeHash)
+		}
+	}
+	return &a, nil
+}
+
+func (r *batchStateReader) ReadAccountStorage(address common.Address, incarnation uint64, key *common.Hash) ([]byte, error) {
+	account, err := r.account(address)
+	if err != nil {
+		return nil, err
+	}
+	enc, ok := account.storage[string(dbutils.PlainGenerateCompositeStorageKey(address[:], incarnation, key[:])[length.Addr:])]
+	if !ok {
+		return r.plain.ReadAccountStorage(address, incarnation, key)
+	}
+	if len(enc) == 0 {
+		return nil, nil
+	}
+	return enc, nil
+}
+
+func (r *batchStateReader) ReadAccountCode(address common.Address, incarnation uint64, codeHash common.Hash) ([]byte, error) {
+	return r.plain.ReadAccountCode(address, incarnation, codeHash)
+}
+
+func (r *batchStateReader) ReadAccountCodeSize(address common.Address, incarnation uint64, codeHash common.Hash) (int, error) {
+	return r.plain.ReadAccountCodeSize(address, incarnation, codeHash)
+}
+
+func (r *batchStateReader) ReadAccountIncarnation(address common.Address) (uint64, error) {
+	return r.plain.ReadAccountIncarnation(address)
+}
+
+// updatePoSAFinality stores the finalized and safe blocks of the chain ending at the given block,
+// which eth_getBlockByNumber serves for the "finalized" and "safe" tags.
+func updatePoSAFinality(tx kv.RwTx, cfg PostExecCfg, posa consensus.PoSA, to uint64, ctx context.Context) error {
+	header, err := cfg.blockReader.HeaderByNumber(ctx, tx, to)
+	if err != nil {
+		return err
+	}
+	if header == nil {
+		return fmt.Errorf("header %d not found", to)
+	}
+	finalized, safe, err := posa.Finality(ChainReader{Cfg: *cfg.chainConfig, Db: tx}, header)
+	if err != nil {
+		return fmt.Errorf("computing finality at %d: %w", to, err)
+	}
+	if finalized != nil {
+		if err = rawdb.WritePoSAFinalized(tx, finalized.Hash()); err != nil {
+			return err
+		}
+	}
+	if safe != nil {
+		if err = rawdb.WritePoSASafe(tx, safe.Hash()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func UnwindPostExecStage(u *UnwindState, s *StageState, tx kv.RwTx, cfg PostExecCfg, ctx context.Context) (err error) {
 	useExternalTx := tx != nil
 	if !useExternalTx {
@@ -72,6 +475,21 @@ func UnwindPostExecStage(u *UnwindState, s *StageState, tx kv.RwTx, cfg PostExec
 
 	//logPrefix := u.LogPrefix()
 
+	if posa, ok := cfg.engine.(consensus.PoSA); ok {
+		if err = unwindPoSAActivity(u.LogPrefix(), s.BlockNumber, u.UnwindPoint, tx, cfg, posa, ctx); err != nil {
+			return err
+		}
+		if err = rawdb.DeleteNewerEpochs(tx, u.UnwindPoint+1); err != nil {
+			return err
+		}
+		// the finality is computed again from the new head of the chain
+		if safe := rawdb.ReadHeaderNumber(tx, rawdb.ReadPoSASafe(tx)); safe != nil && *safe > u.UnwindPoint {
+			if err = rawdb.DeletePoSAFinality(tx); err != nil {
+				return err
+			}
+		}
+	}
+
 	if err = u.Done(tx); err != nil {
 		return err
 	}
@@ -0,0 +1,68 @@
+package stagedsync
+
+import (
+	"testing"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/state"
+	"github.com/ledgerwatch/erigon/core/types/accounts"
+)
+
+func TestBatchStateReader(t *testing.T) {
+	_, tx := memdb.NewTestTx(t)
+	var (
+		contract = common.Address{1}
+		other    = common.Address{2}
+		slot     = common.Hash{3}
+	)
+	account := func(balance uint64) *accounts.Account {
+		a := accounts.NewAccount()
+		a.Balance.SetUint64(balance)
+		a.Incarnation = 1
+		a.Initialised = true
+		return &a
+	}
+	genesis := state.NewPlainStateWriterNoHistory(tx)
+	require.NoError(t, genesis.UpdateAccountData(contract, &accounts.Account{}, account(100)))
+	require.NoError(t, genesis.WriteAccountStorage(contract, 1, &slot, uint256.NewInt(0), uint256.NewInt(100)))
+	require.NoError(t, genesis.UpdateAccountData(other, &accounts.Account{}, account(7)))
+	// the contract changes in every block, the other account in block 2 only
+	for blockNum := uint64(1); blockNum <= 5; blockNum++ {
+		w := state.NewPlainStateWriter(tx, tx, blockNum)
+		previous := blockNum - 1
+		if blockNum == 1 {
+			previous = 100
+		}
+		require.NoError(t, w.UpdateAccountData(contract, account(previous), account(blockNum)))
+		require.NoError(t, w.WriteAccountStorage(contract, 1, &slot, uint256.NewInt(previous), uint256.NewInt(blockNum)))
+		if blockNum == 2 {
+			require.NoError(t, w.UpdateAccountData(other, account(7), account(8)))
+		}
+		require.NoError(t, w.WriteChangeSets())
+	}
+
+	r := newBatchStateReader(tx, 5)
+	defer r.Close()
+	check := func(blockNum, balance, value, otherBalance uint64) {
+		require.NoError(t, r.SetBlockNumber(blockNum))
+		a, err := r.ReadAccountData(contract)
+		require.NoError(t, err)
+		require.Equal(t, balance, a.Balance.Uint64(), "balance at %d", blockNum)
+		enc, err := r.ReadAccountStorage(contract, 1, &slot)
+		require.NoError(t, err)
+		require.Equal(t, value, new(uint256.Int).SetBytes(enc).Uint64(), "storage at %d", blockNum)
+		a, err = r.ReadAccountData(other)
+		require.NoError(t, err)
+		require.Equal(t, otherBalance, a.Balance.Uint64(), "other balance at %d", blockNum)
+	}
+	check(6, 5, 5, 8)
+	check(5, 4, 4, 8)
+	check(3, 2, 2, 8)
+	check(2, 1, 1, 7)
+	check(1, 100, 100, 7)
+	require.Error(t, r.SetBlockNumber(2), "the reader only moves down")
+}
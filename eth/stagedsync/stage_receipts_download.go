@@ -0,0 +1,206 @@
+package stagedsync
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/turbo/services"
+	"github.com/ledgerwatch/erigon/turbo/stages/headerdownload"
+	"github.com/ledgerwatch/erigon/turbo/stages/receiptsdownload"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// receiptsDownloadWindow is the number of blocks scanned for missing receipts, which are then
+// downloaded before the progress of the stage is saved.
+const receiptsDownloadWindow = 4096
+
+// receiptsDownloadStallTimeout is the time without any delivery after which the stage gives up
+// until the next cycle, saving the progress up to the first block which receipts are missing.
+const receiptsDownloadStallTimeout = 2 * time.Minute
+
+type ReceiptsDownloadCfg struct {
+	db              kv.RwDB
+	rd              *receiptsdownload.ReceiptsDownload
+	receiptsReqSend func(context.Context, *receiptsdownload.ReceiptsRequest) ([64]byte, bool)
+	penalise        func(context.Context, []headerdownload.PenaltyItem)
+	blockReader     services.FullBlockReader
+	enabled         bool
+}
+
+func StageReceiptsDownloadCfg(
+	db kv.RwDB,
+	rd *receiptsdownload.ReceiptsDownload,
+	receiptsReqSend func(context.Context, *receiptsdownload.ReceiptsRequest) ([64]byte, bool),
+	penalise func(context.Context, []headerdownload.PenaltyItem),
+	blockReader services.FullBlockReader,
+	enabled bool,
+) ReceiptsDownloadCfg {
+	return ReceiptsDownloadCfg{db: db, rd: rd, receiptsReqSend: receiptsReqSend, penalise: penalise, blockReader: blockReader, enabled: enabled}
+}
+
+// SpawnReceiptsDownload downloads from the peers the receipts of the executed blocks which are
+// not stored, e.g. the blocks which receipts are pruned or which were executed without writing
+// them, and writes them once they are validated against the receipt root of their headers.
+// The Execution stage doesn't prune the receipts while this stage is enabled. The peers which
+// don't answer a request are kicked so that the next requests go to other peers, and the stage
+// gives up until the next cycle when nothing is delivered for receiptsDownloadStallTimeout.
+func SpawnReceiptsDownload(s *StageState, tx kv.RwTx, cfg ReceiptsDownloadCfg, ctx context.Context) (err error) {
+	useExternalTx := tx != nil
+	if !useExternalTx {
+		tx, err = cfg.db.BeginRw(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+	}
+
+	to, err := stages.GetStageProgress(tx, stages.Execution)
+	if err != nil {
+		return fmt.Errorf("getting execution progress: %w", err)
+	}
+	from := s.BlockNumber + 1
+
+	logPrefix := s.LogPrefix()
+	logEvery := time.NewTicker(logInterval)
+	defer logEvery.Stop()
+	timer := time.NewTicker(time.Second)
+	defer timer.Stop()
+	cfg.rd.Reset()
+	defer cfg.rd.Reset()
+
+	var downloaded int
+	lastDelivery := time.Now()
+windows:
+	for windowFrom := from; windowFrom <= to; {
+		windowTo := windowFrom + receiptsDownloadWindow - 1
+		if windowTo > to {
+			windowTo = to
+		}
+		missing, err := missingReceipts(ctx, tx, cfg.blockReader, windowFrom, windowTo)
+		if err != nil {
+			return err
+		}
+		cfg.rd.Schedule(missing...)
+		for {
+			deliveries := cfg.rd.Deliveries()
+			if err := writeDownloadedReceipts(tx, deliveries); err != nil {
+				return err
+			}
+			if len(deliveries) > 0 {
+				downloaded += len(deliveries)
+				lastDelivery = time.Now()
+			}
+			if failed := cfg.rd.FailedPeers(); len(failed) > 0 && cfg.penalise != nil {
+				penalties := make([]headerdownload.PenaltyItem, 0, len(failed))
+				for _, peerID := range failed {
+					penalties = append(penalties, headerdownload.PenaltyItem{PeerID: peerID, Penalty: headerdownload.NoReceiptsPenalty})
+				}
+				cfg.penalise(ctx, penalties)
+			}
+			outstanding := cfg.rd.Outstanding()
+			if outstanding == 0 {
+				break
+			}
+			if time.Since(lastDelivery) > receiptsDownloadStallTimeout {
+				lowest, _ := cfg.rd.LowestOutstanding()
+				log.Warn(fmt.Sprintf("[%s] No receipts delivered, retrying in the next cycle", logPrefix), "block", lowest, "outstanding", outstanding)
+				if lowest > from {
+					if err = s.Update(tx, lowest-1); err != nil {
+						return err
+					}
+				}
+				break windows
+			}
+			if req := cfg.rd.RequestMoreReceipts(time.Now()); req != nil {
+				if peerID, ok := cfg.receiptsReqSend(ctx, req); ok {
+					cfg.rd.RequestSent(req, peerID)
+				} else {
+					cfg.rd.CancelRequest(req)
+				}
+			}
+			select {
+			case <-ctx.Done():
+				return libcommon.ErrStopped
+			case <-logEvery.C:
+				log.Info(fmt.Sprintf("[%s] Downloading receipts", logPrefix), "from", windowFrom, "to", windowTo, "outstanding", outstanding)
+			case <-cfg.rd.DeliveryNotify:
+			case <-timer.C:
+			}
+		}
+		if err = s.Update(tx, windowTo); err != nil {
+			return err
+		}
+		windowFrom = windowTo + 1
+	}
+	if downloaded > 0 {
+		log.Info(fmt.Sprintf("[%s] Downloaded receipts", logPrefix), "blocks", downloaded, "to", to)
+	}
+
+	if !useExternalTx {
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// missingReceipts returns the headers of the canonical blocks in the range which have
+// transactions but no stored receipts.
+func missingReceipts(ctx context.Context, tx kv.Tx, blockReader services.FullBlockReader, from, to uint64) ([]*types.Header, error) {
+	var missing []*types.Header
+	for blockNum := from; blockNum <= to; blockNum++ {
+		header, err := blockReader.HeaderByNumber(ctx, tx, blockNum)
+		if err != nil {
+			return nil, err
+		}
+		if header == nil || header.ReceiptHash == types.EmptyRootHash {
+			continue
+		}
+		has, err := tx.Has(kv.Receipts, dbutils.EncodeBlockNumber(blockNum))
+		if err != nil {
+			return nil, err
+		}
+		if !has {
+			missing = append(missing, header)
+		}
+	}
+	return missing, nil
+}
+
+func writeDownloadedReceipts(tx kv.RwTx, deliveries []receiptsdownload.Delivery) error {
+	for _, d := range deliveries {
+		if err := rawdb.WriteReceipts(tx, d.Header.Number.Uint64(), d.Receipts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func UnwindReceiptsDownload(u *UnwindState, tx kv.RwTx, cfg ReceiptsDownloadCfg, ctx context.Context) (err error) {
+	useExternalTx := tx != nil
+	if !useExternalTx {
+		tx, err = cfg.db.BeginRw(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+	}
+
+	// the receipts of the unwound blocks are truncated by the Execution stage
+	if err = u.Done(tx); err != nil {
+		return err
+	}
+	if !useExternalTx {
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
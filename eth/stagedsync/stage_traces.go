@@ -0,0 +1,207 @@
+package stagedsync
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	libcommon "github.com/ledgerwatch/erigon-lib/common"
+	"github.com/ledgerwatch/erigon-lib/common/cmp"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/ledgerwatch/erigon/ethdb/tracedb"
+	"github.com/ledgerwatch/erigon/turbo/services"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// tracesCommitEvery is the number of blocks which traces are committed at once to the traces database.
+const tracesCommitEvery = 1024
+
+// BlockTracer returns the encoded traces of the transactions of a canonical block, re-executing it
+// on top of the historical state read from the transaction.
+type BlockTracer func(ctx context.Context, tx kv.Tx, block *types.Block) ([]byte, error)
+
+type TracesCfg struct {
+	db          kv.RwDB
+	tracesDB    kv.RwDB
+	traceBlock  BlockTracer
+	prune       prune.Mode
+	blockReader services.FullBlockReader
+}
+
+func StageTracesCfg(
+	db kv.RwDB,
+	tracesDB kv.RwDB,
+	traceBlock BlockTracer,
+	prune prune.Mode,
+	blockReader services.FullBlockReader,
+) TracesCfg {
+	return TracesCfg{db: db, tracesDB: tracesDB, traceBlock: traceBlock, prune: prune, blockReader: blockReader}
+}
+
+// enabled returns whether there is a database to store the traces into.
+func (cfg TracesCfg) enabled() bool {
+	return cfg.tracesDB != nil && cfg.traceBlock != nil
+}
+
+// SpawnTraces stores the traces of the blocks indexed by the CallTraces stage into the traces
+// database, so that trace_block, trace_transaction and trace_filter serve them instead of
+// re-executing the blocks. Like the call traces, the traces of the blocks pruned by the
+// call traces prune mode are not stored, neither the ones of the blocks which history is pruned,
+// as they can't be re-executed. The blocks without transactions have no traces to store.
+// The stage runs after the history index stages, which the re-execution reads the state from.
+func SpawnTraces(s *StageState, tx kv.RwTx, cfg TracesCfg, ctx context.Context) (err error) {
+	useExternalTx := tx != nil
+	if !useExternalTx {
+		tx, err = cfg.db.BeginRw(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+	}
+
+	to, err := stages.GetStageProgress(tx, stages.CallTraces)
+	if err != nil {
+		return fmt.Errorf("getting call traces progress: %w", err)
+	}
+	if to <= s.BlockNumber {
+		return nil
+	}
+	from := s.BlockNumber + 1
+	if pruneTo := cfg.prune.CallTraces.PruneTo(to); from < pruneTo {
+		from = pruneTo
+	}
+	if pruneTo := cfg.prune.History.PruneTo(to); from < pruneTo {
+		from = pruneTo
+	}
+
+	logPrefix := s.LogPrefix()
+	logEvery := time.NewTicker(logInterval)
+	defer logEvery.Stop()
+
+	var traced int
+	for batchFrom := from; batchFrom <= to; {
+		batchTo := batchFrom + tracesCommitEvery - 1
+		if batchTo > to {
+			batchTo = to
+		}
+		if err = cfg.tracesDB.Update(ctx, func(tracesTx kv.RwTx) error {
+			for blockNum := batchFrom; blockNum <= batchTo; blockNum++ {
+				select {
+				case <-ctx.Done():
+					return libcommon.ErrStopped
+				case <-logEvery.C:
+					log.Info(fmt.Sprintf("[%s] Tracing blocks", logPrefix), "number", blockNum, "to", to)
+				default:
+				}
+				hash, err := cfg.blockReader.CanonicalHash(ctx, tx, blockNum)
+				if err != nil {
+					return err
+				}
+				block, _, err := cfg.blockReader.BlockWithSenders(ctx, tx, hash, blockNum)
+				if err != nil {
+					return err
+				}
+				if block == nil {
+					return fmt.Errorf("block %d not found", blockNum)
+				}
+				if len(block.Transactions()) == 0 {
+					continue
+				}
+				traces, err := cfg.traceBlock(ctx, tx, block)
+				if err != nil {
+					return fmt.Errorf("tracing block %d: %w", blockNum, err)
+				}
+				if err = tracedb.WriteBlockTraces(tracesTx, blockNum, hash, traces); err != nil {
+					return err
+				}
+				traced++
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		batchFrom = batchTo + 1
+	}
+	// the traces are committed before the progress, so that none is missing after a crash
+	if err = s.Update(tx, to); err != nil {
+		return err
+	}
+	if traced > 0 {
+		log.Info(fmt.Sprintf("[%s] Stored traces", logPrefix), "blocks", traced, "to", to)
+	}
+
+	if !useExternalTx {
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func UnwindTraces(u *UnwindState, s *StageState, tx kv.RwTx, cfg TracesCfg, ctx context.Context) (err error) {
+	useExternalTx := tx != nil
+	if !useExternalTx {
+		tx, err = cfg.db.BeginRw(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+	}
+
+	if s.BlockNumber > u.UnwindPoint {
+		if err = cfg.tracesDB.Update(ctx, func(tracesTx kv.RwTx) error {
+			return tracedb.TruncateBlockTraces(tracesTx, u.UnwindPoint+1)
+		}); err != nil {
+			return err
+		}
+	}
+	if err = u.Done(tx); err != nil {
+		return err
+	}
+	if !useExternalTx {
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PruneTraces deletes the traces of the blocks pruned by the call traces prune mode or by the
+// history prune mode, the same blocks which traces SpawnTraces doesn't store.
+func PruneTraces(p *PruneState, tx kv.RwTx, cfg TracesCfg, ctx context.Context) (err error) {
+	useExternalTx := tx != nil
+	if !useExternalTx {
+		tx, err = cfg.db.BeginRw(ctx)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+	}
+
+	var pruneTo uint64
+	if cfg.prune.CallTraces.Enabled() {
+		pruneTo = cfg.prune.CallTraces.PruneTo(p.ForwardProgress)
+	}
+	if cfg.prune.History.Enabled() {
+		pruneTo = cmp.Max(pruneTo, cfg.prune.History.PruneTo(p.ForwardProgress))
+	}
+	if pruneTo > 0 {
+		if err = cfg.tracesDB.Update(ctx, func(tracesTx kv.RwTx) error {
+			return tracedb.PruneBlockTraces(tracesTx, pruneTo)
+		}); err != nil {
+			return err
+		}
+	}
+	if err = p.Done(tx); err != nil {
+		return err
+	}
+	if !useExternalTx {
+		if err = tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -15,6 +15,7 @@ type ChainEventNotifier interface {
 	OnNewHeader(newHeadersRlp [][]byte)
 	OnNewPendingLogs(types.Logs)
 	OnLogs([]*remote.SubscribeLogsReply)
+	OnSyncProgress(progressRlp []byte)
 	HasLogSubsriptions() bool
 }
 
@@ -22,6 +23,8 @@ type Notifications struct {
 	Events               *privateapi.Events
 	Accumulator          *shards.Accumulator
 	StateChangesConsumer shards.StateChangeConsumer
+	syncing              bool   // the last sync progress sent was syncing
+	startingBlock        uint64 // the block the current sync started from
 }
 
 func MiningStages(
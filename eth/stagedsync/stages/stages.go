@@ -35,13 +35,16 @@ var (
 	Bodies              SyncStage = "Bodies"              // Block bodies are downloaded, TxHash and UncleHash are getting verified
 	Senders             SyncStage = "Senders"             // "From" recovered from signatures, bodies re-written
 	Execution           SyncStage = "Execution"           // Executing each block w/o buildinf a trie
+	ReceiptsDownload    SyncStage = "ReceiptsDownload"    // Receipts not written by the execution are downloaded, ReceiptHash is getting verified
 	Translation         SyncStage = "Translation"         // Translation each marked for translation contract (from EVM to TEVM)
+	PostExec            SyncStage = "PostExec"            // Extra verifications of the contract-based validator engines, which need the state
 	IntermediateHashes  SyncStage = "IntermediateHashes"  // Generate intermediate hashes, calculate the state root hash
 	HashState           SyncStage = "HashState"           // Apply Keccak256 to all the keys in the state
 	AccountHistoryIndex SyncStage = "AccountHistoryIndex" // Generating history index for accounts
 	StorageHistoryIndex SyncStage = "StorageHistoryIndex" // Generating history index for storage
 	LogIndex            SyncStage = "LogIndex"            // Generating logs index (from receipts)
 	CallTraces          SyncStage = "CallTraces"          // Generating call traces index
+	Traces              SyncStage = "Traces"              // Storing the traces of the blocks for the trace_ RPC methods
 	TxLookup            SyncStage = "TxLookup"            // Generating transactions lookup index
 	Issuance            SyncStage = "WatchTheBurn"        // Compute ether issuance for each block
 	Finish              SyncStage = "Finish"              // Nominal stage after all other stages
@@ -57,13 +60,16 @@ var AllStages = []SyncStage{
 	Bodies,
 	Senders,
 	Execution,
+	ReceiptsDownload,
 	Translation,
+	PostExec,
 	HashState,
 	IntermediateHashes,
 	AccountHistoryIndex,
 	StorageHistoryIndex,
 	LogIndex,
 	CallTraces,
+	Traces,
 	TxLookup,
 	Finish,
 }
@@ -0,0 +1,132 @@
+package native
+
+import (
+	"encoding/json"
+	"math/big"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/eth/tracers"
+)
+
+func init() {
+	register("4byteTracer", newFourByteTracer)
+}
+
+// fourByteTracer searches for 4byte-identifiers, and collects them for post-processing.
+// It collects the methods identifiers along with the size of the supplied data, so
+// a reversed signature can be matched against the size of the data.
+//
+// Example:
+//
+//	> debug.traceTransaction( "0x214e597e35da083692f5386141e69f47e973b2c56e7a8073b1ea08fd7571e9de", {tracer: "4byteTracer"})
+//	{
+//	  0x27dc297e-128: 1,
+//	  0x38cc4831-0: 2,
+//	  0x524f3889-96: 1,
+//	  0xadf59f99-288: 1,
+//	  0xc281d19e-0: 1
+//	}
+type fourByteTracer struct {
+	ids         map[string]int // ids aggregates the 4byte ids found
+	precompiles []common.Address
+	input       []byte
+
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+}
+
+// newFourByteTracer returns a native go tracer which collects
+// 4 byte-identifiers of a tx, and implements vm.Tracer.
+func newFourByteTracer(ctx *tracers.Context, _ json.RawMessage) (tracers.Tracer, error) {
+	return &fourByteTracer{ids: make(map[string]int)}, nil
+}
+
+// store saves the given identifier and datasize.
+func (t *fourByteTracer) store(id []byte, size uint64) {
+	key := hexutil.Encode(id) + "-" + strconv.FormatUint(size, 10)
+	t.ids[key] += 1
+}
+
+// CaptureStart implements the vm.Tracer interface to initialize the tracing operation.
+func (t *fourByteTracer) CaptureStart(env *vm.EVM, depth int, from common.Address, to common.Address, precompile bool, create bool, callType vm.CallType, input []byte, gas uint64, value *big.Int, code []byte) {
+	if depth != 0 {
+		return
+	}
+	t.precompiles = vm.ActivePrecompiles(env.ChainRules())
+	t.input = common.CopyBytes(input)
+}
+
+// CaptureState implements the vm.Tracer interface to trace a single step of VM execution.
+func (t *fourByteTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	// Skip if tracing was interrupted
+	if atomic.LoadUint32(&t.interrupt) > 0 {
+		env.Cancel()
+		return
+	}
+	// Skip any opcodes that are not internal calls, find the stack position
+	// of the input memory offset otherwise
+	var inOffPos int
+	switch op {
+	case vm.CALL, vm.CALLCODE:
+		// gas, addr, val, memin, meminsz, memout, memoutsz
+		inOffPos = 3
+	case vm.DELEGATECALL, vm.STATICCALL:
+		// gas, addr, memin, meminsz, memout, memoutsz
+		inOffPos = 2
+	default:
+		return
+	}
+	// Skip any pre-compile invocations, those are just fancy opcodes
+	if isPrecompiled(common.Address(peek(scope, 1).Bytes20()), t.precompiles) {
+		return
+	}
+	// Gather internal call details
+	inSz := peek(scope, inOffPos+1)
+	if !inSz.IsUint64() || inSz.Uint64() < 4 {
+		return
+	}
+	id := memorySlice(scope, peek(scope, inOffPos), uint256.NewInt(4))
+	t.store(id, inSz.Uint64()-4)
+}
+
+func (t *fourByteTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+func (t *fourByteTracer) CaptureEnd(depth int, output []byte, startGas, endGas uint64, d time.Duration, err error) {
+}
+
+func (t *fourByteTracer) CaptureSelfDestruct(from common.Address, to common.Address, value *big.Int) {
+}
+
+func (t *fourByteTracer) CaptureAccountRead(account common.Address) error {
+	return nil
+}
+
+func (t *fourByteTracer) CaptureAccountWrite(account common.Address) error {
+	return nil
+}
+
+// GetResult returns the json-encoded nested list of call traces, and any
+// error arising from the encoding or forceful termination (via `Stop`).
+func (t *fourByteTracer) GetResult() (json.RawMessage, error) {
+	if t.reason != nil {
+		return nil, t.reason
+	}
+	// Save the outer calldata also
+	if len(t.input) >= 4 {
+		t.store(t.input[:4], uint64(len(t.input)-4))
+	}
+	return json.Marshal(t.ids)
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *fourByteTracer) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.interrupt, 1)
+}
@@ -0,0 +1,351 @@
+package native
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/eth/tracers"
+)
+
+func init() {
+	register("callTracer", newCallTracer)
+}
+
+type callLog struct {
+	Address common.Address `json:"address"`
+	Topics  []common.Hash  `json:"topics"`
+	Data    hexutil.Bytes  `json:"data"`
+}
+
+type callFrame struct {
+	Type    string          `json:"type"`
+	From    common.Address  `json:"from"`
+	To      *common.Address `json:"to,omitempty"`
+	Value   *hexutil.Big    `json:"value,omitempty"`
+	Gas     *hexutil.Uint64 `json:"gas,omitempty"`
+	GasUsed *hexutil.Uint64 `json:"gasUsed,omitempty"`
+	Input   *hexutil.Bytes  `json:"input,omitempty"`
+	Output  *hexutil.Bytes  `json:"output,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Time    string          `json:"time,omitempty"`
+	Calls   []*callFrame    `json:"calls,omitempty"`
+	Logs    []callLog       `json:"logs,omitempty"`
+
+	gasIn, gasCost uint64 // gas before the call opcode and its cost
+	outOff, outLen uint64 // memory range of the call output in the caller
+}
+
+type callTracerConfig struct {
+	OnlyTopCall bool `json:"onlyTopCall"` // If true, call tracer won't collect any subcalls
+	WithLog     bool `json:"withLog"`     // If true, call tracer will collect event logs
+}
+
+// callTracer is a native go tracer which extracts and reports all the internal
+// calls made by a transaction, along with any useful information. It follows
+// the logic of call_tracer.js step by step.
+type callTracer struct {
+	env         *vm.EVM
+	config      callTracerConfig
+	precompiles []common.Address
+	callstack   []*callFrame
+	descended   bool // whether we've just descended from an outer call into an inner one
+
+	// top level call context
+	typ      string
+	from, to common.Address
+	input    []byte
+	gas      uint64
+	value    *big.Int
+	output   []byte
+	gasUsed  uint64
+	time     string
+	err      error
+
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+}
+
+// newCallTracer returns a native go tracer which tracks
+// call frames of a tx, and implements vm.Tracer.
+func newCallTracer(ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
+	var config callTracerConfig
+	if cfg != nil {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, err
+		}
+	}
+	// First callframe is a placeholder of the top level call
+	return &callTracer{config: config, callstack: []*callFrame{{}}}, nil
+}
+
+// CaptureStart implements the vm.Tracer interface to initialize the tracing operation.
+func (t *callTracer) CaptureStart(env *vm.EVM, depth int, from common.Address, to common.Address, precompile bool, create bool, callType vm.CallType, input []byte, gas uint64, value *big.Int, code []byte) {
+	if depth != 0 {
+		return
+	}
+	t.env = env
+	t.precompiles = vm.ActivePrecompiles(env.ChainRules())
+	t.typ = vm.CALL.String()
+	if create {
+		t.typ = vm.CREATE.String()
+	}
+	t.from, t.to = from, to
+	t.input = common.CopyBytes(input)
+	t.gas = gas
+	t.value = value
+}
+
+// CaptureState implements the vm.Tracer interface to trace a single step of VM execution.
+func (t *callTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	// Skip if tracing was interrupted
+	if atomic.LoadUint32(&t.interrupt) > 0 {
+		env.Cancel()
+		return
+	}
+	if t.config.OnlyTopCall && depth > 1 {
+		return
+	}
+	// Capture any errors immediately
+	if err != nil {
+		t.fault(err)
+		return
+	}
+	if !t.config.OnlyTopCall {
+		switch op {
+		case vm.CREATE, vm.CREATE2:
+			// If a new contract is being created, add to the call stack
+			t.callstack = append(t.callstack, &callFrame{
+				Type:    op.String(),
+				From:    scope.Contract.Address(),
+				Input:   bytesPtr(memorySlice(scope, peek(scope, 1), peek(scope, 2))),
+				Value:   (*hexutil.Big)(peek(scope, 0).ToBig()),
+				gasIn:   gas,
+				gasCost: cost,
+			})
+			t.descended = true
+			return
+		case vm.SELFDESTRUCT:
+			// If a contract is being self destructed, gather that as a subcall too
+			to := common.Address(peek(scope, 0).Bytes20())
+			top := t.callstack[len(t.callstack)-1]
+			top.Calls = append(top.Calls, &callFrame{
+				Type:  op.String(),
+				From:  scope.Contract.Address(),
+				To:    &to,
+				Value: (*hexutil.Big)(env.IntraBlockState().GetBalance(scope.Contract.Address()).ToBig()),
+			})
+			return
+		case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+			// Skip any pre-compile invocations, those are just fancy opcodes
+			to := common.Address(peek(scope, 1).Bytes20())
+			if isPrecompiled(to, t.precompiles) {
+				return
+			}
+			off := 1
+			if op == vm.DELEGATECALL || op == vm.STATICCALL {
+				off = 0
+			}
+			call := &callFrame{
+				Type:    op.String(),
+				From:    scope.Contract.Address(),
+				To:      &to,
+				Input:   bytesPtr(memorySlice(scope, peek(scope, 2+off), peek(scope, 3+off))),
+				gasIn:   gas,
+				gasCost: cost,
+				outOff:  peek(scope, 4+off).Uint64(),
+				outLen:  peek(scope, 5+off).Uint64(),
+			}
+			if op == vm.CALL || op == vm.CALLCODE {
+				call.Value = (*hexutil.Big)(peek(scope, 2).ToBig())
+			}
+			t.callstack = append(t.callstack, call)
+			t.descended = true
+			return
+		}
+	}
+	// If we've just descended into an inner call, retrieve it's true allowance. We
+	// need to extract if from within the call as there may be funky gas dynamics
+	// with regard to requested and actually given gas (2300 stipend, 63/64 rule).
+	if t.descended {
+		if depth >= len(t.callstack) {
+			t.callstack[len(t.callstack)-1].Gas = uint64Ptr(gas)
+		}
+		t.descended = false
+	}
+	// If an existing call is returning, pop off the call stack
+	if op == vm.REVERT {
+		t.callstack[len(t.callstack)-1].Error = vm.ErrExecutionReverted.Error()
+		return
+	}
+	if depth == len(t.callstack)-1 {
+		// Pop off the last call and get the execution results
+		call := t.callstack[len(t.callstack)-1]
+		t.callstack = t.callstack[:len(t.callstack)-1]
+
+		ret := peek(scope, 0)
+		if call.Type == vm.CREATE.String() || call.Type == vm.CREATE2.String() {
+			// If the call was a CREATE, retrieve the contract address and output code
+			call.GasUsed = uint64Ptr(call.gasIn - call.gasCost - gas)
+			if !ret.IsZero() {
+				to := common.Address(ret.Bytes20())
+				call.To = &to
+				call.Output = bytesPtr(env.IntraBlockState().GetCode(to))
+			} else if call.Error == "" {
+				call.Error = "internal failure"
+			}
+		} else {
+			// If the call was a contract call, retrieve the gas usage and output
+			if call.Gas != nil {
+				call.GasUsed = uint64Ptr(call.gasIn - call.gasCost + uint64(*call.Gas) - gas)
+			}
+			if !ret.IsZero() {
+				call.Output = bytesPtr(memorySlice(scope, uint256.NewInt(call.outOff), uint256.NewInt(call.outLen)))
+			} else if call.Error == "" {
+				call.Error = "internal failure"
+			}
+		}
+		// Inject the call into the previous one
+		top := t.callstack[len(t.callstack)-1]
+		top.Calls = append(top.Calls, call)
+	}
+	if t.config.WithLog && op >= vm.LOG0 && op <= vm.LOG4 {
+		topics := make([]common.Hash, int(op-vm.LOG0))
+		for i := range topics {
+			topics[i] = common.Hash(peek(scope, 2+i).Bytes32())
+		}
+		top := t.callstack[len(t.callstack)-1]
+		top.Logs = append(top.Logs, callLog{
+			Address: scope.Contract.Address(),
+			Topics:  topics,
+			Data:    memorySlice(scope, peek(scope, 0), peek(scope, 1)),
+		})
+	}
+}
+
+// CaptureFault implements the vm.Tracer interface to trace an execution fault.
+func (t *callTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+	if atomic.LoadUint32(&t.interrupt) > 0 || (t.config.OnlyTopCall && depth > 1) {
+		return
+	}
+	t.fault(err)
+}
+
+// fault is invoked when the actual execution of an opcode fails.
+func (t *callTracer) fault(err error) {
+	// If the topmost call already reverted, don't handle the additional fault again
+	if t.callstack[len(t.callstack)-1].Error != "" {
+		return
+	}
+	// Pop off the just failed call
+	call := t.callstack[len(t.callstack)-1]
+	t.callstack = t.callstack[:len(t.callstack)-1]
+	call.Error = err.Error()
+
+	// Consume all available gas
+	if call.Gas != nil {
+		call.GasUsed = uint64Ptr(uint64(*call.Gas))
+	}
+	// Flatten the failed call into its parent
+	if len(t.callstack) > 0 {
+		top := t.callstack[len(t.callstack)-1]
+		top.Calls = append(top.Calls, call)
+		return
+	}
+	// Last call failed too, leave it in the stack
+	t.callstack = append(t.callstack, call)
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *callTracer) CaptureEnd(depth int, output []byte, startGas, endGas uint64, d time.Duration, err error) {
+	if depth != 0 {
+		return
+	}
+	t.output = common.CopyBytes(output)
+	t.gasUsed = startGas - endGas
+	t.time = d.String()
+	t.err = err
+}
+
+func (t *callTracer) CaptureSelfDestruct(from common.Address, to common.Address, value *big.Int) {
+}
+
+func (t *callTracer) CaptureAccountRead(account common.Address) error {
+	return nil
+}
+
+func (t *callTracer) CaptureAccountWrite(account common.Address) error {
+	return nil
+}
+
+// GetResult returns the json-encoded nested list of call traces, and any
+// error arising from the encoding or forceful termination (via `Stop`).
+func (t *callTracer) GetResult() (json.RawMessage, error) {
+	if t.reason != nil {
+		return nil, t.reason
+	}
+	if t.env == nil {
+		return nil, errors.New("incorrect number of top-level calls")
+	}
+	to := t.to
+	result := &callFrame{
+		Type:    t.typ,
+		From:    t.from,
+		To:      &to,
+		Value:   (*hexutil.Big)(t.value),
+		Gas:     uint64Ptr(t.gas),
+		GasUsed: uint64Ptr(t.gasUsed),
+		Input:   bytesPtr(t.input),
+		Output:  bytesPtr(t.output),
+		Time:    t.time,
+		Calls:   t.callstack[0].Calls,
+		Logs:    t.callstack[0].Logs,
+	}
+	if t.callstack[0].Error != "" {
+		result.Error = t.callstack[0].Error
+	} else if t.err != nil {
+		result.Error = t.err.Error()
+	}
+	if result.Error != "" && (result.Error != vm.ErrExecutionReverted.Error() || len(t.output) == 0) {
+		result.Output = nil
+	}
+	if t.config.WithLog {
+		clearFailedLogs(result, false)
+	}
+	return json.Marshal(result)
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *callTracer) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.interrupt, 1)
+}
+
+// clearFailedLogs clears the logs of a callframe and all its children
+// in case of execution failure.
+func clearFailedLogs(cf *callFrame, parentFailed bool) {
+	failed := cf.Error != "" || parentFailed
+	if failed {
+		cf.Logs = nil
+	}
+	for _, call := range cf.Calls {
+		clearFailedLogs(call, failed)
+	}
+}
+
+func uint64Ptr(v uint64) *hexutil.Uint64 {
+	return (*hexutil.Uint64)(&v)
+}
+
+func bytesPtr(b []byte) *hexutil.Bytes {
+	if b == nil {
+		b = []byte{}
+	}
+	return (*hexutil.Bytes)(&b)
+}
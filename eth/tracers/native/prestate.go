@@ -0,0 +1,298 @@
+package native
+
+import (
+	"encoding/json"
+	"errors"
+	"math/big"
+	"sync/atomic"
+	"time"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/tracers"
+)
+
+func init() {
+	register("prestateTracer", newPrestateTracer)
+}
+
+type account struct {
+	Balance *hexutil.Big                  `json:"balance,omitempty"`
+	Nonce   uint64                        `json:"nonce,omitempty"`
+	Code    hexutil.Bytes                 `json:"code,omitempty"`
+	Storage map[common.Hash]hexutil.Bytes `json:"storage,omitempty"`
+
+	exists bool // whether the account existed when it was first accessed
+}
+
+// prestateAccount is the account encoding of the prestate (non-diff) mode,
+// all the fields are always present.
+type prestateAccount struct {
+	Balance *hexutil.Big                  `json:"balance"`
+	Nonce   uint64                        `json:"nonce"`
+	Code    hexutil.Bytes                 `json:"code"`
+	Storage map[common.Hash]hexutil.Bytes `json:"storage"`
+}
+
+type prestateTracerConfig struct {
+	DiffMode bool `json:"diffMode"` // If true, this tracer will return state modifications
+}
+
+// prestateTracer is a native go tracer which collects the state accessed by a
+// transaction, before its execution. It follows the logic of prestate_tracer.js,
+// in diff mode it also reports the state after the execution.
+type prestateTracer struct {
+	env         *vm.EVM
+	config      prestateTracerConfig
+	precompiles []common.Address
+	pre         map[common.Address]*account
+	post        map[common.Address]*account
+	created     map[common.Address]bool
+
+	// top level call context
+	create       bool
+	from, to     common.Address
+	value        *big.Int
+	intrinsicGas uint64
+	gasUsed      uint64
+
+	interrupt uint32 // Atomic flag to signal execution interruption
+	reason    error  // Textual reason for the interruption
+}
+
+func newPrestateTracer(ctx *tracers.Context, cfg json.RawMessage) (tracers.Tracer, error) {
+	var config prestateTracerConfig
+	if cfg != nil {
+		if err := json.Unmarshal(cfg, &config); err != nil {
+			return nil, err
+		}
+	}
+	return &prestateTracer{
+		config:  config,
+		pre:     make(map[common.Address]*account),
+		post:    make(map[common.Address]*account),
+		created: make(map[common.Address]bool),
+	}, nil
+}
+
+// CaptureStart implements the vm.Tracer interface to initialize the tracing operation.
+func (t *prestateTracer) CaptureStart(env *vm.EVM, depth int, from common.Address, to common.Address, precompile bool, create bool, callType vm.CallType, input []byte, gas uint64, value *big.Int, code []byte) {
+	if depth != 0 {
+		return
+	}
+	t.env = env
+	t.precompiles = vm.ActivePrecompiles(env.ChainRules())
+	t.create = create
+	t.from, t.to = from, to
+	t.value = value
+	if create {
+		t.created[to] = true
+	}
+	// Compute intrinsic gas
+	isHomestead := env.ChainConfig().IsHomestead(env.Context().BlockNumber)
+	isIstanbul := env.ChainConfig().IsIstanbul(env.Context().BlockNumber)
+	intrinsicGas, err := core.IntrinsicGas(input, nil, create, isHomestead, isIstanbul)
+	if err != nil {
+		return
+	}
+	t.intrinsicGas = intrinsicGas
+}
+
+// CaptureState implements the vm.Tracer interface to trace a single step of VM execution.
+func (t *prestateTracer) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	// Skip if tracing was interrupted
+	if atomic.LoadUint32(&t.interrupt) > 0 {
+		env.Cancel()
+		return
+	}
+	caller := scope.Contract.Address()
+	// Add the current account if we just started tracing
+	if len(t.pre) == 0 {
+		// Balance will potentially be wrong here, since this will include the value
+		// sent along with the message. We fix that in GetResult.
+		t.lookupAccount(caller)
+	}
+	// Whenever new state is accessed, add it to the prestate
+	switch op {
+	case vm.EXTCODECOPY, vm.EXTCODESIZE, vm.EXTCODEHASH, vm.BALANCE:
+		t.lookupAccount(common.Address(peek(scope, 0).Bytes20()))
+	case vm.CREATE:
+		addr := crypto.CreateAddress(caller, env.IntraBlockState().GetNonce(caller))
+		t.lookupAccount(addr)
+		t.created[addr] = true
+	case vm.CREATE2:
+		// stack: salt, size, offset, endowment
+		code := memorySlice(scope, peek(scope, 1), peek(scope, 2))
+		addr := crypto.CreateAddress2(caller, peek(scope, 3).Bytes32(), crypto.Keccak256(code))
+		t.lookupAccount(addr)
+		t.created[addr] = true
+	case vm.CALL, vm.CALLCODE, vm.DELEGATECALL, vm.STATICCALL:
+		t.lookupAccount(common.Address(peek(scope, 1).Bytes20()))
+	case vm.SSTORE, vm.SLOAD:
+		t.lookupStorage(caller, common.Hash(peek(scope, 0).Bytes32()))
+	}
+}
+
+func (t *prestateTracer) CaptureFault(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, depth int, err error) {
+}
+
+// CaptureEnd is called after the call finishes to finalize the tracing.
+func (t *prestateTracer) CaptureEnd(depth int, output []byte, startGas, endGas uint64, d time.Duration, err error) {
+	if depth != 0 {
+		return
+	}
+	t.gasUsed = startGas - endGas
+}
+
+func (t *prestateTracer) CaptureSelfDestruct(from common.Address, to common.Address, value *big.Int) {
+}
+
+func (t *prestateTracer) CaptureAccountRead(account common.Address) error {
+	return nil
+}
+
+func (t *prestateTracer) CaptureAccountWrite(account common.Address) error {
+	return nil
+}
+
+// GetResult returns the json-encoded prestate, or the pre and post states in diff mode.
+func (t *prestateTracer) GetResult() (json.RawMessage, error) {
+	if t.reason != nil {
+		return nil, t.reason
+	}
+	if t.env == nil {
+		return nil, errors.New("incorrect number of top-level calls")
+	}
+	// At this point, we need to deduct the 'value' from the
+	// outer transaction, and move it back to the origin
+	t.lookupAccount(t.from)
+	t.lookupAccount(t.to)
+	fromBal, toBal := t.pre[t.from].Balance.ToInt(), t.pre[t.to].Balance.ToInt()
+
+	toBal.Sub(toBal, t.value)
+	gasCost := new(big.Int).Mul(new(big.Int).SetUint64(t.gasUsed+t.intrinsicGas), t.env.TxContext().GasPrice)
+	fromBal.Add(fromBal, t.value)
+	fromBal.Add(fromBal, gasCost)
+
+	// Decrement the caller's nonce, and remove empty create targets
+	if t.pre[t.from].Nonce > 0 {
+		t.pre[t.from].Nonce--
+	}
+	if t.config.DiffMode {
+		t.processDiffState()
+		return json.Marshal(struct {
+			Post map[common.Address]*account `json:"post"`
+			Pre  map[common.Address]*account `json:"pre"`
+		}{t.post, t.pre})
+	}
+	if t.create {
+		// We can blindly delete the contract prestate, as any existing state would
+		// have caused the transaction to be rejected as invalid in the first place.
+		delete(t.pre, t.to)
+	}
+	prestate := make(map[common.Address]prestateAccount, len(t.pre))
+	for addr, acc := range t.pre {
+		prestate[addr] = prestateAccount{Balance: acc.Balance, Nonce: acc.Nonce, Code: acc.Code, Storage: acc.Storage}
+	}
+	return json.Marshal(prestate)
+}
+
+// processDiffState keeps in the prestate only the accounts and storage slots
+// modified by the transaction, and collects their values after it into the poststate.
+func (t *prestateTracer) processDiffState() {
+	ibs := t.env.IntraBlockState()
+	for addr, state := range t.pre {
+		// The deleted account's state is pruned from `post` but kept in `pre`
+		if ibs.HasSuicided(addr) || !ibs.Exist(addr) {
+			continue
+		}
+		modified := false
+		postAccount := &account{Storage: make(map[common.Hash]hexutil.Bytes)}
+		newBalance := ibs.GetBalance(addr).ToBig()
+		newNonce := ibs.GetNonce(addr)
+		newCode := ibs.GetCode(addr)
+
+		if newBalance.Cmp(state.Balance.ToInt()) != 0 {
+			modified = true
+			postAccount.Balance = (*hexutil.Big)(newBalance)
+		}
+		if newNonce != state.Nonce {
+			modified = true
+			postAccount.Nonce = newNonce
+		}
+		if string(newCode) != string(state.Code) {
+			modified = true
+			postAccount.Code = newCode
+		}
+		for key, val := range state.Storage {
+			key := key
+			var newVal uint256.Int
+			ibs.GetState(addr, &key, &newVal)
+			if string(newVal.Bytes()) == string(val) {
+				// Omit unchanged slots
+				delete(state.Storage, key)
+				continue
+			}
+			modified = true
+			if !newVal.IsZero() {
+				postAccount.Storage[key] = newVal.Bytes()
+			}
+		}
+		if modified {
+			t.post[addr] = postAccount
+		} else {
+			// if state is not modified, then no need to include into the pre state
+			delete(t.pre, addr)
+		}
+	}
+	// the new created contracts' prestate were empty, so delete them
+	for addr := range t.created {
+		// the created contract maybe exists in the state before the creating tx
+		if s := t.pre[addr]; s != nil && (!s.exists || (t.create && addr == t.to)) {
+			delete(t.pre, addr)
+		}
+	}
+}
+
+// Stop terminates execution of the tracer at the first opportune moment.
+func (t *prestateTracer) Stop(err error) {
+	t.reason = err
+	atomic.StoreUint32(&t.interrupt, 1)
+}
+
+// lookupAccount fetches details of an account and adds it to the prestate
+// if it doesn't exist there.
+func (t *prestateTracer) lookupAccount(addr common.Address) {
+	if _, ok := t.pre[addr]; ok {
+		return
+	}
+	ibs := t.env.IntraBlockState()
+	code := ibs.GetCode(addr)
+	if code == nil {
+		code = []byte{}
+	}
+	t.pre[addr] = &account{
+		Balance: (*hexutil.Big)(ibs.GetBalance(addr).ToBig()),
+		Nonce:   ibs.GetNonce(addr),
+		Code:    common.CopyBytes(code),
+		Storage: make(map[common.Hash]hexutil.Bytes),
+		exists:  ibs.Exist(addr),
+	}
+}
+
+// lookupStorage fetches the requested storage slot and adds
+// it to the prestate of the given contract. It assumes `lookupAccount`
+// has been performed on the contract before.
+func (t *prestateTracer) lookupStorage(addr common.Address, key common.Hash) {
+	t.lookupAccount(addr)
+	if _, ok := t.pre[addr].Storage[key]; ok {
+		return
+	}
+	var val uint256.Int
+	t.env.IntraBlockState().GetState(addr, &key, &val)
+	t.pre[addr].Storage[key] = val.Bytes()
+}
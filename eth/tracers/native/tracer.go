@@ -0,0 +1,89 @@
+/*
+Package native is a collection of tracers written in go.
+
+In order to add a native tracer and have it compiled into the binary, a new
+file needs to be added to this folder, containing an implementation of the
+`tracers.Tracer` interface.
+
+Aside from implementing the tracer, it also needs to register itself, using the
+`register` method -- and this needs to be done in the package initialization. It
+registers the tracer through tracers.RegisterGoTracer, like the Go tracers
+compiled into the binary from other packages.
+
+Example:
+
+	func init() {
+		register("noopTracer", newNoopTracer)
+	}
+
+The built-in tracers port the logic of their JavaScript counterparts in
+eth/tracers/internal/tracers, so that their output stays the same.
+*/
+package native
+
+import (
+	"encoding/json"
+
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/eth/tracers"
+)
+
+// ctorFn is the constructor signature of a native tracer.
+type ctorFn = func(*tracers.Context, json.RawMessage) (tracers.Tracer, error)
+
+// register is used by native tracers to register their presence, as Go tracers. They take
+// priority over the JavaScript tracers of the same name.
+func register(name string, ctor ctorFn) {
+	goCtor := func(ctx *tracers.Context, cfg json.RawMessage) (vm.Tracer, error) {
+		t, err := ctor(ctx, cfg)
+		if err != nil {
+			return nil, err
+		}
+		return t, nil
+	}
+	if err := tracers.RegisterGoTracer(name, goCtor, result); err != nil {
+		panic(err)
+	}
+}
+
+// result returns the result of a native tracer.
+func result(t vm.Tracer) (json.RawMessage, error) {
+	return t.(tracers.Tracer).GetResult()
+}
+
+// peek returns the nth-from-the-top element of the stack, or zero if the stack
+// is not deep enough, as the JavaScript tracers do.
+func peek(scope *vm.ScopeContext, n int) *uint256.Int {
+	if scope.Stack.Len() <= n || n < 0 {
+		return new(uint256.Int)
+	}
+	return scope.Stack.Back(n)
+}
+
+// memorySlice returns a copy of the requested memory range, or an empty slice if
+// the range is out of bounds.
+func memorySlice(scope *vm.ScopeContext, offset, size *uint256.Int) []byte {
+	if size.IsZero() {
+		return []byte{}
+	}
+	if !offset.IsUint64() || !size.IsUint64() {
+		return []byte{}
+	}
+	begin, length := offset.Uint64(), size.Uint64()
+	if begin+length < begin || uint64(scope.Memory.Len()) < begin+length {
+		return []byte{}
+	}
+	return scope.Memory.GetCopy(begin, length)
+}
+
+// isPrecompiled returns whether the address is one of the given precompiles.
+func isPrecompiled(addr common.Address, precompiles []common.Address) bool {
+	for _, p := range precompiles {
+		if p == addr {
+			return true
+		}
+	}
+	return false
+}
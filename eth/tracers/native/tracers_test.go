@@ -0,0 +1,321 @@
+package native_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+	"github.com/ledgerwatch/erigon/common/math"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/ledgerwatch/erigon/eth/tracers"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/ledgerwatch/erigon/tests"
+	"github.com/stretchr/testify/require"
+
+	// Force-load the native tracers to trigger registration
+	_ "github.com/ledgerwatch/erigon/eth/tracers/native"
+)
+
+type callContext struct {
+	Number     math.HexOrDecimal64   `json:"number"`
+	Difficulty *math.HexOrDecimal256 `json:"difficulty"`
+	Time       math.HexOrDecimal64   `json:"timestamp"`
+	GasLimit   math.HexOrDecimal64   `json:"gasLimit"`
+	Miner      common.Address        `json:"miner"`
+}
+
+type callTrace struct {
+	Type    string          `json:"type"`
+	From    common.Address  `json:"from"`
+	To      common.Address  `json:"to"`
+	Input   hexutil.Bytes   `json:"input"`
+	Output  hexutil.Bytes   `json:"output"`
+	Gas     *hexutil.Uint64 `json:"gas,omitempty"`
+	GasUsed *hexutil.Uint64 `json:"gasUsed,omitempty"`
+	Value   *hexutil.Big    `json:"value,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	Calls   []callTrace     `json:"calls,omitempty"`
+}
+
+// callTracerTest defines a single test to check the call tracer against.
+type callTracerTest struct {
+	Genesis *core.Genesis   `json:"genesis"`
+	Context *callContext    `json:"context"`
+	Input   string          `json:"input"`
+	Result  json.RawMessage `json:"result"`
+}
+
+// traceTestdata runs the tracer created by newTracer for every transaction of the
+// JavaScript tracers test suite, and passes the results to check.
+func traceTestdata(t *testing.T, newTracer func() (tracers.Tracer, error), check func(t *testing.T, test *callTracerTest, res json.RawMessage)) {
+	dir := filepath.Join("..", "testdata")
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	for _, file := range files {
+		if !strings.HasPrefix(file.Name(), "call_tracer_") {
+			continue
+		}
+		file := file // capture range variable
+		t.Run(strings.TrimSuffix(strings.TrimPrefix(file.Name(), "call_tracer_"), ".json"), func(t *testing.T) {
+			blob, err := os.ReadFile(filepath.Join(dir, file.Name()))
+			require.NoError(t, err)
+			test := new(callTracerTest)
+			require.NoError(t, json.Unmarshal(blob, test))
+
+			tracer, err := newTracer()
+			require.NoError(t, err)
+			check(t, test, runTracer(t, test, tracer))
+		})
+	}
+}
+
+// requireCallTrace checks a call tracer result against the expected call trace,
+// ignoring the fields which are not part of the expectations.
+func requireCallTrace(t *testing.T, want, have json.RawMessage) {
+	wantTrace, haveTrace := new(callTrace), new(callTrace)
+	require.NoError(t, json.Unmarshal(want, wantTrace))
+	require.NoError(t, json.Unmarshal(have, haveTrace))
+	wantJSON, err := json.Marshal(wantTrace)
+	require.NoError(t, err)
+	haveJSON, err := json.Marshal(haveTrace)
+	require.NoError(t, err)
+	require.JSONEq(t, string(wantJSON), string(haveJSON))
+}
+
+// sender returns the sender of the test transaction.
+func sender(t *testing.T, test *callTracerTest) common.Address {
+	txn, err := types.DecodeTransaction(rlp.NewStream(bytes.NewReader(common.FromHex(test.Input)), 0))
+	require.NoError(t, err)
+	origin, err := types.MakeSigner(test.Genesis.Config, uint64(test.Context.Number)).Sender(txn)
+	require.NoError(t, err)
+	return origin
+}
+
+func runTracer(t *testing.T, test *callTracerTest, tracer tracers.Tracer) json.RawMessage {
+	txn, err := types.DecodeTransaction(rlp.NewStream(bytes.NewReader(common.FromHex(test.Input)), 0))
+	require.NoError(t, err)
+	signer := types.MakeSigner(test.Genesis.Config, uint64(test.Context.Number))
+	origin, _ := signer.Sender(txn)
+	txContext := vm.TxContext{
+		Origin:   origin,
+		GasPrice: big.NewInt(int64(txn.GetPrice().Uint64())),
+	}
+	context := vm.BlockContext{
+		CanTransfer:     core.CanTransfer,
+		Transfer:        core.Transfer,
+		Coinbase:        test.Context.Miner,
+		BlockNumber:     uint64(test.Context.Number),
+		Time:            uint64(test.Context.Time),
+		Difficulty:      (*big.Int)(test.Context.Difficulty),
+		GasLimit:        uint64(test.Context.GasLimit),
+		ContractHasTEVM: func(common.Hash) (bool, error) { return false, nil },
+	}
+	_, tx := memdb.NewTestTx(t)
+	rules := &params.Rules{}
+	statedb, err := tests.MakePreState(rules, tx, test.Genesis.Alloc, uint64(test.Context.Number))
+	require.NoError(t, err)
+
+	evm := vm.NewEVM(context, txContext, statedb, test.Genesis.Config, vm.Config{Debug: true, Tracer: tracer})
+	msg, err := txn.AsMessage(*signer, nil, rules)
+	require.NoError(t, err)
+	st := core.NewStateTransition(evm, msg, new(core.GasPool).AddGas(txn.GetGas()))
+	_, err = st.TransitionDb(false, false)
+	require.NoError(t, err)
+	res, err := tracer.GetResult()
+	require.NoError(t, err)
+	return res
+}
+
+// jsTracer runs the JavaScript version of the named tracer on the same transaction.
+func jsTracer(t *testing.T, name string, test *callTracerTest) json.RawMessage {
+	tracer, err := tracers.NewJsTracer(name, new(tracers.Context))
+	require.NoError(t, err)
+	return runTracer(t, test, tracer)
+}
+
+// normalize decodes a json result into generic values, dropping the time fields.
+func normalize(t *testing.T, res json.RawMessage) interface{} {
+	var v interface{}
+	require.NoError(t, json.Unmarshal(res, &v))
+	var drop func(v interface{})
+	drop = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			delete(v, "time")
+			for _, child := range v {
+				drop(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				drop(child)
+			}
+		}
+	}
+	drop(v)
+	return v
+}
+
+func TestNativeTracersTakePriority(t *testing.T) {
+	for _, name := range []string{"callTracer", "prestateTracer", "4byteTracer", "noopTracer"} {
+		tracer, err := tracers.New(name, new(tracers.Context), nil)
+		require.NoError(t, err)
+		if _, ok := tracer.(*tracers.JsTracer); ok {
+			t.Fatalf("%s: JavaScript tracer selected", name)
+		}
+	}
+	// they are registered as Go tracers, whose names are unique
+	require.Subset(t, tracers.GoTracerNames(), []string{"callTracer", "prestateTracer", "4byteTracer", "noopTracer"})
+	require.Error(t, tracers.RegisterGoTracer("callTracer", func(*tracers.Context, json.RawMessage) (vm.Tracer, error) {
+		return vm.NewStructLogger(nil), nil
+	}, func(vm.Tracer) (json.RawMessage, error) { return nil, nil }))
+	// Tracers which are not ported are still served by the JavaScript engine
+	tracer, err := tracers.New("opcountTracer", new(tracers.Context), nil)
+	require.NoError(t, err)
+	require.IsType(t, &tracers.JsTracer{}, tracer)
+}
+
+func TestNativeTracersRejectBadConfig(t *testing.T) {
+	for _, name := range []string{"callTracer", "prestateTracer"} {
+		// the name isn't tried as JavaScript, which would ignore the config
+		tracer, err := tracers.New(name, new(tracers.Context), json.RawMessage(`{"onlyTopCall":"yes","diffMode":"yes"}`))
+		require.Error(t, err, name)
+		require.Nil(t, tracer, name)
+		var ctorErr *tracers.CtorError
+		require.ErrorAs(t, err, &ctorErr, name)
+	}
+}
+
+func TestCallTracer(t *testing.T) {
+	traceTestdata(t, func() (tracers.Tracer, error) {
+		return tracers.New("callTracer", new(tracers.Context), nil)
+	}, func(t *testing.T, test *callTracerTest, res json.RawMessage) {
+		require.Equal(t, normalize(t, jsTracer(t, "callTracer", test)), normalize(t, res))
+		requireCallTrace(t, test.Result, res)
+	})
+}
+
+func TestCallTracerOnlyTopCall(t *testing.T) {
+	traceTestdata(t, func() (tracers.Tracer, error) {
+		return tracers.New("callTracer", new(tracers.Context), json.RawMessage(`{"onlyTopCall": true}`))
+	}, func(t *testing.T, test *callTracerTest, res json.RawMessage) {
+		want := new(callTrace)
+		require.NoError(t, json.Unmarshal(test.Result, want))
+		want.Calls = nil
+		blob, err := json.Marshal(want)
+		require.NoError(t, err)
+		requireCallTrace(t, blob, res)
+	})
+}
+
+func TestCallTracerWithLog(t *testing.T) {
+	var logs int
+	traceTestdata(t, func() (tracers.Tracer, error) {
+		return tracers.New("callTracer", new(tracers.Context), json.RawMessage(`{"withLog": true}`))
+	}, func(t *testing.T, test *callTracerTest, res json.RawMessage) {
+		// apart from the logs, the result is the same
+		have := normalize(t, res)
+		var drop func(v interface{}, failed bool)
+		drop = func(v interface{}, failed bool) {
+			frame := v.(map[string]interface{})
+			failed = failed || frame["error"] != nil
+			if frameLogs, ok := frame["logs"]; ok {
+				require.False(t, failed, "logs of a failed call")
+				logs += len(frameLogs.([]interface{}))
+				delete(frame, "logs")
+			}
+			if calls, ok := frame["calls"]; ok {
+				for _, call := range calls.([]interface{}) {
+					drop(call, failed)
+				}
+			}
+		}
+		drop(have, false)
+		blob, err := json.Marshal(have)
+		require.NoError(t, err)
+		requireCallTrace(t, test.Result, blob)
+	})
+	require.NotZero(t, logs)
+}
+
+func TestPrestateTracer(t *testing.T) {
+	traceTestdata(t, func() (tracers.Tracer, error) {
+		return tracers.New("prestateTracer", new(tracers.Context), nil)
+	}, func(t *testing.T, test *callTracerTest, res json.RawMessage) {
+		var prestate map[common.Address]struct {
+			Balance *hexutil.Big                  `json:"balance"`
+			Nonce   uint64                        `json:"nonce"`
+			Code    hexutil.Bytes                 `json:"code"`
+			Storage map[common.Hash]hexutil.Bytes `json:"storage"`
+		}
+		require.NoError(t, json.Unmarshal(res, &prestate))
+		origin := sender(t, test)
+		require.Contains(t, prestate, origin)
+		// the prestate of the accessed accounts is the genesis, apart from the balance
+		// of the sender which depends on the gas refunds
+		for addr, acc := range prestate {
+			alloc := test.Genesis.Alloc[addr]
+			if addr != origin {
+				require.Equal(t, alloc.Balance.String(), acc.Balance.ToInt().String(), "balance %x", addr)
+			}
+			require.Equal(t, alloc.Nonce, acc.Nonce, "nonce %x", addr)
+			require.Equal(t, common.CopyBytes(alloc.Code), []byte(acc.Code), "code %x", addr)
+			for key, val := range acc.Storage {
+				require.Equal(t, alloc.Storage[key].Big().String(), new(big.Int).SetBytes(val).String(), "storage %x %x", addr, key)
+			}
+		}
+	})
+}
+
+func TestPrestateTracerDiffMode(t *testing.T) {
+	traceTestdata(t, func() (tracers.Tracer, error) {
+		return tracers.New("prestateTracer", new(tracers.Context), json.RawMessage(`{"diffMode": true}`))
+	}, func(t *testing.T, test *callTracerTest, res json.RawMessage) {
+		type diffAccount struct {
+			Nonce   uint64                        `json:"nonce"`
+			Storage map[common.Hash]hexutil.Bytes `json:"storage"`
+		}
+		var result struct {
+			Pre  map[common.Address]diffAccount `json:"pre"`
+			Post map[common.Address]diffAccount `json:"post"`
+		}
+		require.NoError(t, json.Unmarshal(res, &result))
+		// the sender pays for the gas and increments its nonce
+		origin := sender(t, test)
+		require.Contains(t, result.Pre, origin)
+		require.Contains(t, result.Post, origin)
+		require.Equal(t, test.Genesis.Alloc[origin].Nonce, result.Pre[origin].Nonce)
+		require.Equal(t, result.Pre[origin].Nonce+1, result.Post[origin].Nonce)
+		// only the modified slots are reported
+		for addr, acc := range result.Post {
+			for key, val := range acc.Storage {
+				require.NotEqual(t, test.Genesis.Alloc[addr].Storage[key].Big().String(), new(big.Int).SetBytes(val).String(), "storage %x %x", addr, key)
+			}
+		}
+	})
+}
+
+func TestFourByteTracer(t *testing.T) {
+	traceTestdata(t, func() (tracers.Tracer, error) {
+		return tracers.New("4byteTracer", new(tracers.Context), nil)
+	}, func(t *testing.T, test *callTracerTest, res json.RawMessage) {
+		require.Equal(t, normalize(t, jsTracer(t, "4byteTracer", test)), normalize(t, res))
+	})
+}
+
+func TestNoopTracer(t *testing.T) {
+	traceTestdata(t, func() (tracers.Tracer, error) {
+		return tracers.New("noopTracer", new(tracers.Context), nil)
+	}, func(t *testing.T, test *callTracerTest, res json.RawMessage) {
+		require.Equal(t, normalize(t, jsTracer(t, "noopTracer", test)), normalize(t, res))
+	})
+}
@@ -0,0 +1,113 @@
+package tracers
+
+import (
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/ledgerwatch/erigon/core/vm"
+)
+
+// GoTracerCtor constructs a Go tracer, given the tracer config of the trace request
+// (TraceConfig.TracerConfig), which is nil when the request has none.
+type GoTracerCtor func(ctx *Context, cfg json.RawMessage) (vm.Tracer, error)
+
+// GoTracerResult marshals the result of a Go tracer built by its GoTracerCtor, once
+// the traced transaction has been executed.
+type GoTracerResult func(tracer vm.Tracer) (json.RawMessage, error)
+
+type goTracerEntry struct {
+	ctor   GoTracerCtor
+	result GoTracerResult
+}
+
+var (
+	goTracersLock sync.RWMutex
+	goTracers     = make(map[string]goTracerEntry)
+)
+
+// RegisterGoTracer makes a tracer written in Go against vm.Tracer available by name to
+// debug_traceTransaction, debug_traceCall and debug_traceBlock*, like the built-in tracers.
+// It's meant to be called from the init function of a package compiled into the binary, e.g.
+//
+//	func init() {
+//		tracers.RegisterGoTracer("opcodeCounter", newOpcodeCounter, opcodeCounterResult)
+//	}
+//
+// The built-in native tracers of eth/tracers/native are registered the same way. The name
+// can't be the one of a Go tracer registered before, built-in or not; a Go tracer takes
+// priority over the JavaScript tracer of the same name, as the native ports of the JavaScript
+// tracers do. A tracer implementing Stop(error) is stopped on the timeout of the request, the
+// others run to the end of the transaction and the timeout is reported instead of their result.
+func RegisterGoTracer(name string, ctor GoTracerCtor, result GoTracerResult) error {
+	if name == "" || ctor == nil || result == nil {
+		return fmt.Errorf("go tracer %q: name, constructor and result marshaller are required", name)
+	}
+	goTracersLock.Lock()
+	defer goTracersLock.Unlock()
+	if _, ok := goTracers[name]; ok {
+		return fmt.Errorf("go tracer %q: already registered", name)
+	}
+	goTracers[name] = goTracerEntry{ctor: ctor, result: result}
+	return nil
+}
+
+// GoTracerNames returns the names of the registered Go tracers, the built-in native ones included.
+func GoTracerNames() []string {
+	goTracersLock.RLock()
+	defer goTracersLock.RUnlock()
+	names := make([]string, 0, len(goTracers))
+	for name := range goTracers {
+		names = append(names, name)
+	}
+	return names
+}
+
+// lookupGoTracer returns the registered Go tracer of the given name.
+func lookupGoTracer(name string, ctx *Context, cfg json.RawMessage) (Tracer, error) {
+	goTracersLock.RLock()
+	entry, ok := goTracers[name]
+	goTracersLock.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("no go tracer %q", name)
+	}
+	t, err := entry.ctor(ctx, cfg)
+	if err != nil {
+		return nil, &CtorError{Name: name, Err: err}
+	}
+	return &goTracer{Tracer: t, result: entry.result}, nil
+}
+
+// CtorError is returned by a lookup when the constructor of the Go tracer matching the name fails, e.g. on
+// an invalid tracer config. It ends the lookup of the tracer, instead of trying the name as JavaScript code.
+type CtorError struct {
+	Name string
+	Err  error
+}
+
+func (e *CtorError) Error() string { return fmt.Sprintf("go tracer %q: %v", e.Name, e.Err) }
+func (e *CtorError) Unwrap() error { return e.Err }
+
+// goTracer adapts a registered Go tracer to the Tracer interface.
+type goTracer struct {
+	vm.Tracer
+	result GoTracerResult
+	reason atomic.Value // error the tracer was stopped with
+}
+
+// GetResult marshals the result of the tracer, or returns the reason it was stopped.
+func (t *goTracer) GetResult() (json.RawMessage, error) {
+	if reason, ok := t.reason.Load().(error); ok {
+		return nil, reason
+	}
+	return t.result(t.Tracer)
+}
+
+// Stop terminates execution of the tracer if it supports it.
+func (t *goTracer) Stop(err error) {
+	t.reason.Store(err)
+	if s, ok := t.Tracer.(interface{ Stop(error) }); ok {
+		s.Stop(err)
+	}
+}
@@ -0,0 +1,73 @@
+package tracers
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/core/vm"
+	"github.com/stretchr/testify/require"
+)
+
+// opcodeCounter is a Go tracer counting the executed opcodes, optionally only the given one.
+type opcodeCounter struct {
+	vm.Tracer
+	only   string
+	counts map[string]int
+}
+
+func (c *opcodeCounter) CaptureState(env *vm.EVM, pc uint64, op vm.OpCode, gas, cost uint64, scope *vm.ScopeContext, rData []byte, depth int, err error) {
+	if c.only == "" || c.only == op.String() {
+		c.counts[op.String()]++
+	}
+}
+
+func TestRegisterGoTracer(t *testing.T) {
+	ctor := func(_ *Context, cfg json.RawMessage) (vm.Tracer, error) {
+		var config struct {
+			Only string `json:"only"`
+		}
+		if cfg != nil {
+			if err := json.Unmarshal(cfg, &config); err != nil {
+				return nil, err
+			}
+		}
+		return &opcodeCounter{Tracer: vm.NewStructLogger(nil), only: config.Only, counts: map[string]int{}}, nil
+	}
+	result := func(tracer vm.Tracer) (json.RawMessage, error) {
+		return json.Marshal(tracer.(*opcodeCounter).counts)
+	}
+	require.NoError(t, RegisterGoTracer("opcodeCounter", ctor, result))
+	defer func() {
+		goTracersLock.Lock()
+		delete(goTracers, "opcodeCounter")
+		goTracersLock.Unlock()
+	}()
+	require.Contains(t, GoTracerNames(), "opcodeCounter")
+
+	require.Error(t, RegisterGoTracer("opcodeCounter", ctor, result), "registered twice")
+	require.Error(t, RegisterGoTracer("noResult", ctor, nil))
+
+	tracer, err := New("opcodeCounter", new(Context), nil)
+	require.NoError(t, err)
+	res, err := runTrace(tracer, testCtx())
+	require.NoError(t, err)
+	require.JSONEq(t, `{"PUSH1":2,"STOP":1}`, string(res))
+
+	tracer, err = New("opcodeCounter", new(Context), json.RawMessage(`{"only":"STOP"}`))
+	require.NoError(t, err)
+	res, err = runTrace(tracer, testCtx())
+	require.NoError(t, err)
+	require.JSONEq(t, `{"STOP":1}`, string(res))
+
+	_, err = New("opcodeCounter", new(Context), json.RawMessage(`{"only":1}`))
+	var typeErr *json.UnmarshalTypeError
+	require.ErrorAs(t, err, &typeErr, "the error of the constructor is returned")
+
+	tracer, err = New("opcodeCounter", new(Context), nil)
+	require.NoError(t, err)
+	timeout := errors.New("execution timeout")
+	tracer.Stop(timeout)
+	_, err = tracer.GetResult()
+	require.Equal(t, timeout, err)
+}
@@ -18,12 +18,60 @@
 package tracers
 
 import (
+	"encoding/json"
+	"errors"
 	"strings"
 	"unicode"
 
+	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/eth/tracers/internal/tracers"
 )
 
+// Tracer interface extends vm.Tracer and additionally
+// allows collecting the tracing result.
+type Tracer interface {
+	vm.Tracer
+	GetResult() (json.RawMessage, error)
+	// Stop terminates execution of the tracer at the first opportune moment.
+	Stop(err error)
+}
+
+type lookupFunc func(string, *Context, json.RawMessage) (Tracer, error)
+
+var (
+	lookups []lookupFunc
+)
+
+// RegisterLookup registers a method as a lookup for tracers, meaning that
+// users can invoke a named tracer through that lookup. If 'wildcard' is true,
+// then the lookup will be placed last. This is typically meant for interpreted
+// engines (js) which can evaluate dynamic user-supplied code.
+func RegisterLookup(wildcard bool, lookup lookupFunc) {
+	if wildcard {
+		lookups = append(lookups, lookup)
+	} else {
+		lookups = append([]lookupFunc{lookup}, lookups...)
+	}
+}
+
+// New returns a new instance of a tracer, by iterating through the
+// registered lookups. The native Go tracers take priority over the
+// JavaScript ones, which also evaluate user-supplied code.
+func New(code string, ctx *Context, cfg json.RawMessage) (Tracer, error) {
+	err := errors.New("tracer not found")
+	for _, lookup := range lookups {
+		var tracer Tracer
+		if tracer, err = lookup(code, ctx, cfg); err == nil {
+			return tracer, nil
+		}
+		var ctorErr *CtorError
+		if errors.As(err, &ctorErr) {
+			return nil, err
+		}
+	}
+	return nil, err
+}
+
 // all contains all the built in JavaScript tracers by name.
 var all = make(map[string]string)
 
@@ -42,6 +90,10 @@ func init() {
 		name := camel(strings.TrimSuffix(file, ".js"))
 		all[name] = string(tracers.MustAsset(file))
 	}
+	RegisterLookup(true, func(code string, ctx *Context, _ json.RawMessage) (Tracer, error) {
+		return NewJsTracer(code, ctx)
+	})
+	RegisterLookup(false, lookupGoTracer)
 }
 
 // tracer retrieves a specific JavaScript tracer by name.
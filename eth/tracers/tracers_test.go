@@ -181,7 +181,7 @@ func TestPrestateTracerCreate2(t *testing.T) {
 	statedb, _ := tests.MakePreState(rules, tx, alloc, context.BlockNumber)
 
 	// Create the tracer, the EVM environment and run it
-	tracer, err := New("prestateTracer", new(Context))
+	tracer, err := New("prestateTracer", new(Context), nil)
 	if err != nil {
 		t.Fatalf("failed to create call tracer: %v", err)
 	}
@@ -261,7 +261,7 @@ func TestCallTracer(t *testing.T) {
 			require.NoError(t, err)
 
 			// Create the tracer, the EVM environment and run it
-			tracer, err := New("callTracer", new(Context))
+			tracer, err := New("callTracer", new(Context), nil)
 			if err != nil {
 				t.Fatalf("failed to create call tracer: %v", err)
 			}
@@ -36,7 +36,17 @@ import (
 // 2.2.0 - add NodesInfo function
 // 3.0.0 - adding PoS interfaces
 // 3.1.0 - add Subscribe to logs
-var EthBackendAPIVersion = &types2.VersionReply{Major: 3, Minor: 1, Patch: 0}
+// 3.2.0 - add Subscribe to the sync progress (EventSyncing)
+var EthBackendAPIVersion = &types2.VersionReply{Major: 3, Minor: 2, Patch: 0}
+
+// ErrSyncingUnsupported is returned when subscribing to the sync progress of a backend older than 3.2.0.
+var ErrSyncingUnsupported = errors.New("the backend doesn't serve the sync progress")
+
+// SupportsSyncing returns whether the backend of the given version serves EventSyncing subscriptions. The older
+// ones don't know the event type and would stream the headers instead.
+func SupportsSyncing(version *types2.VersionReply) bool {
+	return version.Major == EthBackendAPIVersion.Major && version.Minor >= 2
+}
 
 const MaxBuilders = 128
 
@@ -140,6 +150,12 @@ func (s *EthBackendServer) NetPeerCount(_ context.Context, _ *remote.NetPeerCoun
 }
 
 func (s *EthBackendServer) Subscribe(r *remote.SubscribeRequest, subscribeServer remote.ETHBACKEND_SubscribeServer) (err error) {
+	if r.Type == EventSyncing {
+		return s.subscribeSyncing(subscribeServer)
+	}
+	if _, ok := remote.Event_name[int32(r.Type)]; !ok {
+		return fmt.Errorf("unsupported event type %d", r.Type)
+	}
 	log.Debug("Establishing event subscription channel with the RPC daemon ...")
 	ch, clean := s.events.AddHeaderSubscription()
 	defer clean()
@@ -179,6 +195,25 @@ func (s *EthBackendServer) Subscribe(r *remote.SubscribeRequest, subscribeServer
 	}
 }
 
+// subscribeSyncing streams the sync progress to the subscriptions asking for EventSyncing only,
+// the other subscriptions not knowing the event type.
+func (s *EthBackendServer) subscribeSyncing(subscribeServer remote.ETHBACKEND_SubscribeServer) error {
+	syncingCh, syncingClean := s.events.AddSyncingSubscription()
+	defer syncingClean()
+	for {
+		select {
+		case <-s.ctx.Done():
+			return s.ctx.Err()
+		case <-subscribeServer.Context().Done():
+			return subscribeServer.Context().Err()
+		case progressRlp := <-syncingCh:
+			if err := subscribeServer.Send(&remote.SubscribeReply{Type: EventSyncing, Data: progressRlp}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
 func (s *EthBackendServer) ProtocolVersion(_ context.Context, _ *remote.ProtocolVersionRequest) (*remote.ProtocolVersionReply, error) {
 	// Hardcoding to avoid import cycle
 	return &remote.ProtocolVersionReply{Id: 66}, nil
@@ -0,0 +1,26 @@
+package privateapi
+
+import (
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/remote"
+	types2 "github.com/ledgerwatch/erigon-lib/gointerfaces/types"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSupportsSyncing(t *testing.T) {
+	require.True(t, SupportsSyncing(EthBackendAPIVersion))
+	// the backends of 3.1.0 don't know EventSyncing and would stream the headers instead
+	require.False(t, SupportsSyncing(&types2.VersionReply{Major: 3, Minor: 1, Patch: 0}))
+	require.False(t, SupportsSyncing(&types2.VersionReply{Major: 4, Minor: 2, Patch: 0}))
+}
+
+func TestEventSyncing(t *testing.T) {
+	// erigon-lib must not give the value of the sync progress to another event, the remote rpcdaemons
+	// built against it would receive the sync progress for it
+	if name, ok := remote.Event_name[int32(EventSyncing)]; ok {
+		require.Equal(t, "SYNCING", name)
+	} else {
+		require.EqualValues(t, syncingEventValue, EventSyncing)
+	}
+}
@@ -10,6 +10,39 @@ import (
 
 type RpcEventType uint64
 
+// EventSyncing is the type of the Subscribe replies carrying the sync progress, a RLP encoded
+// SyncProgress. They are sent only to the subscriptions requesting this type, so the clients
+// which don't know it never receive it. The type is the SYNCING value of remote.Event, whose
+// enum is generated from the remote proto of erigon-lib; as long as the erigon-lib required by
+// go.mod doesn't declare it, the value reserved for it there, 4, is used. TestEventSyncing fails
+// on an erigon-lib giving this value to another event. The event is negotiated by the version of
+// the backend: only the backends of version 3.2.0 and later serve it, see SupportsSyncing.
+var EventSyncing = eventSyncing()
+
+// syncingEventValue is the value of the SYNCING event in the remote proto of erigon-lib
+const syncingEventValue = 4
+
+func eventSyncing() remote.Event {
+	if v, ok := remote.Event_value["SYNCING"]; ok {
+		return remote.Event(v)
+	}
+	return syncingEventValue
+}
+
+// SyncProgress is the sync status sent on every sync cycle, as reported by eth_syncing.
+type SyncProgress struct {
+	Syncing       bool
+	StartingBlock uint64
+	CurrentBlock  uint64
+	HighestBlock  uint64
+	Stages        []StageProgress
+}
+
+type StageProgress struct {
+	Stage       string
+	BlockNumber uint64
+}
+
 type NewSnapshotSubscription func() error
 type HeaderSubscription func(headerRLP []byte) error
 type PendingLogsSubscription func(types.Logs) error
@@ -22,6 +55,7 @@ type Events struct {
 	id                        int
 	headerSubscriptions       map[int]chan [][]byte
 	newSnapshotSubscription   map[int]chan struct{}
+	syncingSubscriptions      map[int]chan []byte
 	pendingLogsSubscriptions  map[int]PendingLogsSubscription
 	pendingBlockSubscriptions map[int]PendingBlockSubscription
 	pendingTxsSubscriptions   map[int]PendingTxsSubscription
@@ -38,6 +72,7 @@ func NewEvents() *Events {
 		pendingTxsSubscriptions:   map[int]PendingTxsSubscription{},
 		logsSubscriptions:         map[int]chan []*remote.SubscribeLogsReply{},
 		newSnapshotSubscription:   map[int]chan struct{}{},
+		syncingSubscriptions:      map[int]chan []byte{},
 	}
 }
 
@@ -67,6 +102,19 @@ func (e *Events) AddNewSnapshotSubscription() (chan struct{}, func()) {
 	}
 }
 
+func (e *Events) AddSyncingSubscription() (chan []byte, func()) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	ch := make(chan []byte, 8)
+	e.id++
+	id := e.id
+	e.syncingSubscriptions[id] = ch
+	return ch, func() {
+		delete(e.syncingSubscriptions, id)
+		close(ch)
+	}
+}
+
 func (e *Events) AddLogsSubscription() (chan []*remote.SubscribeLogsReply, func()) {
 	e.lock.Lock()
 	defer e.lock.Unlock()
@@ -120,6 +168,14 @@ func (e *Events) OnNewHeader(newHeadersRlp [][]byte) {
 	}
 }
 
+func (e *Events) OnSyncProgress(progressRlp []byte) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	for _, ch := range e.syncingSubscriptions {
+		common.PrioritizedSend(ch, progressRlp)
+	}
+}
+
 func (e *Events) OnNewPendingLogs(logs types.Logs) {
 	e.lock.Lock()
 	defer e.lock.Unlock()
@@ -0,0 +1,100 @@
+// Package tracedb is the database of the traces of the blocks stored by the Traces stage, so that
+// the trace_ RPC methods serve them instead of re-executing the blocks.
+//
+// The traces are kept apart from the chaindata, as the tables of the chaindata are defined by
+// erigon-lib. The database has a single table:
+//
+//	block_num_u64 + block_hash -> snappy(encoded traces of the transactions of the block)
+//
+// The encoding of the traces is up to the producer and the consumers of the traces.
+package tracedb
+
+import (
+	"encoding/binary"
+
+	"github.com/c2h5oh/datasize"
+	"github.com/golang/snappy"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/mdbx"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/log/v3"
+)
+
+// BlockTraces is the table of the traces, see the package doc.
+const BlockTraces = "BlockTrace"
+
+func tablesCfg(_ kv.TableCfg) kv.TableCfg {
+	return kv.TableCfg{
+		BlockTraces: {},
+	}
+}
+
+// Open opens the database of the traces at the path. A database opened read-only must exist.
+func Open(path string, logger log.Logger, readonly bool) (kv.RwDB, error) {
+	opts := mdbx.NewMDBX(logger).Path(path).WithTableCfg(tablesCfg)
+	if readonly {
+		opts = opts.Readonly()
+	} else {
+		opts = opts.GrowthStep(16 * datasize.MB)
+	}
+	return opts.Open()
+}
+
+// OpenInMem opens an in-memory database of the traces, for the tests.
+func OpenInMem(logger log.Logger) kv.RwDB {
+	return mdbx.NewMDBX(logger).InMem().WithTableCfg(tablesCfg).MustOpen()
+}
+
+// WriteBlockTraces stores the encoded traces of a block, compressed.
+func WriteBlockTraces(tx kv.Putter, number uint64, hash common.Hash, traces []byte) error {
+	return tx.Put(BlockTraces, dbutils.BlockBodyKey(number, hash), snappy.Encode(nil, traces))
+}
+
+// ReadBlockTraces returns the encoded traces of a block, nil if they are not stored.
+func ReadBlockTraces(tx kv.Getter, number uint64, hash common.Hash) ([]byte, error) {
+	v, err := tx.GetOne(BlockTraces, dbutils.BlockBodyKey(number, hash))
+	if err != nil || v == nil {
+		return nil, err
+	}
+	return snappy.Decode(nil, v)
+}
+
+// TruncateBlockTraces deletes the traces of the blocks from the given number on.
+func TruncateBlockTraces(tx kv.RwTx, from uint64) error {
+	c, err := tx.RwCursor(BlockTraces)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	for k, _, err := c.Seek(dbutils.EncodeBlockNumber(from)); k != nil; k, _, err = c.Next() {
+		if err != nil {
+			return err
+		}
+		if err = c.DeleteCurrent(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PruneBlockTraces deletes the traces of the blocks below the given number.
+func PruneBlockTraces(tx kv.RwTx, to uint64) error {
+	c, err := tx.RwCursor(BlockTraces)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	for k, _, err := c.First(); k != nil; k, _, err = c.Next() {
+		if err != nil {
+			return err
+		}
+		if binary.BigEndian.Uint64(k) >= to {
+			break
+		}
+		if err = c.DeleteCurrent(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
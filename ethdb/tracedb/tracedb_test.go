@@ -0,0 +1,42 @@
+package tracedb
+
+import (
+	"context"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTruncateAndPruneBlockTraces(t *testing.T) {
+	db := OpenInMem(log.New())
+	defer db.Close()
+	hash := common.Hash{1}
+
+	require.NoError(t, db.Update(context.Background(), func(tx kv.RwTx) error {
+		for number := uint64(1); number <= 10; number++ {
+			if err := WriteBlockTraces(tx, number, hash, []byte{byte(number)}); err != nil {
+				return err
+			}
+		}
+		if err := TruncateBlockTraces(tx, 7); err != nil {
+			return err
+		}
+		return PruneBlockTraces(tx, 3)
+	}))
+
+	require.NoError(t, db.View(context.Background(), func(tx kv.Tx) error {
+		for number := uint64(1); number <= 10; number++ {
+			traces, err := ReadBlockTraces(tx, number, hash)
+			require.NoError(t, err)
+			if number < 3 || number >= 7 {
+				require.Nil(t, traces, "block %d", number)
+			} else {
+				require.Equal(t, []byte{byte(number)}, traces, "block %d", number)
+			}
+		}
+		return nil
+	}))
+}
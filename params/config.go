@@ -45,6 +45,12 @@ func readChainSpec(filename string) *ChainConfig {
 	if err != nil {
 		panic(fmt.Sprintf("Could not parse chainspec for %s: %v", filename, err))
 	}
+	// files referenced by the chainspec are relative to it
+	if err = spec.ResolveSystemContractForks(func(name string) ([]byte, error) {
+		return chainspecs.ReadFile(path.Join(path.Dir(filename), name))
+	}); err != nil {
+		panic(fmt.Sprintf("Could not load system contract forks for %s: %v", filename, err))
+	}
 	return spec
 }
 
@@ -110,6 +116,9 @@ var (
 
 	RialtoChainConfig = readChainSpec("chainspecs/rialto.json")
 
+	// ParliaDevChainConfig contains the chain parameters of a local multi-validator Parlia devnet.
+	ParliaDevChainConfig = readChainSpec("chainspecs/parlia-dev.json")
+
 	SokolChainConfig = readChainSpec("chainspecs/sokol.json")
 
 	FermionChainConfig = readChainSpec("chainspecs/fermion.json")
@@ -253,6 +262,10 @@ type ChainConfig struct {
 	BrunoBlock      *big.Int `json:"brunoBlock,omitempty" toml:",omitempty"`      // brunoBlock switch block (nil = no fork, 0 = already activated)
 	EulerBlock      *big.Int `json:"eulerBlock,omitempty" toml:",omitempty"`      // eulerBlock switch block (nil = no fork, 0 = already activated)
 
+	// System contract upgrades declared by the chain spec, applied in order at their activation blocks
+	SystemContractForks     []*SystemContractFork `json:"systemContractForks,omitempty" toml:",omitempty"`
+	SystemContractForksFile string                `json:"systemContractForksFile,omitempty" toml:",omitempty"` // Sidecar file with more forks, resolved when the chain spec is loaded
+
 	// EIP-3675: Upgrade consensus to Proof-of-Stake
 	TerminalTotalDifficulty       *big.Int    `json:"terminalTotalDifficulty,omitempty"`       // The merge happens when terminal total difficulty is reached
 	TerminalTotalDifficultyPassed bool        `json:"terminalTotalDifficultyPassed,omitempty"` // Disable PoW sync for networks that have already passed through the Merge
@@ -711,7 +724,7 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head uint64) *ConfigC
 	if isForkIncompatible(c.EulerBlock, newcfg.EulerBlock, head) {
 		return newCompatError("Euler fork block", c.EulerBlock, newcfg.EulerBlock)
 	}
-	return nil
+	return c.checkSystemContractForksCompatible(newcfg, head)
 }
 
 // isForkIncompatible returns true if a fork scheduled at s1 cannot be rescheduled to
@@ -827,6 +840,8 @@ func ChainConfigByChainName(chain string) *ChainConfig {
 		return ChapelChainConfig
 	case networkname.RialtoChainName:
 		return RialtoChainConfig
+	case networkname.ParliaDevChainName:
+		return ParliaDevChainConfig
 	case networkname.MumbaiChainName:
 		return MumbaiChainConfig
 	case networkname.BorMainnetChainName:
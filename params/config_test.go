@@ -20,6 +20,11 @@ import (
 	"math/big"
 	"reflect"
 	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/common"
 )
 
 func TestCheckCompatible(t *testing.T) {
@@ -87,6 +92,29 @@ func TestCheckCompatible(t *testing.T) {
 				RewindTo:     30,
 			},
 		},
+		{
+			stored:  &ChainConfig{SystemContractForks: []*SystemContractFork{{Name: "a", Block: big.NewInt(10)}}},
+			new:     &ChainConfig{SystemContractForks: []*SystemContractFork{{Name: "a", Block: big.NewInt(20)}}},
+			head:    9,
+			wantErr: nil,
+		},
+		{
+			stored:  &ChainConfig{SystemContractForks: []*SystemContractFork{{Name: "a", Block: big.NewInt(10)}}},
+			new:     &ChainConfig{SystemContractForks: []*SystemContractFork{{Name: "a", Block: big.NewInt(10)}, {Name: "b", Block: big.NewInt(30)}}},
+			head:    20,
+			wantErr: nil,
+		},
+		{
+			stored: &ChainConfig{SystemContractForks: []*SystemContractFork{{Name: "a", Block: big.NewInt(10)}}},
+			new:    &ChainConfig{SystemContractForks: []*SystemContractFork{{Name: "a", Block: big.NewInt(10)}, {Name: "b", Block: big.NewInt(15)}}},
+			head:   20,
+			wantErr: &ConfigCompatError{
+				What:         "b system contract fork block",
+				StoredConfig: nil,
+				NewConfig:    big.NewInt(15),
+				RewindTo:     14,
+			},
+		},
 	}
 
 	for _, test := range tests {
@@ -96,3 +124,32 @@ func TestCheckCompatible(t *testing.T) {
 		}
 	}
 }
+
+func TestResolveSystemContractForks(t *testing.T) {
+	files := fstest.MapFS{
+		"forks.json":     {Data: []byte(`[{"name": "b", "block": 20, "contracts": [{"address": "0x0000000000000000000000000000000000001001", "codeFile": "code/slash.hex"}]}]`)},
+		"code/slash.hex": {Data: []byte("6080\n")},
+		"broken.hex":     {Data: []byte("0x608")},
+	}
+	config := &ChainConfig{
+		SystemContractForks: []*SystemContractFork{
+			{Name: "a", Block: big.NewInt(10), Contracts: []*SystemContractUpgrade{{Address: common.HexToAddress("0x1000"), CodeFile: "broken.hex"}}},
+		},
+		SystemContractForksFile: "forks.json",
+	}
+	require.Error(t, config.ResolveSystemContractForks(files.ReadFile))
+
+	config.SystemContractForks[0].Contracts[0].Code = []byte{0x60}
+	require.Error(t, config.ResolveSystemContractForks(files.ReadFile), "code and code file are exclusive")
+
+	config.SystemContractForks[0].Contracts[0].CodeFile = ""
+	require.NoError(t, config.ResolveSystemContractForks(files.ReadFile))
+	require.Empty(t, config.SystemContractForksFile)
+	require.Len(t, config.SystemContractForks, 2)
+	b := config.SystemContractForks[1]
+	require.Equal(t, "b", b.Name)
+	require.Equal(t, big.NewInt(20), b.Block)
+	require.Empty(t, b.Contracts[0].CodeFile)
+	require.Equal(t, []byte{0x60, 0x80}, []byte(b.Contracts[0].Code))
+	require.Equal(t, []*big.Int{big.NewInt(10), big.NewInt(20)}, config.SystemContractForkBlocks())
+}
@@ -9,6 +9,9 @@ import (
 	"github.com/ledgerwatch/erigon/common/hexutil"
 )
 
+// SignHashFn signs the hash with the key of the signer
+type SignHashFn func(signer common.Address, hash []byte) ([]byte, error)
+
 // MiningConfig is the configuration parameters of mining.
 type MiningConfig struct {
 	Enabled    bool
@@ -16,6 +19,7 @@ type MiningConfig struct {
 	Noverify   bool              // Disable remote mining solution verification(only useful in ethash).
 	Etherbase  common.Address    `toml:",omitempty"` // Public address for block mining rewards
 	SigKey     *ecdsa.PrivateKey // ECDSA private key for signing blocks
+	SignHash   SignHashFn        `toml:"-"`          // Signs block hashes with a key of a keystore, instead of SigKey (only useful in parlia)
 	Notify     []string          `toml:",omitempty"` // HTTP URL list to be notified of new work packages(only useful in ethash).
 	ExtraData  hexutil.Bytes     `toml:",omitempty"` // Block extra data set by the miner
 	GasLimit   uint64            // Target gas limit for mined blocks.
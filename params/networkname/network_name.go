@@ -13,6 +13,7 @@ const (
 	BSCChainName        = "bsc"
 	ChapelChainName     = "chapel"
 	RialtoChainName     = "rialto"
+	ParliaDevChainName  = "parlia-dev"
 	MumbaiChainName     = "mumbai"
 	BorMainnetChainName = "bor-mainnet"
 	BorDevnetChainName  = "bor-devnet"
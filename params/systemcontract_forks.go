@@ -0,0 +1,115 @@
+package params
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"sort"
+	"strings"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/hexutil"
+)
+
+// SystemContractFork is a named upgrade of system contracts declared by the chain spec,
+// which replaces the code of the listed contracts at the activation block.
+type SystemContractFork struct {
+	Name      string                   `json:"name"`
+	Block     *big.Int                 `json:"block"`
+	Contracts []*SystemContractUpgrade `json:"contracts"`
+}
+
+// SystemContractUpgrade is the new code of a system contract, together with the names of
+// the hooks run before and after the code is replaced.
+type SystemContractUpgrade struct {
+	Address       common.Address `json:"address"`
+	Code          hexutil.Bytes  `json:"code,omitempty"`
+	CodeFile      string         `json:"codeFile,omitempty"` // File with the hex encoded code, replaced by Code when the chain spec is loaded
+	CommitUrl     string         `json:"commitUrl,omitempty"`
+	BeforeUpgrade string         `json:"beforeUpgrade,omitempty"`
+	AfterUpgrade  string         `json:"afterUpgrade,omitempty"`
+}
+
+// ResolveSystemContractForks loads the system contract forks of the sidecar file and the
+// code files referenced by the chain spec, using the given function to read the files.
+// The references are then removed, so the resolved config is self-contained.
+func (c *ChainConfig) ResolveSystemContractForks(readFile func(name string) ([]byte, error)) error {
+	if c.SystemContractForksFile != "" {
+		data, err := readFile(c.SystemContractForksFile)
+		if err != nil {
+			return fmt.Errorf("read system contract forks: %w", err)
+		}
+		var forks []*SystemContractFork
+		if err = json.Unmarshal(data, &forks); err != nil {
+			return fmt.Errorf("parse system contract forks %s: %w", c.SystemContractForksFile, err)
+		}
+		c.SystemContractForks = append(c.SystemContractForks, forks...)
+		c.SystemContractForksFile = ""
+	}
+	for _, fork := range c.SystemContractForks {
+		for _, upgrade := range fork.Contracts {
+			if upgrade.CodeFile == "" {
+				continue
+			}
+			if len(upgrade.Code) > 0 {
+				return fmt.Errorf("system contract fork %s: both code and code file are set for %x", fork.Name, upgrade.Address)
+			}
+			data, err := readFile(upgrade.CodeFile)
+			if err != nil {
+				return fmt.Errorf("system contract fork %s: %w", fork.Name, err)
+			}
+			code := strings.TrimSpace(string(data))
+			if !strings.HasPrefix(code, "0x") {
+				code = "0x" + code
+			}
+			if upgrade.Code, err = hexutil.Decode(code); err != nil {
+				return fmt.Errorf("system contract fork %s: decode %s: %w", fork.Name, upgrade.CodeFile, err)
+			}
+			upgrade.CodeFile = ""
+		}
+	}
+	return nil
+}
+
+// systemContractForkBlocks returns the activation blocks of the declared system contract forks.
+func (c *ChainConfig) systemContractForkBlocks() map[string]*big.Int {
+	blocks := make(map[string]*big.Int, len(c.SystemContractForks))
+	for _, fork := range c.SystemContractForks {
+		blocks[fork.Name] = fork.Block
+	}
+	return blocks
+}
+
+// SystemContractForkBlocks returns the activation blocks of the declared system contract forks,
+// so that they are part of the fork id.
+func (c *ChainConfig) SystemContractForkBlocks() []*big.Int {
+	blocks := make([]*big.Int, 0, len(c.SystemContractForks))
+	for _, fork := range c.SystemContractForks {
+		if fork.Block != nil {
+			blocks = append(blocks, fork.Block)
+		}
+	}
+	return blocks
+}
+
+// checkSystemContractForksCompatible checks that no declared system contract fork was added,
+// removed or rescheduled before the head.
+func (c *ChainConfig) checkSystemContractForksCompatible(newcfg *ChainConfig, head uint64) *ConfigCompatError {
+	stored, updated := c.systemContractForkBlocks(), newcfg.systemContractForkBlocks()
+	names := make([]string, 0, len(stored)+len(updated))
+	for name := range stored {
+		names = append(names, name)
+	}
+	for name := range updated {
+		if _, ok := stored[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if isForkIncompatible(stored[name], updated[name], head) {
+			return newCompatError(fmt.Sprintf("%s system contract fork block", name), stored[name], updated[name])
+		}
+	}
+	return nil
+}
@@ -25,6 +25,8 @@ var (
 	_ Error = new(invalidRequestError)
 	_ Error = new(invalidMessageError)
 	_ Error = new(invalidParamsError)
+	_ Error = new(limitExceededError)
+	_ Error = new(responseTooLargeError)
 	_ Error = new(CustomError)
 )
 
@@ -74,6 +76,24 @@ func (e *invalidParamsError) ErrorCode() int { return -32602 }
 
 func (e *invalidParamsError) Error() string { return e.message }
 
+// the client went over the rate of requests allowed by the server
+type limitExceededError struct{ method string }
+
+func (e *limitExceededError) ErrorCode() int { return -32005 }
+
+func (e *limitExceededError) Error() string {
+	return fmt.Sprintf("request rate limit exceeded, %s not served", e.method)
+}
+
+// the response is larger than the server allows
+type responseTooLargeError struct{ limit int }
+
+func (e *responseTooLargeError) ErrorCode() int { return -32005 }
+
+func (e *responseTooLargeError) Error() string {
+	return fmt.Sprintf("response size limit exceeded, larger than %d bytes", e.limit)
+}
+
 type CustomError struct {
 	Code    int
 	Message string
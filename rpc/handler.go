@@ -21,6 +21,7 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"io"
 	"reflect"
 	"strconv"
 	"strings"
@@ -71,6 +72,7 @@ type handler struct {
 	serverSubs          map[ID]*Subscription
 	maxBatchConcurrency uint
 	traceRequests       bool
+	limiter             *clientLimiter // the limits of the server for the client, nil if none
 }
 
 type callProc struct {
@@ -110,7 +112,7 @@ func HandleError(err error, stream *jsoniter.Stream) error {
 	return nil
 }
 
-func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry, allowList AllowList, maxBatchConcurrency uint, traceRequests bool) *handler {
+func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *serviceRegistry, allowList AllowList, maxBatchConcurrency uint, traceRequests bool, limiter *clientLimiter) *handler {
 	rootCtx, cancelRoot := context.WithCancel(connCtx)
 	forbiddenList := newForbiddenList()
 	h := &handler{
@@ -129,6 +131,7 @@ func newHandler(connCtx context.Context, conn jsonWriter, idgen func() ID, reg *
 
 		maxBatchConcurrency: maxBatchConcurrency,
 		traceRequests:       traceRequests,
+		limiter:             limiter,
 	}
 
 	if conn.remoteAddr() != "" {
@@ -147,6 +150,12 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 		})
 		return
 	}
+	if h.limiter.batchTooLarge(len(msgs)) {
+		h.startCallProc(func(cp *callProc) {
+			h.conn.writeJSON(cp.ctx, errorMessage(&invalidRequestError{fmt.Sprintf("batch of %d requests, larger than the limit of %d", len(msgs), h.limiter.limits.MaxBatchSize)}))
+		})
+		return
+	}
 
 	// Handle non-call messages first:
 	calls := make([]*jsonrpcMessage, 0, len(msgs))
@@ -167,6 +176,8 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 		defer close(boundedConcurrency)
 		wg := sync.WaitGroup{}
 		wg.Add(len(msgs))
+		// the calls producing a response larger than the limit are cancelled, each in its own process
+		procs := make([]*callProc, len(calls))
 		for i := range calls {
 			boundedConcurrency <- struct{}{}
 			go func(i int) {
@@ -181,9 +192,31 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 				default:
 				}
 
-				buf := bytes.NewBuffer(nil)
-				stream := jsoniter.NewStream(jsoniter.ConfigDefault, buf, 4096)
-				if res := h.handleCallMsg(cp, calls[i], stream); res != nil {
+				callCp, buf := cp, bytes.NewBuffer(nil)
+				var out io.Writer = buf
+				var limited *limitedWriter
+				if h.limiter.limitsResponseSize() {
+					ctx, cancel := context.WithCancel(cp.ctx)
+					defer cancel()
+					callCp = &callProc{ctx: ctx}
+					procs[i] = callCp
+					limited = &limitedWriter{limit: h.limiter.limits.MaxResponseSize, cancel: cancel}
+					out = limited
+				}
+				stream := jsoniter.NewStream(jsoniter.ConfigDefault, out, 4096)
+				res := h.handleCallMsg(callCp, calls[i], stream)
+				if limited != nil {
+					if res != nil {
+						buffer, _ := json.Marshal(res)
+						stream.Write(buffer)
+					}
+					_ = stream.Flush()
+					if response := limited.response(calls[i]); len(response) > 0 {
+						answersWithNils[i] = response
+					}
+					return
+				}
+				if res != nil {
 					answersWithNils[i] = res
 				}
 				_ = stream.Flush()
@@ -193,11 +226,26 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 			}(i)
 		}
 		wg.Wait()
+		for _, proc := range procs {
+			if proc != nil {
+				cp.notifiers = append(cp.notifiers, proc.notifiers...)
+			}
+		}
 		answers := make([]interface{}, 0, len(msgs))
-		for _, answer := range answersWithNils {
-			if answer != nil {
-				answers = append(answers, answer)
+		// the size limit applies to the whole response: the call crossing it and the following ones are answered with an error
+		size, tooLarge := 0, false
+		for i, answer := range answersWithNils {
+			if answer == nil {
+				continue
+			}
+			if !tooLarge && h.limiter.limitsResponseSize() {
+				size += answerSize(answer)
+				tooLarge = h.limiter.responseTooLarge(size)
 			}
+			if tooLarge {
+				answer = calls[i].errorResponse(&responseTooLargeError{h.limiter.limits.MaxResponseSize})
+			}
+			answers = append(answers, answer)
 		}
 		h.addSubscriptions(cp.notifiers)
 		if len(answers) > 0 {
@@ -209,12 +257,25 @@ func (h *handler) handleBatch(msgs []*jsonrpcMessage) {
 	})
 }
 
+// answerSize returns the size of an answer in the response to a batch
+func answerSize(answer interface{}) int {
+	if raw, ok := answer.(json.RawMessage); ok {
+		return len(raw)
+	}
+	buffer, _ := json.Marshal(answer)
+	return len(buffer)
+}
+
 // handleMsg handles a single message.
 func (h *handler) handleMsg(msg *jsonrpcMessage, stream *jsoniter.Stream) {
 	if ok := h.handleImmediate(msg); ok {
 		return
 	}
 	h.startCallProc(func(cp *callProc) {
+		if h.limiter.limitsResponseSize() {
+			h.handleLimitedMsg(cp, msg, stream)
+			return
+		}
 		needWriteStream := false
 		if stream == nil {
 			stream = jsoniter.NewStream(jsoniter.ConfigDefault, nil, 4096)
@@ -237,6 +298,33 @@ func (h *handler) handleMsg(msg *jsonrpcMessage, stream *jsoniter.Stream) {
 	})
 }
 
+// handleLimitedMsg handles a single message of a client limiting the size of the responses. The response is
+// streamed to a limitedWriter, and sent once complete, as it's replaced by an error if it crosses the limit.
+func (h *handler) handleLimitedMsg(cp *callProc, msg *jsonrpcMessage, stream *jsoniter.Stream) {
+	ctx, cancel := context.WithCancel(cp.ctx)
+	defer cancel()
+	limited := &limitedWriter{limit: h.limiter.limits.MaxResponseSize, cancel: cancel}
+	callCp := &callProc{ctx: ctx}
+	out := jsoniter.NewStream(jsoniter.ConfigDefault, limited, 4096)
+	answer := h.handleCallMsg(callCp, msg, out)
+	cp.notifiers = callCp.notifiers
+	h.addSubscriptions(cp.notifiers)
+	if answer != nil {
+		buffer, _ := json.Marshal(answer)
+		out.Write(buffer)
+	}
+	_ = out.Flush()
+	if response := limited.response(msg); stream == nil {
+		h.conn.writeJSON(cp.ctx, response)
+	} else {
+		stream.Write(response)
+		stream.Write([]byte("\n"))
+	}
+	for _, n := range cp.notifiers {
+		n.activate()
+	}
+}
+
 // close cancels all requests except for inflightReq and waits for
 // call goroutines to shut down.
 func (h *handler) close(err error, inflightReq *requestOp) {
@@ -424,6 +512,9 @@ func (h *handler) isMethodAllowedByGranularControl(method string) bool {
 
 // handleCall processes method calls.
 func (h *handler) handleCall(cp *callProc, msg *jsonrpcMessage, stream *jsoniter.Stream) *jsonrpcMessage {
+	if !h.limiter.allow(msg.Method) {
+		return msg.errorResponse(&limitExceededError{method: msg.Method})
+	}
 	if msg.isSubscribe() {
 		return h.handleSubscribe(cp, msg, stream)
 	}
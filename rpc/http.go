@@ -223,11 +223,12 @@ func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("content-type", contentType)
 	codec := newHTTPServerConn(r, w)
 	defer codec.close()
+	limiter := s.limiter.httpClient(r)
 	var stream *jsoniter.Stream
 	if !s.disableStreaming {
 		stream = jsoniter.NewStream(jsoniter.ConfigDefault, w, 4096)
 	}
-	s.serveSingleRequest(ctx, codec, stream)
+	s.serveSingleRequest(ctx, codec, stream, limiter)
 }
 
 // validateRequest returns a non-zero response code and error message if the
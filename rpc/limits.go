@@ -0,0 +1,161 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru"
+	"golang.org/x/time/rate"
+
+	"github.com/ledgerwatch/erigon/rpc/rpccfg"
+)
+
+// limitedClients is the number of clients whose rate of requests is tracked, the least recent ones are
+// forgotten, which resets their rate.
+const limitedClients = 100_000
+
+// requestLimiter applies the RequestLimits of a server, with a token bucket per client.
+type requestLimiter struct {
+	limits  rpccfg.RequestLimits
+	burst   int
+	keys    map[string]struct{} // the accepted API keys
+	buckets *lru.Cache          // client -> *rate.Limiter
+}
+
+func newRequestLimiter(limits rpccfg.RequestLimits) *requestLimiter {
+	l := &requestLimiter{limits: limits, burst: limits.Burst, keys: make(map[string]struct{}, len(limits.APIKeys))}
+	for _, key := range limits.APIKeys {
+		l.keys[key] = struct{}{}
+	}
+	if l.burst == 0 {
+		l.burst = limits.Rate
+	}
+	l.buckets, _ = lru.NewWithEvict(limitedClients, func(key interface{}, value interface{}) {
+		atomic.AddInt64(&rpcLimitedClients, -1)
+	})
+	return l
+}
+
+// client returns the limiter of a client, nil if the server has no limits.
+func (l *requestLimiter) client(client string) *clientLimiter {
+	if l == nil {
+		return nil
+	}
+	return &clientLimiter{requestLimiter: l, client: client}
+}
+
+// httpClient tells apart the clients of an HTTP or websocket request by API key, or else by IP. Only the accepted
+// keys are told apart, so that a client can't get a new bucket for each key it makes up.
+func (l *requestLimiter) httpClient(r *http.Request) *clientLimiter {
+	if l == nil {
+		return nil
+	}
+	if l.limits.APIKeyHeader != "" {
+		if key := r.Header.Get(l.limits.APIKeyHeader); key != "" {
+			if _, ok := l.keys[key]; ok {
+				return l.client("key:" + key)
+			}
+		}
+	}
+	return l.client(remoteIP(r.RemoteAddr))
+}
+
+func remoteIP(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}
+
+// clientLimiter applies the limits of a server to the requests of one client, a nil one limits nothing.
+type clientLimiter struct {
+	*requestLimiter
+	client string
+}
+
+// allow charges the cost of a call to the client, and tells whether it's within its rate.
+func (c *clientLimiter) allow(method string) bool {
+	if c == nil || c.limits.Rate == 0 {
+		return true
+	}
+	bucket, ok := c.buckets.Get(c.client)
+	if !ok {
+		bucket = rate.NewLimiter(rate.Limit(c.limits.Rate), c.burst)
+		if found, _ := c.buckets.ContainsOrAdd(c.client, bucket); !found {
+			atomic.AddInt64(&rpcLimitedClients, 1)
+		} else if existing, ok := c.buckets.Get(c.client); ok {
+			bucket = existing
+		}
+	}
+	// the costliest methods are allowed at once when nothing was spent
+	cost := c.limits.Cost(method)
+	if cost > c.burst {
+		cost = c.burst
+	}
+	if !bucket.(*rate.Limiter).AllowN(time.Now(), cost) {
+		rpcRateLimited.Inc()
+		return false
+	}
+	return true
+}
+
+func (c *clientLimiter) batchTooLarge(size int) bool {
+	if c == nil || c.limits.MaxBatchSize == 0 || size <= c.limits.MaxBatchSize {
+		return false
+	}
+	rpcBatchLimited.Inc()
+	return true
+}
+
+func (c *clientLimiter) limitsResponseSize() bool {
+	return c != nil && c.limits.MaxResponseSize > 0
+}
+
+func (c *clientLimiter) responseTooLarge(size int) bool {
+	if c == nil || c.limits.MaxResponseSize == 0 || size <= c.limits.MaxResponseSize {
+		return false
+	}
+	rpcResponseLimited.Inc()
+	return true
+}
+
+// errResponseTooLarge fails the writes of a response crossing the size limit of the client
+var errResponseTooLarge = errors.New("response too large")
+
+// limitedWriter holds the response to a call up to the size limit of the client, so that a response crossing it is
+// replaced by an error instead of being sent truncated, without holding more than the limit. The write crossing the
+// limit drops what is held, fails with the following ones, and cancels the call so that it stops producing it.
+type limitedWriter struct {
+	buf      bytes.Buffer
+	limit    int
+	cancel   context.CancelFunc
+	tooLarge bool
+}
+
+func (w *limitedWriter) Write(p []byte) (int, error) {
+	if !w.tooLarge && w.buf.Len()+len(p) > w.limit {
+		w.tooLarge = true
+		w.buf = bytes.Buffer{}
+		w.cancel()
+		rpcResponseLimited.Inc()
+	}
+	if w.tooLarge {
+		return 0, errResponseTooLarge
+	}
+	return w.buf.Write(p)
+}
+
+// response returns the response held, or the error answering the call when the response crossed the limit.
+func (w *limitedWriter) response(msg *jsonrpcMessage) json.RawMessage {
+	if w.tooLarge {
+		buffer, _ := json.Marshal(msg.errorResponse(&responseTooLargeError{w.limit}))
+		return buffer
+	}
+	return w.buf.Bytes()
+}
@@ -0,0 +1,247 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	jsoniter "github.com/json-iterator/go"
+
+	"github.com/ledgerwatch/erigon/rpc/rpccfg"
+)
+
+func postLimited(t *testing.T, url, apiKey, body string) []byte {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodPost, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("content-type", contentType)
+	if apiKey != "" {
+		req.Header.Set("X-API-Key", apiKey)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return respBody
+}
+
+func errorCodeOf(t *testing.T, resp []byte) int {
+	t.Helper()
+	var msg jsonrpcMessage
+	if err := json.Unmarshal(resp, &msg); err != nil {
+		t.Fatalf("invalid response %s: %v", resp, err)
+	}
+	if msg.Error == nil {
+		return 0
+	}
+	return msg.Error.Code
+}
+
+func TestRequestLimitsRate(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	server.SetRequestLimits(rpccfg.RequestLimits{Rate: 1, Burst: 2, MethodCosts: map[string]int{"test_echo": 2}, APIKeyHeader: "X-API-Key", APIKeys: []string{"key"}})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	const call = `{"jsonrpc":"2.0","id":1,"method":"test_noArgsRets"}`
+	for i, want := range []int{0, 0, -32005} {
+		if code := errorCodeOf(t, postLimited(t, ts.URL, "", call)); code != want {
+			t.Fatalf("call %d: error code %d, want %d", i, code, want)
+		}
+	}
+	// the clients with an unknown API key are told apart by IP
+	if code := errorCodeOf(t, postLimited(t, ts.URL, "unknown", call)); code != -32005 {
+		t.Fatalf("call with an unknown key: error code %d, want -32005", code)
+	}
+	// the clients with an API key have their own rate, the weight of a method being charged
+	if code := errorCodeOf(t, postLimited(t, ts.URL, "key", `{"jsonrpc":"2.0","id":1,"method":"test_echo","params":["x",1]}`)); code != 0 {
+		t.Fatalf("echo: error code %d, want none", code)
+	}
+	if code := errorCodeOf(t, postLimited(t, ts.URL, "key", call)); code != -32005 {
+		t.Fatalf("call after echo: error code %d, want -32005", code)
+	}
+}
+
+func TestRequestLimitsBatch(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	server.SetRequestLimits(rpccfg.RequestLimits{MaxBatchSize: 2})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	const call = `{"jsonrpc":"2.0","id":1,"method":"test_noArgsRets"}`
+	var answers []jsonrpcMessage
+	if err := json.Unmarshal(postLimited(t, ts.URL, "", "["+call+","+call+"]"), &answers); err != nil {
+		t.Fatal(err)
+	}
+	if len(answers) != 2 || answers[0].Error != nil || answers[1].Error != nil {
+		t.Fatalf("unexpected answers to a batch within the limit: %v", answers)
+	}
+	if code := errorCodeOf(t, postLimited(t, ts.URL, "", "["+call+","+call+","+call+"]")); code != -32600 {
+		t.Fatalf("batch over the limit: error code %d, want -32600", code)
+	}
+}
+
+func TestRequestLimitsResponse(t *testing.T) {
+	server := NewServer(50, false /* traceRequests */, false)
+	defer server.Stop()
+	if err := server.RegisterName("test", largeRespService{1000}); err != nil {
+		t.Fatal(err)
+	}
+	server.SetRequestLimits(rpccfg.RequestLimits{MaxResponseSize: 100})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	const call = `{"jsonrpc":"2.0","id":1,"method":"test_largeResp"}`
+	var msg jsonrpcMessage
+	if err := json.Unmarshal(postLimited(t, ts.URL, "", call), &msg); err != nil {
+		t.Fatal(err)
+	}
+	if msg.Error == nil || msg.Error.Code != -32005 || msg.Error.Message != (&responseTooLargeError{100}).Error() {
+		t.Fatalf("large response: error %v, want the response size limit", msg.Error)
+	}
+	var answers []jsonrpcMessage
+	if err := json.Unmarshal(postLimited(t, ts.URL, "", "["+call+"]"), &answers); err != nil {
+		t.Fatal(err)
+	}
+	if len(answers) != 1 || answers[0].Error == nil || answers[0].Error.Code != -32005 {
+		t.Fatalf("unexpected answers to a batch with a large response: %v", answers)
+	}
+}
+
+func TestRequestLimitsBatchResponse(t *testing.T) {
+	server := NewServer(50, false /* traceRequests */, false)
+	defer server.Stop()
+	if err := server.RegisterName("test", largeRespService{20}); err != nil {
+		t.Fatal(err)
+	}
+	server.SetRequestLimits(rpccfg.RequestLimits{MaxResponseSize: 150})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	// each answer is below the limit, the batch response crosses it with the third one
+	batch := make([]string, 4)
+	for i := range batch {
+		batch[i] = fmt.Sprintf(`{"jsonrpc":"2.0","id":%d,"method":"test_largeResp"}`, i+1)
+	}
+	var answers []jsonrpcMessage
+	if err := json.Unmarshal(postLimited(t, ts.URL, "", "["+strings.Join(batch, ",")+"]"), &answers); err != nil {
+		t.Fatal(err)
+	}
+	if len(answers) != len(batch) {
+		t.Fatalf("got %d answers, want %d", len(answers), len(batch))
+	}
+	for i, answer := range answers {
+		if id := string(answer.ID); id != fmt.Sprint(i+1) {
+			t.Fatalf("answer %d has id %s", i, id)
+		}
+		if tooLarge := answer.Error != nil && answer.Error.Code == -32005; tooLarge != (i >= 2) {
+			t.Fatalf("answer %d: %v", i, answer)
+		}
+	}
+}
+
+// endlessService streams a response until its call is cancelled.
+type endlessService struct{ cancelled chan struct{} }
+
+func (s *endlessService) Stream(ctx context.Context, stream *jsoniter.Stream) error {
+	stream.WriteArrayStart()
+	for i := 0; ; i++ {
+		select {
+		case <-ctx.Done():
+			close(s.cancelled)
+			return ctx.Err()
+		default:
+		}
+		if i > 0 {
+			stream.WriteMore()
+		}
+		stream.WriteString("item")
+		_ = stream.Flush()
+	}
+}
+
+func TestRequestLimitsStreamedResponse(t *testing.T) {
+	server := NewServer(50, false /* traceRequests */, false)
+	defer server.Stop()
+	service := &endlessService{}
+	if err := server.RegisterName("test", service); err != nil {
+		t.Fatal(err)
+	}
+	server.SetRequestLimits(rpccfg.RequestLimits{MaxResponseSize: 1000})
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	// the call streaming its response is cancelled once it crosses the limit
+	for _, body := range []string{`{"jsonrpc":"2.0","id":1,"method":"test_stream"}`, `[{"jsonrpc":"2.0","id":1,"method":"test_stream"}]`} {
+		service.cancelled = make(chan struct{})
+		resp := postLimited(t, ts.URL, "", body)
+		if body[0] == '[' {
+			resp = bytes.TrimSuffix(bytes.TrimPrefix(bytes.TrimSpace(resp), []byte("[")), []byte("]"))
+		}
+		if code := errorCodeOf(t, resp); code != -32005 {
+			t.Fatalf("streamed response %s: error code %d, want -32005", body, code)
+		}
+		<-service.cancelled
+	}
+}
+
+func TestRequestLimitsWebsocket(t *testing.T) {
+	server := newTestServer()
+	defer server.Stop()
+	server.SetRequestLimits(rpccfg.RequestLimits{Rate: 1, Burst: 1})
+	httpsrv := httptest.NewServer(server.WebsocketHandler([]string{"*"}, nil, false))
+	defer httpsrv.Close()
+
+	client, err := DialWebsocket(context.Background(), "ws:"+strings.TrimPrefix(httpsrv.URL, "http:"), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+	if err := client.Call(nil, "test_noArgsRets"); err != nil {
+		t.Fatal(err)
+	}
+	err = client.Call(nil, "test_noArgsRets")
+	if rpcErr, ok := err.(Error); !ok || rpcErr.ErrorCode() != -32005 {
+		t.Fatalf("second call: error %v, want the rate limit", err)
+	}
+}
+
+func TestRequestLimitsCost(t *testing.T) {
+	limits := rpccfg.RequestLimits{MethodCosts: map[string]int{"trace_": 20, "eth_getLogs": 7}}
+	for method, want := range map[string]int{
+		"trace_block":      20,
+		"eth_getLogs":      7,
+		"debug_traceBlock": 10,
+		"eth_call":         2,
+		"eth_blockNumber":  1,
+	} {
+		if cost := limits.Cost(method); cost != want {
+			t.Errorf("cost of %s: %d, want %d", method, cost, want)
+		}
+	}
+	costs, err := rpccfg.ParseMethodCosts("trace_=20, eth_getLogs=7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(costs) != 2 || costs["trace_"] != 20 || costs["eth_getLogs"] != 7 {
+		t.Fatalf("unexpected costs %v", costs)
+	}
+	if _, err := rpccfg.ParseMethodCosts("trace_"); err == nil {
+		t.Fatal("expected an error for a method without cost")
+	}
+}
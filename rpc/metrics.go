@@ -18,15 +18,39 @@ package rpc
 
 import (
 	"fmt"
+	"sync/atomic"
 
 	"github.com/VictoriaMetrics/metrics"
+
+	"github.com/ledgerwatch/erigon/rpc/rpccfg"
 )
 
 var (
 	rpcRequestGauge    = metrics.GetOrCreateCounter("rpc_total")
 	failedReqeustGauge = metrics.GetOrCreateCounter("rpc_failure")
+
+	rpcRateLimited     = metrics.GetOrCreateCounter(`rpc_limited_total{limit="rate"}`)
+	rpcBatchLimited    = metrics.GetOrCreateCounter(`rpc_limited_total{limit="batch_size"}`)
+	rpcResponseLimited = metrics.GetOrCreateCounter(`rpc_limited_total{limit="response_size"}`)
+	rpcLimitedClients  int64 // clients whose rate of requests is tracked
+	rpcLimits          [4]int64
 )
 
+func init() {
+	metrics.GetOrCreateGauge("rpc_limited_clients", func() float64 { return float64(atomic.LoadInt64(&rpcLimitedClients)) })
+	for i, limit := range []string{"rate", "burst", "batch_size", "response_size"} {
+		i := i
+		metrics.GetOrCreateGauge(fmt.Sprintf(`rpc_limit{limit="%s"}`, limit), func() float64 { return float64(atomic.LoadInt64(&rpcLimits[i])) })
+	}
+}
+
+// exportRequestLimits exports the limits of the last server configured with some.
+func exportRequestLimits(limits rpccfg.RequestLimits, burst int) {
+	for i, limit := range []int{limits.Rate, burst, limits.MaxBatchSize, limits.MaxResponseSize} {
+		atomic.StoreInt64(&rpcLimits[i], int64(limit))
+	}
+}
+
 func newRPCServingTimerMS(method string, valid bool) *metrics.Summary {
 	flag := "success"
 	if !valid {
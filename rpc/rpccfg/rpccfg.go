@@ -1,6 +1,11 @@
 package rpccfg
 
 import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -35,3 +40,100 @@ var DefaultHTTPTimeouts = HTTPTimeouts{
 	WriteTimeout: 30 * time.Minute,
 	IdleTimeout:  120 * time.Second,
 }
+
+// RequestLimits caps what each client of the RPC server may request, the clients being told apart by
+// their API key, or else by their IP. The zero value doesn't limit anything.
+type RequestLimits struct {
+	// Rate is the cost of the requests a client may make per second, unlimited if zero. A request
+	// costs the weight of its method.
+	Rate int
+	// Burst is the cost a client may spend at once, Rate if zero.
+	Burst int
+	// MethodCosts are the weights of the methods, by name or by namespace like "trace_", overriding
+	// DefaultMethodCosts. The other methods cost 1.
+	MethodCosts map[string]int
+	// MaxBatchSize is the number of requests a batch may hold, unlimited if zero.
+	MaxBatchSize int
+	// MaxResponseSize is the size in bytes of the response to a request, unlimited if zero. A
+	// response is held up to this size until complete, the call producing a larger one is cancelled.
+	MaxResponseSize int
+	// APIKeyHeader is the HTTP header carrying the API key of the clients, if any.
+	APIKeyHeader string
+	// APIKeys are the API keys telling apart the clients, the requests carrying another key are told
+	// apart by IP.
+	APIKeys []string
+}
+
+// DefaultMethodCosts weighs the methods re-executing transactions or scanning ranges of blocks.
+var DefaultMethodCosts = map[string]int{
+	"trace_":               10,
+	"debug_":               10,
+	"ots_":                 5,
+	"eth_getLogs":          5,
+	"erigon_getLogs":       5,
+	"eth_callBundle":       5,
+	"eth_callMany":         5,
+	"eth_call":             2,
+	"eth_estimateGas":      2,
+	"eth_createAccessList": 2,
+}
+
+// Cost returns the weight of a method.
+func (l RequestLimits) Cost(method string) int {
+	namespace := method
+	if i := strings.IndexByte(method, '_'); i >= 0 {
+		namespace = method[:i+1]
+	}
+	for _, costs := range []map[string]int{l.MethodCosts, DefaultMethodCosts} {
+		if cost, ok := costs[method]; ok {
+			return cost
+		}
+		if cost, ok := costs[namespace]; ok {
+			return cost
+		}
+	}
+	return 1
+}
+
+// ParseMethodCosts parses weights of methods or namespaces given as "trace_=20,eth_getLogs=10".
+func ParseMethodCosts(s string) (map[string]int, error) {
+	costs := map[string]int{}
+	for _, pair := range strings.Split(s, ",") {
+		if pair = strings.TrimSpace(pair); pair == "" {
+			continue
+		}
+		i := strings.IndexByte(pair, '=')
+		if i <= 0 {
+			return nil, fmt.Errorf("invalid method cost %q, expected method=cost", pair)
+		}
+		cost, err := strconv.Atoi(pair[i+1:])
+		if err != nil || cost < 0 {
+			return nil, fmt.Errorf("invalid cost of %s: %q", pair[:i], pair[i+1:])
+		}
+		costs[pair[:i]] = cost
+	}
+	return costs, nil
+}
+
+// ReadAPIKeys reads the API keys of a file, one per line, ignoring the blank lines.
+func ReadAPIKeys(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	var keys []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if key := strings.TrimSpace(scanner.Text()); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no API key in %s", path)
+	}
+	return keys, nil
+}
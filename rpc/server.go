@@ -24,6 +24,8 @@ import (
 	mapset "github.com/deckarep/golang-set"
 	jsoniter "github.com/json-iterator/go"
 	"github.com/ledgerwatch/log/v3"
+
+	"github.com/ledgerwatch/erigon/rpc/rpccfg"
 )
 
 const MetadataApi = "rpc"
@@ -51,7 +53,8 @@ type Server struct {
 
 	batchConcurrency uint
 	disableStreaming bool
-	traceRequests    bool // Whether to print requests at INFO level
+	traceRequests    bool            // Whether to print requests at INFO level
+	limiter          *requestLimiter // nil if the requests aren't limited
 }
 
 // NewServer creates a new server instance with no registered handlers.
@@ -69,6 +72,17 @@ func (s *Server) SetAllowList(allowList AllowList) {
 	s.methodAllowList = allowList
 }
 
+// SetRequestLimits sets the limits of the rate of requests, of the batches and of the responses of each
+// client, told apart by API key or by IP. Zero limits disable them.
+func (s *Server) SetRequestLimits(limits rpccfg.RequestLimits) {
+	if limits.Rate == 0 && limits.MaxBatchSize == 0 && limits.MaxResponseSize == 0 {
+		s.limiter = nil
+		return
+	}
+	s.limiter = newRequestLimiter(limits)
+	exportRequestLimits(limits, s.limiter.burst)
+}
+
 // RegisterName creates a service for the given receiver type under the given name. When no
 // methods on the given receiver match the criteria to be either a RPC method or a
 // subscription an error is returned. Otherwise a new service is created and added to the
@@ -83,6 +97,10 @@ func (s *Server) RegisterName(name string, receiver interface{}) error {
 //
 // Note that codec options are no longer supported.
 func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
+	s.serveCodec(codec, s.limiter.client(remoteIP(codec.remoteAddr())))
+}
+
+func (s *Server) serveCodec(codec ServerCodec, limiter *clientLimiter) {
 	defer codec.close()
 
 	// Don't serve if server is stopped.
@@ -94,7 +112,7 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 	s.codecs.Add(codec)
 	defer s.codecs.Remove(codec)
 
-	c := initClient(codec, s.idgen, &s.services)
+	c := initClient(codec, s.idgen, &s.services, limiter)
 	<-codec.closed()
 	c.Close()
 }
@@ -102,13 +120,13 @@ func (s *Server) ServeCodec(codec ServerCodec, options CodecOption) {
 // serveSingleRequest reads and processes a single RPC request from the given codec. This
 // is used to serve HTTP connections. Subscriptions and reverse calls are not allowed in
 // this mode.
-func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec, stream *jsoniter.Stream) {
+func (s *Server) serveSingleRequest(ctx context.Context, codec ServerCodec, stream *jsoniter.Stream, limiter *clientLimiter) {
 	// Don't serve if server is stopped.
 	if atomic.LoadInt32(&s.run) == 0 {
 		return
 	}
 
-	h := newHandler(ctx, codec, s.idgen, &s.services, s.methodAllowList, s.batchConcurrency, s.traceRequests)
+	h := newHandler(ctx, codec, s.idgen, &s.services, s.methodAllowList, s.batchConcurrency, s.traceRequests, limiter)
 	h.allowSubscribe = false
 	defer h.close(io.EOF, nil)
 
@@ -0,0 +1,51 @@
+package lightclient
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/vm"
+)
+
+const (
+	tmHeaderValidate        = byte(100)
+	iavlMerkleProofValidate = byte(101)
+)
+
+func FuzzTmHeaderValidate(data []byte) int        { return fuzz(tmHeaderValidate, data) }
+func FuzzIAVLMerkleProofValidate(data []byte) int { return fuzz(iavlMerkleProofValidate, data) }
+
+// The fuzzer functions must return
+// 1 if the fuzzer should increase priority of the
+//
+//	given input during subsequent fuzzing (for example, the input is lexically
+//	correct and was parsed successfully);
+//
+// -1 if the input must not be added to corpus even if gives new coverage; and
+// 0  otherwise
+// other values are reserved for future use.
+//
+// The data is the payload of the precompile, the length prefix the precompiles expect is added here,
+// otherwise nearly all the inputs would be rejected by the length check.
+func fuzz(id byte, data []byte) int {
+	precompile := vm.PrecompiledContractsIstanbulForBSC[common.BytesToAddress([]byte{id})]
+	input := make([]byte, 32+len(data))
+	binary.BigEndian.PutUint64(input[24:32], uint64(len(data)))
+	copy(input[32:], data)
+	cpy := common.CopyBytes(input)
+	_, err := precompile.Run(cpy)
+	if !bytes.Equal(cpy, input) {
+		panic(fmt.Sprintf("input data modified, precompile %d: %x %x", id, input, cpy))
+	}
+	// the precompiles recover from panics, which are consensus failures if the implementations differ
+	if errors.Is(err, vm.ErrLightClientInternal) {
+		panic(fmt.Sprintf("precompile %d: %v", id, err))
+	}
+	if err != nil {
+		return 0
+	}
+	return 1
+}
@@ -0,0 +1,44 @@
+package lightclient
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ledgerwatch/erigon/common"
+)
+
+// addVectors seeds the corpus with the payloads of the conformance vectors of the precompile, so that
+// the mutations start from valid consensus states, headers and proofs.
+func addVectors(f *testing.F, name string) {
+	for _, file := range []string{name + ".json", "fail-" + name + ".json"} {
+		data, err := os.ReadFile(filepath.Join("..", "..", "..", "core", "vm", "testdata", "precompiles", file))
+		if err != nil {
+			f.Fatal(err)
+		}
+		var vectors []struct{ Input string }
+		if err = json.Unmarshal(data, &vectors); err != nil {
+			f.Fatal(err)
+		}
+		for _, v := range vectors {
+			if input := common.FromHex(v.Input); len(input) >= 32 {
+				f.Add(input[32:])
+			}
+		}
+	}
+}
+
+func FuzzTmHeaderValidateNative(f *testing.F) {
+	addVectors(f, "tmHeaderValidate")
+	f.Fuzz(func(t *testing.T, data []byte) {
+		FuzzTmHeaderValidate(data)
+	})
+}
+
+func FuzzIAVLMerkleProofValidateNative(f *testing.F) {
+	addVectors(f, "iavlMerkleProofValidate")
+	f.Fuzz(func(t *testing.T, data []byte) {
+		FuzzIAVLMerkleProofValidate(data)
+	})
+}
@@ -22,6 +22,7 @@ import (
 	"sort"
 
 	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/params/networkname"
 )
 
 // Forks table defines supported forks and their chain config.
@@ -242,6 +243,144 @@ var Forks = map[string]*params.ChainConfig{
 		ArrowGlacierBlock:       big.NewInt(0),
 		TerminalTotalDifficulty: big.NewInt(0xC0000),
 	},
+	// The BSC forks apply on top of Muir Glacier, with the system contract upgrades of the BSC mainnet.
+	"Ramanujan": {
+		ChainID:               big.NewInt(1),
+		ChainName:             networkname.BSCChainName,
+		HomesteadBlock:        big.NewInt(0),
+		TangerineWhistleBlock: big.NewInt(0),
+		SpuriousDragonBlock:   big.NewInt(0),
+		ByzantiumBlock:        big.NewInt(0),
+		ConstantinopleBlock:   big.NewInt(0),
+		PetersburgBlock:       big.NewInt(0),
+		IstanbulBlock:         big.NewInt(0),
+		MuirGlacierBlock:      big.NewInt(0),
+		RamanujanBlock:        big.NewInt(0),
+	},
+	"Niels": {
+		ChainID:               big.NewInt(1),
+		ChainName:             networkname.BSCChainName,
+		HomesteadBlock:        big.NewInt(0),
+		TangerineWhistleBlock: big.NewInt(0),
+		SpuriousDragonBlock:   big.NewInt(0),
+		ByzantiumBlock:        big.NewInt(0),
+		ConstantinopleBlock:   big.NewInt(0),
+		PetersburgBlock:       big.NewInt(0),
+		IstanbulBlock:         big.NewInt(0),
+		MuirGlacierBlock:      big.NewInt(0),
+		RamanujanBlock:        big.NewInt(0),
+		NielsBlock:            big.NewInt(0),
+	},
+	"MirrorSync": {
+		ChainID:               big.NewInt(1),
+		ChainName:             networkname.BSCChainName,
+		HomesteadBlock:        big.NewInt(0),
+		TangerineWhistleBlock: big.NewInt(0),
+		SpuriousDragonBlock:   big.NewInt(0),
+		ByzantiumBlock:        big.NewInt(0),
+		ConstantinopleBlock:   big.NewInt(0),
+		PetersburgBlock:       big.NewInt(0),
+		IstanbulBlock:         big.NewInt(0),
+		MuirGlacierBlock:      big.NewInt(0),
+		RamanujanBlock:        big.NewInt(0),
+		NielsBlock:            big.NewInt(0),
+		MirrorSyncBlock:       big.NewInt(0),
+	},
+	"Bruno": {
+		ChainID:               big.NewInt(1),
+		ChainName:             networkname.BSCChainName,
+		HomesteadBlock:        big.NewInt(0),
+		TangerineWhistleBlock: big.NewInt(0),
+		SpuriousDragonBlock:   big.NewInt(0),
+		ByzantiumBlock:        big.NewInt(0),
+		ConstantinopleBlock:   big.NewInt(0),
+		PetersburgBlock:       big.NewInt(0),
+		IstanbulBlock:         big.NewInt(0),
+		MuirGlacierBlock:      big.NewInt(0),
+		RamanujanBlock:        big.NewInt(0),
+		NielsBlock:            big.NewInt(0),
+		MirrorSyncBlock:       big.NewInt(0),
+		BrunoBlock:            big.NewInt(0),
+	},
+	"Euler": {
+		ChainID:               big.NewInt(1),
+		ChainName:             networkname.BSCChainName,
+		HomesteadBlock:        big.NewInt(0),
+		TangerineWhistleBlock: big.NewInt(0),
+		SpuriousDragonBlock:   big.NewInt(0),
+		ByzantiumBlock:        big.NewInt(0),
+		ConstantinopleBlock:   big.NewInt(0),
+		PetersburgBlock:       big.NewInt(0),
+		IstanbulBlock:         big.NewInt(0),
+		MuirGlacierBlock:      big.NewInt(0),
+		RamanujanBlock:        big.NewInt(0),
+		NielsBlock:            big.NewInt(0),
+		MirrorSyncBlock:       big.NewInt(0),
+		BrunoBlock:            big.NewInt(0),
+		EulerBlock:            big.NewInt(0),
+	},
+	"RamanujanToNielsAt5": {
+		ChainID:               big.NewInt(1),
+		ChainName:             networkname.BSCChainName,
+		HomesteadBlock:        big.NewInt(0),
+		TangerineWhistleBlock: big.NewInt(0),
+		SpuriousDragonBlock:   big.NewInt(0),
+		ByzantiumBlock:        big.NewInt(0),
+		ConstantinopleBlock:   big.NewInt(0),
+		PetersburgBlock:       big.NewInt(0),
+		IstanbulBlock:         big.NewInt(0),
+		MuirGlacierBlock:      big.NewInt(0),
+		RamanujanBlock:        big.NewInt(0),
+		NielsBlock:            big.NewInt(5),
+	},
+	"NielsToMirrorSyncAt5": {
+		ChainID:               big.NewInt(1),
+		ChainName:             networkname.BSCChainName,
+		HomesteadBlock:        big.NewInt(0),
+		TangerineWhistleBlock: big.NewInt(0),
+		SpuriousDragonBlock:   big.NewInt(0),
+		ByzantiumBlock:        big.NewInt(0),
+		ConstantinopleBlock:   big.NewInt(0),
+		PetersburgBlock:       big.NewInt(0),
+		IstanbulBlock:         big.NewInt(0),
+		MuirGlacierBlock:      big.NewInt(0),
+		RamanujanBlock:        big.NewInt(0),
+		NielsBlock:            big.NewInt(0),
+		MirrorSyncBlock:       big.NewInt(5),
+	},
+	"MirrorSyncToBrunoAt5": {
+		ChainID:               big.NewInt(1),
+		ChainName:             networkname.BSCChainName,
+		HomesteadBlock:        big.NewInt(0),
+		TangerineWhistleBlock: big.NewInt(0),
+		SpuriousDragonBlock:   big.NewInt(0),
+		ByzantiumBlock:        big.NewInt(0),
+		ConstantinopleBlock:   big.NewInt(0),
+		PetersburgBlock:       big.NewInt(0),
+		IstanbulBlock:         big.NewInt(0),
+		MuirGlacierBlock:      big.NewInt(0),
+		RamanujanBlock:        big.NewInt(0),
+		NielsBlock:            big.NewInt(0),
+		MirrorSyncBlock:       big.NewInt(0),
+		BrunoBlock:            big.NewInt(5),
+	},
+	"BrunoToEulerAt5": {
+		ChainID:               big.NewInt(1),
+		ChainName:             networkname.BSCChainName,
+		HomesteadBlock:        big.NewInt(0),
+		TangerineWhistleBlock: big.NewInt(0),
+		SpuriousDragonBlock:   big.NewInt(0),
+		ByzantiumBlock:        big.NewInt(0),
+		ConstantinopleBlock:   big.NewInt(0),
+		PetersburgBlock:       big.NewInt(0),
+		IstanbulBlock:         big.NewInt(0),
+		MuirGlacierBlock:      big.NewInt(0),
+		RamanujanBlock:        big.NewInt(0),
+		NielsBlock:            big.NewInt(0),
+		MirrorSyncBlock:       big.NewInt(0),
+		BrunoBlock:            big.NewInt(0),
+		EulerBlock:            big.NewInt(5),
+	},
 }
 
 // Returns the set of defined fork names
@@ -3,10 +3,12 @@ package app
 import (
 	"encoding/json"
 	"os"
+	"path/filepath"
 
 	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/cmd/utils"
 	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/systemcontracts"
 	"github.com/ledgerwatch/erigon/node"
 	"github.com/ledgerwatch/log/v3"
 	"github.com/urfave/cli"
@@ -48,6 +50,20 @@ func initGenesis(ctx *cli.Context) error {
 	if err := json.NewDecoder(file).Decode(genesis); err != nil {
 		utils.Fatalf("invalid genesis file: %v", err)
 	}
+	if genesis.Config != nil {
+		// files referenced by the chain config are relative to the genesis file
+		if err := genesis.Config.ResolveSystemContractForks(func(name string) ([]byte, error) {
+			if !filepath.IsAbs(name) {
+				name = filepath.Join(filepath.Dir(genesisPath), name)
+			}
+			return os.ReadFile(name)
+		}); err != nil {
+			utils.Fatalf("invalid genesis file: %v", err)
+		}
+		if err := systemcontracts.ValidateSystemContractForks(genesis.Config); err != nil {
+			utils.Fatalf("invalid genesis file: %v", err)
+		}
+	}
 
 	// Open and initialise both full and light databases
 	stack := MakeConfigNodeDefault(ctx)
@@ -32,6 +32,7 @@ var DefaultFlags = []cli.Flag{
 	PruneReceiptBeforeFlag,
 	PruneTxIndexBeforeFlag,
 	PruneCallTracesBeforeFlag,
+	ReceiptsDownloadFlag,
 	BatchSizeFlag,
 	BlockDownloaderWindowFlag,
 	DatabaseVerbosityFlag,
@@ -44,6 +45,7 @@ var DefaultFlags = []cli.Flag{
 	TLSCACertFlag,
 	StateStreamDisableFlag,
 	SyncLoopThrottleFlag,
+	TracesFlag,
 	BadBlockFlag,
 
 	utils.HTTPEnabledFlag,
@@ -63,10 +65,18 @@ var DefaultFlags = []cli.Flag{
 	utils.StateCacheFlag,
 	utils.RpcBatchConcurrencyFlag,
 	utils.RpcStreamingDisableFlag,
+	utils.RpcLimitsRateFlag,
+	utils.RpcLimitsBurstFlag,
+	utils.RpcLimitsCostsFlag,
+	utils.RpcLimitsBatchFlag,
+	utils.RpcLimitsResponseFlag,
+	utils.RpcLimitsAPIKeyHeaderFlag,
+	utils.RpcLimitsAPIKeysFlag,
 	utils.DBReadConcurrencyFlag,
 	utils.RpcAccessListFlag,
 	utils.RpcTraceCompatFlag,
 	utils.RpcGasCapFlag,
+	utils.RpcMaxGetProofRewindBlockCountFlag,
 	utils.StarknetGrpcAddressFlag,
 	utils.TevmFlag,
 	utils.MemoryOverlayFlag,
@@ -104,6 +114,9 @@ var DefaultFlags = []cli.Flag{
 	utils.MaxPeersFlag,
 	utils.ChainFlag,
 	utils.DeveloperPeriodFlag,
+	utils.ParliaDevKeystoreFlag,
+	utils.ParliaDevValidatorsFlag,
+	utils.PasswordFileFlag,
 	utils.VMEnableDebugFlag,
 	utils.NetworkIdFlag,
 	utils.FakePoWFlag,
@@ -137,6 +150,7 @@ var DefaultFlags = []cli.Flag{
 	HealthCheckFlag,
 	utils.HeimdallURLFlag,
 	utils.WithoutHeimdallFlag,
+	utils.DiffSyncFlag,
 	utils.EthStatsURLFlag,
 	utils.OverrideTerminalTotalDifficulty,
 	utils.OverrideMergeNetsplitBlock,
@@ -104,6 +104,10 @@ var (
 		Name:  "prune.c.before",
 		Usage: `Prune data before this block`,
 	}
+	ReceiptsDownloadFlag = cli.BoolFlag{
+		Name:  "sync.receipts.download",
+		Usage: "Download from the peers the receipts of the executed blocks which are not stored, the pruned ones included, and keep them",
+	}
 
 	ExperimentsFlag = cli.StringFlag{
 		Name: "experiments",
@@ -138,6 +142,10 @@ var (
 	}
 
 	// Throttling Flags
+	TracesFlag = cli.BoolFlag{
+		Name:  "sync.traces",
+		Usage: "Store the traces of the blocks not pruned, so that trace_block, trace_transaction and trace_filter don't re-execute them",
+	}
 	SyncLoopThrottleFlag = cli.StringFlag{
 		Name:  "sync.loop.throttle",
 		Usage: "Sets the minimum time between sync loop starts (e.g. 1h30m, default is none)",
@@ -225,6 +233,8 @@ func ApplyFlagsForEthConfig(ctx *cli.Context, cfg *ethconfig.Config) {
 
 	cfg.StateStream = !ctx.GlobalBool(StateStreamDisableFlag.Name)
 	cfg.Sync.BlockDownloaderWindow = ctx.GlobalInt(BlockDownloaderWindowFlag.Name)
+	cfg.Sync.ReceiptsDownload = ctx.GlobalBool(ReceiptsDownloadFlag.Name)
+	cfg.Sync.Traces = ctx.GlobalBool(TracesFlag.Name)
 
 	if ctx.GlobalString(SyncLoopThrottleFlag.Name) != "" {
 		syncLoopThrottle, err := time.ParseDuration(ctx.GlobalString(SyncLoopThrottleFlag.Name))
@@ -351,19 +361,28 @@ func setEmbeddedRpcDaemon(ctx *cli.Context, cfg *nodecfg.Config) {
 			IdleTimeout:  ctx.GlobalDuration(HTTPIdleTimeoutFlag.Name),
 		},
 
-		WebsocketEnabled:     ctx.GlobalIsSet(utils.WSEnabledFlag.Name),
-		RpcBatchConcurrency:  ctx.GlobalUint(utils.RpcBatchConcurrencyFlag.Name),
-		RpcStreamingDisable:  ctx.GlobalBool(utils.RpcStreamingDisableFlag.Name),
-		DBReadConcurrency:    ctx.GlobalInt(utils.DBReadConcurrencyFlag.Name),
-		RpcAllowListFilePath: ctx.GlobalString(utils.RpcAccessListFlag.Name),
-		Gascap:               ctx.GlobalUint64(utils.RpcGasCapFlag.Name),
-		MaxTraces:            ctx.GlobalUint64(utils.TraceMaxtracesFlag.Name),
-		TraceCompatibility:   ctx.GlobalBool(utils.RpcTraceCompatFlag.Name),
-		StarknetGRPCAddress:  ctx.GlobalString(utils.StarknetGrpcAddressFlag.Name),
-		TevmEnabled:          ctx.GlobalBool(utils.TevmFlag.Name),
+		WebsocketEnabled:            ctx.GlobalIsSet(utils.WSEnabledFlag.Name),
+		RpcBatchConcurrency:         ctx.GlobalUint(utils.RpcBatchConcurrencyFlag.Name),
+		RpcStreamingDisable:         ctx.GlobalBool(utils.RpcStreamingDisableFlag.Name),
+		DBReadConcurrency:           ctx.GlobalInt(utils.DBReadConcurrencyFlag.Name),
+		RpcAllowListFilePath:        ctx.GlobalString(utils.RpcAccessListFlag.Name),
+		Gascap:                      ctx.GlobalUint64(utils.RpcGasCapFlag.Name),
+		MaxTraces:                   ctx.GlobalUint64(utils.TraceMaxtracesFlag.Name),
+		MaxGetProofRewindBlockCount: ctx.GlobalInt(utils.RpcMaxGetProofRewindBlockCountFlag.Name),
+		TraceCompatibility:          ctx.GlobalBool(utils.RpcTraceCompatFlag.Name),
+		StarknetGRPCAddress:         ctx.GlobalString(utils.StarknetGrpcAddressFlag.Name),
+		TevmEnabled:                 ctx.GlobalBool(utils.TevmFlag.Name),
 
 		TxPoolApiAddr: ctx.GlobalString(utils.TxpoolApiAddrFlag.Name),
 
+		RpcLimits: rpccfg.RequestLimits{
+			Rate:            ctx.GlobalInt(utils.RpcLimitsRateFlag.Name),
+			Burst:           ctx.GlobalInt(utils.RpcLimitsBurstFlag.Name),
+			MaxBatchSize:    ctx.GlobalInt(utils.RpcLimitsBatchFlag.Name),
+			MaxResponseSize: ctx.GlobalInt(utils.RpcLimitsResponseFlag.Name),
+			APIKeyHeader:    ctx.GlobalString(utils.RpcLimitsAPIKeyHeaderFlag.Name),
+		},
+
 		StateCache: kvcache.DefaultCoherentConfig,
 	}
 	if ctx.GlobalIsSet(utils.HttpCompressionFlag.Name) {
@@ -378,6 +397,19 @@ func setEmbeddedRpcDaemon(ctx *cli.Context, cfg *nodecfg.Config) {
 	}
 
 	c.StateCache.CodeKeysLimit = ctx.GlobalInt(utils.StateCacheFlag.Name)
+	costs, err := rpccfg.ParseMethodCosts(ctx.GlobalString(utils.RpcLimitsCostsFlag.Name))
+	if err != nil {
+		utils.Fatalf("Invalid %s: %v", utils.RpcLimitsCostsFlag.Name, err)
+	}
+	c.RpcLimits.MethodCosts = costs
+	if c.RpcLimits.APIKeyHeader != "" {
+		if !ctx.GlobalIsSet(utils.RpcLimitsAPIKeysFlag.Name) {
+			utils.Fatalf("%s requires %s", utils.RpcLimitsAPIKeyHeaderFlag.Name, utils.RpcLimitsAPIKeysFlag.Name)
+		}
+		if c.RpcLimits.APIKeys, err = rpccfg.ReadAPIKeys(ctx.GlobalString(utils.RpcLimitsAPIKeysFlag.Name)); err != nil {
+			utils.Fatalf("Invalid %s: %v", utils.RpcLimitsAPIKeysFlag.Name, err)
+		}
+	}
 
 	/*
 		rootCmd.PersistentFlags().BoolVar(&cfg.GRPCServerEnabled, "grpc", false, "Enable GRPC server")
@@ -70,6 +70,8 @@ func NewNodConfigUrfave(ctx *cli.Context) *nodecfg.Config {
 		log.Info("Starting Erigon on Chapel testnet...")
 	case networkname.DevChainName:
 		log.Info("Starting Erigon in ephemeral dev mode...")
+	case networkname.ParliaDevChainName:
+		log.Info("Starting Erigon on a local Parlia devnet...")
 	case networkname.MumbaiChainName:
 		log.Info("Starting Erigon on Mumbai testnet...")
 	case networkname.BorMainnetChainName:
@@ -23,6 +23,7 @@ import (
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/eth/filters"
+	"github.com/ledgerwatch/erigon/ethdb/privateapi"
 	"github.com/ledgerwatch/erigon/rlp"
 )
 
@@ -32,6 +33,7 @@ type (
 	PendingLogsSubID  SubscriptionID
 	PendingBlockSubID SubscriptionID
 	PendingTxsSubID   SubscriptionID
+	SyncingSubID      SubscriptionID
 	LogsSubID         uint64
 )
 
@@ -44,6 +46,7 @@ type Filters struct {
 	pendingLogsSubs  map[PendingLogsSubID]chan types.Logs
 	pendingBlockSubs map[PendingBlockSubID]chan *types.Block
 	pendingTxsSubs   map[PendingTxsSubID]chan []types.Transaction
+	syncingSubs      map[SyncingSubID]chan *privateapi.SyncProgress
 	logsSubs         *LogsFilterAggregator
 	logsRequestor    atomic.Value
 	onNewSnapshot    func()
@@ -62,6 +65,7 @@ func New(ctx context.Context, ethBackend ApiBackend, txPool txpool.TxpoolClient,
 		pendingTxsSubs:     make(map[PendingTxsSubID]chan []types.Transaction),
 		pendingLogsSubs:    make(map[PendingLogsSubID]chan types.Logs),
 		pendingBlockSubs:   make(map[PendingBlockSubID]chan *types.Block),
+		syncingSubs:        make(map[SyncingSubID]chan *privateapi.SyncProgress),
 		logsSubs:           NewLogsFilterAggregator(),
 		onNewSnapshot:      onNewSnapshot,
 		logsStores:         make(map[LogsSubID][]*types.Log),
@@ -95,6 +99,36 @@ func New(ctx context.Context, ethBackend ApiBackend, txPool txpool.TxpoolClient,
 		}
 	}()
 
+	go func() {
+		if ethBackend == nil {
+			return
+		}
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			default:
+			}
+
+			if err := ethBackend.SubscribeSyncing(ctx, ff.OnNewEvent); err != nil {
+				select {
+				case <-ctx.Done():
+					return
+				default:
+				}
+				if errors.Is(err, privateapi.ErrSyncingUnsupported) {
+					log.Warn("rpc filters: the syncing subscriptions are not served", "err", err)
+					return
+				}
+				if grpcutil.IsEndOfStream(err) || grpcutil.IsRetryLater(err) {
+					time.Sleep(3 * time.Second)
+					continue
+				}
+				log.Warn("rpc filters: error subscribing to sync progress", "err", err)
+			}
+		}
+	}()
+
 	go func() {
 		if ethBackend == nil {
 			return
@@ -375,6 +409,25 @@ func (ff *Filters) UnsubscribePendingTxs(id PendingTxsSubID) bool {
 	return false
 }
 
+func (ff *Filters) SubscribeSyncing(out chan *privateapi.SyncProgress) SyncingSubID {
+	ff.mu.Lock()
+	defer ff.mu.Unlock()
+	id := SyncingSubID(generateSubscriptionID())
+	ff.syncingSubs[id] = out
+	return id
+}
+
+func (ff *Filters) UnsubscribeSyncing(id SyncingSubID) bool {
+	ff.mu.Lock()
+	defer ff.mu.Unlock()
+	if ch, ok := ff.syncingSubs[id]; ok {
+		close(ch)
+		delete(ff.syncingSubs, id)
+		return true
+	}
+	return false
+}
+
 func (ff *Filters) SubscribeLogs(out chan *types.Log, crit filters.FilterCriteria) LogsSubID {
 	id, f := ff.logsSubs.insertLogsFilter(out)
 	f.addrs = map[common.Address]int{}
@@ -485,6 +538,16 @@ func (ff *Filters) OnNewEvent(event *remote.SubscribeReply) {
 		}
 	case remote.Event_NEW_SNAPSHOT:
 		ff.onNewSnapshot()
+	case privateapi.EventSyncing:
+		var progress privateapi.SyncProgress
+		if err := rlp.DecodeBytes(event.Data, &progress); err != nil {
+			// ignoring what we can't unmarshal
+			log.Warn("OnNewEvent rpc filters (syncing), unprocessable payload", "err", err)
+		} else {
+			for _, v := range ff.syncingSubs {
+				v <- &progress
+			}
+		}
 	//case remote.Event_PENDING_LOGS:
 	//	payload := event.Data
 	//	var logs types.Logs
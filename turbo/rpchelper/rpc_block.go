@@ -40,6 +40,10 @@ func GetFinalizedBlockNumber(tx kv.Tx) (uint64, error) {
 			return *forkchoiceFinalizedNum, nil
 		}
 	}
+	// PoSA chains have no Engine API, the finalized block is the one sealed on by a majority of the validators
+	if finalizedNum := rawdb.ReadHeaderNumber(tx, rawdb.ReadPoSAFinalized(tx)); finalizedNum != nil {
+		return *finalizedNum, nil
+	}
 
 	return 0, UnknownBlockError
 }
@@ -52,5 +56,8 @@ func GetSafeBlockNumber(tx kv.Tx) (uint64, error) {
 			return *forkchoiceSafeNum, nil
 		}
 	}
+	if safeNum := rawdb.ReadHeaderNumber(tx, rawdb.ReadPoSASafe(tx)); safeNum != nil {
+		return *safeNum, nil
+	}
 	return 0, UnknownBlockError
 }
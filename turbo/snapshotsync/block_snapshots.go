@@ -328,6 +328,7 @@ type RoSnapshots struct {
 	Headers *headerSegments
 	Bodies  *bodySegments
 	Txs     *txnSegments
+	Parlia  *parliaSegments
 
 	dir         string
 	segmentsMax atomic.Uint64 // all types of .seg files are available - up to this number
@@ -341,7 +342,7 @@ type RoSnapshots struct {
 //   - gaps are not allowed
 //   - segment have [from:to) semantic
 func NewRoSnapshots(cfg ethconfig.Snapshot, snapDir string) *RoSnapshots {
-	return &RoSnapshots{dir: snapDir, cfg: cfg, Headers: &headerSegments{}, Bodies: &bodySegments{}, Txs: &txnSegments{}}
+	return &RoSnapshots{dir: snapDir, cfg: cfg, Headers: &headerSegments{}, Bodies: &bodySegments{}, Txs: &txnSegments{}, Parlia: &parliaSegments{}}
 }
 
 func (s *RoSnapshots) Cfg() ethconfig.Snapshot { return s.cfg }
@@ -410,6 +411,8 @@ func (s *RoSnapshots) Files() (list []string) {
 	defer s.Bodies.lock.RUnlock()
 	s.Txs.lock.RLock()
 	defer s.Txs.lock.RUnlock()
+	s.Parlia.lock.RLock()
+	defer s.Parlia.lock.RUnlock()
 	max := s.BlocksAvailable()
 	for _, seg := range s.Bodies.segments {
 		if seg.seg == nil {
@@ -441,6 +444,16 @@ func (s *RoSnapshots) Files() (list []string) {
 		_, fName := filepath.Split(seg.Seg.FilePath())
 		list = append(list, fName)
 	}
+	for _, seg := range s.Parlia.segments {
+		if seg.seg == nil {
+			continue
+		}
+		if seg.ranges.from > max {
+			continue
+		}
+		_, fName := filepath.Split(seg.seg.FilePath())
+		list = append(list, fName)
+	}
 	slices.Sort(list)
 	return list
 }
@@ -453,6 +466,8 @@ func (s *RoSnapshots) ReopenList(fileNames []string, optimistic bool) error {
 	defer s.Bodies.lock.Unlock()
 	s.Txs.lock.Lock()
 	defer s.Txs.lock.Unlock()
+	s.Parlia.lock.Lock()
+	defer s.Parlia.lock.Unlock()
 
 	s.closeWhatNotInList(fileNames)
 	var segmentsMax uint64
@@ -571,6 +586,34 @@ Loop:
 			if err := sn.reopenIdxIfNeed(s.dir, optimistic); err != nil {
 				return err
 			}
+		case snap.Parlia:
+			for _, sn := range s.Parlia.segments {
+				if sn.seg == nil {
+					continue
+				}
+				_, name := filepath.Split(sn.seg.FilePath())
+				if fName == name {
+					if err := sn.reopenIdxIfNeed(s.dir, optimistic); err != nil {
+						return err
+					}
+					continue Loop
+				}
+			}
+
+			sn := &ParliaSegment{ranges: Range{f.From, f.To}}
+			if err := sn.reopenSeg(s.dir); err != nil {
+				if optimistic || errors.Is(err, os.ErrNotExist) {
+					log.Warn("[snapshots] open segment", "err", err)
+					continue Loop
+				}
+				return err
+			}
+			s.Parlia.segments = append(s.Parlia.segments, sn)
+			if err := sn.reopenIdxIfNeed(s.dir, optimistic); err != nil {
+				return err
+			}
+			// parlia segments are optional, they don't affect the blocks available
+			continue Loop
 		}
 
 		if f.To > 0 {
@@ -634,6 +677,8 @@ func (s *RoSnapshots) Close() {
 	defer s.Bodies.lock.Unlock()
 	s.Txs.lock.Lock()
 	defer s.Txs.lock.Unlock()
+	s.Parlia.lock.Lock()
+	defer s.Parlia.lock.Unlock()
 	s.closeWhatNotInList(nil)
 }
 
@@ -671,6 +716,17 @@ Loop3:
 		sn.close()
 		s.Txs.segments[i] = nil
 	}
+Loop4:
+	for i, sn := range s.Parlia.segments {
+		_, name := filepath.Split(sn.seg.FilePath())
+		for _, fName := range l {
+			if fName == name {
+				continue Loop4
+			}
+		}
+		sn.close()
+		s.Parlia.segments[i] = nil
+	}
 	var i int
 	for i = 0; i < len(s.Headers.segments) && s.Headers.segments[i] != nil && s.Headers.segments[i].seg != nil; i++ {
 	}
@@ -704,6 +760,17 @@ Loop3:
 			tailC[i] = nil
 		}
 	}
+
+	for i = 0; i < len(s.Parlia.segments) && s.Parlia.segments[i] != nil && s.Parlia.segments[i].seg != nil; i++ {
+	}
+	tailD := s.Parlia.segments[i:]
+	s.Parlia.segments = s.Parlia.segments[:i]
+	for i = 0; i < len(tailD); i++ {
+		if tailD[i] != nil {
+			tailD[i].close()
+			tailD[i] = nil
+		}
+	}
 }
 
 func (s *RoSnapshots) PrintDebug() {
@@ -760,6 +827,10 @@ func buildIdx(ctx context.Context, sn snap.FileInfo, chainID uint256.Int, tmpDir
 		if err := TransactionsIdx(ctx, chainID, sn.From, sn.To, dir, tmpDir, p, lvl, borCfg); err != nil {
 			return err
 		}
+	case snap.Parlia:
+		if err := ParliaIdx(ctx, sn.Path, sn.From, tmpDir, p, lvl); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -777,7 +848,7 @@ func BuildMissedIndices(ctx context.Context, dir string, chainID uint256.Int, tm
 	ps := background.NewProgressSet()
 	sem := semaphore.NewWeighted(int64(workers))
 	go func() {
-		for _, t := range snap.AllSnapshotTypes {
+		for _, t := range append(snap.AllSnapshotTypes, snap.Parlia) {
 			for index := range segments {
 				segment := segments[index]
 				if segment.T != t {
@@ -927,6 +998,17 @@ func Segments(dir string) (res []snap.FileInfo, missingSnapshots []Range, err er
 		l, _ = noGaps(noOverlaps(allTypeOfSegmentsMustExist(dir, l)))
 		res = append(res, l...)
 	}
+	{
+		// parlia segments are not produced for the ranges without checkpoints, gaps are fine
+		var l []snap.FileInfo
+		for _, f := range list {
+			if f.T != snap.Parlia {
+				continue
+			}
+			l = append(l, f)
+		}
+		res = append(res, noOverlaps(l)...)
+	}
 
 	return res, missingSnapshots, nil
 }
@@ -946,6 +1028,7 @@ type BlockRetire struct {
 	tmpDir    string
 	snapshots *RoSnapshots
 	db        kv.RoDB
+	parliaDB  kv.RwDB
 
 	downloader proto_downloader.DownloaderClient
 	notifier   DBEventNotifier
@@ -959,6 +1042,10 @@ type BlockRetireResult struct {
 func NewBlockRetire(workers int, tmpDir string, snapshots *RoSnapshots, db kv.RoDB, downloader proto_downloader.DownloaderClient, notifier DBEventNotifier) *BlockRetire {
 	return &BlockRetire{workers: workers, tmpDir: tmpDir, snapshots: snapshots, wg: &sync.WaitGroup{}, db: db, downloader: downloader, notifier: notifier}
 }
+
+// SetParliaDB makes the retire of blocks freeze the parlia snapshots of the checkpoints too,
+// and prune them from the consensus database of Parlia
+func (br *BlockRetire) SetParliaDB(db kv.RwDB)  { br.parliaDB = db }
 func (br *BlockRetire) Snapshots() *RoSnapshots { return br.snapshots }
 func (br *BlockRetire) Working() bool           { return br.working.Load() }
 func (br *BlockRetire) Wait()                   { br.wg.Wait() }
@@ -1009,7 +1096,7 @@ func CanDeleteTo(curBlockNum uint64, snapshots *RoSnapshots) (blockTo uint64) {
 func (br *BlockRetire) RetireBlocks(ctx context.Context, blockFrom, blockTo uint64, lvl log.Lvl) error {
 	chainConfig := tool.ChainConfigFromDB(br.db)
 	chainID, _ := uint256.FromBig(chainConfig.ChainID)
-	return retireBlocks(ctx, blockFrom, blockTo, *chainID, br.tmpDir, br.snapshots, br.db, br.workers, br.downloader, lvl, br.notifier)
+	return retireBlocks(ctx, blockFrom, blockTo, *chainID, br.tmpDir, br.snapshots, br.db, br.parliaDB, br.workers, br.downloader, lvl, br.notifier)
 }
 
 func (br *BlockRetire) PruneAncientBlocks(tx kv.RwTx) error {
@@ -1027,6 +1114,11 @@ func (br *BlockRetire) PruneAncientBlocks(tx kv.RwTx) error {
 	if err := rawdb.PruneTable(tx, kv.Senders, canDeleteTo, context.Background(), 100); err != nil {
 		return err
 	}
+	if br.parliaDB != nil {
+		if err := pruneParliaSnapshots(context.Background(), br.parliaDB, br.snapshots, canDeleteTo); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -1064,12 +1156,17 @@ type DBEventNotifier interface {
 	OnNewSnapshot()
 }
 
-func retireBlocks(ctx context.Context, blockFrom, blockTo uint64, chainID uint256.Int, tmpDir string, snapshots *RoSnapshots, db kv.RoDB, workers int, downloader proto_downloader.DownloaderClient, lvl log.Lvl, notifier DBEventNotifier) error {
+func retireBlocks(ctx context.Context, blockFrom, blockTo uint64, chainID uint256.Int, tmpDir string, snapshots *RoSnapshots, db kv.RoDB, parliaDB kv.RoDB, workers int, downloader proto_downloader.DownloaderClient, lvl log.Lvl, notifier DBEventNotifier) error {
 	log.Log(lvl, "[snapshots] Retire Blocks", "range", fmt.Sprintf("%dk-%dk", blockFrom/1000, blockTo/1000))
 	// in future we will do it in background
 	if err := DumpBlocks(ctx, blockFrom, blockTo, snap.DEFAULT_SEGMENT_SIZE, tmpDir, snapshots.Dir(), db, workers, lvl); err != nil {
 		return fmt.Errorf("DumpBlocks: %w", err)
 	}
+	if parliaDB != nil {
+		if err := DumpParlia(ctx, blockFrom, blockTo, snap.DEFAULT_SEGMENT_SIZE, tmpDir, snapshots.Dir(), db, parliaDB, workers, lvl); err != nil {
+			return fmt.Errorf("DumpParlia: %w", err)
+		}
+	}
 	if err := snapshots.ReopenFolder(); err != nil {
 		return fmt.Errorf("reopen: %w", err)
 	}
@@ -1080,6 +1177,10 @@ func retireBlocks(ctx context.Context, blockFrom, blockTo uint64, chainID uint25
 	merger := NewMerger(tmpDir, workers, lvl, chainID, notifier)
 	rangesToMerge := merger.FindMergeRanges(snapshots.Ranges())
 	if len(rangesToMerge) == 0 {
+		// the parlia segments of the retired range may be seedable without merging
+		if downloadRequest := parliaDownloadRequests(snapshots, blockFrom, blockTo); len(downloadRequest) > 0 {
+			return RequestSnapshotsDownload(ctx, downloadRequest, downloader)
+		}
 		return nil
 	}
 	chainConfig := tool.ChainConfigFromDB(db)
@@ -1118,6 +1219,7 @@ func retireBlocks(ctx context.Context, blockFrom, blockTo uint64, chainID uint25
 	for i := range rangesToMerge {
 		downloadRequest = append(downloadRequest, NewDownloadRequest(&rangesToMerge[i], "", ""))
 	}
+	downloadRequest = append(downloadRequest, parliaDownloadRequests(snapshots, cmp.Min(blockFrom, rangesToMerge[0].from), blockTo)...)
 
 	return RequestSnapshotsDownload(ctx, downloadRequest, downloader)
 }
@@ -1186,7 +1288,7 @@ func hasIdxFile(sn *snap.FileInfo) bool {
 			return false
 		}
 		_ = idx.Close()
-	case snap.Bodies:
+	case snap.Bodies, snap.Parlia:
 		idx, err := recsplit.OpenIndex(path.Join(dir, fName))
 		if err != nil {
 			return false
@@ -1986,7 +2088,15 @@ func (m *Merger) filesByRange(snapshots *RoSnapshots, from, to uint64) (map[snap
 					toMerge[snap.Transactions] = append(toMerge[snap.Transactions], tSegments[i].Seg.FilePath())
 				}
 
-				return nil
+				return snapshots.Parlia.View(func(pSegments []*ParliaSegment) error {
+					for _, sn := range pSegments {
+						if sn.ranges.from < from || sn.ranges.to > to {
+							continue
+						}
+						toMerge[snap.Parlia] = append(toMerge[snap.Parlia], sn.seg.FilePath())
+					}
+					return nil
+				})
 			})
 		})
 	})
@@ -2006,7 +2116,10 @@ func (m *Merger) Merge(ctx context.Context, snapshots *RoSnapshots, mergeRanges
 		if err != nil {
 			return err
 		}
-		for _, t := range snap.AllSnapshotTypes {
+		for _, t := range append(snap.AllSnapshotTypes, snap.Parlia) {
+			if len(toMerge[t]) == 0 { // no parlia segments in the range
+				continue
+			}
 			segName := snap.SegmentFileName(r.from, r.to, t)
 			f, _ := snap.ParseFileName(snapDir, segName)
 			if err := m.merge(ctx, toMerge[t], f.Path, logEvery); err != nil {
@@ -2028,7 +2141,7 @@ func (m *Merger) Merge(ctx context.Context, snapshots *RoSnapshots, mergeRanges
 			m.notifier.OnNewSnapshot()
 			time.Sleep(1 * time.Second) // i working on blocking API - to ensure client does not use old snapsthos - and then delete them
 		}
-		for _, t := range snap.AllSnapshotTypes {
+		for _, t := range append(snap.AllSnapshotTypes, snap.Parlia) {
 			m.removeOldFiles(toMerge[t], snapDir)
 		}
 	}
@@ -0,0 +1,322 @@
+package snapshotsync
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sync"
+
+	"github.com/ledgerwatch/erigon-lib/common/background"
+	"github.com/ledgerwatch/erigon-lib/common/cmp"
+	"github.com/ledgerwatch/erigon-lib/common/dbg"
+	"github.com/ledgerwatch/erigon-lib/compress"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/recsplit"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync/snap"
+	"github.com/ledgerwatch/log/v3"
+)
+
+type ParliaSegment struct {
+	seg           *compress.Decompressor // value: checkpoint_block_num_u64 + checkpoint_hash + json(parlia.Snapshot)
+	idxCheckpoint *recsplit.Index        // checkpoint_block_num_u64 -> parlia_segment_offset
+	ranges        Range
+}
+
+func (sn *ParliaSegment) closeIdx() {
+	if sn.idxCheckpoint != nil {
+		sn.idxCheckpoint.Close()
+		sn.idxCheckpoint = nil
+	}
+}
+func (sn *ParliaSegment) closeSeg() {
+	if sn.seg != nil {
+		sn.seg.Close()
+		sn.seg = nil
+	}
+}
+func (sn *ParliaSegment) close() {
+	sn.closeSeg()
+	sn.closeIdx()
+}
+func (sn *ParliaSegment) reopenSeg(dir string) (err error) {
+	sn.closeSeg()
+	fileName := snap.SegmentFileName(sn.ranges.from, sn.ranges.to, snap.Parlia)
+	sn.seg, err = compress.NewDecompressor(path.Join(dir, fileName))
+	if err != nil {
+		return fmt.Errorf("%w, fileName: %s", err, fileName)
+	}
+	return nil
+}
+func (sn *ParliaSegment) reopenIdxIfNeed(dir string, optimistic bool) (err error) {
+	if sn.idxCheckpoint != nil {
+		return nil
+	}
+	err = sn.reopenIdx(dir)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			if optimistic {
+				log.Warn("[snapshots] open index", "err", err)
+			} else {
+				return err
+			}
+		}
+	}
+	return nil
+}
+func (sn *ParliaSegment) reopenIdx(dir string) (err error) {
+	sn.closeIdx()
+	fileName := snap.IdxFileName(sn.ranges.from, sn.ranges.to, snap.Parlia.String())
+	sn.idxCheckpoint, err = recsplit.OpenIndex(path.Join(dir, fileName))
+	if err != nil {
+		return fmt.Errorf("%w, fileName: %s", err, fileName)
+	}
+	return nil
+}
+
+// Snapshot returns the json encoded parlia snapshot of the checkpoint
+func (sn *ParliaSegment) Snapshot(blockNum uint64, hash common.Hash) ([]byte, bool) {
+	if sn.idxCheckpoint == nil || sn.idxCheckpoint.KeyCount() == 0 {
+		return nil, false
+	}
+	reader := recsplit.NewIndexReader(sn.idxCheckpoint)
+	localID := reader.Lookup(dbutils.EncodeBlockNumber(blockNum))
+	if localID >= sn.idxCheckpoint.KeyCount() {
+		return nil, false
+	}
+	gg := sn.seg.MakeGetter()
+	gg.Reset(sn.idxCheckpoint.OrdinalLookup(localID))
+	if !gg.HasNext() {
+		return nil, false
+	}
+	word, _ := gg.Next(nil)
+	// the index returns a random offset for the checkpoints missing in the segment
+	if len(word) < 8+32 || binary.BigEndian.Uint64(word) != blockNum || !bytes.Equal(word[8:8+32], hash[:]) {
+		return nil, false
+	}
+	return word[8+32:], true
+}
+
+type parliaSegments struct {
+	lock     sync.RWMutex
+	segments []*ParliaSegment
+}
+
+func (s *parliaSegments) View(f func([]*ParliaSegment) error) error {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	return f(s.segments)
+}
+func (s *parliaSegments) ViewSegment(blockNum uint64, f func(*ParliaSegment) error) (found bool, err error) {
+	s.lock.RLock()
+	defer s.lock.RUnlock()
+	for _, seg := range s.segments {
+		if !(blockNum >= seg.ranges.from && blockNum < seg.ranges.to) {
+			continue
+		}
+		return true, f(seg)
+	}
+	return false, nil
+}
+
+// ParliaSnapshot returns the json encoded parlia snapshot of the checkpoint if it's frozen in the snapshots
+func (s *RoSnapshots) ParliaSnapshot(blockNum uint64, hash common.Hash) (blob []byte, found bool) {
+	if !s.indicesReady.Load() {
+		return nil, false
+	}
+	_, _ = s.Parlia.ViewSegment(blockNum, func(sn *ParliaSegment) error {
+		blob, found = sn.Snapshot(blockNum, hash)
+		return nil
+	})
+	return blob, found
+}
+
+// ParliaAvailable returns the block number the parlia segments cover the checkpoints up to, exclusive
+func (s *RoSnapshots) ParliaAvailable() (blockTo uint64) {
+	_ = s.Parlia.View(func(segments []*ParliaSegment) error {
+		if len(segments) > 0 {
+			blockTo = segments[len(segments)-1].ranges.to
+		}
+		return nil
+	})
+	return blockTo
+}
+
+// DumpParlia freezes the parlia snapshots of the checkpoints of the range, split into files the same way
+// as the blocks
+func DumpParlia(ctx context.Context, blockFrom, blockTo, blocksPerFile uint64, tmpDir, snapDir string, chainDB, parliaDB kv.RoDB, workers int, lvl log.Lvl) error {
+	if blocksPerFile == 0 {
+		return nil
+	}
+	for i := blockFrom; i < blockTo; i = chooseSegmentEnd(i, blockTo, blocksPerFile) {
+		to := chooseSegmentEnd(i, blockTo, blocksPerFile)
+		f, _ := snap.ParseFileName(snapDir, snap.SegmentFileName(i, to, snap.Parlia))
+		checkpoints, err := DumpParliaSnapshots(ctx, chainDB, parliaDB, f.Path, tmpDir, i, to, workers, lvl)
+		if err != nil {
+			return fmt.Errorf("DumpParliaSnapshots: %w", err)
+		}
+		if checkpoints == 0 {
+			continue
+		}
+		if err := ParliaIdx(ctx, f.Path, f.From, tmpDir, &background.Progress{}, lvl); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// DumpParliaSnapshots - [from, to) writes the parlia snapshots of the canonical checkpoints of the range,
+// returns the amount of checkpoints written. The segment file is not created when there are none.
+func DumpParliaSnapshots(ctx context.Context, chainDB, parliaDB kv.RoDB, segmentFilePath, tmpDir string, blockFrom, blockTo uint64, workers int, lvl log.Lvl) (checkpoints int, err error) {
+	chainTx, err := chainDB.BeginRo(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer chainTx.Rollback()
+
+	f, err := compress.NewCompressor(ctx, "Snapshot Parlia", segmentFilePath, tmpDir, compress.MinPatternScore, workers, lvl)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+
+	if err := parliaDB.View(ctx, func(tx kv.Tx) error {
+		c, err := tx.Cursor(kv.ParliaSnapshot)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		for k, v, err := c.Seek(dbutils.EncodeBlockNumber(blockFrom)); k != nil; k, v, err = c.Next() {
+			if err != nil {
+				return err
+			}
+			if len(k) != 8+32 {
+				continue
+			}
+			blockNum := binary.BigEndian.Uint64(k)
+			if blockNum >= blockTo {
+				break
+			}
+			canonical, err := rawdb.ReadCanonicalHash(chainTx, blockNum)
+			if err != nil {
+				return err
+			}
+			if !bytes.Equal(canonical[:], k[8:]) { // snapshots of the forks left behind
+				continue
+			}
+			value := make([]byte, len(k)+len(v))
+			copy(value, k)
+			copy(value[len(k):], v)
+			if err := f.AddWord(value); err != nil {
+				return err
+			}
+			checkpoints++
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			default:
+			}
+		}
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	if checkpoints == 0 {
+		return 0, nil
+	}
+	if err := f.Compress(); err != nil {
+		return 0, fmt.Errorf("compress: %w", err)
+	}
+	return checkpoints, nil
+}
+
+func ParliaIdx(ctx context.Context, segmentFilePath string, firstBlockNumInSegment uint64, tmpDir string, p *background.Progress, lvl log.Lvl) (err error) {
+	defer func() {
+		if rec := recover(); rec != nil {
+			_, fName := filepath.Split(segmentFilePath)
+			err = fmt.Errorf("ParliaIdx: at=%s, %v, %s", fName, rec, dbg.Stack())
+		}
+	}()
+
+	d, err := compress.NewDecompressor(segmentFilePath)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+
+	_, fname := filepath.Split(segmentFilePath)
+	p.Name.Store(fname)
+	p.Total.Store(uint64(d.Count()))
+
+	if err := Idx(ctx, d, firstBlockNumInSegment, tmpDir, log.LvlDebug, func(idx *recsplit.RecSplit, i, offset uint64, word []byte) error {
+		p.Processed.Inc()
+		if err := idx.AddKey(word[:8], offset); err != nil {
+			return err
+		}
+		return nil
+	}); err != nil {
+		return fmt.Errorf("ParliaIdx: %w", err)
+	}
+	return nil
+}
+
+// parliaDownloadRequests returns the requests to seed the parlia segments of [blockFrom, blockTo) which
+// are large enough, like the block segments of the merged ranges.
+func parliaDownloadRequests(snapshots *RoSnapshots, blockFrom, blockTo uint64) (downloadRequest []DownloadRequest) {
+	_ = snapshots.Parlia.View(func(segments []*ParliaSegment) error {
+		for _, sn := range segments {
+			if sn.ranges.to <= blockFrom || sn.ranges.from >= blockTo || sn.ranges.to-sn.ranges.from != snap.DEFAULT_SEGMENT_SIZE {
+				continue
+			}
+			downloadRequest = append(downloadRequest, NewDownloadRequest(nil, snap.SegmentFileName(sn.ranges.from, sn.ranges.to, snap.Parlia), ""))
+		}
+		return nil
+	})
+	return downloadRequest
+}
+
+// pruneParliaSnapshots deletes from the parlia database the snapshots of the checkpoints below blockTo
+// which are frozen in the parlia segments. The checkpoints of the ranges without a segment, e.g. the ones
+// of block segments downloaded from the peers, are kept.
+func pruneParliaSnapshots(ctx context.Context, parliaDB kv.RwDB, snapshots *RoSnapshots, blockTo uint64) error {
+	var ranges []Range
+	_ = snapshots.Parlia.View(func(segments []*ParliaSegment) error {
+		for _, sn := range segments {
+			if sn.ranges.from < blockTo {
+				ranges = append(ranges, Range{sn.ranges.from, cmp.Min(sn.ranges.to, blockTo)})
+			}
+		}
+		return nil
+	})
+	if len(ranges) == 0 {
+		return nil
+	}
+	return parliaDB.Update(ctx, func(tx kv.RwTx) error {
+		c, err := tx.RwCursor(kv.ParliaSnapshot)
+		if err != nil {
+			return err
+		}
+		defer c.Close()
+		for _, r := range ranges {
+			for k, _, err := c.Seek(dbutils.EncodeBlockNumber(r.from)); k != nil; k, _, err = c.Next() {
+				if err != nil {
+					return err
+				}
+				if binary.BigEndian.Uint64(k) >= r.to {
+					break
+				}
+				if err = c.DeleteCurrent(); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+}
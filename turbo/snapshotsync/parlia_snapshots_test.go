@@ -0,0 +1,92 @@
+package snapshotsync
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"testing"
+
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/common/dbutils"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync/snap"
+	"github.com/ledgerwatch/log/v3"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDumpParliaSnapshots(t *testing.T) {
+	dir, require := t.TempDir(), require.New(t)
+	chainDB, parliaDB := memdb.NewTestDB(t), memdb.NewTestDB(t)
+	ctx := context.Background()
+
+	hash := func(number uint64) common.Hash { return common.BytesToHash(dbutils.EncodeBlockNumber(number + 1)) }
+	blob := func(number uint64) []byte { return []byte(fmt.Sprintf(`{"number":%d}`, number)) }
+	require.NoError(chainDB.Update(ctx, func(tx kv.RwTx) error {
+		for number := uint64(0); number < 3_000; number++ {
+			if err := rawdb.WriteCanonicalHash(tx, hash(number), number); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	require.NoError(parliaDB.Update(ctx, func(tx kv.RwTx) error {
+		for _, number := range []uint64{1024, 2048} {
+			if err := tx.Put(kv.ParliaSnapshot, append(dbutils.EncodeBlockNumber(number), hash(number).Bytes()...), blob(number)); err != nil {
+				return err
+			}
+		}
+		// the snapshot of a non-canonical checkpoint is not frozen
+		return tx.Put(kv.ParliaSnapshot, append(dbutils.EncodeBlockNumber(2048), common.Hash{1}.Bytes()...), blob(1))
+	}))
+
+	require.NoError(DumpParlia(ctx, 0, 3_000, 1_000, dir, dir, chainDB, parliaDB, 1, log.LvlInfo))
+	segments, err := snap.Segments(dir)
+	require.NoError(err)
+	require.Len(segments, 2, "no segment for the range without checkpoints")
+
+	s := NewRoSnapshots(ethconfig.Snapshot{Enabled: true}, dir)
+	defer s.Close()
+	require.NoError(s.ReopenFolder())
+	require.Equal(uint64(3_000), s.ParliaAvailable())
+	require.Zero(s.BlocksAvailable(), "parlia segments don't make blocks available")
+
+	for _, number := range []uint64{1024, 2048} {
+		got, ok := s.ParliaSnapshot(number, hash(number))
+		require.True(ok)
+		require.Equal(blob(number), got)
+	}
+	_, ok := s.ParliaSnapshot(2048, common.Hash{1})
+	require.False(ok)
+	_, ok = s.ParliaSnapshot(1025, hash(1025))
+	require.False(ok)
+	_, ok = s.ParliaSnapshot(0, hash(0))
+	require.False(ok)
+
+	// checkpoints of the ranges without a segment are not pruned
+	require.NoError(parliaDB.Update(ctx, func(tx kv.RwTx) error {
+		for _, number := range []uint64{512, 3072} {
+			if err := tx.Put(kv.ParliaSnapshot, append(dbutils.EncodeBlockNumber(number), hash(number).Bytes()...), blob(number)); err != nil {
+				return err
+			}
+		}
+		return nil
+	}))
+	checkpointsLeft := func() (left []uint64) {
+		require.NoError(parliaDB.View(ctx, func(tx kv.Tx) error {
+			return tx.ForEach(kv.ParliaSnapshot, nil, func(k, v []byte) error {
+				left = append(left, binary.BigEndian.Uint64(k))
+				return nil
+			})
+		}))
+		return left
+	}
+	require.NoError(pruneParliaSnapshots(ctx, parliaDB, s, 2048))
+	require.Equal([]uint64{512, 2048, 2048, 3072}, checkpointsLeft())
+	require.NoError(pruneParliaSnapshots(ctx, parliaDB, s, 4096))
+	require.Equal([]uint64{512, 3072}, checkpointsLeft())
+
+	require.Empty(parliaDownloadRequests(s, 0, 3_000), "the segments are too small to be seeded")
+}
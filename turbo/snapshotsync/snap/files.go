@@ -20,6 +20,9 @@ const (
 	Headers Type = iota
 	Bodies
 	Transactions
+	// Parlia segments hold the validator snapshots of the checkpoints of the blocks range. Only the
+	// nodes of Parlia chains produce them, so they are not a part of AllSnapshotTypes.
+	Parlia
 	NumberOfTypes
 )
 
@@ -31,6 +34,8 @@ func (ft Type) String() string {
 		return "bodies"
 	case Transactions:
 		return "transactions"
+	case Parlia:
+		return "parlia"
 	default:
 		panic(fmt.Sprintf("unknown file type: %d", ft))
 	}
@@ -44,6 +49,8 @@ func ParseFileType(s string) (Type, bool) {
 		return Bodies, true
 	case "transactions":
 		return Transactions, true
+	case "parlia":
+		return Parlia, true
 	default:
 		return NumberOfTypes, false
 	}
@@ -122,6 +129,8 @@ func ParseFileName(dir, fileName string) (res FileInfo, err error) {
 		snapshotType = Bodies
 	case Transactions:
 		snapshotType = Transactions
+	case Parlia:
+		snapshotType = Parlia
 	default:
 		return res, fmt.Errorf("unexpected snapshot suffix: %s,%w", parts[2], ErrInvalidFileName)
 	}
@@ -183,6 +183,7 @@ const (
 	TooFarPastPenalty
 	AbandonedAnchorPenalty
 	NewBlockGossipAfterMergePenalty
+	NoReceiptsPenalty
 )
 
 type PeerPenalty struct {
@@ -374,6 +375,8 @@ func (p Penalty) String() string {
 		return "TooFarPast"
 	case NewBlockGossipAfterMergePenalty:
 		return "NewBlockGossipAfterMerge"
+	case NoReceiptsPenalty:
+		return "NoReceipts"
 	default:
 		return fmt.Sprintf("Unknown(%d)", p)
 	}
@@ -0,0 +1,214 @@
+package stages_test
+
+import (
+	"crypto/ecdsa"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/VictoriaMetrics/metrics"
+	"github.com/holiman/uint256"
+	"github.com/ledgerwatch/erigon-lib/gointerfaces/sentry"
+	"github.com/ledgerwatch/erigon-lib/kv"
+	"github.com/ledgerwatch/erigon-lib/kv/memdb"
+	"github.com/stretchr/testify/require"
+
+	"github.com/ledgerwatch/erigon/accounts/keystore"
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/consensus/parlia"
+	"github.com/ledgerwatch/erigon/core"
+	"github.com/ledgerwatch/erigon/core/rawdb"
+	"github.com/ledgerwatch/erigon/core/systemcontracts"
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/ledgerwatch/erigon/crypto"
+	"github.com/ledgerwatch/erigon/eth/stagedsync"
+	syncstages "github.com/ledgerwatch/erigon/eth/stagedsync/stages"
+	"github.com/ledgerwatch/erigon/ethdb/prune"
+	"github.com/ledgerwatch/erigon/params"
+	"github.com/ledgerwatch/erigon/rlp"
+	"github.com/ledgerwatch/erigon/turbo/snapshotsync"
+	"github.com/ledgerwatch/erigon/turbo/stages"
+)
+
+// TestParliaDevnet mines blocks with the mining stages on a devnet of three validators, each
+// running its own node, and imports every sealed block into all the nodes.
+func TestParliaDevnet(t *testing.T) {
+	if testing.Short() {
+		t.Skip("seals blocks in real time")
+	}
+	ks := keystore.NewKeyStore(t.TempDir(), keystore.LightScryptN, keystore.LightScryptP)
+	keys := make(map[common.Address]*ecdsa.PrivateKey, 3)
+	for i := 0; i < 3; i++ {
+		key, err := crypto.GenerateKey()
+		require.NoError(t, err)
+		validator, err := ks.ImportECDSA(key, "devnet")
+		require.NoError(t, err)
+		require.NoError(t, ks.Unlock(validator, "devnet"))
+		keys[validator] = key
+	}
+	validators := ks.Accounts()
+	require.Len(t, validators, 3)
+
+	const period = 1
+	genesis, err := core.ParliaDevGenesisBlock(period, validators)
+	require.NoError(t, err)
+	genesis.Config.Parlia.Epoch = 4 // verify the validators returned by the validator contract
+	nodes := make(map[common.Address]*stages.MockSentry, len(validators))
+	for _, validator := range validators {
+		engine := parlia.New(genesis.Config, memdb.New(), nil, memdb.New())
+		engine.Authorize(validator, func(validator common.Address, payload []byte, _ *big.Int) ([]byte, error) {
+			return ks.SignHash(validator, payload)
+		})
+		nodes[validator] = stages.MockWithEverything(t, genesis, keys[validator], prune.DefaultMode, engine, true, false)
+	}
+
+	var (
+		parent    = nodes[validators[0]].Genesis.Header()
+		last      common.Address
+		finalized common.Hash
+	)
+	for number := uint64(1); number <= 6; number++ {
+		// block 3 is sealed out of turn, and so is block 4 as its in-turn validator signed block 3
+		inturn := validators[number%uint64(len(validators))]
+		signer := inturn
+		if number == 3 || signer == last {
+			for _, validator := range validators {
+				if validator != inturn && validator != last {
+					signer = validator
+					break
+				}
+			}
+		}
+		node := nodes[signer]
+
+		if number == 2 {
+			// a user transaction, so that the fees are distributed to the validator
+			require.Eventually(t, node.TxPool.Started, 5*time.Second, 10*time.Millisecond, "the pool starts with the first block")
+			tx, err := types.SignTx(types.NewTransaction(0, common.Address{1}, uint256.NewInt(params.Ether), params.TxGas, uint256.NewInt(params.GWei), nil), *types.LatestSignerForChainID(genesis.Config.ChainID), node.Key)
+			require.NoError(t, err)
+			b, err := rlp.EncodeToBytes(types.Transactions{tx})
+			require.NoError(t, err)
+			node.ReceiveWg.Add(1)
+			for _, err = range node.Send(&sentry.InboundMessage{Id: sentry.MessageId_TRANSACTIONS_66, Data: b, PeerId: node.PeerId}) {
+				require.NoError(t, err)
+			}
+			node.ReceiveWg.Wait()
+			require.Eventually(t, func() bool {
+				pending, _, _ := node.TxPool.CountContent()
+				return pending == 1
+			}, 5*time.Second, 10*time.Millisecond)
+		}
+
+		require.NoError(t, stages.MiningStep(node.Ctx, node.DB, node.MiningSync))
+		<-node.PendingBlocks
+		block := <-node.MinedBlocks
+		header := block.Header()
+		require.Equal(t, number, header.Number.Uint64())
+		require.Equal(t, signer, header.Coinbase)
+
+		if signer == inturn {
+			require.Equal(t, uint64(2), header.Difficulty.Uint64(), "block %d", number)
+			require.GreaterOrEqual(t, header.Time, parent.Time+period)
+		} else {
+			require.Equal(t, uint64(1), header.Difficulty.Uint64(), "block %d", number)
+			// out-of-turn validators back off to give way to the in-turn one
+			require.GreaterOrEqual(t, header.Time, parent.Time+period+1)
+		}
+		systemTxs := 0
+		for _, tx := range block.Transactions() {
+			if to := tx.GetTo(); to != nil && tx.GetPrice().IsZero() && (*to == systemcontracts.ValidatorContract || *to == systemcontracts.SlashContract || *to == systemcontracts.SystemRewardContract) {
+				systemTxs++
+			}
+		}
+		switch number {
+		case 1:
+			// but the validator contract, initialized in the genesis
+			require.Len(t, block.Transactions(), 6, "init system contracts")
+			for _, tx := range block.Transactions() {
+				require.NotEqual(t, systemcontracts.ValidatorContract, *tx.GetTo())
+			}
+		case 2:
+			require.Equal(t, 1+systemTxs, block.Transactions().Len())
+			require.NotZero(t, systemTxs, "fees are distributed")
+		case 3:
+			require.Equal(t, 1, systemTxs, "the in-turn validator is slashed")
+		}
+
+		chain := &core.ChainPack{Headers: []*types.Header{header}, Blocks: []*types.Block{block}, TopBlock: block}
+		for _, validator := range validators {
+			require.NoError(t, nodes[validator].InsertChain(chain), "block %d on the node of %x", number, validator)
+		}
+		parent, last = header, signer
+		if number == 4 {
+			finalized = header.Hash()
+		}
+	}
+
+	// 2 of the 3 validators sealed blocks 5 and 6 on top of block 4
+	for _, validator := range validators {
+		require.NoError(t, nodes[validator].DB.View(nodes[validator].Ctx, func(tx kv.Tx) error {
+			require.Equal(t, finalized, rawdb.ReadPoSAFinalized(tx), "finalized block on the node of %x", validator)
+			require.Equal(t, finalized, rawdb.ReadPoSASafe(tx), "safe block on the node of %x", validator)
+			return nil
+		}))
+	}
+
+	// the activity of the validators is recorded once per node
+	blocksRecorded := func(suffix string) (n uint64) {
+		for _, validator := range validators {
+			for _, turn := range []string{"in", "out"} {
+				n += metrics.GetOrCreateCounter(fmt.Sprintf(`parlia_blocks%s{validator="0x%x",turn="%s"}`, suffix, validator, turn)).Get()
+			}
+		}
+		return n
+	}
+	require.EqualValues(t, 6*len(nodes), blocksRecorded(""))
+	require.Zero(t, blocksRecorded("_reorged"))
+
+	// and accounted as reorged for the unwound blocks
+	node := nodes[validators[0]]
+	tx, err := node.DB.BeginRw(node.Ctx)
+	require.NoError(t, err)
+	defer tx.Rollback()
+	s, err := node.Sync.StageState(syncstages.PostExec, tx, node.DB)
+	require.NoError(t, err)
+	cfg := stagedsync.StagePostExecCfg(node.DB, nil, prune.DefaultMode, node.ChainConfig, node.Engine, snapshotsync.NewBlockReader())
+	require.NoError(t, stagedsync.UnwindPostExecStage(node.Sync.NewUnwindState(syncstages.PostExec, 3, 6), s, tx, cfg, node.Ctx))
+	require.EqualValues(t, 6*len(nodes), blocksRecorded(""))
+	require.EqualValues(t, 3, blocksRecorded("_reorged"))
+
+	// the PostExec stage unwinds an epoch block whose validators aren't the ones of the validator contract
+	epochBlock, senders, err := rawdb.ReadBlockWithSenders(tx, rawdb.ReadHeaderByNumber(tx, 4).Hash(), 4)
+	require.NoError(t, err)
+	const extraVanity, extraSeal = 32, 65
+	header := epochBlock.Header()
+	header.Extra = make([]byte, extraVanity+2*common.AddressLength+extraSeal)
+	copy(header.Extra[extraVanity:], validators[0][:])
+	copy(header.Extra[extraVanity+common.AddressLength:], validators[1][:])
+	mismatching := types.NewBlockWithHeader(header).WithBody(epochBlock.Transactions(), nil)
+	require.NoError(t, rawdb.WriteBlock(tx, mismatching))
+	require.NoError(t, rawdb.WriteSenders(tx, mismatching.Hash(), 4, senders))
+	require.NoError(t, rawdb.WriteCanonicalHash(tx, mismatching.Hash(), 4))
+
+	s, err = node.Sync.StageState(syncstages.PostExec, tx, node.DB)
+	require.NoError(t, err)
+	require.Equal(t, uint64(3), s.BlockNumber)
+	u := &unwindRecorder{}
+	require.NoError(t, stagedsync.SpawnPostExecStage(s, u, tx, cfg, node.Ctx))
+	require.Equal(t, uint64(3), u.unwindPoint)
+	require.Equal(t, mismatching.Hash(), u.badBlock)
+	// the epoch blocks are checked once the range is recorded
+	require.EqualValues(t, 6*len(nodes)+3, blocksRecorded(""))
+	require.EqualValues(t, 6, blocksRecorded("_reorged"), "the activity of the range isn't kept")
+}
+
+// unwindRecorder records the unwind a stage asks for.
+type unwindRecorder struct {
+	unwindPoint uint64
+	badBlock    common.Hash
+}
+
+func (u *unwindRecorder) UnwindTo(unwindPoint uint64, badBlock common.Hash) {
+	u.unwindPoint, u.badBlock = unwindPoint, badBlock
+}
@@ -0,0 +1,246 @@
+package receiptsdownload
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ledgerwatch/erigon/common"
+	"github.com/ledgerwatch/erigon/core/types"
+)
+
+// MaxReceiptsInRequest is the maximum number of blocks which receipts are requested at once.
+const MaxReceiptsInRequest = 256
+
+var (
+	// ErrUnrequestedReceipts is returned for a response to a request which is unknown, e.g. timed out.
+	ErrUnrequestedReceipts = errors.New("unrequested receipts")
+	// ErrInvalidReceipts is returned for receipts not matching the ReceiptHash of their block header.
+	ErrInvalidReceipts = errors.New("invalid receipts")
+)
+
+// ReceiptsRequest is a request for the receipts of the blocks of the given hashes.
+type ReceiptsRequest struct {
+	RequestID uint64
+	BlockNums []uint64
+	Hashes    []common.Hash
+}
+
+// Delivery is a validated list of receipts of a block.
+type Delivery struct {
+	Header   *types.Header
+	Receipts types.Receipts
+}
+
+type pendingRequest struct {
+	hashes   []common.Hash
+	deadline time.Time
+	peerID   [64]byte
+}
+
+// ReceiptsDownload keeps track of the receipts downloaded from the peers for the
+// scheduled blocks. A response is matched to its request by the request id, as the
+// eth protocol carries no block hash in it, and the receipts of each block are
+// validated against the ReceiptHash of the header before they are delivered. The peers
+// which let a request time out or answer it with no receipts are reported, so that the
+// next requests go to other peers.
+type ReceiptsDownload struct {
+	lock           sync.Mutex
+	DeliveryNotify chan struct{}
+	timeout        time.Duration
+	wanted         map[common.Hash]*types.Header // scheduled blocks, until their receipts are delivered
+	requested      map[common.Hash]uint64        // request id of the scheduled blocks being requested
+	pending        map[uint64]*pendingRequest
+	deliveries     []Delivery
+	failedPeers    [][64]byte
+}
+
+// NewReceiptsDownload creates a ReceiptsDownload which requests the receipts again
+// when they are not received within the timeout.
+func NewReceiptsDownload(timeout time.Duration) *ReceiptsDownload {
+	return &ReceiptsDownload{
+		DeliveryNotify: make(chan struct{}, 1),
+		timeout:        timeout,
+		wanted:         map[common.Hash]*types.Header{},
+		requested:      map[common.Hash]uint64{},
+		pending:        map[uint64]*pendingRequest{},
+	}
+}
+
+// Schedule adds blocks which receipts are to be downloaded.
+func (rd *ReceiptsDownload) Schedule(headers ...*types.Header) {
+	rd.lock.Lock()
+	defer rd.lock.Unlock()
+	for _, header := range headers {
+		rd.wanted[header.Hash()] = header
+	}
+}
+
+// Outstanding returns the number of scheduled blocks which receipts are not delivered yet.
+func (rd *ReceiptsDownload) Outstanding() int {
+	rd.lock.Lock()
+	defer rd.lock.Unlock()
+	return len(rd.wanted)
+}
+
+// RequestMoreReceipts returns a request for the lowest scheduled blocks not being
+// requested, including the ones which requests timed out, nil if there is none.
+func (rd *ReceiptsDownload) RequestMoreReceipts(now time.Time) *ReceiptsRequest {
+	rd.lock.Lock()
+	defer rd.lock.Unlock()
+	for id, req := range rd.pending {
+		if now.After(req.deadline) {
+			rd.fail(id)
+		}
+	}
+	var headers []*types.Header
+	for hash, header := range rd.wanted {
+		if _, ok := rd.requested[hash]; !ok {
+			headers = append(headers, header)
+		}
+	}
+	if len(headers) == 0 {
+		return nil
+	}
+	sort.Slice(headers, func(i, j int) bool { return headers[i].Number.Uint64() < headers[j].Number.Uint64() })
+	if len(headers) > MaxReceiptsInRequest {
+		headers = headers[:MaxReceiptsInRequest]
+	}
+	req := &ReceiptsRequest{RequestID: rand.Uint64()} // nolint: gosec
+	for _, header := range headers {
+		hash := header.Hash()
+		req.BlockNums = append(req.BlockNums, header.Number.Uint64())
+		req.Hashes = append(req.Hashes, hash)
+		rd.requested[hash] = req.RequestID
+	}
+	rd.pending[req.RequestID] = &pendingRequest{hashes: req.Hashes, deadline: now.Add(rd.timeout)}
+	return req
+}
+
+// RequestSent records the peer a request was sent to.
+func (rd *ReceiptsDownload) RequestSent(req *ReceiptsRequest, peerID [64]byte) {
+	rd.lock.Lock()
+	defer rd.lock.Unlock()
+	if pending, ok := rd.pending[req.RequestID]; ok {
+		pending.peerID = peerID
+	}
+}
+
+// CancelRequest makes the blocks of a request which couldn't be sent available to the next one.
+func (rd *ReceiptsDownload) CancelRequest(req *ReceiptsRequest) {
+	rd.lock.Lock()
+	defer rd.lock.Unlock()
+	rd.cancel(req.RequestID)
+}
+
+// fail cancels a request which the peer didn't answer, reporting the peer.
+func (rd *ReceiptsDownload) fail(requestID uint64) {
+	if req, ok := rd.pending[requestID]; ok && req.peerID != [64]byte{} {
+		rd.failedPeers = append(rd.failedPeers, req.peerID)
+	}
+	rd.cancel(requestID)
+}
+
+func (rd *ReceiptsDownload) cancel(requestID uint64) {
+	req, ok := rd.pending[requestID]
+	if !ok {
+		return
+	}
+	delete(rd.pending, requestID)
+	for _, hash := range req.hashes {
+		if rd.requested[hash] == requestID {
+			delete(rd.requested, hash)
+		}
+	}
+}
+
+// DeliverReceipts accepts the response to a request. The response may hold the receipts
+// of the first blocks of the request only, the others are requested again. Nothing is
+// accepted from a response which receipts don't match the ReceiptHash of their block.
+// The peer of an empty response, which doesn't have the receipts, is reported.
+func (rd *ReceiptsDownload) DeliverReceipts(requestID uint64, receipts [][]*types.Receipt) error {
+	rd.lock.Lock()
+	defer rd.lock.Unlock()
+	req, ok := rd.pending[requestID]
+	if !ok {
+		return ErrUnrequestedReceipts
+	}
+	if len(receipts) == 0 {
+		rd.fail(requestID)
+		return nil
+	}
+	if len(receipts) > len(req.hashes) {
+		rd.cancel(requestID)
+		return fmt.Errorf("%w: %d lists for %d blocks", ErrInvalidReceipts, len(receipts), len(req.hashes))
+	}
+	deliveries := make([]Delivery, 0, len(receipts))
+	for i, list := range receipts {
+		header, ok := rd.wanted[req.hashes[i]]
+		if !ok { // delivered by another peer meanwhile
+			continue
+		}
+		if hash := types.DeriveSha(types.Receipts(list)); hash != header.ReceiptHash {
+			rd.cancel(requestID)
+			return fmt.Errorf("%w: block %d has receipt hash %x, got %x", ErrInvalidReceipts, header.Number.Uint64(), header.ReceiptHash, hash)
+		}
+		deliveries = append(deliveries, Delivery{Header: header, Receipts: list})
+	}
+	rd.cancel(requestID)
+	for _, d := range deliveries {
+		delete(rd.wanted, d.Header.Hash())
+	}
+	rd.deliveries = append(rd.deliveries, deliveries...)
+	if len(deliveries) > 0 {
+		select {
+		case rd.DeliveryNotify <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// Deliveries returns the receipts delivered since the last call.
+func (rd *ReceiptsDownload) Deliveries() []Delivery {
+	rd.lock.Lock()
+	defer rd.lock.Unlock()
+	deliveries := rd.deliveries
+	rd.deliveries = nil
+	return deliveries
+}
+
+// FailedPeers returns the peers which failed a request since the last call.
+func (rd *ReceiptsDownload) FailedPeers() [][64]byte {
+	rd.lock.Lock()
+	defer rd.lock.Unlock()
+	peers := rd.failedPeers
+	rd.failedPeers = nil
+	return peers
+}
+
+// LowestOutstanding returns the lowest scheduled block which receipts are not delivered yet.
+func (rd *ReceiptsDownload) LowestOutstanding() (uint64, bool) {
+	rd.lock.Lock()
+	defer rd.lock.Unlock()
+	var lowest uint64
+	found := false
+	for _, header := range rd.wanted {
+		if num := header.Number.Uint64(); !found || num < lowest {
+			lowest, found = num, true
+		}
+	}
+	return lowest, found
+}
+
+// Reset forgets the scheduled blocks, the outstanding requests, the undelivered receipts and the failed peers.
+func (rd *ReceiptsDownload) Reset() {
+	rd.lock.Lock()
+	defer rd.lock.Unlock()
+	rd.wanted = map[common.Hash]*types.Header{}
+	rd.requested = map[common.Hash]uint64{}
+	rd.pending = map[uint64]*pendingRequest{}
+	rd.deliveries = nil
+	rd.failedPeers = nil
+}
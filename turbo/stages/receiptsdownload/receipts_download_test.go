@@ -0,0 +1,103 @@
+package receiptsdownload
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/ledgerwatch/erigon/core/types"
+	"github.com/stretchr/testify/require"
+)
+
+func testReceipts(n uint64) []*types.Receipt {
+	var receipts []*types.Receipt
+	for i := uint64(1); i <= n; i++ {
+		receipts = append(receipts, &types.Receipt{Status: types.ReceiptStatusSuccessful, CumulativeGasUsed: 21000 * i, Logs: []*types.Log{}})
+	}
+	return receipts
+}
+
+func testHeader(number uint64) *types.Header {
+	return &types.Header{Number: new(big.Int).SetUint64(number), Difficulty: big.NewInt(1), ReceiptHash: types.DeriveSha(types.Receipts(testReceipts(number)))}
+}
+
+func TestDeliverReceipts(t *testing.T) {
+	rd := NewReceiptsDownload(time.Minute)
+	rd.Schedule(testHeader(3), testHeader(1), testHeader(2))
+	require.Equal(t, 3, rd.Outstanding())
+
+	now := time.Now()
+	req := rd.RequestMoreReceipts(now)
+	require.Equal(t, []uint64{1, 2, 3}, req.BlockNums)
+	require.Nil(t, rd.RequestMoreReceipts(now), "all the blocks are requested")
+
+	require.ErrorIs(t, rd.DeliverReceipts(req.RequestID+1, nil), ErrUnrequestedReceipts)
+	// the response holds the receipts of the first blocks only
+	require.NoError(t, rd.DeliverReceipts(req.RequestID, [][]*types.Receipt{testReceipts(1), testReceipts(2)}))
+	<-rd.DeliveryNotify
+	deliveries := rd.Deliveries()
+	require.Len(t, deliveries, 2)
+	require.Equal(t, uint64(2), deliveries[1].Header.Number.Uint64())
+	require.Len(t, deliveries[1].Receipts, 2)
+	require.Equal(t, 1, rd.Outstanding())
+	require.ErrorIs(t, rd.DeliverReceipts(req.RequestID, nil), ErrUnrequestedReceipts, "a request is answered once")
+
+	req = rd.RequestMoreReceipts(now)
+	require.Equal(t, []uint64{3}, req.BlockNums)
+	require.ErrorIs(t, rd.DeliverReceipts(req.RequestID, [][]*types.Receipt{testReceipts(2)}), ErrInvalidReceipts)
+	require.Empty(t, rd.Deliveries())
+
+	// the invalid response made the block available to the next request
+	req = rd.RequestMoreReceipts(now)
+	require.Equal(t, []uint64{3}, req.BlockNums)
+	require.NoError(t, rd.DeliverReceipts(req.RequestID, [][]*types.Receipt{testReceipts(3)}))
+	require.Len(t, rd.Deliveries(), 1)
+	require.Equal(t, 0, rd.Outstanding())
+}
+
+func TestRequestMoreReceiptsAfterTimeout(t *testing.T) {
+	rd := NewReceiptsDownload(time.Minute)
+	rd.Schedule(testHeader(1))
+
+	now := time.Now()
+	req := rd.RequestMoreReceipts(now)
+	require.NotNil(t, req)
+	require.Nil(t, rd.RequestMoreReceipts(now.Add(30*time.Second)))
+
+	retry := rd.RequestMoreReceipts(now.Add(2 * time.Minute))
+	require.NotNil(t, retry)
+	require.Equal(t, req.Hashes, retry.Hashes)
+	require.ErrorIs(t, rd.DeliverReceipts(req.RequestID, [][]*types.Receipt{testReceipts(1)}), ErrUnrequestedReceipts, "the request timed out")
+
+	rd.CancelRequest(retry)
+	require.NotNil(t, rd.RequestMoreReceipts(now.Add(2*time.Minute)), "the request couldn't be sent")
+}
+
+func TestFailedPeers(t *testing.T) {
+	rd := NewReceiptsDownload(time.Minute)
+	rd.Schedule(testHeader(2), testHeader(1))
+	lowest, ok := rd.LowestOutstanding()
+	require.True(t, ok)
+	require.Equal(t, uint64(1), lowest)
+
+	now := time.Now()
+	peer1, peer2 := [64]byte{1}, [64]byte{2}
+	req := rd.RequestMoreReceipts(now)
+	rd.RequestSent(req, peer1)
+	require.NoError(t, rd.DeliverReceipts(req.RequestID, nil))
+	require.Equal(t, [][64]byte{peer1}, rd.FailedPeers(), "the peer answered with no receipts")
+	require.Empty(t, rd.FailedPeers())
+
+	req = rd.RequestMoreReceipts(now)
+	require.Equal(t, []uint64{1, 2}, req.BlockNums, "the blocks are requested again")
+	rd.RequestSent(req, peer2)
+	req = rd.RequestMoreReceipts(now.Add(2 * time.Minute))
+	require.NotNil(t, req)
+	require.Equal(t, [][64]byte{peer2}, rd.FailedPeers(), "the request of the peer timed out")
+
+	require.NoError(t, rd.DeliverReceipts(req.RequestID, [][]*types.Receipt{testReceipts(1)}))
+	require.Empty(t, rd.FailedPeers(), "a partial response is not a failure")
+	lowest, ok = rd.LowestOutstanding()
+	require.True(t, ok)
+	require.Equal(t, uint64(2), lowest)
+}
@@ -19,10 +19,12 @@ import (
 	"github.com/ledgerwatch/erigon/cmd/state/exec22"
 	"github.com/ledgerwatch/erigon/common"
 	"github.com/ledgerwatch/erigon/consensus/misc"
+	"github.com/ledgerwatch/erigon/consensus/parlia"
 	"github.com/ledgerwatch/erigon/core/rawdb"
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/eth/ethconfig"
+	"github.com/ledgerwatch/erigon/eth/protocols/diff"
 	"github.com/ledgerwatch/erigon/eth/stagedsync"
 	"github.com/ledgerwatch/erigon/eth/stagedsync/stages"
 	"github.com/ledgerwatch/erigon/node/nodecfg/datadir"
@@ -140,7 +142,7 @@ func StageLoopStep(
 		if err != nil {
 			return err
 		}
-		return nil
+		return stagedsync.NotifySyncProgress(notifications, tx)
 	}); err != nil {
 		return headBlockHash, err
 	}
@@ -208,6 +210,9 @@ func StageLoopStep(
 		updateHead(ctx, head, headHash, headTd256)
 	}
 	if notifications != nil {
+		if err = stagedsync.NotifySyncProgress(notifications, rotx); err != nil {
+			return headBlockHash, err
+		}
 		if notifications.Accumulator != nil {
 			header := rawdb.ReadCurrentHeader(rotx)
 			if header != nil {
@@ -350,6 +355,9 @@ func NewStagedSync(ctx context.Context,
 	headCh chan *types.Block,
 	txNums *exec22.TxNums, agg *state.Aggregator22,
 	forkValidator *engineapi.ForkValidator,
+	diffs *diff.Store,
+	tracesDB kv.RwDB,
+	traceBlock stagedsync.BlockTracer,
 ) (*stagedsync.Sync, error) {
 	dirs := cfg.Dirs
 	var blockReader services.FullBlockReader
@@ -359,6 +367,9 @@ func NewStagedSync(ctx context.Context,
 		blockReader = snapshotsync.NewBlockReader()
 	}
 	blockRetire := snapshotsync.NewBlockRetire(1, dirs.Tmp, snapshots, db, snapDownloader, notifications.Events)
+	if p, ok := controlServer.Engine.(*parlia.Parlia); ok {
+		blockRetire.SetParliaDB(p.DB)
+	}
 
 	// During Import we don't want other services like header requests, body requests etc. to be running.
 	// Hence we run it in the test mode.
@@ -426,13 +437,17 @@ func NewStagedSync(ctx context.Context,
 				cfg.Sync.ExecWorkerCount,
 				txNums,
 				agg,
+				diffs,
 			),
+			stagedsync.StageReceiptsDownloadCfg(db, controlServer.Rd, controlServer.SendReceiptsRequest, controlServer.Penalize, blockReader, cfg.Sync.ReceiptsDownload),
 			stagedsync.StageTranspileCfg(db, cfg.BatchSize, controlServer.ChainConfig),
+			stagedsync.StagePostExecCfg(db, nil, cfg.Prune, controlServer.ChainConfig, controlServer.Engine, blockReader),
 			stagedsync.StageHashStateCfg(db, dirs, cfg.HistoryV2, txNums, agg),
-			stagedsync.StageTrieCfg(db, true, true, false, dirs.Tmp, blockReader, controlServer.Hd, cfg.HistoryV2, txNums, agg),
+			stagedsync.StageTrieCfg(db, true, true, false, dirs.Tmp, blockReader, controlServer.Hd, diffs, cfg.HistoryV2, txNums, agg),
 			stagedsync.StageHistoryCfg(db, cfg.Prune, dirs.Tmp),
 			stagedsync.StageLogIndexCfg(db, cfg.Prune, dirs.Tmp),
 			stagedsync.StageCallTracesCfg(db, cfg.Prune, 0, dirs.Tmp),
+			stagedsync.StageTracesCfg(db, tracesDB, traceBlock, cfg.Prune, blockReader),
 			stagedsync.StageTxLookupCfg(db, cfg.Prune, dirs.Tmp, snapshots, isBor, sprint),
 			stagedsync.StageFinishCfg(db, dirs.Tmp, headCh, forkValidator), runInTestMode),
 		stagedsync.DefaultUnwindOrder,
@@ -500,9 +515,10 @@ func NewInMemoryExecution(ctx context.Context, db kv.RwDB, cfg *ethconfig.Config
 				1,
 				txNums,
 				agg,
+				nil,
 			),
 			stagedsync.StageHashStateCfg(db, dirs, cfg.HistoryV2, txNums, agg),
-			stagedsync.StageTrieCfg(db, true, true, true, dirs.Tmp, blockReader, controlServer.Hd, cfg.HistoryV2, txNums, agg)),
+			stagedsync.StageTrieCfg(db, true, true, true, dirs.Tmp, blockReader, controlServer.Hd, nil, cfg.HistoryV2, txNums, agg)),
 		stagedsync.StateUnwindOrder,
 		nil,
 	), nil
@@ -19,6 +19,7 @@ import (
 	"github.com/ledgerwatch/erigon/core/types"
 	"github.com/ledgerwatch/erigon/core/vm"
 	"github.com/ledgerwatch/erigon/eth/tracers"
+	_ "github.com/ledgerwatch/erigon/eth/tracers/native" // register the native tracers
 	"github.com/ledgerwatch/erigon/params"
 )
 
@@ -95,6 +96,7 @@ func TraceTx(
 		tracer vm.Tracer
 		err    error
 	)
+	var namedTracer tracers.Tracer
 	var streaming bool
 	switch {
 	case config != nil && config.Tracer != nil:
@@ -106,18 +108,19 @@ func TraceTx(
 				return err
 			}
 		}
-		// Construct the JavaScript tracer to execute with
-		if tracer, err = tracers.New(*config.Tracer, &tracers.Context{
+		// Construct the native or JavaScript tracer to execute with
+		if namedTracer, err = tracers.New(*config.Tracer, &tracers.Context{
 			TxHash: txCtx.TxHash,
-		}); err != nil {
+		}, config.TracerConfig); err != nil {
 			stream.WriteNil()
 			return err
 		}
+		tracer = namedTracer
 		// Handle timeouts and RPC cancellations
 		deadlineCtx, cancel := context.WithTimeout(ctx, timeout)
 		go func() {
 			<-deadlineCtx.Done()
-			tracer.(*tracers.Tracer).Stop(errors.New("execution timeout"))
+			namedTracer.Stop(errors.New("execution timeout"))
 		}()
 		defer cancel()
 		streaming = false
@@ -168,7 +171,7 @@ func TraceTx(
 		stream.WriteString(returnVal)
 		stream.WriteObjectEnd()
 	} else {
-		if r, err1 := tracer.(*tracers.Tracer).GetResult(); err1 == nil {
+		if r, err1 := namedTracer.GetResult(); err1 == nil {
 			stream.Write(r)
 		} else {
 			return err1
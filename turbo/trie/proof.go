@@ -4,9 +4,33 @@ import (
 	"bytes"
 	"fmt"
 
+	"github.com/ledgerwatch/erigon-lib/kv"
 	"github.com/ledgerwatch/erigon/common"
 )
 
+// LoadTrieForProofs builds the state trie from the hashed state and the intermediate hashes
+// (TrieOfAccounts, TrieOfStorage) of the given transaction. Only the nodes on the paths to the
+// keys of `proofKeys` are constructed, the rest of the trie is represented by hash nodes, so
+// the resulting trie can be used to generate proofs with Prove.
+// `rd` decides which intermediate hashes can not be used by the loader, it must contain `proofKeys`.
+// Account keys are addrHash, storage keys are addrHash+incarnation+keyHash.
+func LoadTrieForProofs(logPrefix string, tx kv.Tx, rd RetainDeciderWithMarker, proofKeys RetainDecider, quit <-chan struct{}) (*Trie, error) {
+	loader := NewFlatDBTrieLoader(logPrefix)
+	if err := loader.Reset(rd, nil, nil, false); err != nil {
+		return nil, err
+	}
+	loader.defaultReceiver.retain = proofKeys
+	root, err := loader.CalcTrieRoot(tx, []byte{}, quit)
+	if err != nil {
+		return nil, err
+	}
+	t := New(root)
+	if loader.defaultReceiver.rootNode != nil {
+		t.root = loader.defaultReceiver.rootNode
+	}
+	return t, nil
+}
+
 // Prove constructs a merkle proof for key. The result contains all encoded nodes
 // on the path to the value at key. The value itself is also included in the last
 // node and can be retrieved by verifying the proof.
@@ -75,13 +75,20 @@ func (rl *RetainList) AddKeyWithMarker(key []byte, marker bool) {
 		nibbles[i*2] = b / 16
 		nibbles[i*2+1] = b % 16
 	}
-	rl.AddHex(nibbles)
-	rl.markers = append(rl.markers, marker)
+	rl.addHexWithMarker(nibbles, marker)
 }
 
 // AddHex adds a new key (in HEX encoding) to the list
 func (rl *RetainList) AddHex(hex []byte) {
+	rl.addHexWithMarker(hex, false)
+}
+
+// addHexWithMarker keeps the markers aligned with the keys, and the list sorted again
+// if keys are added after it was used
+func (rl *RetainList) addHexWithMarker(hex []byte, marker bool) {
 	rl.hexes = append(rl.hexes, hex)
+	rl.markers = append(rl.markers, marker)
+	rl.inited = false
 }
 
 // AddCodeTouch adds a new code touch into the resolve set
@@ -122,6 +122,11 @@ type RootHashAggregator struct {
 	a              accounts.Account
 	leafData       GenStructStepLeafData
 	accData        GenStructStepAccountData
+
+	// retain decides which trie nodes are constructed in memory (e.g. to build proofs), nil - only the root hash is calculated
+	retain    RetainDecider
+	retainBuf []byte
+	rootNode  node
 }
 
 type StreamReceiver interface {
@@ -327,6 +332,23 @@ func (r *RootHashAggregator) RetainNothing(_ []byte) bool {
 	return false
 }
 
+func (r *RootHashAggregator) retainAccount(prefix []byte) bool {
+	if r.retain == nil {
+		return false
+	}
+	return r.retain.Retain(prefix)
+}
+
+// retainStorage - prefix is relative to the storage trie of the current account, so prepend the account key with incarnation
+func (r *RootHashAggregator) retainStorage(prefix []byte) bool {
+	if r.retain == nil {
+		return false
+	}
+	hexutil.DecompressNibbles(r.currAccK, &r.retainBuf)
+	r.retainBuf = append(r.retainBuf, prefix...)
+	return r.retain.Retain(r.retainBuf)
+}
+
 func (r *RootHashAggregator) Reset(hc HashCollector2, shc StorageHashCollector2, trace bool) {
 	r.hc = hc
 	r.shc = shc
@@ -344,6 +366,7 @@ func (r *RootHashAggregator) Reset(hc HashCollector2, shc StorageHashCollector2,
 	r.valueStorage = nil
 	r.wasIHStorage = false
 	r.root = common.Hash{}
+	r.rootNode = nil
 	r.trace = trace
 	r.hb.trace = trace
 }
@@ -493,6 +516,9 @@ func (r *RootHashAggregator) Receive(itemType StreamItem,
 		}
 		if r.hb.hasRoot() {
 			r.root = r.hb.rootHash()
+			if r.retain != nil {
+				r.rootNode = r.hb.root()
+			}
 		} else {
 			r.root = EmptyRoot
 		}
@@ -563,7 +589,7 @@ func (r *RootHashAggregator) genStructStorage() error {
 		r.leafData.Value = rlphacks.RlpSerializableBytes(r.valueStorage)
 		data = &r.leafData
 	}
-	r.groupsStorage, r.hasTreeStorage, r.hasHashStorage, err = GenStructStep(r.RetainNothing, r.currStorage.Bytes(), r.succStorage.Bytes(), r.hb, func(keyHex []byte, hasState, hasTree, hasHash uint16, hashes, rootHash []byte) error {
+	r.groupsStorage, r.hasTreeStorage, r.hasHashStorage, err = GenStructStep(r.retainStorage, r.currStorage.Bytes(), r.succStorage.Bytes(), r.hb, func(keyHex []byte, hasState, hasTree, hasHash uint16, hashes, rootHash []byte) error {
 		if r.shc == nil {
 			return nil
 		}
@@ -631,7 +657,7 @@ func (r *RootHashAggregator) genStructAccount() error {
 	r.currStorage.Reset()
 	r.succStorage.Reset()
 	var err error
-	if r.groups, r.hasTree, r.hasHash, err = GenStructStep(r.RetainNothing, r.curr.Bytes(), r.succ.Bytes(), r.hb, func(keyHex []byte, hasState, hasTree, hasHash uint16, hashes, rootHash []byte) error {
+	if r.groups, r.hasTree, r.hasHash, err = GenStructStep(r.retainAccount, r.curr.Bytes(), r.succ.Bytes(), r.hb, func(keyHex []byte, hasState, hasTree, hasHash uint16, hashes, rootHash []byte) error {
 		if r.hc == nil {
 			return nil
 		}
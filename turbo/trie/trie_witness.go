@@ -53,3 +53,83 @@ func extractWitnessFromRootNode(root node, trace bool, retainDec RetainDecider)
 	}
 	return builder.Build(limiter)
 }
+
+// DeletionSiblings returns the hex prefixes of the hash nodes which have to be expanded to delete the
+// given keys (in KEY encoding) from the trie: a branch node left with a single child is replaced by a
+// short node, which requires to know whether that child is a short node itself. The nodes on the paths
+// to the keys must be loaded. Every child on the path to a key is assumed to be emptied by the deletions,
+// so a few more nodes than necessary can be returned.
+func (t *Trie) DeletionSiblings(keys [][]byte) [][]byte {
+	hexes := make([][]byte, len(keys))
+	for i, key := range keys {
+		hexes[i] = keybytesToHex(key)
+	}
+	return deletionSiblings(t.root, []byte{}, hexes, nil)
+}
+
+// deletionSiblings collects the siblings below nd, keys being the remainders of the hex keys after the prefix hex
+func deletionSiblings(nd node, hex []byte, keys [][]byte, siblings [][]byte) [][]byte {
+	switch n := nd.(type) {
+	case *shortNode:
+		var below [][]byte
+		for _, key := range keys {
+			if matchlen := prefixLen(key, n.Key); matchlen == len(n.Key) || n.Key[matchlen] == 16 {
+				below = append(below, key[matchlen:])
+			}
+		}
+		if len(below) == 0 {
+			return siblings
+		}
+		h := n.Key
+		// Remove terminator
+		if h[len(h)-1] == 16 {
+			h = h[:len(h)-1]
+		}
+		return deletionSiblings(n.Val, concat(hex, h...), below, siblings)
+	case *accountNode:
+		// the keys of the storage items go on after the account key, the terminator only is left for the account itself
+		var storageKeys [][]byte
+		for _, key := range keys {
+			if len(key) > 1 {
+				storageKeys = append(storageKeys, key)
+			}
+		}
+		if len(storageKeys) == 0 {
+			return siblings
+		}
+		return deletionSiblings(n.storage, hex, storageKeys, siblings)
+	case *duoNode:
+		var children [17]node
+		i1, i2 := n.childrenIdx()
+		children[i1], children[i2] = n.child1, n.child2
+		return branchDeletionSiblings(&children, hex, keys, siblings)
+	case *fullNode:
+		return branchDeletionSiblings(&n.Children, hex, keys, siblings)
+	default:
+		return siblings
+	}
+}
+
+func branchDeletionSiblings(children *[17]node, hex []byte, keys [][]byte, siblings [][]byte) [][]byte {
+	var below [16][][]byte
+	for _, key := range keys {
+		if len(key) > 0 && key[0] < 16 {
+			below[key[0]] = append(below[key[0]], key[1:])
+		}
+	}
+	// the children off the paths to the keys are the ones which can be left alone
+	var untouched int
+	for i := 0; i < 16; i++ {
+		if children[i] != nil && len(below[i]) == 0 {
+			untouched++
+		}
+	}
+	for i := 0; i < 16; i++ {
+		if len(below[i]) > 0 {
+			siblings = deletionSiblings(children[i], concat(hex, byte(i)), below[i], siblings)
+		} else if _, ok := children[i].(hashNode); ok && untouched == 1 {
+			siblings = append(siblings, concat(hex, byte(i)))
+		}
+	}
+	return siblings
+}
@@ -77,3 +77,29 @@ func TestBlockWitnessAccount(t *testing.T) {
 		t.Errorf("received account is not equal to the initial one")
 	}
 }
+
+func TestDeletionSiblings(t *testing.T) {
+	key1 := common.FromHex("0x1100000000000000000000000000000000000000000000000000000000000001")
+	key2 := common.FromHex("0x1200000000000000000000000000000000000000000000000000000000000002")
+	key3 := common.FromHex("0x2000000000000000000000000000000000000000000000000000000000000003")
+	tr := New(common.Hash{})
+	for _, key := range [][]byte{key1, key2, key3} {
+		tr.Update(key, []byte("a value long enough for the leaf to be hashed"))
+	}
+	tr.Hash()
+
+	// the leaves are always in the witness, the sibling is loaded
+	if siblings := tr.DeletionSiblings([][]byte{key1}); len(siblings) != 0 {
+		t.Fatalf("unexpected siblings: %x", siblings)
+	}
+	// the leaf of key2 is left alone under the branch node once key1 is deleted
+	tr.EvictNode([]byte{1, 2})
+	siblings := tr.DeletionSiblings([][]byte{key1})
+	if len(siblings) != 1 || !bytes.Equal(siblings[0], []byte{1, 2}) {
+		t.Fatalf("unexpected siblings: %x", siblings)
+	}
+	// the sibling of key3 is a branch node, which doesn't merge with the node above
+	if siblings = tr.DeletionSiblings([][]byte{key3}); len(siblings) != 0 {
+		t.Fatalf("unexpected siblings: %x", siblings)
+	}
+}